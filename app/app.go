@@ -10,6 +10,7 @@ import (
 	"github.com/kobradag/kobrad/infrastructure/config"
 	"github.com/kobradag/kobrad/infrastructure/db/database"
 	"github.com/kobradag/kobrad/infrastructure/db/database/ldb"
+	"github.com/kobradag/kobrad/infrastructure/db/database/pebbledb"
 	"github.com/kobradag/kobrad/infrastructure/logger"
 	"github.com/kobradag/kobrad/infrastructure/os/execenv"
 	"github.com/kobradag/kobrad/infrastructure/os/limits"
@@ -21,8 +22,9 @@ import (
 )
 
 const (
-	leveldbCacheSizeMiB = 256
-	defaultDataDirname  = "datadir2"
+	databaseCacheSizeMiB = 256
+	defaultDataDirname   = "datadir2"
+	pebbleDataDirname    = "datadir2-pebble"
 )
 
 var desiredLimits = &limits.DesiredLimits{
@@ -161,9 +163,24 @@ func (app *kobradApp) main(startedChan chan<- struct{}) error {
 	return nil
 }
 
-// dbPath returns the path to the block database given a database type.
+// databasePath returns the path to the block database of the configured database type.
 func databasePath(cfg *config.Config) string {
-	return filepath.Join(cfg.AppDir, defaultDataDirname)
+	return DatabasePath(cfg.AppDir, cfg.DbType)
+}
+
+// DatabasePath returns the path to the block database of the given database type
+// within the given (network specific) app directory. Every database type has its own
+// directory, so that a database is never opened by a backend that didn't create it.
+func DatabasePath(appDir string, dbType string) string {
+	if dbType == config.DbTypePebble {
+		return filepath.Join(appDir, pebbleDataDirname)
+	}
+	return filepath.Join(appDir, defaultDataDirname)
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
 func removeDatabase(cfg *config.Config) error {
@@ -174,13 +191,26 @@ func removeDatabase(cfg *config.Config) error {
 func openDB(cfg *config.Config) (database.Database, error) {
 	dbPath := databasePath(cfg)
 
+	if cfg.DbType == config.DbTypePebble && !pathExists(dbPath) &&
+		pathExists(DatabasePath(cfg.AppDir, config.DbTypeLevelDB)) {
+
+		log.Warnf("A leveldb database exists in '%s' but no %s database was found, so a new one is created. "+
+			"Use kobradbconvert to convert the existing database instead of syncing from scratch",
+			DatabasePath(cfg.AppDir, config.DbTypeLevelDB), cfg.DbType)
+	}
+
 	err := checkDatabaseVersion(dbPath)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("Loading database from '%s'", dbPath)
-	db, err := ldb.NewLevelDB(dbPath, leveldbCacheSizeMiB)
+	log.Infof("Loading %s database from '%s'", cfg.DbType, dbPath)
+	var db database.Database
+	if cfg.DbType == config.DbTypePebble {
+		db, err = pebbledb.NewPebbleDB(dbPath, databaseCacheSizeMiB)
+	} else {
+		db, err = ldb.NewLevelDB(dbPath, databaseCacheSizeMiB)
+	}
 	if err != nil {
 		return nil, err
 	}
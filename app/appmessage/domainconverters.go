@@ -460,6 +460,28 @@ func BlockWithTrustedDataToDomainBlockWithTrustedData(block *MsgBlockWithTrusted
 	}
 }
 
+// BlockWithTrustedDataV4ToDomainBlockWithTrustedData converts *MsgBlockWithTrustedDataV4, along with the
+// *MsgTrustedData its indices point into, to *externalapi.BlockWithTrustedData
+func BlockWithTrustedDataV4ToDomainBlockWithTrustedData(block *MsgBlockWithTrustedDataV4,
+	trustedData *MsgTrustedData) *externalapi.BlockWithTrustedData {
+
+	daaWindow := make([]*externalapi.TrustedDataDataDAAHeader, len(block.DAAWindowIndices))
+	for i, index := range block.DAAWindowIndices {
+		daaWindow[i] = TrustedDataDataDAABlockV4ToTrustedDataDataDAAHeader(trustedData.DAAWindow[index])
+	}
+
+	ghostdagData := make([]*externalapi.BlockGHOSTDAGDataHashPair, len(block.GHOSTDAGDataIndices))
+	for i, index := range block.GHOSTDAGDataIndices {
+		ghostdagData[i] = GHOSTDAGHashPairToDomainGHOSTDAGHashPair(trustedData.GHOSTDAGData[index])
+	}
+
+	return &externalapi.BlockWithTrustedData{
+		Block:        MsgBlockToDomainBlock(block.Block),
+		DAAWindow:    daaWindow,
+		GHOSTDAGData: ghostdagData,
+	}
+}
+
 // TrustedDataDataDAABlockV4ToTrustedDataDataDAAHeader converts *TrustedDataDAAHeader to *externalapi.TrustedDataDataDAAHeader
 func TrustedDataDataDAABlockV4ToTrustedDataDataDAAHeader(daaBlock *TrustedDataDAAHeader) *externalapi.TrustedDataDataDAAHeader {
 	return &externalapi.TrustedDataDataDAAHeader{
@@ -73,6 +73,9 @@ const (
 	CmdRequestIBDChainBlockLocator
 	CmdIBDChainBlockLocator
 	CmdRequestAnticone
+	CmdCompactBlock
+	CmdRequestBlockTransactions
+	CmdBlockTransactions
 
 	// rpc
 	CmdGetCurrentNetworkRequestMessage
@@ -163,6 +166,18 @@ const (
 	CmdGetMempoolEntriesByAddressesResponseMessage
 	CmdGetCoinSupplyRequestMessage
 	CmdGetCoinSupplyResponseMessage
+	CmdGetTransactionRequestMessage
+	CmdGetTransactionResponseMessage
+	CmdGetFeeEstimateRequestMessage
+	CmdGetFeeEstimateResponseMessage
+	CmdSaveMempoolRequestMessage
+	CmdSaveMempoolResponseMessage
+	CmdLoadMempoolRequestMessage
+	CmdLoadMempoolResponseMessage
+	CmdExportPruningPointSnapshotRequestMessage
+	CmdExportPruningPointSnapshotResponseMessage
+	CmdGetNetTotalsRequestMessage
+	CmdGetNetTotalsResponseMessage
 )
 
 // ProtocolMessageCommandToString maps all MessageCommands to their string representation
@@ -210,6 +225,9 @@ var ProtocolMessageCommandToString = map[MessageCommand]string{
 	CmdRequestIBDChainBlockLocator:                 "RequestIBDChainBlockLocator",
 	CmdIBDChainBlockLocator:                        "IBDChainBlockLocator",
 	CmdRequestAnticone:                             "RequestAnticone",
+	CmdCompactBlock:                                "CompactBlock",
+	CmdRequestBlockTransactions:                    "RequestBlockTransactions",
+	CmdBlockTransactions:                           "BlockTransactions",
 }
 
 // RPCMessageCommandToString maps all MessageCommands to their string representation
@@ -300,6 +318,18 @@ var RPCMessageCommandToString = map[MessageCommand]string{
 	CmdGetMempoolEntriesByAddressesResponseMessage:                "GetMempoolEntriesByAddressesResponse",
 	CmdGetCoinSupplyRequestMessage:                                "GetCoinSupplyRequest",
 	CmdGetCoinSupplyResponseMessage:                               "GetCoinSupplyResponse",
+	CmdGetTransactionRequestMessage:                               "GetTransactionRequest",
+	CmdGetTransactionResponseMessage:                              "GetTransactionResponse",
+	CmdGetFeeEstimateRequestMessage:                               "GetFeeEstimateRequest",
+	CmdGetFeeEstimateResponseMessage:                              "GetFeeEstimateResponse",
+	CmdSaveMempoolRequestMessage:                                  "SaveMempoolRequest",
+	CmdSaveMempoolResponseMessage:                                 "SaveMempoolResponse",
+	CmdLoadMempoolRequestMessage:                                  "LoadMempoolRequest",
+	CmdLoadMempoolResponseMessage:                                 "LoadMempoolResponse",
+	CmdExportPruningPointSnapshotRequestMessage:                   "ExportPruningPointSnapshotRequest",
+	CmdExportPruningPointSnapshotResponseMessage:                  "ExportPruningPointSnapshotResponse",
+	CmdGetNetTotalsRequestMessage:                                 "GetNetTotalsRequest",
+	CmdGetNetTotalsResponseMessage:                                "GetNetTotalsResponse",
 }
 
 // Message is an interface that describes a kobra message. A type that
@@ -0,0 +1,35 @@
+package appmessage
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+// MsgBlockTransactions implements the Message interface and represents a kobra
+// BlockTransactions message. It is sent in response to a MsgRequestBlockTransactions,
+// with the requested transactions in the order in which they were requested.
+type MsgBlockTransactions struct {
+	baseMessage
+	BlockHash    *externalapi.DomainHash
+	Transactions []*MsgTx
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgBlockTransactions) Command() MessageCommand {
+	return CmdBlockTransactions
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver. This is part of the Message interface implementation.
+func (msg *MsgBlockTransactions) MaxPayloadLength(pver uint32) uint32 {
+	return MaxMessagePayload
+}
+
+// NewMsgBlockTransactions returns a new kobra BlockTransactions message that conforms to
+// the Message interface. See MsgBlockTransactions for details.
+func NewMsgBlockTransactions(blockHash *externalapi.DomainHash, transactions []*MsgTx) *MsgBlockTransactions {
+	return &MsgBlockTransactions{
+		BlockHash:    blockHash,
+		Transactions: transactions,
+	}
+}
@@ -0,0 +1,58 @@
+package appmessage
+
+// MsgCompactBlock implements the Message interface and represents a kobra
+// CompactBlock message. It is sent instead of a MsgBlock in response to a
+// MsgRequestRelayBlocks when the peer supports compact block relay.
+//
+// A compact block carries the header of the block and, instead of its
+// transactions, the short IDs of their transaction IDs, which the receiver
+// matches against the transactions in its mempool. Transactions the receiver
+// is unlikely to have, such as the coinbase, are sent in full as prefilled
+// transactions.
+type MsgCompactBlock struct {
+	baseMessage
+	Header *MsgBlockHeader
+
+	// ShortIDNonce is mixed into the short IDs so that they can't be
+	// precomputed to collide
+	ShortIDNonce uint64
+
+	// ShortIDs are the short IDs of the transactions that aren't prefilled,
+	// in the order in which they appear in the block
+	ShortIDs []uint64
+
+	// PrefilledTransactions are ordered by their index in the block
+	PrefilledTransactions []*PrefilledTransaction
+}
+
+// PrefilledTransaction is a transaction that is sent in full as part of a
+// compact block, along with its index in the block
+type PrefilledTransaction struct {
+	Index       uint32
+	Transaction *MsgTx
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgCompactBlock) Command() MessageCommand {
+	return CmdCompactBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver. This is part of the Message interface implementation.
+func (msg *MsgCompactBlock) MaxPayloadLength(pver uint32) uint32 {
+	return MaxMessagePayload
+}
+
+// NewMsgCompactBlock returns a new kobra CompactBlock message that conforms to
+// the Message interface. See MsgCompactBlock for details.
+func NewMsgCompactBlock(header *MsgBlockHeader, shortIDNonce uint64, shortIDs []uint64,
+	prefilledTransactions []*PrefilledTransaction) *MsgCompactBlock {
+
+	return &MsgCompactBlock{
+		Header:                header,
+		ShortIDNonce:          shortIDNonce,
+		ShortIDs:              shortIDs,
+		PrefilledTransactions: prefilledTransactions,
+	}
+}
@@ -0,0 +1,31 @@
+package appmessage
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+// MsgRequestBlockTransactions implements the Message interface and represents a kobra
+// RequestBlockTransactions message. It is used to request the transactions of a compact
+// block that the receiver couldn't find in its mempool.
+type MsgRequestBlockTransactions struct {
+	baseMessage
+	BlockHash *externalapi.DomainHash
+
+	// Indexes are the indexes of the requested transactions in the block, in ascending order
+	Indexes []uint32
+}
+
+// Command returns the protocol command string for the message. This is part
+// of the Message interface implementation.
+func (msg *MsgRequestBlockTransactions) Command() MessageCommand {
+	return CmdRequestBlockTransactions
+}
+
+// NewMsgRequestBlockTransactions returns a new kobra RequestBlockTransactions message that conforms to
+// the Message interface. See MsgRequestBlockTransactions for details.
+func NewMsgRequestBlockTransactions(blockHash *externalapi.DomainHash, indexes []uint32) *MsgRequestBlockTransactions {
+	return &MsgRequestBlockTransactions{
+		BlockHash: blockHash,
+		Indexes:   indexes,
+	}
+}
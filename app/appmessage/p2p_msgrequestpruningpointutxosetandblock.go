@@ -8,6 +8,10 @@ import (
 type MsgRequestPruningPointUTXOSet struct {
 	baseMessage
 	PruningPointHash *externalapi.DomainHash
+
+	// FromOutpoint is the outpoint after which the UTXO set should be sent, or
+	// nil if it should be sent from the start
+	FromOutpoint *externalapi.DomainOutpoint
 }
 
 // Command returns the protocol command string for the message
@@ -16,8 +20,11 @@ func (msg *MsgRequestPruningPointUTXOSet) Command() MessageCommand {
 }
 
 // NewMsgRequestPruningPointUTXOSet returns a new MsgRequestPruningPointUTXOSet
-func NewMsgRequestPruningPointUTXOSet(pruningPointHash *externalapi.DomainHash) *MsgRequestPruningPointUTXOSet {
+func NewMsgRequestPruningPointUTXOSet(pruningPointHash *externalapi.DomainHash,
+	fromOutpoint *externalapi.DomainOutpoint) *MsgRequestPruningPointUTXOSet {
+
 	return &MsgRequestPruningPointUTXOSet{
 		PruningPointHash: pruningPointHash,
+		FromOutpoint:     fromOutpoint,
 	}
 }
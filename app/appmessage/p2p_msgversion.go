@@ -56,6 +56,9 @@ type MsgVersion struct {
 	// Don't announce transactions to peer.
 	DisableRelayTx bool
 
+	// Don't exchange transactions or addresses with the peer, only blocks.
+	BlockRelayOnly bool
+
 	// The subnetwork of the generator of the version message. Should be nil in full nodes
 	SubnetworkID *externalapi.DomainSubnetworkID
 }
@@ -95,6 +98,7 @@ func NewMsgVersion(addr *NetAddress, id *id.ID, network string,
 		ID:              id,
 		UserAgent:       DefaultUserAgent,
 		DisableRelayTx:  false,
+		BlockRelayOnly:  false,
 		SubnetworkID:    subnetworkID,
 	}
 }
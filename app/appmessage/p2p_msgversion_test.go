@@ -47,6 +47,10 @@ func TestVersion(t *testing.T) {
 		t.Errorf("NewMsgVersion: disable relay tx is not false by "+
 			"default - got %v, want %v", msg.DisableRelayTx, false)
 	}
+	if msg.BlockRelayOnly {
+		t.Errorf("NewMsgVersion: block relay only is not false by "+
+			"default - got %v, want %v", msg.BlockRelayOnly, false)
+	}
 
 	msg.AddUserAgent("myclient", "1.2.3", "optional", "comments")
 	customUserAgent := DefaultUserAgent + "myclient:1.2.3(optional; comments)/"
@@ -5,11 +5,35 @@
 package appmessage
 
 import (
+	"encoding/base32"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/kobradag/kobrad/util/mstime"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/sha3"
 )
 
+// OnionV3PublicKeySize is the size of the ed25519 public key that identifies a
+// Tor v3 onion service
+const OnionV3PublicKeySize = 32
+
+const (
+	onionV3Version        = 0x03
+	onionV3ChecksumSize   = 2
+	onionV3ChecksumPrefix = ".onion checksum"
+	onionSuffix           = ".onion"
+)
+
+// onionCatPrefix is the IPv6 prefix OnionCat uses for onion addresses
+// (fd87:d87e:eb43::/48). Onion v3 addresses are given an IP in this range,
+// derived from their public key, so that they can be keyed, grouped and
+// banned like any other address.
+var onionCatPrefix = []byte{0xfd, 0x87, 0xd8, 0x7e, 0xeb, 0x43}
+
+var onionV3Encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
 // NetAddress defines information about a peer on the network including the time
 // it was last seen, the services it supports, its IP address, and port.
 type NetAddress struct {
@@ -22,6 +46,26 @@ type NetAddress struct {
 	// Port the peer is using. This is encoded in big endian on the appmessage
 	// which differs from most everything else.
 	Port uint16
+
+	// OnionV3PublicKey is the public key of the peer's Tor v3 onion service,
+	// or nil if the peer isn't an onion service. The IP of an onion address is
+	// an OnionCat IP derived from this key.
+	OnionV3PublicKey []byte
+}
+
+// IsOnionV3 returns whether the address is a Tor v3 onion address
+func (na *NetAddress) IsOnionV3() bool {
+	return len(na.OnionV3PublicKey) == OnionV3PublicKeySize
+}
+
+// OnionV3Host returns the host name of the onion service, in the form
+// "<56 base32 characters>.onion". It must only be called on onion addresses.
+func (na *NetAddress) OnionV3Host() string {
+	encoded := make([]byte, 0, OnionV3PublicKeySize+onionV3ChecksumSize+1)
+	encoded = append(encoded, na.OnionV3PublicKey...)
+	encoded = append(encoded, onionV3Checksum(na.OnionV3PublicKey)...)
+	encoded = append(encoded, onionV3Version)
+	return strings.ToLower(onionV3Encoding.EncodeToString(encoded)) + onionSuffix
 }
 
 // TCPAddress converts the NetAddress to *net.TCPAddr
@@ -53,12 +97,94 @@ func NewNetAddressTimestamp(
 	return &na
 }
 
+// NewNetAddressOnionV3 returns a new NetAddress for the Tor v3 onion service with
+// the provided public key and port.
+func NewNetAddressOnionV3(publicKey []byte, port uint16) *NetAddress {
+	na := NewNetAddressIPPort(onionV3IP(publicKey), port)
+	na.OnionV3PublicKey = publicKey
+	return na
+}
+
+// onionV3IP returns the OnionCat IP of the onion service with the provided public key.
+// Only the first 80 bits of the key fit in the IP, which is still too many to find
+// another key with the same IP.
+func onionV3IP(publicKey []byte) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, onionCatPrefix)
+	copy(ip[len(onionCatPrefix):], publicKey)
+	return ip
+}
+
+func onionV3Checksum(publicKey []byte) []byte {
+	hasher := sha3.New256()
+	hasher.Write([]byte(onionV3ChecksumPrefix))
+	hasher.Write(publicKey)
+	hasher.Write([]byte{onionV3Version})
+	return hasher.Sum(nil)[:onionV3ChecksumSize]
+}
+
+// ParseOnionV3Host parses the host name of a Tor v3 onion service and returns its
+// public key
+func ParseOnionV3Host(host string) ([]byte, error) {
+	if !strings.HasSuffix(host, onionSuffix) {
+		return nil, errors.Errorf("%s is not an onion address", host)
+	}
+	decoded, err := onionV3Encoding.DecodeString(strings.ToUpper(strings.TrimSuffix(host, onionSuffix)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is not a valid onion address", host)
+	}
+	if len(decoded) != OnionV3PublicKeySize+onionV3ChecksumSize+1 {
+		return nil, errors.Errorf("%s is not a v3 onion address", host)
+	}
+	publicKey := decoded[:OnionV3PublicKeySize]
+	checksum := decoded[OnionV3PublicKeySize : OnionV3PublicKeySize+onionV3ChecksumSize]
+	version := decoded[len(decoded)-1]
+	if version != onionV3Version {
+		return nil, errors.Errorf("%s has unsupported onion version %d", host, version)
+	}
+	if string(checksum) != string(onionV3Checksum(publicKey)) {
+		return nil, errors.Errorf("%s has an invalid checksum", host)
+	}
+	return publicKey, nil
+}
+
+// ParseNetAddress parses an address in the form host:port, where host is either an
+// IP or an onion v3 host name. Other host names are not resolved.
+func ParseNetAddress(address string) (*NetAddress, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid port in address %s", address)
+	}
+	if strings.HasSuffix(host, onionSuffix) {
+		publicKey, err := ParseOnionV3Host(host)
+		if err != nil {
+			return nil, err
+		}
+		return NewNetAddressOnionV3(publicKey, uint16(port)), nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, errors.Errorf("%s is neither an IP nor an onion address", host)
+	}
+	return NewNetAddressIPPort(ip, uint16(port)), nil
+}
+
 // NewNetAddress returns a new NetAddress using the provided TCP address and
 // supported services with defaults for the remaining fields.
 func NewNetAddress(addr *net.TCPAddr) *NetAddress {
 	return NewNetAddressIPPort(addr.IP, uint16(addr.Port))
 }
 
+// String returns the address in the form host:port. For onion addresses the host
+// is the onion host name rather than the OnionCat IP, so that it can be dialed
+// through a Tor proxy.
 func (na NetAddress) String() string {
+	if na.IsOnionV3() {
+		return net.JoinHostPort(na.OnionV3Host(), strconv.Itoa(int(na.Port)))
+	}
 	return na.TCPAddress().String()
 }
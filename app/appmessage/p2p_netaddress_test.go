@@ -26,3 +26,42 @@ func TestNetAddress(t *testing.T) {
 			port)
 	}
 }
+
+// TestOnionV3NetAddress tests parsing and formatting onion v3 addresses.
+func TestOnionV3NetAddress(t *testing.T) {
+	const host = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion"
+	address := host + ":16111"
+
+	na, err := ParseNetAddress(address)
+	if err != nil {
+		t.Fatalf("ParseNetAddress: %s", err)
+	}
+	if !na.IsOnionV3() {
+		t.Fatalf("ParseNetAddress: %s is not an onion address", na)
+	}
+	if na.String() != address {
+		t.Errorf("String: got %s, want %s", na.String(), address)
+	}
+	if !na.IP.Equal(net.ParseIP("fd87:d87e:eb43:1d04:a1d0:4a33:8c6e:6ae9")) {
+		t.Errorf("ParseNetAddress: unexpected OnionCat IP %s", na.IP)
+	}
+
+	// Changing a single character breaks the checksum
+	_, err = ParseOnionV3Host("euckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion")
+	if err == nil {
+		t.Errorf("ParseOnionV3Host: expected an error for an invalid checksum")
+	}
+	// v2 onion addresses are too short
+	_, err = ParseOnionV3Host("expyuzz4wqqyqhjn.onion")
+	if err == nil {
+		t.Errorf("ParseOnionV3Host: expected an error for a v2 onion address")
+	}
+
+	na, err = ParseNetAddress("127.0.0.1:16111")
+	if err != nil {
+		t.Fatalf("ParseNetAddress: %s", err)
+	}
+	if na.IsOnionV3() || na.String() != "127.0.0.1:16111" {
+		t.Errorf("ParseNetAddress: unexpected address %s", na)
+	}
+}
@@ -0,0 +1,49 @@
+package appmessage
+
+// ExportPruningPointSnapshotRequestMessage is an appmessage corresponding to
+// its respective RPC message
+type ExportPruningPointSnapshotRequestMessage struct {
+	baseMessage
+	Path string
+}
+
+// Command returns the protocol command string for the message
+func (msg *ExportPruningPointSnapshotRequestMessage) Command() MessageCommand {
+	return CmdExportPruningPointSnapshotRequestMessage
+}
+
+// NewExportPruningPointSnapshotRequestMessage returns a instance of the message
+func NewExportPruningPointSnapshotRequestMessage(path string) *ExportPruningPointSnapshotRequestMessage {
+	return &ExportPruningPointSnapshotRequestMessage{
+		Path: path,
+	}
+}
+
+// ExportPruningPointSnapshotResponseMessage is an appmessage corresponding to
+// its respective RPC message
+type ExportPruningPointSnapshotResponseMessage struct {
+	baseMessage
+	PruningPointHash string
+	HeaderCount      uint64
+	BlockCount       uint64
+	UTXOCount        uint64
+
+	Error *RPCError
+}
+
+// Command returns the protocol command string for the message
+func (msg *ExportPruningPointSnapshotResponseMessage) Command() MessageCommand {
+	return CmdExportPruningPointSnapshotResponseMessage
+}
+
+// NewExportPruningPointSnapshotResponseMessage returns a instance of the message
+func NewExportPruningPointSnapshotResponseMessage(pruningPointHash string,
+	headerCount, blockCount, utxoCount uint64) *ExportPruningPointSnapshotResponseMessage {
+
+	return &ExportPruningPointSnapshotResponseMessage{
+		PruningPointHash: pruningPointHash,
+		HeaderCount:      headerCount,
+		BlockCount:       blockCount,
+		UTXOCount:        utxoCount,
+	}
+}
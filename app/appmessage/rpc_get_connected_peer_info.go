@@ -47,4 +47,12 @@ type GetConnectedPeerInfoMessage struct {
 	AdvertisedProtocolVersion uint32
 	TimeConnected             int64
 	IsIBDPeer                 bool
+	BanScore                  float64
+	Reputation                float64
+	BytesSent                 uint64
+	BytesReceived             uint64
+	BandwidthByMessageType    []*MessageTypeBandwidth
+	IsBlockRelayOnly          bool
+	IsEncrypted               bool
+	IdentityKey               string
 }
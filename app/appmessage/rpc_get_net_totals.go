@@ -0,0 +1,58 @@
+package appmessage
+
+// GetNetTotalsRequestMessage is an appmessage corresponding to
+// its respective RPC message
+type GetNetTotalsRequestMessage struct {
+	baseMessage
+}
+
+// Command returns the protocol command string for the message
+func (msg *GetNetTotalsRequestMessage) Command() MessageCommand {
+	return CmdGetNetTotalsRequestMessage
+}
+
+// NewGetNetTotalsRequestMessage returns a instance of the message
+func NewGetNetTotalsRequestMessage() *GetNetTotalsRequestMessage {
+	return &GetNetTotalsRequestMessage{}
+}
+
+// GetNetTotalsResponseMessage is an appmessage corresponding to
+// its respective RPC message
+type GetNetTotalsResponseMessage struct {
+	baseMessage
+	TotalBytesSent         uint64
+	TotalBytesReceived     uint64
+	MaxUploadRate          uint64
+	MaxPeerUploadRate      uint64
+	BandwidthByMessageType []*MessageTypeBandwidth
+
+	Error *RPCError
+}
+
+// MessageTypeBandwidth is the amount of bytes and messages of a single
+// message type that were sent and received
+type MessageTypeBandwidth struct {
+	Command          string
+	BytesSent        uint64
+	BytesReceived    uint64
+	MessagesSent     uint64
+	MessagesReceived uint64
+}
+
+// Command returns the protocol command string for the message
+func (msg *GetNetTotalsResponseMessage) Command() MessageCommand {
+	return CmdGetNetTotalsResponseMessage
+}
+
+// NewGetNetTotalsResponseMessage returns a instance of the message
+func NewGetNetTotalsResponseMessage(totalBytesSent, totalBytesReceived, maxUploadRate, maxPeerUploadRate uint64,
+	bandwidthByMessageType []*MessageTypeBandwidth) *GetNetTotalsResponseMessage {
+
+	return &GetNetTotalsResponseMessage{
+		TotalBytesSent:         totalBytesSent,
+		TotalBytesReceived:     totalBytesReceived,
+		MaxUploadRate:          maxUploadRate,
+		MaxPeerUploadRate:      maxPeerUploadRate,
+		BandwidthByMessageType: bandwidthByMessageType,
+	}
+}
@@ -4,8 +4,9 @@ package appmessage
 // its respective RPC message
 type SubmitTransactionRequestMessage struct {
 	baseMessage
-	Transaction *RPCTransaction
-	AllowOrphan bool
+	Transaction      *RPCTransaction
+	AllowOrphan      bool
+	AllowReplacement bool
 }
 
 // Command returns the protocol command string for the message
@@ -25,7 +26,8 @@ func NewSubmitTransactionRequestMessage(transaction *RPCTransaction, allowOrphan
 // its respective RPC message
 type SubmitTransactionResponseMessage struct {
 	baseMessage
-	TransactionID string
+	TransactionID          string
+	ReplacedTransactionIDs []string
 
 	Error *RPCError
 }
@@ -7,11 +7,14 @@ import (
 	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 
 	"github.com/kobradag/kobrad/domain/miningmanager/mempool"
+	miningmanagermodel "github.com/kobradag/kobrad/domain/miningmanager/model"
 
 	"github.com/kobradag/kobrad/app/protocol"
 	"github.com/kobradag/kobrad/app/rpc"
+	"github.com/kobradag/kobrad/app/snapshot"
 	"github.com/kobradag/kobrad/domain"
 	"github.com/kobradag/kobrad/domain/consensus"
+	"github.com/kobradag/kobrad/domain/txindex"
 	"github.com/kobradag/kobrad/domain/utxoindex"
 	"github.com/kobradag/kobrad/infrastructure/config"
 	infrastructuredatabase "github.com/kobradag/kobrad/infrastructure/db/database"
@@ -19,7 +22,9 @@ import (
 	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/id"
+	"github.com/kobradag/kobrad/infrastructure/network/torcontrol"
 	"github.com/kobradag/kobrad/util/panics"
+	"github.com/pkg/errors"
 )
 
 // ComponentManager is a wrapper for all the kobrad services
@@ -30,6 +35,7 @@ type ComponentManager struct {
 	rpcManager        *rpc.Manager
 	connectionManager *connmanager.ConnectionManager
 	netAdapter        *netadapter.NetAdapter
+	torController     *torcontrol.Controller
 
 	started, shutdown int32
 }
@@ -43,14 +49,47 @@ func (a *ComponentManager) Start() {
 
 	log.Trace("Starting kobrad")
 
+	if a.cfg.BootstrapFile != "" {
+		a.importBootstrapFile()
+	}
+
+	if !a.cfg.NoMempoolPersistence {
+		_, err := a.protocolManager.LoadMempool()
+		if err != nil {
+			log.Errorf("Error loading the mempool: %+v", err)
+		}
+	}
+
 	err := a.netAdapter.Start()
 	if err != nil {
 		panics.Exit(log, fmt.Sprintf("Error starting the net adapter: %+v", err))
 	}
 
+	if a.cfg.TorControl != "" {
+		err := a.startOnionService()
+		if err != nil {
+			log.Errorf("Error starting the onion service: %+v", err)
+		}
+	}
+
 	a.connectionManager.Start()
 }
 
+// importBootstrapFile imports the pruning point snapshot in the bootstrap file. This happens
+// before the net adapter starts, so the imported consensus is never raced by IBD.
+func (a *ComponentManager) importBootstrapFile() {
+	log.Infof("Importing the pruning point snapshot in %s", a.cfg.BootstrapFile)
+	summary, err := a.protocolManager.ImportPruningPointSnapshot(a.cfg.BootstrapFile)
+	if err != nil {
+		if errors.Is(err, snapshot.ErrPruningPointAlreadyKnown) {
+			log.Infof("Skipping the bootstrap file: %s", err)
+			return
+		}
+		panics.Exit(log, fmt.Sprintf("Error importing the bootstrap file: %+v", err))
+	}
+	log.Infof("Bootstrapped from the snapshot of pruning point %s", summary.PruningPointHash)
+}
+
 // Stop gracefully shuts down all the kobrad services.
 func (a *ComponentManager) Stop() {
 	// Make sure this only happens once.
@@ -61,13 +100,31 @@ func (a *ComponentManager) Stop() {
 
 	log.Warnf("kobrad shutting down")
 
+	// Anchors are saved before the connection manager disconnects from all the peers
+	savedAnchorCount, err := a.protocolManager.SaveAnchorPeers()
+	if err != nil {
+		log.Errorf("Error saving the anchor peers: %+v", err)
+	} else {
+		log.Infof("Saved %d anchor peers", savedAnchorCount)
+	}
+
 	a.connectionManager.Stop()
+	a.stopOnionService()
 
-	err := a.netAdapter.Stop()
+	err = a.netAdapter.Stop()
 	if err != nil {
 		log.Errorf("Error stopping the net adapter: %+v", err)
 	}
 
+	if !a.cfg.NoMempoolPersistence {
+		savedTransactionCount, err := a.protocolManager.SaveMempool()
+		if err != nil {
+			log.Errorf("Error saving the mempool: %+v", err)
+		} else {
+			log.Infof("Saved %d mempool transactions", savedTransactionCount)
+		}
+	}
+
 	a.protocolManager.Close()
 	close(a.protocolManager.Context().Domain().ConsensusEventsChannel())
 
@@ -87,6 +144,16 @@ func NewComponentManager(cfg *config.Config, db infrastructuredatabase.Database,
 	mempoolConfig := mempool.DefaultConfig(&consensusConfig.Params)
 	mempoolConfig.MaximumOrphanTransactionCount = cfg.MaxOrphanTxs
 	mempoolConfig.MinimumRelayTransactionFee = cfg.MinRelayTxFee
+	replaceByFeePolicy, err := mempool.ParseReplaceByFeePolicy(cfg.RBFPolicy)
+	if err != nil {
+		return nil, err
+	}
+	mempoolConfig.ReplaceByFeePolicy = replaceByFeePolicy
+	transactionSelectionMode, err := miningmanagermodel.ParseTransactionSelectionMode(cfg.TxSelection)
+	if err != nil {
+		return nil, err
+	}
+	mempoolConfig.TransactionSelectionMode = transactionSelectionMode
 
 	domain, err := domain.New(&consensusConfig, mempoolConfig, db)
 	if err != nil {
@@ -113,6 +180,16 @@ func NewComponentManager(cfg *config.Config, db infrastructuredatabase.Database,
 		log.Infof("UTXO index started")
 	}
 
+	var txIndex *txindex.TXIndex
+	if cfg.TXIndex {
+		txIndex, err = txindex.New(domain, db)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infof("TX index started")
+	}
+
 	connectionManager, err := connmanager.New(cfg, netAdapter, addressManager)
 	if err != nil {
 		return nil, err
@@ -121,7 +198,7 @@ func NewComponentManager(cfg *config.Config, db infrastructuredatabase.Database,
 	if err != nil {
 		return nil, err
 	}
-	rpcManager := setupRPC(cfg, domain, netAdapter, protocolManager, connectionManager, addressManager, utxoIndex, domain.ConsensusEventsChannel(), interrupt)
+	rpcManager := setupRPC(cfg, domain, netAdapter, protocolManager, connectionManager, addressManager, utxoIndex, txIndex, domain.ConsensusEventsChannel(), interrupt)
 
 	return &ComponentManager{
 		cfg:               cfg,
@@ -142,6 +219,7 @@ func setupRPC(
 	connectionManager *connmanager.ConnectionManager,
 	addressManager *addressmanager.AddressManager,
 	utxoIndex *utxoindex.UTXOIndex,
+	txIndex *txindex.TXIndex,
 	consensusEventsChan chan externalapi.ConsensusEvent,
 	shutDownChan chan<- struct{},
 ) *rpc.Manager {
@@ -154,6 +232,7 @@ func setupRPC(
 		connectionManager,
 		addressManager,
 		utxoIndex,
+		txIndex,
 		consensusEventsChan,
 		shutDownChan,
 	)
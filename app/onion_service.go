@@ -0,0 +1,96 @@
+package app
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/addressmanager"
+	"github.com/kobradag/kobrad/infrastructure/network/torcontrol"
+	"github.com/pkg/errors"
+)
+
+// onionServiceKeyFilename is the name of the file in the app directory that keeps the
+// private key of the onion service, so that its address stays the same across restarts
+const onionServiceKeyFilename = "onion_v3_private_key"
+
+// startOnionService creates an onion service through the Tor control port, which forwards
+// incoming connections over Tor to the first P2P listener, and advertises its address to
+// onion peers. The onion service is removed by Tor when the control connection is closed.
+func (a *ComponentManager) startOnionService() error {
+	target, port, err := onionServiceTarget(a.cfg.Listeners)
+	if err != nil {
+		return err
+	}
+
+	controller, err := torcontrol.Connect(a.cfg.TorControl, a.cfg.TorPassword)
+	if err != nil {
+		return err
+	}
+
+	keyPath := filepath.Join(a.cfg.AppDir, onionServiceKeyFilename)
+	savedKey, err := os.ReadFile(keyPath)
+	if err != nil && !os.IsNotExist(err) {
+		controller.Close()
+		return errors.Wrapf(err, "could not read the onion service key")
+	}
+	host, key, err := controller.AddOnion(strings.TrimSpace(string(savedKey)), port, target)
+	if err != nil {
+		controller.Close()
+		return err
+	}
+	a.torController = controller
+
+	if key != strings.TrimSpace(string(savedKey)) {
+		err = os.WriteFile(keyPath, []byte(key), 0600)
+		if err != nil {
+			return errors.Wrapf(err, "could not save the onion service key")
+		}
+	}
+
+	publicKey, err := appmessage.ParseOnionV3Host(host)
+	if err != nil {
+		return err
+	}
+	err = a.addressManager.AddLocalAddress(appmessage.NewNetAddressOnionV3(publicKey, port), addressmanager.OnionPrio)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Accepting incoming connections over Tor at %s",
+		net.JoinHostPort(host, strconv.Itoa(int(port))))
+	return nil
+}
+
+func (a *ComponentManager) stopOnionService() {
+	if a.torController == nil {
+		return
+	}
+	err := a.torController.Close()
+	if err != nil {
+		log.Errorf("Error closing the Tor control connection: %+v", err)
+	}
+}
+
+// onionServiceTarget returns the local address the onion service forwards to, which is
+// the first P2P listener, and its port, which is also the port of the onion service
+func onionServiceTarget(listeners []string) (target string, port uint16, err error) {
+	if len(listeners) == 0 {
+		return "", 0, errors.New("an onion service requires a P2P listener")
+	}
+	host, portString, err := net.SplitHostPort(listeners[0])
+	if err != nil {
+		return "", 0, err
+	}
+	parsedPort, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "invalid port in listener %s", listeners[0])
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, portString), uint16(parsedPort), nil
+}
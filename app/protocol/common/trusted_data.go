@@ -0,0 +1,103 @@
+package common
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+)
+
+// PruningPointAndItsAnticoneTrustedData is the trusted data that a node needs in order to
+// insert the pruning point and its anticone without their past
+type PruningPointAndItsAnticoneTrustedData struct {
+	// PointAndItsAnticone is the pruning point followed by its anticone
+	PointAndItsAnticone []*externalapi.DomainHash
+	// TrustedData holds the DAA window and GHOSTDAG data of all the blocks in PointAndItsAnticone
+	TrustedData *appmessage.MsgTrustedData
+
+	daaWindowIndexes    map[externalapi.DomainHash][]uint64
+	ghostdagDataIndexes map[externalapi.DomainHash][]uint64
+}
+
+// BuildPruningPointAndItsAnticoneTrustedData collects the trusted data of the current pruning point
+// and its anticone. Every DAA window block and GHOSTDAG data is included only once.
+func BuildPruningPointAndItsAnticoneTrustedData(consensus externalapi.Consensus, params *dagconfig.Params) (
+	*PruningPointAndItsAnticoneTrustedData, error) {
+
+	pointAndItsAnticone, err := consensus.PruningPointAndItsAnticone()
+	if err != nil {
+		return nil, err
+	}
+
+	windowSize := params.DifficultyAdjustmentWindowSize
+	daaWindowBlocks := make([]*externalapi.TrustedDataDataDAAHeader, 0, windowSize)
+	daaWindowHashesToIndex := make(map[externalapi.DomainHash]int, windowSize)
+	trustedDataDAABlockIndexes := make(map[externalapi.DomainHash][]uint64)
+
+	ghostdagData := make([]*externalapi.BlockGHOSTDAGDataHashPair, 0)
+	ghostdagDataHashToIndex := make(map[externalapi.DomainHash]int)
+	trustedDataGHOSTDAGDataIndexes := make(map[externalapi.DomainHash][]uint64)
+	for _, blockHash := range pointAndItsAnticone {
+		blockDAAWindowHashes, err := consensus.BlockDAAWindowHashes(blockHash)
+		if err != nil {
+			return nil, err
+		}
+
+		trustedDataDAABlockIndexes[*blockHash] = make([]uint64, 0, windowSize)
+		for i, daaBlockHash := range blockDAAWindowHashes {
+			index, exists := daaWindowHashesToIndex[*daaBlockHash]
+			if !exists {
+				trustedDataDataDAAHeader, err := consensus.TrustedDataDataDAAHeader(blockHash, daaBlockHash, uint64(i))
+				if err != nil {
+					return nil, err
+				}
+				daaWindowBlocks = append(daaWindowBlocks, trustedDataDataDAAHeader)
+				index = len(daaWindowBlocks) - 1
+				daaWindowHashesToIndex[*daaBlockHash] = index
+			}
+
+			trustedDataDAABlockIndexes[*blockHash] = append(trustedDataDAABlockIndexes[*blockHash], uint64(index))
+		}
+
+		ghostdagDataBlockHashes, err := consensus.TrustedBlockAssociatedGHOSTDAGDataBlockHashes(blockHash)
+		if err != nil {
+			return nil, err
+		}
+
+		trustedDataGHOSTDAGDataIndexes[*blockHash] = make([]uint64, 0, params.K)
+		for _, ghostdagDataBlockHash := range ghostdagDataBlockHashes {
+			index, exists := ghostdagDataHashToIndex[*ghostdagDataBlockHash]
+			if !exists {
+				data, err := consensus.TrustedGHOSTDAGData(ghostdagDataBlockHash)
+				if err != nil {
+					return nil, err
+				}
+				ghostdagData = append(ghostdagData, &externalapi.BlockGHOSTDAGDataHashPair{
+					Hash:         ghostdagDataBlockHash,
+					GHOSTDAGData: data,
+				})
+				index = len(ghostdagData) - 1
+				ghostdagDataHashToIndex[*ghostdagDataBlockHash] = index
+			}
+
+			trustedDataGHOSTDAGDataIndexes[*blockHash] = append(trustedDataGHOSTDAGDataIndexes[*blockHash], uint64(index))
+		}
+	}
+
+	return &PruningPointAndItsAnticoneTrustedData{
+		PointAndItsAnticone: pointAndItsAnticone,
+		TrustedData:         appmessage.DomainTrustedDataToTrustedData(daaWindowBlocks, ghostdagData),
+		daaWindowIndexes:    trustedDataDAABlockIndexes,
+		ghostdagDataIndexes: trustedDataGHOSTDAGDataIndexes,
+	}, nil
+}
+
+// BlockWithTrustedData returns the given block of PointAndItsAnticone along with the indexes
+// of its DAA window and GHOSTDAG data in TrustedData
+func (d *PruningPointAndItsAnticoneTrustedData) BlockWithTrustedData(
+	block *externalapi.DomainBlock) *appmessage.MsgBlockWithTrustedDataV4 {
+
+	blockHash := consensushashing.BlockHash(block)
+	return appmessage.DomainBlockWithTrustedDataToBlockWithTrustedDataV4(
+		block, d.daaWindowIndexes[*blockHash], d.ghostdagDataIndexes[*blockHash])
+}
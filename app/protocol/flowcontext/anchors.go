@@ -0,0 +1,56 @@
+package flowcontext
+
+import (
+	"sort"
+	"time"
+
+	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
+	"github.com/kobradag/kobrad/infrastructure/network/addressmanager"
+)
+
+// minAnchorConnectionDuration is the minimum time an outbound peer should have been
+// connected in order to be saved as an anchor
+const minAnchorConnectionDuration = 30 * time.Minute
+
+// maxAnchorBanScore is the maximum ban score an outbound peer may have in order to be
+// saved as an anchor. It's lower than the points of any misbehavior, so only peers that
+// haven't misbehaved recently are saved.
+const maxAnchorBanScore = 1
+
+// SaveAnchorPeers saves the long-lived and well-behaved outbound peers as anchors, which
+// are reconnected to first on the next startup. Block-relay-only peers are preferred, since
+// they're harder for an attacker to learn about, and then peers with a higher reputation.
+func (f *FlowContext) SaveAnchorPeers() (savedAnchorCount int, err error) {
+	var candidates []*peerpkg.Peer
+	for _, peer := range f.Peers() {
+		if !peer.IsOutbound() || peer.TimeConnected() < minAnchorConnectionDuration ||
+			peer.BanScore() >= maxAnchorBanScore {
+			continue
+		}
+		candidates = append(candidates, peer)
+	}
+
+	reputations := make(map[*peerpkg.Peer]float64, len(candidates))
+	for _, candidate := range candidates {
+		reputations[candidate] = candidate.Reputation()
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].IsBlockRelayOnly() != candidates[j].IsBlockRelayOnly() {
+			return candidates[i].IsBlockRelayOnly()
+		}
+		return reputations[candidates[i]] > reputations[candidates[j]]
+	})
+
+	anchors := make([]*addressmanager.Anchor, len(candidates))
+	for i, candidate := range candidates {
+		anchors[i] = &addressmanager.Anchor{
+			NetAddress:       candidate.Connection().NetAddress(),
+			IsBlockRelayOnly: candidate.IsBlockRelayOnly(),
+		}
+	}
+	err = f.addressManager.SetAnchors(anchors)
+	if err != nil {
+		return 0, err
+	}
+	return len(f.addressManager.Anchors()), nil
+}
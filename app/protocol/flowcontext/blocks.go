@@ -148,6 +148,24 @@ func (f *FlowContext) TrySetIBDRunning(ibdPeer *peerpkg.Peer) bool {
 	return true
 }
 
+// InterruptedPruningPointUTXOSetDownload returns the pruning point whose UTXO set download was
+// interrupted and can be resumed from the staging consensus, or nil if there's no such download
+func (f *FlowContext) InterruptedPruningPointUTXOSetDownload() *externalapi.DomainHash {
+	f.ibdPeerMutex.RLock()
+	defer f.ibdPeerMutex.RUnlock()
+
+	return f.interruptedPruningPointUTXOSetDownload
+}
+
+// SetInterruptedPruningPointUTXOSetDownload sets the pruning point whose UTXO set download was
+// interrupted. A nil pruningPoint means there's no download to resume.
+func (f *FlowContext) SetInterruptedPruningPointUTXOSetDownload(pruningPoint *externalapi.DomainHash) {
+	f.ibdPeerMutex.Lock()
+	defer f.ibdPeerMutex.Unlock()
+
+	f.interruptedPruningPointUTXOSetDownload = pruningPoint
+}
+
 // UnsetIBDRunning unsets isInIBD
 func (f *FlowContext) UnsetIBDRunning() {
 	f.ibdPeerMutex.Lock()
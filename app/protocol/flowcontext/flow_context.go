@@ -52,6 +52,11 @@ type FlowContext struct {
 	ibdPeer      *peerpkg.Peer
 	ibdPeerMutex sync.RWMutex
 
+	// The pruning point whose UTXO set download was interrupted, and is kept in the
+	// staging consensus so that it could be resumed. Guarded by ibdPeerMutex.
+	// It's kept in memory only, since the staging consensus is deleted on startup.
+	interruptedPruningPointUTXOSetDownload *externalapi.DomainHash
+
 	peers      map[id.ID]*peerpkg.Peer
 	peersMutex sync.RWMutex
 
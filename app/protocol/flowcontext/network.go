@@ -4,6 +4,7 @@ import (
 	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/app/protocol/common"
 	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
+	"github.com/kobradag/kobrad/infrastructure/network/addressmanager"
 	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
 	"github.com/pkg/errors"
@@ -33,12 +34,20 @@ func (f *FlowContext) AddToPeers(peer *peerpkg.Peer) error {
 	return nil
 }
 
-// RemoveFromPeers remove this peer from the peers list.
+// RemoveFromPeers remove this peer from the peers list. The reputation of outbound
+// peers is recorded in the address manager, so that reputable peers are preferred
+// for future outgoing connections.
 func (f *FlowContext) RemoveFromPeers(peer *peerpkg.Peer) {
 	f.peersMutex.Lock()
-	defer f.peersMutex.Unlock()
-
 	delete(f.peers, *peer.ID())
+	f.peersMutex.Unlock()
+
+	if peer.IsOutbound() {
+		err := f.addressManager.SetReputation(peer.Connection().NetAddress(), peer.Reputation())
+		if err != nil && !errors.Is(err, addressmanager.ErrAddressNotFound) {
+			log.Warnf("Failed to record the reputation of %s: %s", peer, err)
+		}
+	}
 }
 
 // readyPeerConnections returns the NetConnections of all the ready peers.
@@ -54,6 +63,21 @@ func (f *FlowContext) readyPeerConnections() []*netadapter.NetConnection {
 	return peerConnections
 }
 
+// transactionRelayPeerConnections returns the NetConnections of all the ready peers
+// that transactions are relayed to, which excludes block-relay-only peers.
+func (f *FlowContext) transactionRelayPeerConnections() []*netadapter.NetConnection {
+	f.peersMutex.RLock()
+	defer f.peersMutex.RUnlock()
+	peerConnections := make([]*netadapter.NetConnection, 0, len(f.peers))
+	for _, peer := range f.peers {
+		if peer.IsBlockRelayOnly() {
+			continue
+		}
+		peerConnections = append(peerConnections, peer.Connection())
+	}
+	return peerConnections
+}
+
 // Broadcast broadcast the given message to all the ready peers.
 func (f *FlowContext) Broadcast(message appmessage.Message) error {
 	return f.netAdapter.P2PBroadcast(f.readyPeerConnections(), message)
@@ -15,8 +15,9 @@ import (
 // on: 2^orphanResolutionRange * PHANTOM K.
 const maxOrphans = 600
 
-// AddOrphan adds the block to the orphan set
-func (f *FlowContext) AddOrphan(orphanBlock *externalapi.DomainBlock) {
+// AddOrphan adds the block to the orphan set. It returns whether the orphan set
+// was full, in which case a random orphan was evicted to make room for the block.
+func (f *FlowContext) AddOrphan(orphanBlock *externalapi.DomainBlock) (wasFull bool) {
 	f.orphansMutex.Lock()
 	defer f.orphansMutex.Unlock()
 
@@ -26,9 +27,11 @@ func (f *FlowContext) AddOrphan(orphanBlock *externalapi.DomainBlock) {
 	if len(f.orphans) > maxOrphans {
 		log.Debugf("Orphan collection size exceeded. Evicting a random orphan")
 		f.evictRandomOrphan()
+		wasFull = true
 	}
 
 	log.Infof("Received a block with missing parents, adding to orphan pool: %s", orphanHash)
+	return wasFull
 }
 
 func (f *FlowContext) evictRandomOrphan() {
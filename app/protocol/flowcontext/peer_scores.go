@@ -0,0 +1,41 @@
+package flowcontext
+
+import (
+	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
+	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
+)
+
+// AddPeerMisbehavior adds the given misbehavior to the ban score of the given peer. Once
+// the ban score reaches the ban threshold it returns a protocol error, which the calling
+// flow should return in order to disconnect from the peer (and ban it, if banning is enabled).
+func (f *FlowContext) AddPeerMisbehavior(peer *peerpkg.Peer, misbehavior peerpkg.Misbehavior) error {
+	if f.IsWhitelisted(peer.Connection()) {
+		return nil
+	}
+
+	banScore := peer.AddMisbehavior(misbehavior)
+	if !f.HasReachedBanThreshold(banScore) {
+		return nil
+	}
+	return protocolerrors.Errorf(false, "ban score of %s reached %.1f (last misbehavior: %s)",
+		peer, banScore, misbehavior.Reason)
+}
+
+// HasReachedBanThreshold returns whether the given ban score is high enough
+// to disconnect from and ban a peer
+func (f *FlowContext) HasReachedBanThreshold(banScore float64) bool {
+	return banScore >= float64(f.cfg.BanThreshold)
+}
+
+// IsWhitelisted returns whether the IP of the given connection is whitelisted. Whitelisted
+// peers don't have their ban score increased.
+func (f *FlowContext) IsWhitelisted(netConnection *netadapter.NetConnection) bool {
+	ip := netConnection.NetAddress().IP
+	for _, whitelist := range f.cfg.Whitelists {
+		if whitelist.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package flowcontext
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/kobradag/kobrad/app/snapshot"
+	"github.com/pkg/errors"
+)
+
+// snapshotFilePath resolves the given relative path against the app directory. Absolute paths
+// and paths that lead outside of the app directory are rejected, since the path comes from RPC
+// clients.
+func snapshotFilePath(appDir string, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", errors.Errorf("snapshot path %s must be relative to the app directory", path)
+	}
+	relativePath := filepath.Clean(path)
+	if relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("snapshot path %s leads outside of the app directory", path)
+	}
+	return filepath.Join(appDir, relativePath), nil
+}
+
+// ExportPruningPointSnapshot writes a snapshot of the current pruning point and its UTXO set to the
+// file in the given path. The path must be relative to the app directory and stay inside it.
+func (f *FlowContext) ExportPruningPointSnapshot(path string) (*snapshot.Summary, error) {
+	filePath, err := snapshotFilePath(f.Config().AppDir, path)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Export(f.Domain().Consensus(), f.Config().NetParams(), filePath)
+}
+
+// ImportPruningPointSnapshot replaces the current consensus with the snapshot in the file in the given
+// path. The path is used as given, so relative paths are resolved against the working directory.
+func (f *FlowContext) ImportPruningPointSnapshot(path string) (*snapshot.Summary, error) {
+	summary, err := snapshot.Import(f.Domain(), f.Config().NetParams(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.OnPruningPointUTXOSetOverride()
+	if err != nil {
+		return nil, err
+	}
+	err = f.OnNewBlockTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
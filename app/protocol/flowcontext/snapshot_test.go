@@ -0,0 +1,46 @@
+package flowcontext
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotFilePath(t *testing.T) {
+	appDir := filepath.Join(t.TempDir(), "appdir")
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedPath string
+		expectsError bool
+	}{
+		{name: "file in the app directory", path: "snapshot.bin", expectedPath: filepath.Join(appDir, "snapshot.bin")},
+		{name: "file in a subdirectory", path: filepath.Join("snapshots", "snapshot.bin"),
+			expectedPath: filepath.Join(appDir, "snapshots", "snapshot.bin")},
+		{name: "parent directory that is cleaned away", path: filepath.Join("snapshots", "..", "snapshot.bin"),
+			expectedPath: filepath.Join(appDir, "snapshot.bin")},
+		{name: "file named with leading dots", path: "..snapshot.bin", expectedPath: filepath.Join(appDir, "..snapshot.bin")},
+		{name: "absolute path", path: filepath.Join(appDir, "snapshot.bin"), expectsError: true},
+		{name: "parent directory", path: "..", expectsError: true},
+		{name: "file outside of the app directory", path: filepath.Join("..", "snapshot.bin"), expectsError: true},
+		{name: "path that leaves the app directory after cleaning",
+			path: filepath.Join("snapshots", "..", "..", "snapshot.bin"), expectsError: true},
+	}
+
+	for _, test := range tests {
+		path, err := snapshotFilePath(appDir, test.path)
+		if test.expectsError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got path %s", test.name, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		if path != test.expectedPath {
+			t.Errorf("%s: expected path %s, got %s", test.name, test.expectedPath, path)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package flowcontext
 
 import (
+	"path/filepath"
 	"time"
 
 	"github.com/kobradag/kobrad/app/appmessage"
@@ -22,6 +23,53 @@ func (f *FlowContext) AddTransaction(tx *externalapi.DomainTransaction, allowOrp
 	return f.EnqueueTransactionIDsForPropagation(acceptedTransactionIDs)
 }
 
+// AddReplacementTransaction adds transaction to the mempool, replacing the mempool transactions
+// it conflicts with if it pays a higher fee, and propagates it. The replaced transactions are returned.
+func (f *FlowContext) AddReplacementTransaction(tx *externalapi.DomainTransaction, allowOrphan bool) (
+	replacedTransactions []*externalapi.DomainTransaction, err error) {
+
+	acceptedTransactions, replacedTransactions, err :=
+		f.Domain().MiningManager().ValidateAndReplaceTransaction(tx, true, allowOrphan)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptedTransactionIDs := consensushashing.TransactionIDs(acceptedTransactions)
+	err = f.EnqueueTransactionIDsForPropagation(acceptedTransactionIDs)
+	if err != nil {
+		return nil, err
+	}
+	return replacedTransactions, nil
+}
+
+// MempoolFileName is the name of the file in the app directory the mempool is saved to
+const MempoolFileName = "mempool.dat"
+
+func (f *FlowContext) mempoolFilePath() string {
+	return filepath.Join(f.Config().AppDir, MempoolFileName)
+}
+
+// SaveMempool saves all the transactions in the mempool to the mempool file in the app directory
+func (f *FlowContext) SaveMempool() (savedTransactionCount int, err error) {
+	return f.Domain().MiningManager().SaveMempool(f.mempoolFilePath())
+}
+
+// LoadMempool adds the transactions in the mempool file in the app directory to the
+// mempool, and propagates the accepted ones. It returns the number of accepted transactions.
+func (f *FlowContext) LoadMempool() (acceptedTransactionCount int, err error) {
+	acceptedTransactions, err := f.Domain().MiningManager().LoadMempool(f.mempoolFilePath())
+	if err != nil {
+		return 0, err
+	}
+
+	acceptedTransactionIDs := consensushashing.TransactionIDs(acceptedTransactions)
+	err = f.EnqueueTransactionIDsForPropagation(acceptedTransactionIDs)
+	if err != nil {
+		return 0, err
+	}
+	return len(acceptedTransactions), nil
+}
+
 func (f *FlowContext) shouldRebroadcastTransactions() bool {
 	const rebroadcastInterval = 30 * time.Second
 	return time.Since(f.lastRebroadcastTime) > rebroadcastInterval
@@ -67,7 +115,7 @@ func (f *FlowContext) maybePropagateTransactions() error {
 		log.Debugf("Transaction propagation: broadcasting %d transactions", len(transactionIDsToBroadcast))
 
 		inv := appmessage.NewMsgInvTransaction(transactionIDsToBroadcast)
-		err := f.Broadcast(inv)
+		err := f.netAdapter.P2PBroadcast(f.transactionRelayPeerConnections(), inv)
 		if err != nil {
 			return err
 		}
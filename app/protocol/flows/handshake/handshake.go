@@ -8,6 +8,7 @@ import (
 	"github.com/kobradag/kobrad/app/protocol/common"
 	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
 	"github.com/kobradag/kobrad/infrastructure/network/addressmanager"
+	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
 
 	"github.com/kobradag/kobrad/infrastructure/config"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
@@ -24,6 +25,7 @@ type HandleHandshakeContext interface {
 	NetAdapter() *netadapter.NetAdapter
 	Domain() domain.Domain
 	AddressManager() *addressmanager.AddressManager
+	ConnectionManager() *connmanager.ConnectionManager
 	AddToPeers(peer *peerpkg.Peer) error
 	HandleError(err error, flowName string, isStopping *uint32, errChan chan<- error)
 }
@@ -45,7 +47,7 @@ func HandleHandshake(context HandleHandshakeContext, netConnection *netadapter.N
 	isStopping := uint32(0)
 	errChan := make(chan error)
 
-	peer := peerpkg.New(netConnection)
+	peer := peerpkg.New(netConnection, context.ConnectionManager().PeerScore(netConnection))
 
 	var peerAddress *appmessage.NetAddress
 	spawn("HandleHandshake-ReceiveVersion", func() {
@@ -88,8 +90,9 @@ func HandleHandshake(context HandleHandshakeContext, netConnection *netadapter.N
 		return nil, err
 	}
 
-	if peerAddress != nil {
-		err := context.AddressManager().AddAddresses(peerAddress)
+	// Addresses aren't exchanged with block-relay-only peers, including their own
+	if peerAddress != nil && !peer.IsBlockRelayOnly() {
+		err := context.AddressManager().AddAddressesFromSource(netConnection.NetAddress(), peerAddress)
 		if err != nil {
 			return nil, err
 		}
@@ -73,6 +73,12 @@ func (flow *sendVersionFlow) start() error {
 	// Advertise if inv messages for transactions are desired.
 	msg.DisableRelayTx = flow.Config().BlocksOnly
 
+	// Ask not to exchange transactions and addresses on block-relay-only connections.
+	if flow.peer.Connection().IsBlockRelayOnly() {
+		msg.DisableRelayTx = true
+		msg.BlockRelayOnly = true
+	}
+
 	err := flow.outgoingRoute.Enqueue(msg)
 	if err != nil {
 		return err
@@ -0,0 +1,28 @@
+package addressexchange
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// SendNoAddresses handles address exchange with block-relay-only peers. Peers that don't
+// know about block-relay-only mode still request addresses and wait for an answer, so
+// their requests are answered with an empty address list, and any addresses they send
+// are ignored.
+func SendNoAddresses(incomingRoute *router.Route, outgoingRoute *router.Route) error {
+	for {
+		message, err := incomingRoute.Dequeue()
+		if err != nil {
+			return err
+		}
+
+		if _, ok := message.(*appmessage.MsgRequestAddresses); !ok {
+			continue
+		}
+
+		err = outgoingRoute.Enqueue(appmessage.NewMsgAddresses(nil))
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -3,6 +3,7 @@ package blockrelay
 import (
 	"testing"
 
+	"github.com/kobradag/kobrad/app/protocol/peer"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 )
 
@@ -15,3 +16,13 @@ func TestIBDBatchSizeLessThanRouteCapacity(t *testing.T) {
 			ibdBatchSize, router.DefaultMaxMessages)
 	}
 }
+
+func TestInFlightIBDBlockBodiesLessThanRouteCapacity(t *testing.T) {
+	// All the blocks of the block bodies requests that are in flight to a single peer
+	// are queued in its IBD route, so they must not exceed its capacity
+	inFlightBlocks := peer.MaxInFlightIBDBlockBodiesRequests * ibdBatchSize
+	if inFlightBlocks >= router.DefaultMaxMessages {
+		t.Fatalf("In-flight IBD block bodies (%d) must be fewer than router.DefaultMaxMessages (%d)",
+			inFlightBlocks, router.DefaultMaxMessages)
+	}
+}
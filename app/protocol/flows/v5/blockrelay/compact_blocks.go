@@ -0,0 +1,220 @@
+package blockrelay
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/consensus/utils/merkle"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Compact block relay: a block that's requested by a peer that supports it is sent as
+// its header along with short IDs of its transactions, which the peer matches against
+// the transactions in its mempool. Only the transactions the peer doesn't have are then
+// requested with a MsgRequestBlockTransactions. Since relayed blocks mostly consist of
+// transactions that were already relayed, this saves most of the bandwidth of block relay,
+// and lets blocks propagate faster.
+
+// compactBlockRelayProtocolVersion is the lowest protocol version that supports compact block relay
+const compactBlockRelayProtocolVersion = 6
+
+// shortIDMask keeps the lower 6 bytes of a short ID. 6 bytes are enough to make accidental
+// collisions between the transactions of a block and the mempool rare, and collisions
+// are detected by the hash merkle root of the block anyway.
+const shortIDMask = 1<<48 - 1
+
+// supportsCompactBlocks returns whether compact blocks can be relayed to and from a peer
+// that uses the given protocol version
+func supportsCompactBlocks(protocolVersion uint32) bool {
+	return protocolVersion >= compactBlockRelayProtocolVersion
+}
+
+// shortIDHasher computes the short IDs of the transactions of a single compact block.
+// Short IDs are keyed by the block hash and a random nonce, so that an attacker can't
+// create transactions whose short IDs collide in every block.
+type shortIDHasher struct {
+	key []byte
+}
+
+func newShortIDHasher(blockHash *externalapi.DomainHash, nonce uint64) *shortIDHasher {
+	key := make([]byte, externalapi.DomainHashSize+8)
+	copy(key, blockHash.ByteSlice())
+	binary.LittleEndian.PutUint64(key[externalapi.DomainHashSize:], nonce)
+	return &shortIDHasher{key: key}
+}
+
+func (h *shortIDHasher) shortID(transactionID *externalapi.DomainTransactionID) uint64 {
+	hasher, err := blake2b.New(8, h.key)
+	if err != nil {
+		panic(errors.Wrap(err, "this should never happen. The key is shorter than blake2b.Size"))
+	}
+	hasher.Write(transactionID.ByteSlice())
+	return binary.LittleEndian.Uint64(hasher.Sum(nil)) & shortIDMask
+}
+
+// buildCompactBlock returns the compact block of the given block. The coinbase transaction
+// is always prefilled, since it's never in the mempool of the receiver.
+func buildCompactBlock(block *externalapi.DomainBlock) (*appmessage.MsgCompactBlock, error) {
+	var nonceBytes [8]byte
+	_, err := rand.Read(nonceBytes[:])
+	if err != nil {
+		return nil, err
+	}
+	nonce := binary.LittleEndian.Uint64(nonceBytes[:])
+	hasher := newShortIDHasher(consensushashing.BlockHash(block), nonce)
+
+	var prefilledTransactions []*appmessage.PrefilledTransaction
+	shortIDs := make([]uint64, 0, len(block.Transactions))
+	for i, transaction := range block.Transactions {
+		if i == 0 {
+			prefilledTransactions = append(prefilledTransactions, &appmessage.PrefilledTransaction{
+				Index:       uint32(i),
+				Transaction: appmessage.DomainTransactionToMsgTx(transaction),
+			})
+			continue
+		}
+		shortIDs = append(shortIDs, hasher.shortID(consensushashing.TransactionID(transaction)))
+	}
+
+	header := appmessage.DomainBlockHeaderToBlockHeader(block.Header)
+	return appmessage.NewMsgCompactBlock(header, nonce, shortIDs, prefilledTransactions), nil
+}
+
+// reconstructCompactBlockTransactions fills the transactions of a compact block from its
+// prefilled transactions and from the given mempool transactions, which are aligned with the
+// short IDs of the compact block and are nil for short IDs that weren't matched. It returns
+// the transactions of the block, in which the ones that weren't found are nil, and the
+// indexes of these missing transactions.
+func reconstructCompactBlockTransactions(msgCompactBlock *appmessage.MsgCompactBlock,
+	mempoolTransactions []*externalapi.DomainTransaction) (
+	transactions []*externalapi.DomainTransaction, missingIndexes []uint32, err error) {
+
+	if len(mempoolTransactions) != len(msgCompactBlock.ShortIDs) {
+		return nil, nil, errors.Errorf("got %d mempool transactions for %d short IDs",
+			len(mempoolTransactions), len(msgCompactBlock.ShortIDs))
+	}
+
+	transactionCount := len(msgCompactBlock.ShortIDs) + len(msgCompactBlock.PrefilledTransactions)
+	transactions = make([]*externalapi.DomainTransaction, transactionCount)
+	for i, prefilledTransaction := range msgCompactBlock.PrefilledTransactions {
+		index := prefilledTransaction.Index
+		if int(index) >= transactionCount {
+			return nil, nil, errors.Errorf("prefilled transaction index %d is out of range of "+
+				"the %d transactions of the block", index, transactionCount)
+		}
+		if i > 0 && index <= msgCompactBlock.PrefilledTransactions[i-1].Index {
+			return nil, nil, errors.Errorf("prefilled transaction indexes are not in ascending order")
+		}
+		transactions[index] = appmessage.MsgTxToDomainTransaction(prefilledTransaction.Transaction)
+	}
+
+	shortIDIndex := 0
+	for i := range transactions {
+		if transactions[i] != nil {
+			continue
+		}
+		transaction := mempoolTransactions[shortIDIndex]
+		shortIDIndex++
+		if transaction == nil {
+			missingIndexes = append(missingIndexes, uint32(i))
+			continue
+		}
+		// Mempool transactions carry data that isn't part of the block, such as populated
+		// UTXO entries, so the block gets copies of them as they would've been received
+		transactions[i] = appmessage.MsgTxToDomainTransaction(appmessage.DomainTransactionToMsgTx(transaction))
+	}
+	return transactions, missingIndexes, nil
+}
+
+// receiveCompactBlock reconstructs the block of a compact block from the mempool, and
+// requests the transactions that are missing from it from the peer. If the reconstructed
+// block doesn't match its hash merkle root, which happens if a short ID collided with a
+// different mempool transaction, all the transactions that were taken from the mempool
+// are requested from the peer as well.
+func (flow *handleRelayInvsFlow) receiveCompactBlock(requestHash *externalapi.DomainHash,
+	msgCompactBlock *appmessage.MsgCompactBlock) (*externalapi.DomainBlock, error) {
+
+	header := appmessage.BlockHeaderToDomainBlockHeader(msgCompactBlock.Header)
+	blockHash := consensushashing.HeaderHash(header)
+	if !blockHash.Equal(requestHash) {
+		return nil, protocolerrors.Errorf(true, "got unrequested block %s", blockHash)
+	}
+
+	// A short ID that's shared by several mempool transactions is ambiguous, and the
+	// transaction is requested from the peer instead
+	hasher := newShortIDHasher(blockHash, msgCompactBlock.ShortIDNonce)
+	mempoolTransactions := flow.Domain().MiningManager().TransactionsByShortIDs(msgCompactBlock.ShortIDs, hasher.shortID)
+	transactions, missingIndexes, err := reconstructCompactBlockTransactions(msgCompactBlock, mempoolTransactions)
+	if err != nil {
+		return nil, protocolerrors.Wrapf(true, err, "got malformed compact block %s", blockHash)
+	}
+	log.Debugf("Reconstructed %d out of %d transactions of compact block %s from the mempool",
+		len(transactions)-len(missingIndexes), len(transactions), blockHash)
+
+	if len(missingIndexes) > 0 {
+		err := flow.requestBlockTransactions(blockHash, transactions, missingIndexes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !merkle.CalculateHashMerkleRoot(transactions).Equal(header.HashMerkleRoot()) {
+		log.Debugf("Compact block %s doesn't match its hash merkle root. Requesting all of its transactions",
+			blockHash)
+
+		prefilledIndexes := make(map[uint32]struct{}, len(msgCompactBlock.PrefilledTransactions))
+		for _, prefilledTransaction := range msgCompactBlock.PrefilledTransactions {
+			prefilledIndexes[prefilledTransaction.Index] = struct{}{}
+		}
+		indexes := make([]uint32, 0, len(msgCompactBlock.ShortIDs))
+		for i := range transactions {
+			if _, ok := prefilledIndexes[uint32(i)]; !ok {
+				indexes = append(indexes, uint32(i))
+			}
+		}
+		err := flow.requestBlockTransactions(blockHash, transactions, indexes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &externalapi.DomainBlock{Header: header, Transactions: transactions}, nil
+}
+
+// requestBlockTransactions requests the transactions in the given indexes of a block from
+// the peer, and sets them in transactions
+func (flow *handleRelayInvsFlow) requestBlockTransactions(blockHash *externalapi.DomainHash,
+	transactions []*externalapi.DomainTransaction, indexes []uint32) error {
+
+	err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestBlockTransactions(blockHash, indexes))
+	if err != nil {
+		return err
+	}
+
+	message, err := flow.readMessageQueueingInvs()
+	if err != nil {
+		return err
+	}
+	msgBlockTransactions, ok := message.(*appmessage.MsgBlockTransactions)
+	if !ok {
+		return protocolerrors.Errorf(true, "unexpected message %s", message.Command())
+	}
+	if !msgBlockTransactions.BlockHash.Equal(blockHash) {
+		return protocolerrors.Errorf(true, "got transactions of unrequested block %s",
+			msgBlockTransactions.BlockHash)
+	}
+	if len(msgBlockTransactions.Transactions) != len(indexes) {
+		return protocolerrors.Errorf(true, "got %d transactions of block %s instead of the %d requested",
+			len(msgBlockTransactions.Transactions), blockHash, len(indexes))
+	}
+
+	for i, index := range indexes {
+		transactions[index] = appmessage.MsgTxToDomainTransaction(msgBlockTransactions.Transactions[i])
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package blockrelay
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/protocol/flowcontext"
+	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/blockheader"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/consensus/utils/merkle"
+	"github.com/kobradag/kobrad/domain/consensus/utils/subnetworks"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+	"github.com/pkg/errors"
+)
+
+func testTransaction(subnetworkID externalapi.DomainSubnetworkID, index uint32) *externalapi.DomainTransaction {
+	return &externalapi.DomainTransaction{
+		Inputs: []*externalapi.DomainTransactionInput{{
+			PreviousOutpoint: externalapi.DomainOutpoint{Index: index},
+			SignatureScript:  []byte{byte(index)},
+		}},
+		Outputs: []*externalapi.DomainTransactionOutput{{
+			Value:           uint64(index),
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{}},
+		}},
+		SubnetworkID: subnetworkID,
+		Payload:      []byte{},
+	}
+}
+
+func testBlock(transactions []*externalapi.DomainTransaction) *externalapi.DomainBlock {
+	header := blockheader.NewImmutableBlockHeader(0, []externalapi.BlockLevelParents{},
+		merkle.CalculateHashMerkleRoot(transactions), &externalapi.DomainHash{}, &externalapi.DomainHash{},
+		0, 0, 0, 0, 0, big.NewInt(0), &externalapi.DomainHash{})
+	return &externalapi.DomainBlock{Header: header, Transactions: transactions}
+}
+
+// matchShortIDs returns the given mempool transactions that match the short IDs of the given
+// compact block, aligned with its short IDs, the way the mempool matches them
+func matchShortIDs(blockHash *externalapi.DomainHash, msgCompactBlock *appmessage.MsgCompactBlock,
+	mempoolTransactions []*externalapi.DomainTransaction) []*externalapi.DomainTransaction {
+
+	hasher := newShortIDHasher(blockHash, msgCompactBlock.ShortIDNonce)
+	matched := make([]*externalapi.DomainTransaction, len(msgCompactBlock.ShortIDs))
+	for i, shortID := range msgCompactBlock.ShortIDs {
+		for _, transaction := range mempoolTransactions {
+			if hasher.shortID(consensushashing.TransactionID(transaction)) == shortID {
+				matched[i] = transaction
+			}
+		}
+	}
+	return matched
+}
+
+func TestCompactBlockReconstruction(t *testing.T) {
+	transactions := []*externalapi.DomainTransaction{testTransaction(subnetworks.SubnetworkIDCoinbase, 0)}
+	for i := uint32(1); i <= 4; i++ {
+		transactions = append(transactions, testTransaction(subnetworks.SubnetworkIDNative, i))
+	}
+	block := testBlock(transactions)
+	blockHash := consensushashing.BlockHash(block)
+
+	msgCompactBlock, err := buildCompactBlock(block)
+	if err != nil {
+		t.Fatalf("buildCompactBlock: %s", err)
+	}
+	if len(msgCompactBlock.PrefilledTransactions) != 1 || msgCompactBlock.PrefilledTransactions[0].Index != 0 {
+		t.Fatalf("buildCompactBlock: expected only the coinbase to be prefilled")
+	}
+	if len(msgCompactBlock.ShortIDs) != 4 {
+		t.Fatalf("buildCompactBlock: expected 4 short IDs, got %d", len(msgCompactBlock.ShortIDs))
+	}
+
+	// The mempool has all but the third transaction, and an unrelated one
+	mempoolTransactions := []*externalapi.DomainTransaction{
+		transactions[4], transactions[1], transactions[2], testTransaction(subnetworks.SubnetworkIDNative, 5),
+	}
+	reconstructed, missingIndexes, err := reconstructCompactBlockTransactions(msgCompactBlock,
+		matchShortIDs(blockHash, msgCompactBlock, mempoolTransactions))
+	if err != nil {
+		t.Fatalf("reconstructCompactBlockTransactions: %s", err)
+	}
+	if len(missingIndexes) != 1 || missingIndexes[0] != 3 {
+		t.Fatalf("reconstructCompactBlockTransactions: expected transaction 3 to be missing, got %v",
+			missingIndexes)
+	}
+	for i, transaction := range reconstructed {
+		if i == 3 {
+			if transaction != nil {
+				t.Fatalf("reconstructCompactBlockTransactions: missing transaction is not nil")
+			}
+			continue
+		}
+		if !transaction.Equal(transactions[i]) {
+			t.Fatalf("reconstructCompactBlockTransactions: transaction %d is %v instead of %v",
+				i, transaction, transactions[i])
+		}
+	}
+
+	reconstructed[3] = transactions[3]
+	if !merkle.CalculateHashMerkleRoot(reconstructed).Equal(block.Header.HashMerkleRoot()) {
+		t.Fatalf("reconstructCompactBlockTransactions: reconstructed block doesn't match its hash merkle root")
+	}
+}
+
+func TestMalformedCompactBlock(t *testing.T) {
+	transactions := []*externalapi.DomainTransaction{
+		testTransaction(subnetworks.SubnetworkIDCoinbase, 0),
+		testTransaction(subnetworks.SubnetworkIDNative, 1),
+	}
+	block := testBlock(transactions)
+	msgCompactBlock, err := buildCompactBlock(block)
+	if err != nil {
+		t.Fatalf("buildCompactBlock: %s", err)
+	}
+
+	msgCompactBlock.PrefilledTransactions[0].Index = 2
+	_, _, err = reconstructCompactBlockTransactions(msgCompactBlock, make([]*externalapi.DomainTransaction, 1))
+	if err == nil {
+		t.Fatalf("reconstructCompactBlockTransactions: expected an error for an out of range prefilled index")
+	}
+}
+
+type fakeRelayInvsContext struct {
+	RelayInvsContext
+	sharedRequestedBlocks *flowcontext.SharedRequestedBlocks
+}
+
+func (f *fakeRelayInvsContext) SharedRequestedBlocks() *flowcontext.SharedRequestedBlocks {
+	return f.sharedRequestedBlocks
+}
+
+// TestUnexpectedMessageWhileRequestingBlock makes sure that a peer that answers a block request
+// with the wrong message type gets a banning protocol error, rather than an error that crashes the node
+func TestUnexpectedMessageWhileRequestingBlock(t *testing.T) {
+	newFlow := func() *handleRelayInvsFlow {
+		incomingRoute := router.NewRoute("incoming")
+		err := incomingRoute.Enqueue(appmessage.NewMsgPing(0))
+		if err != nil {
+			t.Fatalf("Enqueue: %s", err)
+		}
+		return &handleRelayInvsFlow{
+			RelayInvsContext: &fakeRelayInvsContext{sharedRequestedBlocks: flowcontext.NewSharedRequestedBlocks()},
+			incomingRoute:    incomingRoute,
+			outgoingRoute:    router.NewRoute("outgoing"),
+		}
+	}
+	checkBanningProtocolError := func(name string, err error) {
+		protocolErr := protocolerrors.ProtocolError{}
+		if err == nil || !errors.As(err, &protocolErr) || !protocolErr.ShouldBan {
+			t.Fatalf("%s: expected a banning protocol error, got %+v", name, err)
+		}
+	}
+
+	blockHash := &externalapi.DomainHash{}
+	_, _, err := newFlow().requestBlock(blockHash)
+	checkBanningProtocolError("requestBlock", err)
+
+	err = newFlow().requestBlockTransactions(blockHash, make([]*externalapi.DomainTransaction, 2), []uint32{1})
+	checkBanningProtocolError("requestBlockTransactions", err)
+}
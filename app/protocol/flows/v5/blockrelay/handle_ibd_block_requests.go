@@ -2,6 +2,7 @@ package blockrelay
 
 import (
 	"github.com/kobradag/kobrad/app/appmessage"
+	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
 	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
 	"github.com/kobradag/kobrad/domain"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
@@ -16,7 +17,7 @@ type HandleIBDBlockRequestsContext interface {
 // HandleIBDBlockRequests listens to appmessage.MsgRequestRelayBlocks messages and sends
 // their corresponding blocks to the requesting peer.
 func HandleIBDBlockRequests(context HandleIBDBlockRequestsContext, incomingRoute *router.Route,
-	outgoingRoute *router.Route) error {
+	outgoingRoute *router.Route, peer *peerpkg.Peer) error {
 
 	for {
 		message, err := incomingRoute.Dequeue()
@@ -40,6 +41,10 @@ func HandleIBDBlockRequests(context HandleIBDBlockRequestsContext, incomingRoute
 
 			blockMessage := appmessage.DomainBlockToMsgBlock(block)
 			ibdBlockMessage := appmessage.NewMsgIBDBlock(blockMessage)
+			err = peer.Connection().ReserveUploadBandwidth(ibdBlockMessage)
+			if err != nil {
+				return err
+			}
 			err = outgoingRoute.Enqueue(ibdBlockMessage)
 			if err != nil {
 				return err
@@ -4,10 +4,10 @@ import (
 	"sync/atomic"
 
 	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/protocol/common"
 	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
 	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
 	"github.com/kobradag/kobrad/domain"
-	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/kobradag/kobrad/infrastructure/config"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 )
@@ -54,72 +54,18 @@ func HandlePruningPointAndItsAnticoneRequests(context PruningPointAndItsAnticone
 				return err
 			}
 
-			pointAndItsAnticone, err := context.Domain().Consensus().PruningPointAndItsAnticone()
+			trustedData, err := common.BuildPruningPointAndItsAnticoneTrustedData(
+				context.Domain().Consensus(), context.Config().NetParams())
 			if err != nil {
 				return err
 			}
 
-			windowSize := context.Config().NetParams().DifficultyAdjustmentWindowSize
-			daaWindowBlocks := make([]*externalapi.TrustedDataDataDAAHeader, 0, windowSize)
-			daaWindowHashesToIndex := make(map[externalapi.DomainHash]int, windowSize)
-			trustedDataDAABlockIndexes := make(map[externalapi.DomainHash][]uint64)
-
-			ghostdagData := make([]*externalapi.BlockGHOSTDAGDataHashPair, 0)
-			ghostdagDataHashToIndex := make(map[externalapi.DomainHash]int)
-			trustedDataGHOSTDAGDataIndexes := make(map[externalapi.DomainHash][]uint64)
-			for _, blockHash := range pointAndItsAnticone {
-				blockDAAWindowHashes, err := context.Domain().Consensus().BlockDAAWindowHashes(blockHash)
-				if err != nil {
-					return err
-				}
-
-				trustedDataDAABlockIndexes[*blockHash] = make([]uint64, 0, windowSize)
-				for i, daaBlockHash := range blockDAAWindowHashes {
-					index, exists := daaWindowHashesToIndex[*daaBlockHash]
-					if !exists {
-						trustedDataDataDAAHeader, err := context.Domain().Consensus().TrustedDataDataDAAHeader(blockHash, daaBlockHash, uint64(i))
-						if err != nil {
-							return err
-						}
-						daaWindowBlocks = append(daaWindowBlocks, trustedDataDataDAAHeader)
-						index = len(daaWindowBlocks) - 1
-						daaWindowHashesToIndex[*daaBlockHash] = index
-					}
-
-					trustedDataDAABlockIndexes[*blockHash] = append(trustedDataDAABlockIndexes[*blockHash], uint64(index))
-				}
-
-				ghostdagDataBlockHashes, err := context.Domain().Consensus().TrustedBlockAssociatedGHOSTDAGDataBlockHashes(blockHash)
-				if err != nil {
-					return err
-				}
-
-				trustedDataGHOSTDAGDataIndexes[*blockHash] = make([]uint64, 0, context.Config().NetParams().K)
-				for _, ghostdagDataBlockHash := range ghostdagDataBlockHashes {
-					index, exists := ghostdagDataHashToIndex[*ghostdagDataBlockHash]
-					if !exists {
-						data, err := context.Domain().Consensus().TrustedGHOSTDAGData(ghostdagDataBlockHash)
-						if err != nil {
-							return err
-						}
-						ghostdagData = append(ghostdagData, &externalapi.BlockGHOSTDAGDataHashPair{
-							Hash:         ghostdagDataBlockHash,
-							GHOSTDAGData: data,
-						})
-						index = len(ghostdagData) - 1
-						ghostdagDataHashToIndex[*ghostdagDataBlockHash] = index
-					}
-
-					trustedDataGHOSTDAGDataIndexes[*blockHash] = append(trustedDataGHOSTDAGDataIndexes[*blockHash], uint64(index))
-				}
-			}
-
-			err = outgoingRoute.Enqueue(appmessage.DomainTrustedDataToTrustedData(daaWindowBlocks, ghostdagData))
+			err = outgoingRoute.Enqueue(trustedData.TrustedData)
 			if err != nil {
 				return err
 			}
 
-			for i, blockHash := range pointAndItsAnticone {
+			for i, blockHash := range trustedData.PointAndItsAnticone {
 				block, found, err := context.Domain().Consensus().GetBlock(blockHash)
 				if err != nil {
 					return err
@@ -129,7 +75,12 @@ func HandlePruningPointAndItsAnticoneRequests(context PruningPointAndItsAnticone
 					return protocolerrors.Errorf(false, "pruning point anticone block %s not found", blockHash)
 				}
 
-				err = outgoingRoute.Enqueue(appmessage.DomainBlockWithTrustedDataToBlockWithTrustedDataV4(block, trustedDataDAABlockIndexes[*blockHash], trustedDataGHOSTDAGDataIndexes[*blockHash]))
+				blockWithTrustedDataMessage := trustedData.BlockWithTrustedData(block)
+				err = peer.Connection().ReserveUploadBandwidth(blockWithTrustedDataMessage)
+				if err != nil {
+					return err
+				}
+				err = outgoingRoute.Enqueue(blockWithTrustedDataMessage)
 				if err != nil {
 					return err
 				}
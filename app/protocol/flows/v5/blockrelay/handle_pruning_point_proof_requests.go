@@ -30,6 +30,10 @@ func HandlePruningPointProofRequests(context PruningPointProofRequestsContext, i
 			return err
 		}
 		pruningPointProofMessage := appmessage.DomainPruningPointProofToMsgPruningPointProof(pruningPointProof)
+		err = peer.Connection().ReserveUploadBandwidth(pruningPointProofMessage)
+		if err != nil {
+			return err
+		}
 		err = outgoingRoute.Enqueue(pruningPointProofMessage)
 		if err != nil {
 			return err
@@ -15,7 +15,9 @@ type RelayBlockRequestsContext interface {
 }
 
 // HandleRelayBlockRequests listens to appmessage.MsgRequestRelayBlocks messages and sends
-// their corresponding blocks to the requesting peer.
+// their corresponding blocks to the requesting peer. Blocks are sent as compact blocks to
+// peers that support them, and the transactions these peers are missing are sent in response
+// to appmessage.MsgRequestBlockTransactions messages.
 func HandleRelayBlockRequests(context RelayBlockRequestsContext, incomingRoute *router.Route,
 	outgoingRoute *router.Route, peer *peerpkg.Peer) error {
 
@@ -24,26 +26,81 @@ func HandleRelayBlockRequests(context RelayBlockRequestsContext, incomingRoute *
 		if err != nil {
 			return err
 		}
-		getRelayBlocksMessage := message.(*appmessage.MsgRequestRelayBlocks)
-		log.Debugf("Got request for relay blocks with hashes %s", getRelayBlocksMessage.Hashes)
-		for _, hash := range getRelayBlocksMessage.Hashes {
-			// Fetch the block from the database.
-			block, found, err := context.Domain().Consensus().GetBlock(hash)
-			if err != nil {
-				return errors.Wrapf(err, "unable to fetch requested block hash %s", hash)
-			}
 
-			if !found {
-				return protocolerrors.Errorf(false, "Relay block %s not found", hash)
-			}
+		switch message := message.(type) {
+		case *appmessage.MsgRequestRelayBlocks:
+			err = sendRelayBlocks(context, outgoingRoute, peer, message)
+		case *appmessage.MsgRequestBlockTransactions:
+			err = sendBlockTransactions(context, outgoingRoute, message)
+		default:
+			err = errors.Errorf("unexpected message %s", message.Command())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func sendRelayBlocks(context RelayBlockRequestsContext, outgoingRoute *router.Route, peer *peerpkg.Peer,
+	getRelayBlocksMessage *appmessage.MsgRequestRelayBlocks) error {
+
+	log.Debugf("Got request for relay blocks with hashes %s", getRelayBlocksMessage.Hashes)
+	for _, hash := range getRelayBlocksMessage.Hashes {
+		// Fetch the block from the database.
+		block, found, err := context.Domain().Consensus().GetBlock(hash)
+		if err != nil {
+			return errors.Wrapf(err, "unable to fetch requested block hash %s", hash)
+		}
+
+		if !found {
+			return protocolerrors.Errorf(false, "Relay block %s not found", hash)
+		}
 
-			// TODO (Partial nodes): Convert block to partial block if needed
+		// TODO (Partial nodes): Convert block to partial block if needed
 
-			err = outgoingRoute.Enqueue(appmessage.DomainBlockToMsgBlock(block))
+		var message appmessage.Message = appmessage.DomainBlockToMsgBlock(block)
+		if supportsCompactBlocks(peer.ProtocolVersion()) {
+			message, err = buildCompactBlock(block)
 			if err != nil {
 				return err
 			}
-			log.Debugf("Relayed block with hash %s", hash)
 		}
+		err = outgoingRoute.Enqueue(message)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Relayed block with hash %s", hash)
+	}
+	return nil
+}
+
+func sendBlockTransactions(context RelayBlockRequestsContext, outgoingRoute *router.Route,
+	requestBlockTransactionsMessage *appmessage.MsgRequestBlockTransactions) error {
+
+	blockHash := requestBlockTransactionsMessage.BlockHash
+	log.Debugf("Got request for %d transactions of block %s",
+		len(requestBlockTransactionsMessage.Indexes), blockHash)
+
+	block, found, err := context.Domain().Consensus().GetBlock(blockHash)
+	if err != nil {
+		return errors.Wrapf(err, "unable to fetch requested block hash %s", blockHash)
+	}
+	if !found {
+		return protocolerrors.Errorf(false, "Relay block %s not found", blockHash)
+	}
+
+	transactions := make([]*appmessage.MsgTx, len(requestBlockTransactionsMessage.Indexes))
+	for i, index := range requestBlockTransactionsMessage.Indexes {
+		if int(index) >= len(block.Transactions) {
+			return protocolerrors.Errorf(true, "requested transaction %d of block %s, which has "+
+				"only %d transactions", index, blockHash, len(block.Transactions))
+		}
+		// Indexes must be ascending, so that a transaction can't be requested several times
+		if i > 0 && index <= requestBlockTransactionsMessage.Indexes[i-1] {
+			return protocolerrors.Errorf(true, "requested transactions of block %s are not in ascending order",
+				blockHash)
+		}
+		transactions[i] = appmessage.DomainTransactionToMsgTx(block.Transactions[index])
 	}
+	return outgoingRoute.Enqueue(appmessage.NewMsgBlockTransactions(blockHash, transactions))
 }
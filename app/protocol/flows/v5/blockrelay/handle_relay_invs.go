@@ -31,7 +31,8 @@ type RelayInvsContext interface {
 	OnPruningPointUTXOSetOverride() error
 	SharedRequestedBlocks() *flowcontext.SharedRequestedBlocks
 	Broadcast(message appmessage.Message) error
-	AddOrphan(orphanBlock *externalapi.DomainBlock)
+	AddOrphan(orphanBlock *externalapi.DomainBlock) (wasFull bool)
+	AddPeerMisbehavior(peer *peerpkg.Peer, misbehavior peerpkg.Misbehavior) error
 	GetOrphanRoots(orphanHash *externalapi.DomainHash) ([]*externalapi.DomainHash, bool, error)
 	IsOrphan(blockHash *externalapi.DomainHash) bool
 	IsIBDRunning() bool
@@ -236,6 +237,7 @@ func (flow *handleRelayInvsFlow) start() error {
 		}
 
 		log.Infof("Accepted block %s via relay", inv.Hash)
+		flow.peer.AddGoodBehavior(peerpkg.GoodBehaviorNewBlock)
 		err = flow.OnNewBlock(block)
 		if err != nil {
 			return err
@@ -269,6 +271,8 @@ func (flow *handleRelayInvsFlow) readInv() (invRelayBlock, error) {
 		return invRelayBlock{}, protocolerrors.Errorf(true, "unexpected %s message in the block relay handleRelayInvsFlow while "+
 			"expecting an inv message", msg.Command())
 	}
+	// The peer has the announced block, which is used to decide whether it can serve block bodies during IBD
+	flow.peer.SetLastRelayedBlockHash(msgInv.Hash)
 	return invRelayBlock{Hash: msgInv.Hash, IsOrphanRoot: false}, nil
 }
 
@@ -288,12 +292,26 @@ func (flow *handleRelayInvsFlow) requestBlock(requestHash *externalapi.DomainHas
 		return nil, false, err
 	}
 
-	msgBlock, err := flow.readMsgBlock()
+	message, err := flow.readMessageQueueingInvs()
 	if err != nil {
 		return nil, false, err
 	}
 
-	block := appmessage.MsgBlockToDomainBlock(msgBlock)
+	// Peers that support compact block relay send compact blocks, which are reconstructed
+	// from the mempool
+	var block *externalapi.DomainBlock
+	switch message := message.(type) {
+	case *appmessage.MsgBlock:
+		block = appmessage.MsgBlockToDomainBlock(message)
+	case *appmessage.MsgCompactBlock:
+		block, err = flow.receiveCompactBlock(requestHash, message)
+		if err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, protocolerrors.Errorf(true, "unexpected message %s", message.Command())
+	}
+
 	blockHash := consensushashing.BlockHash(block)
 	if !blockHash.Equal(requestHash) {
 		return nil, false, protocolerrors.Errorf(true, "got unrequested block %s", blockHash)
@@ -302,24 +320,20 @@ func (flow *handleRelayInvsFlow) requestBlock(requestHash *externalapi.DomainHas
 	return block, false, nil
 }
 
-// readMsgBlock returns the next msgBlock in msgChan, and populates invsQueue with any inv messages that meanwhile arrive.
-//
-// Note: this function assumes msgChan can contain only appmessage.MsgInvRelayBlock and appmessage.MsgBlock messages.
-func (flow *handleRelayInvsFlow) readMsgBlock() (msgBlock *appmessage.MsgBlock, err error) {
+// readMessageQueueingInvs returns the next message in msgChan that isn't an inv message, and populates
+// invsQueue with any inv messages that meanwhile arrive.
+func (flow *handleRelayInvsFlow) readMessageQueueingInvs() (appmessage.Message, error) {
 	for {
 		message, err := flow.incomingRoute.DequeueWithTimeout(common.DefaultTimeout)
 		if err != nil {
 			return nil, err
 		}
 
-		switch message := message.(type) {
-		case *appmessage.MsgInvRelayBlock:
-			flow.invsQueue = append(flow.invsQueue, invRelayBlock{Hash: message.Hash, IsOrphanRoot: false})
-		case *appmessage.MsgBlock:
+		inv, ok := message.(*appmessage.MsgInvRelayBlock)
+		if !ok {
 			return message, nil
-		default:
-			return nil, errors.Errorf("unexpected message %s", message.Command())
 		}
+		flow.invsQueue = append(flow.invsQueue, invRelayBlock{Hash: inv.Hash, IsOrphanRoot: false})
 	}
 }
 
@@ -379,7 +393,13 @@ func (flow *handleRelayInvsFlow) processOrphan(block *externalapi.DomainBlock) e
 
 		log.Debugf("Block %s is within orphan resolution range. "+
 			"Adding it to the orphan set", blockHash)
-		flow.AddOrphan(block)
+		wasOrphanPoolFull := flow.AddOrphan(block)
+		if wasOrphanPoolFull {
+			err := flow.AddPeerMisbehavior(flow.peer, peerpkg.MisbehaviorExcessiveOrphans)
+			if err != nil {
+				return err
+			}
+		}
 		log.Debugf("Requesting block %s missing ancestors", blockHash)
 		return flow.AddOrphanRootsToQueue(blockHash)
 	}
@@ -96,6 +96,10 @@ func (flow *handleRequestHeadersFlow) start() error {
 			}
 
 			blockHeadersMessage := appmessage.NewBlockHeadersMessage(blockHeaders)
+			err = flow.peer.Connection().ReserveUploadBandwidth(blockHeadersMessage)
+			if err != nil {
+				return err
+			}
 			err = flow.outgoingRoute.Enqueue(blockHeadersMessage)
 			if err != nil {
 				return err
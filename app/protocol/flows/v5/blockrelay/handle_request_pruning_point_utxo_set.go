@@ -5,9 +5,9 @@ import (
 
 	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/app/protocol/common"
+	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
 	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
 	"github.com/kobradag/kobrad/domain"
-	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/kobradag/kobrad/domain/consensus/ruleerrors"
 	"github.com/kobradag/kobrad/infrastructure/logger"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
@@ -21,17 +21,19 @@ type HandleRequestPruningPointUTXOSetContext interface {
 type handleRequestPruningPointUTXOSetFlow struct {
 	HandleRequestPruningPointUTXOSetContext
 	incomingRoute, outgoingRoute *router.Route
+	peer                         *peerpkg.Peer
 }
 
 // HandleRequestPruningPointUTXOSet listens to appmessage.MsgRequestPruningPointUTXOSet messages and sends
 // the pruning point UTXO set and block body.
 func HandleRequestPruningPointUTXOSet(context HandleRequestPruningPointUTXOSetContext, incomingRoute,
-	outgoingRoute *router.Route) error {
+	outgoingRoute *router.Route, peer *peerpkg.Peer) error {
 
 	flow := &handleRequestPruningPointUTXOSetFlow{
 		HandleRequestPruningPointUTXOSetContext: context,
 		incomingRoute:                           incomingRoute,
 		outgoingRoute:                           outgoingRoute,
+		peer:                                    peer,
 	}
 
 	return flow.start()
@@ -71,8 +73,8 @@ func (flow *handleRequestPruningPointUTXOSetFlow) waitForRequestPruningPointUTXO
 	}
 	msgRequestPruningPointUTXOSet, ok := message.(*appmessage.MsgRequestPruningPointUTXOSet)
 	if !ok {
-		// TODO: Change to shouldBan: true once we fix the bug of getting redundant messages
-		return nil, protocolerrors.Errorf(false, "received unexpected message type. "+
+		// Honest peers occasionally send redundant messages here, so this only adds to the ban score
+		return nil, protocolerrors.Wrapf(false, protocolerrors.ErrUnexpectedMessage, "received unexpected message type. "+
 			"expected: %s, got: %s", appmessage.CmdRequestPruningPointUTXOSet, message.Command())
 	}
 	return msgRequestPruningPointUTXOSet, nil
@@ -81,9 +83,11 @@ func (flow *handleRequestPruningPointUTXOSetFlow) waitForRequestPruningPointUTXO
 func (flow *handleRequestPruningPointUTXOSetFlow) sendPruningPointUTXOSet(
 	msgRequestPruningPointUTXOSet *appmessage.MsgRequestPruningPointUTXOSet) error {
 
-	// Send the UTXO set in `step`-sized chunks
+	// Send the UTXO set in `step`-sized chunks. GetPruningPointUTXOs returns the UTXOs after
+	// fromOutpoint, excluding it, so a peer that resumes an interrupted download gets the UTXOs
+	// right after the last one it imported
 	const step = 1000
-	var fromOutpoint *externalapi.DomainOutpoint
+	fromOutpoint := msgRequestPruningPointUTXOSet.FromOutpoint
 	chunksSent := 0
 	for {
 		pruningPointUTXOs, err := flow.Domain().Consensus().GetPruningPointUTXOs(
@@ -99,7 +103,12 @@ func (flow *handleRequestPruningPointUTXOSetFlow) sendPruningPointUTXOSet(
 
 		outpointAndUTXOEntryPairs :=
 			appmessage.DomainOutpointAndUTXOEntryPairsToOutpointAndUTXOEntryPairs(pruningPointUTXOs)
-		err = flow.outgoingRoute.Enqueue(appmessage.NewMsgPruningPointUTXOSetChunk(outpointAndUTXOEntryPairs))
+		chunkMessage := appmessage.NewMsgPruningPointUTXOSetChunk(outpointAndUTXOEntryPairs)
+		err = flow.peer.Connection().ReserveUploadBandwidth(chunkMessage)
+		if err != nil {
+			return err
+		}
+		err = flow.outgoingRoute.Enqueue(chunkMessage)
 		if err != nil {
 			return err
 		}
@@ -125,8 +134,8 @@ func (flow *handleRequestPruningPointUTXOSetFlow) sendPruningPointUTXOSet(
 			}
 			_, ok := message.(*appmessage.MsgRequestNextPruningPointUTXOSetChunk)
 			if !ok {
-				// TODO: Change to shouldBan: true once we fix the bug of getting redundant messages
-				return protocolerrors.Errorf(false, "received unexpected message type. "+
+				// Honest peers occasionally send redundant messages here, so this only adds to the ban score
+				return protocolerrors.Wrapf(false, protocolerrors.ErrUnexpectedMessage, "received unexpected message type. "+
 					"expected: %s, got: %s", appmessage.CmdRequestNextPruningPointUTXOSetChunk, message.Command())
 			}
 
@@ -29,6 +29,9 @@ type IBDContext interface {
 	TrySetIBDRunning(ibdPeer *peerpkg.Peer) bool
 	UnsetIBDRunning()
 	IsRecoverableError(err error) bool
+	Peers() []*peerpkg.Peer
+	InterruptedPruningPointUTXOSetDownload() *externalapi.DomainHash
+	SetInterruptedPruningPointUTXOSetDownload(pruningPoint *externalapi.DomainHash)
 }
 
 type handleIBDFlow struct {
@@ -52,14 +55,22 @@ func HandleIBD(context IBDContext, incomingRoute *router.Route, outgoingRoute *r
 
 func (flow *handleIBDFlow) start() error {
 	for {
-		// Wait for IBD requests triggered by other flows
-		block, ok := <-flow.peer.IBDRequestChannel()
-		if !ok {
-			return nil
-		}
-		err := flow.runIBDIfNotRunning(block)
-		if err != nil {
-			return err
+		// Wait for IBD requests triggered by other flows, and for requests to download
+		// block bodies on behalf of the flow that runs IBD with another peer
+		select {
+		case block, ok := <-flow.peer.IBDRequestChannel():
+			if !ok {
+				return nil
+			}
+			err := flow.runIBDIfNotRunning(block)
+			if err != nil {
+				return err
+			}
+		case request := <-flow.peer.IBDBlockBodiesRequestChannel():
+			err := flow.serveIBDBlockBodiesRequests(request, flow.peer.IBDBlockBodiesRequestChannel())
+			if err != nil {
+				return err
+			}
 		}
 	}
 }
@@ -106,6 +117,11 @@ func (flow *handleIBDFlow) runIBDIfNotRunning(block *externalapi.DomainBlock) er
 			return err
 		}
 	} else {
+		err = flow.discardInterruptedPruningPointUTXOSetDownload()
+		if err != nil {
+			return err
+		}
+
 		if flow.Config().NetParams().DisallowDirectBlocksOnTopOfGenesis && !flow.Config().AllowSubmitBlockWhenNotSynced {
 			isGenesisVirtualSelectedParent, err := flow.isGenesisVirtualSelectedParent()
 			if err != nil {
@@ -653,43 +669,10 @@ func (flow *handleIBDFlow) syncMissingBlockBodies(highHash *externalapi.DomainHa
 		return err
 	}
 
-	for offset := 0; offset < len(hashes); offset += ibdBatchSize {
-		var hashesToRequest []*externalapi.DomainHash
-		if offset+ibdBatchSize < len(hashes) {
-			hashesToRequest = hashes[offset : offset+ibdBatchSize]
-		} else {
-			hashesToRequest = hashes[offset:]
-		}
-
-		err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestIBDBlocks(hashesToRequest))
-		if err != nil {
-			return err
-		}
-
-		for _, expectedHash := range hashesToRequest {
-			message, err := flow.incomingRoute.DequeueWithTimeout(common.DefaultTimeout)
-			if err != nil {
-				return err
-			}
-
-			msgIBDBlock, ok := message.(*appmessage.MsgIBDBlock)
-			if !ok {
-				return protocolerrors.Errorf(true, "received unexpected message type. "+
-					"expected: %s, got: %s", appmessage.CmdIBDBlock, message.Command())
-			}
-
-			block := appmessage.MsgBlockToDomainBlock(msgIBDBlock.MsgBlock)
+	insertBatch := func(blocks []*externalapi.DomainBlock) error {
+		for _, block := range blocks {
 			blockHash := consensushashing.BlockHash(block)
-			if !expectedHash.Equal(blockHash) {
-				return protocolerrors.Errorf(true, "expected block %s but got %s", expectedHash, blockHash)
-			}
-
-			err = flow.banIfBlockIsHeaderOnly(block)
-			if err != nil {
-				return err
-			}
-
-			err = flow.Domain().Consensus().ValidateAndInsertBlock(block, updateVirtual)
+			err := flow.Domain().Consensus().ValidateAndInsertBlock(block, updateVirtual)
 			if err != nil {
 				if errors.Is(err, ruleerrors.ErrDuplicateBlock) {
 					log.Debugf("Skipping IBD Block %s as it has already been added to the DAG", blockHash)
@@ -705,7 +688,13 @@ func (flow *handleIBDFlow) syncMissingBlockBodies(highHash *externalapi.DomainHa
 			highestProcessedDAAScore = block.Header.DAAScore()
 		}
 
-		progressReporter.reportProgress(len(hashesToRequest), highestProcessedDAAScore)
+		progressReporter.reportProgress(len(blocks), highestProcessedDAAScore)
+		return nil
+	}
+
+	err = flow.downloadBlockBodies(highHash, hashes, insertBatch)
+	if err != nil {
+		return err
 	}
 
 	// We need to resolve virtual only if it wasn't updated while syncing block bodies
@@ -719,6 +708,186 @@ func (flow *handleIBDFlow) syncMissingBlockBodies(highHash *externalapi.DomainHa
 	return flow.OnNewBlockTemplate()
 }
 
+// downloadBlockBodies downloads the bodies of the given hashes from the syncer and from every other
+// peer that is known to have them, and passes them to insertBatch in order
+func (flow *handleIBDFlow) downloadBlockBodies(highHash *externalapi.DomainHash, hashes []*externalapi.DomainHash,
+	insertBatch func(blocks []*externalapi.DomainBlock) error) error {
+
+	// The requests assigned to the syncer are served by a separate goroutine, since this one is busy
+	// coordinating the download. It's the only user of the flow routes until the download ends.
+	syncerRequests := make(chan *peerpkg.IBDBlockBodiesRequest, peerpkg.MaxInFlightIBDBlockBodiesRequests)
+	syncerDone := make(chan struct{})
+	var syncerErr error
+	spawn("handleIBDFlow-downloadBlockBodies-syncer", func() {
+		defer close(syncerDone)
+		for request := range syncerRequests {
+			syncerErr = flow.serveIBDBlockBodiesRequests(request, syncerRequests)
+			if syncerErr != nil {
+				return
+			}
+		}
+	})
+
+	sources := []*blockBodiesSource{{name: flow.peer.String(), requests: syncerRequests}}
+	helperPeers, err := flow.blockBodiesHelperPeers(highHash)
+	if err != nil {
+		close(syncerRequests)
+		<-syncerDone
+		return err
+	}
+	for _, peer := range helperPeers {
+		sources = append(sources, &blockBodiesSource{name: peer.String(), requests: peer.IBDBlockBodiesRequestChannel()})
+	}
+	if len(helperPeers) > 0 {
+		log.Infof("Downloading %d block bodies from %d peers", len(hashes), len(sources))
+	}
+
+	err = newBlockBodiesDownloader(hashes, sources, common.DefaultTimeout, insertBatch).download()
+	close(syncerRequests)
+	<-syncerDone
+	if err != nil {
+		return err
+	}
+
+	// A syncer that stalled is disconnected, same as if it were the only source, but
+	// only after its blocks were downloaded from the other peers
+	return syncerErr
+}
+
+// blockBodiesHelperPeers returns the peers other than the syncer that announced highHash
+// or a block in its future, and therefore should have all the bodies in its past
+func (flow *handleIBDFlow) blockBodiesHelperPeers(highHash *externalapi.DomainHash) ([]*peerpkg.Peer, error) {
+	var helperPeers []*peerpkg.Peer
+	for _, peer := range flow.Peers() {
+		if peer == flow.peer {
+			continue
+		}
+		lastRelayedBlockHash := peer.LastRelayedBlockHash()
+		if lastRelayedBlockHash == nil {
+			continue
+		}
+		if lastRelayedBlockHash.Equal(highHash) {
+			helperPeers = append(helperPeers, peer)
+			continue
+		}
+
+		blockInfo, err := flow.Domain().Consensus().GetBlockInfo(lastRelayedBlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if !blockInfo.Exists {
+			// The peer announced a block we don't know yet, so it's most likely ahead of us
+			helperPeers = append(helperPeers, peer)
+			continue
+		}
+		if blockInfo.BlockStatus == externalapi.StatusInvalid {
+			continue
+		}
+		isHighHashInSelectedChain, err := flow.Domain().Consensus().IsInSelectedParentChainOf(
+			highHash, lastRelayedBlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if isHighHashInSelectedChain {
+			helperPeers = append(helperPeers, peer)
+		}
+	}
+	return helperPeers, nil
+}
+
+// fastIBDBlockBodiesResponseDuration is the time within which a peer has to send a batch
+// of block bodies for the response to count towards its reputation
+const fastIBDBlockBodiesResponseDuration = 5 * time.Second
+
+// serveIBDBlockBodiesRequests downloads the block bodies of the given request, along with up to
+// peerpkg.MaxInFlightIBDBlockBodiesRequests-1 requests that are queued after it, from flow.peer.
+// All the requests are sent before waiting for the blocks, so that the peer is never left idle.
+func (flow *handleIBDFlow) serveIBDBlockBodiesRequests(request *peerpkg.IBDBlockBodiesRequest,
+	queuedRequests <-chan *peerpkg.IBDBlockBodiesRequest) error {
+
+	requests := make([]*peerpkg.IBDBlockBodiesRequest, 0, peerpkg.MaxInFlightIBDBlockBodiesRequests)
+	if !request.IsCancelled() {
+		requests = append(requests, request)
+	}
+loop:
+	for len(requests) < peerpkg.MaxInFlightIBDBlockBodiesRequests {
+		select {
+		case request, ok := <-queuedRequests:
+			if !ok {
+				break loop
+			}
+			if !request.IsCancelled() {
+				requests = append(requests, request)
+			}
+		default:
+			break loop
+		}
+	}
+
+	for i, request := range requests {
+		err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestIBDBlocks(request.Hashes))
+		if err != nil {
+			respondToIBDBlockBodiesRequestsWithError(requests[i:], err)
+			return err
+		}
+	}
+	for i, request := range requests {
+		start := time.Now()
+		blocks, err := flow.receiveIBDBlocks(request.Hashes)
+		if err != nil {
+			respondToIBDBlockBodiesRequestsWithError(requests[i:], err)
+			return err
+		}
+		if time.Since(start) < fastIBDBlockBodiesResponseDuration {
+			flow.peer.AddGoodBehavior(peerpkg.GoodBehaviorFastIBDResponse)
+		}
+		respondToIBDBlockBodiesRequest(&peerpkg.IBDBlockBodiesResponse{Request: request, Blocks: blocks})
+	}
+	return nil
+}
+
+func (flow *handleIBDFlow) receiveIBDBlocks(expectedHashes []*externalapi.DomainHash) ([]*externalapi.DomainBlock, error) {
+	blocks := make([]*externalapi.DomainBlock, len(expectedHashes))
+	for i, expectedHash := range expectedHashes {
+		message, err := flow.incomingRoute.DequeueWithTimeout(common.DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		msgIBDBlock, ok := message.(*appmessage.MsgIBDBlock)
+		if !ok {
+			return nil, protocolerrors.Errorf(true, "received unexpected message type. "+
+				"expected: %s, got: %s", appmessage.CmdIBDBlock, message.Command())
+		}
+
+		block := appmessage.MsgBlockToDomainBlock(msgIBDBlock.MsgBlock)
+		blockHash := consensushashing.BlockHash(block)
+		if !expectedHash.Equal(blockHash) {
+			return nil, protocolerrors.Errorf(true, "expected block %s but got %s", expectedHash, blockHash)
+		}
+
+		err = flow.banIfBlockIsHeaderOnly(block)
+		if err != nil {
+			return nil, err
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
+
+func respondToIBDBlockBodiesRequestsWithError(requests []*peerpkg.IBDBlockBodiesRequest, err error) {
+	for _, request := range requests {
+		respondToIBDBlockBodiesRequest(&peerpkg.IBDBlockBodiesResponse{Request: request, Err: err})
+	}
+}
+
+func respondToIBDBlockBodiesRequest(response *peerpkg.IBDBlockBodiesResponse) {
+	select {
+	case response.Request.Responses <- response:
+	case <-response.Request.Cancel:
+	}
+}
+
 func (flow *handleIBDFlow) banIfBlockIsHeaderOnly(block *externalapi.DomainBlock) error {
 	if len(block.Transactions) == 0 {
 		return protocolerrors.Errorf(true, "sent header of %s block where expected block with body",
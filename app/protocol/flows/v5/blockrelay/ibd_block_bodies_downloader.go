@@ -0,0 +1,251 @@
+package blockrelay
+
+import (
+	"time"
+
+	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
+	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+	"github.com/pkg/errors"
+)
+
+// maxIBDBlockBodiesBatchesAhead is the maximum number of batches that may be requested ahead
+// of the first batch that wasn't inserted yet. It bounds the number of downloaded blocks that
+// are kept in memory while waiting for a slow peer.
+const maxIBDBlockBodiesBatchesAhead = 16
+
+// blockBodiesSource is a peer that block bodies can be downloaded from
+type blockBodiesSource struct {
+	name      string
+	requests  chan<- *peerpkg.IBDBlockBodiesRequest
+	inFlight  int
+	isStalled bool
+}
+
+// blockBodiesAssignment is a batch that was requested from a source
+type blockBodiesAssignment struct {
+	batchIndex int
+	source     *blockBodiesSource
+	deadline   time.Time
+	isPending  bool
+}
+
+// blockBodiesDownloader downloads the bodies of the given hashes in batches from several sources
+// in parallel, and inserts them in order. Every source may have up to
+// peerpkg.MaxInFlightIBDBlockBodiesRequests batches in flight. A source that doesn't respond
+// within the timeout per block of the batch, or responds with an error, is considered stalled:
+// its batches are reassigned to the other sources and it's not assigned any new ones.
+type blockBodiesDownloader struct {
+	sources     []*blockBodiesSource
+	batches     [][]*externalapi.DomainHash
+	timeout     time.Duration
+	insertBatch func(blocks []*externalapi.DomainBlock) error
+
+	receivedBatches   [][]*externalapi.DomainBlock
+	assignedCounts    []int
+	assignments       map[*peerpkg.IBDBlockBodiesRequest]*blockBodiesAssignment
+	pendingCount      int
+	nextBatchToInsert int
+	lastErr           error
+
+	responses chan *peerpkg.IBDBlockBodiesResponse
+	cancel    chan struct{}
+}
+
+func newBlockBodiesDownloader(hashes []*externalapi.DomainHash, sources []*blockBodiesSource,
+	timeout time.Duration, insertBatch func(blocks []*externalapi.DomainBlock) error) *blockBodiesDownloader {
+
+	batches := make([][]*externalapi.DomainHash, 0, (len(hashes)+ibdBatchSize-1)/ibdBatchSize)
+	for offset := 0; offset < len(hashes); offset += ibdBatchSize {
+		if offset+ibdBatchSize < len(hashes) {
+			batches = append(batches, hashes[offset:offset+ibdBatchSize])
+		} else {
+			batches = append(batches, hashes[offset:])
+		}
+	}
+
+	return &blockBodiesDownloader{
+		sources:     sources,
+		batches:     batches,
+		timeout:     timeout,
+		insertBatch: insertBatch,
+
+		receivedBatches: make([][]*externalapi.DomainBlock, len(batches)),
+		assignedCounts:  make([]int, len(batches)),
+		assignments:     make(map[*peerpkg.IBDBlockBodiesRequest]*blockBodiesAssignment),
+
+		responses: make(chan *peerpkg.IBDBlockBodiesResponse, len(sources)*peerpkg.MaxInFlightIBDBlockBodiesRequests),
+		cancel:    make(chan struct{}),
+	}
+}
+
+// download downloads and inserts all the batches. It returns once all of them
+// were inserted, an insertion fails, or all the sources have stalled.
+func (d *blockBodiesDownloader) download() error {
+	// Let the sources know that the responses to requests that are still pending are no longer needed
+	defer close(d.cancel)
+
+	for d.nextBatchToInsert < len(d.batches) {
+		d.assignBatches()
+		if d.pendingCount == 0 {
+			if d.lastErr != nil {
+				return d.lastErr
+			}
+			return protocolerrors.Errorf(false, "no peer is available to download block bodies from")
+		}
+
+		timer := time.NewTimer(time.Until(d.earliestDeadline()))
+		select {
+		case response := <-d.responses:
+			timer.Stop()
+			d.handleResponse(response)
+		case <-timer.C:
+			d.handleExpiredAssignments()
+		}
+
+		err := d.insertReceivedBatches()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignBatches requests the next unassigned batches from the sources that have room in their in-flight windows
+func (d *blockBodiesDownloader) assignBatches() {
+	for _, source := range d.sources {
+		for !source.isStalled && source.inFlight < peerpkg.MaxInFlightIBDBlockBodiesRequests {
+			batchIndex, ok := d.nextUnassignedBatch()
+			if !ok {
+				return
+			}
+			if !d.assign(batchIndex, source) {
+				break
+			}
+		}
+	}
+}
+
+func (d *blockBodiesDownloader) nextUnassignedBatch() (int, bool) {
+	end := d.nextBatchToInsert + maxIBDBlockBodiesBatchesAhead
+	if end > len(d.batches) {
+		end = len(d.batches)
+	}
+	for batchIndex := d.nextBatchToInsert; batchIndex < end; batchIndex++ {
+		if d.receivedBatches[batchIndex] == nil && d.assignedCounts[batchIndex] == 0 {
+			return batchIndex, true
+		}
+	}
+	return 0, false
+}
+
+func (d *blockBodiesDownloader) assign(batchIndex int, source *blockBodiesSource) bool {
+	request := &peerpkg.IBDBlockBodiesRequest{
+		Hashes:    d.batches[batchIndex],
+		Responses: d.responses,
+		Cancel:    d.cancel,
+	}
+	select {
+	case source.requests <- request:
+	default:
+		// The source is still busy with requests of an earlier download,
+		// so the batch is left to the other sources
+		return false
+	}
+
+	d.assignments[request] = &blockBodiesAssignment{
+		batchIndex: batchIndex,
+		source:     source,
+		deadline:   time.Now().Add(d.batchTimeout(batchIndex)),
+		isPending:  true,
+	}
+	d.pendingCount++
+	d.assignedCounts[batchIndex]++
+	source.inFlight++
+	return true
+}
+
+func (d *blockBodiesDownloader) earliestDeadline() time.Time {
+	var earliestDeadline time.Time
+	for _, assignment := range d.assignments {
+		if !assignment.isPending {
+			continue
+		}
+		if earliestDeadline.IsZero() || assignment.deadline.Before(earliestDeadline) {
+			earliestDeadline = assignment.deadline
+		}
+	}
+	return earliestDeadline
+}
+
+func (d *blockBodiesDownloader) handleResponse(response *peerpkg.IBDBlockBodiesResponse) {
+	assignment, ok := d.assignments[response.Request]
+	if !ok {
+		return
+	}
+	delete(d.assignments, response.Request)
+	if assignment.isPending {
+		d.unassign(assignment)
+	}
+
+	if response.Err != nil {
+		if !assignment.source.isStalled {
+			log.Infof("Failed to download block bodies from peer %s: %s", assignment.source.name, response.Err)
+			d.markStalled(assignment.source, response.Err)
+		}
+		return
+	}
+
+	// Responses to requests that already expired are still used if their batch
+	// wasn't received from another source in the meantime
+	if assignment.batchIndex >= d.nextBatchToInsert && d.receivedBatches[assignment.batchIndex] == nil {
+		d.receivedBatches[assignment.batchIndex] = response.Blocks
+	}
+}
+
+func (d *blockBodiesDownloader) handleExpiredAssignments() {
+	now := time.Now()
+	for _, assignment := range d.assignments {
+		if !assignment.isPending || now.Before(assignment.deadline) {
+			continue
+		}
+		d.unassign(assignment)
+		if !assignment.source.isStalled {
+			log.Infof("Peer %s did not send block bodies within %s. Requesting them from other peers",
+				assignment.source.name, d.batchTimeout(assignment.batchIndex))
+			d.markStalled(assignment.source, errors.Wrapf(router.ErrTimeout,
+				"timed out waiting for block bodies from peer %s", assignment.source.name))
+		}
+	}
+}
+
+// batchTimeout returns the time a source is given to respond with the given batch
+func (d *blockBodiesDownloader) batchTimeout(batchIndex int) time.Duration {
+	return d.timeout * time.Duration(len(d.batches[batchIndex]))
+}
+
+// unassign releases the batch of the given assignment, so it could be requested from another source
+func (d *blockBodiesDownloader) unassign(assignment *blockBodiesAssignment) {
+	assignment.isPending = false
+	d.pendingCount--
+	d.assignedCounts[assignment.batchIndex]--
+	assignment.source.inFlight--
+}
+
+func (d *blockBodiesDownloader) markStalled(source *blockBodiesSource, err error) {
+	source.isStalled = true
+	d.lastErr = err
+}
+
+func (d *blockBodiesDownloader) insertReceivedBatches() error {
+	for d.nextBatchToInsert < len(d.batches) && d.receivedBatches[d.nextBatchToInsert] != nil {
+		err := d.insertBatch(d.receivedBatches[d.nextBatchToInsert])
+		if err != nil {
+			return err
+		}
+		d.receivedBatches[d.nextBatchToInsert] = nil
+		d.nextBatchToInsert++
+	}
+	return nil
+}
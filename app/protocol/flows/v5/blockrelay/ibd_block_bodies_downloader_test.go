@@ -0,0 +1,146 @@
+package blockrelay
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
+	"github.com/kobradag/kobrad/app/protocol/protocolerrors"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+)
+
+// fakeBlockBodiesSource serves block bodies requests, and stops responding
+// once it has served stallAfter requests. A negative stallAfter means never.
+type fakeBlockBodiesSource struct {
+	requests   chan *peerpkg.IBDBlockBodiesRequest
+	blocks     map[externalapi.DomainHash]*externalapi.DomainBlock
+	stallAfter int
+	served     int
+	done       chan struct{}
+}
+
+func newFakeBlockBodiesSource(blocks map[externalapi.DomainHash]*externalapi.DomainBlock,
+	stallAfter int) *fakeBlockBodiesSource {
+
+	return &fakeBlockBodiesSource{
+		requests:   make(chan *peerpkg.IBDBlockBodiesRequest, peerpkg.MaxInFlightIBDBlockBodiesRequests),
+		blocks:     blocks,
+		stallAfter: stallAfter,
+		done:       make(chan struct{}),
+	}
+}
+
+func (s *fakeBlockBodiesSource) serve(stop <-chan struct{}) {
+	defer close(s.done)
+	for {
+		select {
+		case request := <-s.requests:
+			if s.stallAfter >= 0 && s.served >= s.stallAfter {
+				continue
+			}
+			s.served++
+			blocks := make([]*externalapi.DomainBlock, len(request.Hashes))
+			for i, hash := range request.Hashes {
+				blocks[i] = s.blocks[*hash]
+			}
+			respondToIBDBlockBodiesRequest(&peerpkg.IBDBlockBodiesResponse{Request: request, Blocks: blocks})
+		case <-stop:
+			return
+		}
+	}
+}
+
+func newTestBlocks(count int) ([]*externalapi.DomainHash, map[externalapi.DomainHash]*externalapi.DomainBlock) {
+	hashes := make([]*externalapi.DomainHash, count)
+	blocks := make(map[externalapi.DomainHash]*externalapi.DomainBlock, count)
+	for i := range hashes {
+		var hashBytes [externalapi.DomainHashSize]byte
+		binary.LittleEndian.PutUint64(hashBytes[:], uint64(i))
+		hashes[i] = externalapi.NewDomainHashFromByteArray(&hashBytes)
+		blocks[*hashes[i]] = &externalapi.DomainBlock{}
+	}
+	return hashes, blocks
+}
+
+func TestBlockBodiesDownloaderPeerStallsMidSync(t *testing.T) {
+	hashes, blocks := newTestBlocks(20*ibdBatchSize + 7)
+
+	stop := make(chan struct{})
+	healthySource := newFakeBlockBodiesSource(blocks, -1)
+	stallingSource := newFakeBlockBodiesSource(blocks, 3)
+	go healthySource.serve(stop)
+	go stallingSource.serve(stop)
+
+	sources := []*blockBodiesSource{
+		{name: "stalling", requests: stallingSource.requests},
+		{name: "healthy", requests: healthySource.requests},
+	}
+
+	insertedCount := 0
+	insertBatch := func(batch []*externalapi.DomainBlock) error {
+		for _, block := range batch {
+			if block != blocks[*hashes[insertedCount]] {
+				t.Fatalf("block %d was inserted out of order", insertedCount)
+			}
+			insertedCount++
+		}
+		return nil
+	}
+
+	err := newBlockBodiesDownloader(hashes, sources, time.Millisecond, insertBatch).download()
+	close(stop)
+	<-healthySource.done
+	<-stallingSource.done
+	if err != nil {
+		t.Fatalf("download: %+v", err)
+	}
+	if insertedCount != len(hashes) {
+		t.Fatalf("expected %d inserted blocks, got %d", len(hashes), insertedCount)
+	}
+	if !sources[0].isStalled {
+		t.Fatalf("expected the stalling source to be marked as stalled")
+	}
+	if sources[1].isStalled {
+		t.Fatalf("expected the healthy source not to be marked as stalled")
+	}
+	if stallingSource.served != 3 || healthySource.served == 0 {
+		t.Fatalf("expected both sources to serve batches, got %d from the stalling source and %d "+
+			"from the healthy one", stallingSource.served, healthySource.served)
+	}
+}
+
+func TestBlockBodiesDownloaderAllPeersStall(t *testing.T) {
+	hashes, blocks := newTestBlocks(5 * ibdBatchSize)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	firstSource := newFakeBlockBodiesSource(blocks, 1)
+	secondSource := newFakeBlockBodiesSource(blocks, 0)
+	go firstSource.serve(stop)
+	go secondSource.serve(stop)
+
+	sources := []*blockBodiesSource{
+		{name: "first", requests: firstSource.requests},
+		{name: "second", requests: secondSource.requests},
+	}
+
+	insertedCount := 0
+	insertBatch := func(batch []*externalapi.DomainBlock) error {
+		insertedCount += len(batch)
+		return nil
+	}
+
+	err := newBlockBodiesDownloader(hashes, sources, time.Millisecond/2, insertBatch).download()
+	if err == nil {
+		t.Fatalf("expected download to fail when all the peers stall")
+	}
+	// Stalling peers are a protocol error, which disconnects the syncer rather than crashing the node
+	if !errors.As(err, &protocolerrors.ProtocolError{}) {
+		t.Fatalf("expected a protocol error, got %+v", err)
+	}
+	if insertedCount != ibdBatchSize {
+		t.Fatalf("expected only the first batch to be inserted, got %d blocks", insertedCount)
+	}
+}
@@ -10,22 +10,49 @@ import (
 	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/kobradag/kobrad/domain/consensus/ruleerrors"
 	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 	"github.com/pkg/errors"
 )
 
 func (flow *handleIBDFlow) ibdWithHeadersProof(
 	syncerHeaderSelectedTipHash, relayBlockHash *externalapi.DomainHash, highBlockDAAScore uint64) error {
-	err := flow.Domain().InitStagingConsensusWithoutGenesis()
-	if err != nil {
-		return err
+
+	isResumed := false
+	var err error
+	interruptedPruningPoint := flow.InterruptedPruningPointUTXOSetDownload()
+	if interruptedPruningPoint != nil {
+		flow.SetInterruptedPruningPointUTXOSetDownload(nil)
+		isResumed, err = flow.resumeHeadersAndPruningUTXOSetDownload(interruptedPruningPoint,
+			syncerHeaderSelectedTipHash, relayBlockHash, highBlockDAAScore)
+		if !isResumed && err == nil {
+			log.Infof("Peer %s doesn't have the pruning point %s, so its UTXO set download can't be resumed. "+
+				"Deleting the staging consensus.", flow.peer, interruptedPruningPoint)
+			err = flow.Domain().DeleteStagingConsensus()
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	err = flow.downloadHeadersAndPruningUTXOSet(syncerHeaderSelectedTipHash, relayBlockHash, highBlockDAAScore)
+	if !isResumed {
+		err = flow.Domain().InitStagingConsensusWithoutGenesis()
+		if err != nil {
+			return err
+		}
+
+		err = flow.downloadHeadersAndPruningUTXOSet(syncerHeaderSelectedTipHash, relayBlockHash, highBlockDAAScore)
+	}
 	if err != nil {
 		if !flow.IsRecoverableError(err) {
 			return err
 		}
 
+		if flow.InterruptedPruningPointUTXOSetDownload() != nil {
+			log.Infof("IBD with pruning proof from %s was interrupted while downloading the pruning point UTXO set. "+
+				"Keeping the staging consensus in order to resume the download from another peer. (%s)", flow.peer, err)
+			return err
+		}
+
 		log.Infof("IBD with pruning proof from %s was unsuccessful. Deleting the staging consensus. (%s)", flow.peer, err)
 		deleteStagingConsensusErr := flow.Domain().DeleteStagingConsensus()
 		if deleteStagingConsensusErr != nil {
@@ -202,6 +229,54 @@ func (flow *handleIBDFlow) downloadHeadersAndPruningUTXOSet(
 	return nil
 }
 
+// discardInterruptedPruningPointUTXOSetDownload deletes the staging consensus that was kept in order to
+// resume an interrupted pruning point UTXO set download, if there's one. It's called when syncing with a
+// peer that doesn't require a headers proof, in which case the download is no longer needed.
+func (flow *handleIBDFlow) discardInterruptedPruningPointUTXOSetDownload() error {
+	interruptedPruningPoint := flow.InterruptedPruningPointUTXOSetDownload()
+	if interruptedPruningPoint == nil {
+		return nil
+	}
+
+	log.Infof("IBD with %s doesn't require a headers proof, so the interrupted UTXO set download of pruning "+
+		"point %s is no longer needed. Deleting the staging consensus.", flow.peer, interruptedPruningPoint)
+	flow.SetInterruptedPruningPointUTXOSetDownload(nil)
+	return flow.Domain().DeleteStagingConsensus()
+}
+
+// resumeHeadersAndPruningUTXOSetDownload resumes the interrupted download of the UTXO set of the given
+// pruning point into the staging consensus from flow.peer, and then downloads the headers that the staging
+// consensus is missing up to the syncer selected tip. It returns false if flow.peer has a different pruning
+// point, in which case the download can't be resumed from it.
+func (flow *handleIBDFlow) resumeHeadersAndPruningUTXOSetDownload(pruningPoint *externalapi.DomainHash,
+	syncerHeaderSelectedTipHash, relayBlockHash *externalapi.DomainHash, highBlockDAAScore uint64) (bool, error) {
+
+	log.Infof("Resuming the download of the UTXO set of pruning point %s from %s", pruningPoint, flow.peer)
+	isSuccessful, err := flow.fetchMissingUTXOSet(flow.Domain().StagingConsensus(), pruningPoint)
+	if err != nil {
+		return true, err
+	}
+	if !isSuccessful {
+		return false, nil
+	}
+	log.Info("Fetched the new pruning point UTXO set")
+
+	err = flow.syncPruningPointFutureHeaders(flow.Domain().StagingConsensus(),
+		syncerHeaderSelectedTipHash, pruningPoint, relayBlockHash, highBlockDAAScore)
+	if err != nil {
+		return true, err
+	}
+
+	relayBlockInfo, err := flow.Domain().StagingConsensus().GetBlockInfo(relayBlockHash)
+	if err != nil {
+		return true, err
+	}
+	if !relayBlockInfo.Exists {
+		return true, protocolerrors.Errorf(true, "the triggering IBD block was not sent")
+	}
+	return true, nil
+}
+
 func (flow *handleIBDFlow) syncPruningPointsAndPruningPointAnticone(proofPruningPoint *externalapi.DomainHash) error {
 	log.Infof("Downloading the past pruning points and the pruning point anticone from %s", flow.peer)
 	err := flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestPruningPointAndItsAnticone())
@@ -277,20 +352,7 @@ func (flow *handleIBDFlow) syncPruningPointsAndPruningPointAnticone(proofPruning
 func (flow *handleIBDFlow) processBlockWithTrustedData(
 	consensus externalapi.Consensus, block *appmessage.MsgBlockWithTrustedDataV4, data *appmessage.MsgTrustedData) error {
 
-	blockWithTrustedData := &externalapi.BlockWithTrustedData{
-		Block:        appmessage.MsgBlockToDomainBlock(block.Block),
-		DAAWindow:    make([]*externalapi.TrustedDataDataDAAHeader, 0, len(block.DAAWindowIndices)),
-		GHOSTDAGData: make([]*externalapi.BlockGHOSTDAGDataHashPair, 0, len(block.GHOSTDAGDataIndices)),
-	}
-
-	for _, index := range block.DAAWindowIndices {
-		blockWithTrustedData.DAAWindow = append(blockWithTrustedData.DAAWindow, appmessage.TrustedDataDataDAABlockV4ToTrustedDataDataDAAHeader(data.DAAWindow[index]))
-	}
-
-	for _, index := range block.GHOSTDAGDataIndices {
-		blockWithTrustedData.GHOSTDAGData = append(blockWithTrustedData.GHOSTDAGData, appmessage.GHOSTDAGHashPairToDomainGHOSTDAGHashPair(data.GHOSTDAGData[index]))
-	}
-
+	blockWithTrustedData := appmessage.BlockWithTrustedDataV4ToDomainBlockWithTrustedData(block, data)
 	err := consensus.ValidateAndInsertBlockWithTrustedData(blockWithTrustedData, false)
 	if err != nil {
 		if errors.As(err, &ruleerrors.RuleError{}) {
@@ -413,13 +475,34 @@ func (flow *handleIBDFlow) syncPruningPointUTXOSet(consensus externalapi.Consens
 
 func (flow *handleIBDFlow) fetchMissingUTXOSet(consensus externalapi.Consensus, pruningPointHash *externalapi.DomainHash) (succeed bool, err error) {
 	defer func() {
+		// If the syncer disconnected or stopped responding, the UTXOs that were already
+		// imported are kept so that the download could be resumed from another peer
+		if errors.Is(err, router.ErrRouteClosed) || errors.Is(err, router.ErrTimeout) {
+			cursor, cursorErr := flow.Domain().StagingConsensus().ImportedPruningPointUTXOsCursor()
+			if cursorErr != nil {
+				panic(fmt.Sprintf("failed to get the imported pruning point UTXOs cursor: %s", cursorErr))
+			}
+			if cursor != nil {
+				flow.SetInterruptedPruningPointUTXOSetDownload(pruningPointHash)
+				return
+			}
+		}
+
 		err := flow.Domain().StagingConsensus().ClearImportedPruningPointData()
 		if err != nil {
 			panic(fmt.Sprintf("failed to clear imported pruning point data: %s", err))
 		}
 	}()
 
-	err = flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestPruningPointUTXOSet(pruningPointHash))
+	fromOutpoint, err := flow.Domain().StagingConsensus().ImportedPruningPointUTXOsCursor()
+	if err != nil {
+		return false, err
+	}
+	if fromOutpoint != nil {
+		log.Infof("Requesting the pruning point UTXO set from %s starting after outpoint %s", flow.peer, fromOutpoint)
+	}
+
+	err = flow.outgoingRoute.Enqueue(appmessage.NewMsgRequestPruningPointUTXOSet(pruningPointHash, fromOutpoint))
 	if err != nil {
 		return false, err
 	}
@@ -25,11 +25,22 @@ type protocolManager interface {
 }
 
 // Register is used in order to register all the protocol flows to the given router.
-func Register(m protocolManager, router *routerpkg.Router, errChan chan error, isStopping *uint32) (flows []*common.Flow) {
-	flows = registerAddressFlows(m, router, isStopping, errChan)
+// Block-relay-only peers don't exchange addresses and transactions. Since peers that don't
+// know about block-relay-only mode still send these messages, their routes are registered
+// to flows that ignore them instead.
+func Register(m protocolManager, router *routerpkg.Router, errChan chan error, isStopping *uint32,
+	peer *peerpkg.Peer) (flows []*common.Flow) {
+
+	if peer.IsBlockRelayOnly() {
+		flows = append(flows, registerBlockRelayOnlyFlows(m, router, isStopping, errChan)...)
+	} else {
+		flows = append(flows, registerAddressFlows(m, router, isStopping, errChan)...)
+	}
 	flows = append(flows, registerBlockRelayFlows(m, router, isStopping, errChan)...)
 	flows = append(flows, registerPingFlows(m, router, isStopping, errChan)...)
-	flows = append(flows, registerTransactionRelayFlow(m, router, isStopping, errChan)...)
+	if !peer.IsBlockRelayOnly() {
+		flows = append(flows, registerTransactionRelayFlow(m, router, isStopping, errChan)...)
+	}
 	flows = append(flows, registerRejectsFlow(m, router, isStopping, errChan)...)
 
 	return flows
@@ -53,6 +64,27 @@ func registerAddressFlows(m protocolManager, router *routerpkg.Router, isStoppin
 	}
 }
 
+func registerBlockRelayOnlyFlows(m protocolManager, router *routerpkg.Router, isStopping *uint32, errChan chan error) []*common.Flow {
+	outgoingRoute := router.OutgoingRoute()
+
+	return []*common.Flow{
+		m.RegisterFlow("SendNoAddresses", router,
+			[]appmessage.MessageCommand{appmessage.CmdRequestAddresses, appmessage.CmdAddresses}, isStopping, errChan,
+			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
+				return addressexchange.SendNoAddresses(incomingRoute, outgoingRoute)
+			},
+		),
+
+		m.RegisterFlow("IgnoreRelayedTransactions", router,
+			[]appmessage.MessageCommand{appmessage.CmdInvTransaction, appmessage.CmdTx, appmessage.CmdTransactionNotFound,
+				appmessage.CmdRequestTransactions}, isStopping, errChan,
+			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
+				return transactionrelay.IgnoreRelayedTransactions(incomingRoute, outgoingRoute)
+			},
+		),
+	}
+}
+
 func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStopping *uint32, errChan chan error) []*common.Flow {
 	outgoingRoute := router.OutgoingRoute()
 
@@ -64,6 +96,7 @@ func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStop
 
 		m.RegisterFlow("HandleRelayInvs", router, []appmessage.MessageCommand{
 			appmessage.CmdInvRelayBlock, appmessage.CmdBlock, appmessage.CmdBlockLocator,
+			appmessage.CmdCompactBlock, appmessage.CmdBlockTransactions,
 		},
 			isStopping, errChan, func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
 				return blockrelay.HandleRelayInvs(m.Context(), incomingRoute,
@@ -86,7 +119,9 @@ func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStop
 			},
 		),
 
-		m.RegisterFlow("HandleRelayBlockRequests", router, []appmessage.MessageCommand{appmessage.CmdRequestRelayBlocks}, isStopping, errChan,
+		m.RegisterFlow("HandleRelayBlockRequests", router, []appmessage.MessageCommand{
+			appmessage.CmdRequestRelayBlocks, appmessage.CmdRequestBlockTransactions,
+		}, isStopping, errChan,
 			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
 				return blockrelay.HandleRelayBlockRequests(m.Context(), incomingRoute, outgoingRoute, peer)
 			},
@@ -109,7 +144,7 @@ func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStop
 		m.RegisterFlow("HandleIBDBlockRequests", router,
 			[]appmessage.MessageCommand{appmessage.CmdRequestIBDBlocks}, isStopping, errChan,
 			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
-				return blockrelay.HandleIBDBlockRequests(m.Context(), incomingRoute, outgoingRoute)
+				return blockrelay.HandleIBDBlockRequests(m.Context(), incomingRoute, outgoingRoute, peer)
 			},
 		),
 
@@ -117,7 +152,7 @@ func registerBlockRelayFlows(m protocolManager, router *routerpkg.Router, isStop
 			[]appmessage.MessageCommand{appmessage.CmdRequestPruningPointUTXOSet,
 				appmessage.CmdRequestNextPruningPointUTXOSetChunk}, isStopping, errChan,
 			func(incomingRoute *routerpkg.Route, peer *peerpkg.Peer) error {
-				return blockrelay.HandleRequestPruningPointUTXOSet(m.Context(), incomingRoute, outgoingRoute)
+				return blockrelay.HandleRequestPruningPointUTXOSet(m.Context(), incomingRoute, outgoingRoute, peer)
 			},
 		),
 
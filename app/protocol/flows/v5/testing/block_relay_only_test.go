@@ -0,0 +1,91 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/protocol/flows/v5/addressexchange"
+	"github.com/kobradag/kobrad/app/protocol/flows/v5/transactionrelay"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+	"github.com/pkg/errors"
+)
+
+// TestBlockRelayOnlyWithNonUpgradedPeer makes sure that the address and transaction
+// messages a peer that doesn't know about block-relay-only mode sends over a
+// block-relay-only connection don't fail the flows
+func TestBlockRelayOnlyWithNonUpgradedPeer(t *testing.T) {
+	incomingRoute := router.NewRoute("incoming")
+	outgoingRoute := router.NewRoute("outgoing")
+	errChan := make(chan error)
+	go func() {
+		errChan <- addressexchange.SendNoAddresses(incomingRoute, outgoingRoute)
+	}()
+
+	// Unsolicited addresses are ignored, and address requests are answered with no addresses
+	for _, message := range []appmessage.Message{
+		appmessage.NewMsgAddresses([]*appmessage.NetAddress{{IP: []byte{127, 0, 0, 1}}}),
+		appmessage.NewMsgRequestAddresses(false, nil),
+	} {
+		err := incomingRoute.Enqueue(message)
+		if err != nil {
+			t.Fatalf("Enqueue: %+v", err)
+		}
+	}
+	message, err := outgoingRoute.DequeueWithTimeout(time.Second)
+	if err != nil {
+		t.Fatalf("DequeueWithTimeout: %+v", err)
+	}
+	msgAddresses, ok := message.(*appmessage.MsgAddresses)
+	if !ok {
+		t.Fatalf("expected MsgAddresses, got %s", message.Command())
+	}
+	if len(msgAddresses.AddressList) != 0 {
+		t.Fatalf("expected no addresses, got %d", len(msgAddresses.AddressList))
+	}
+	checkFlowStoppedByClosedRoute(t, incomingRoute, errChan)
+
+	incomingRoute = router.NewRoute("incoming")
+	outgoingRoute = router.NewRoute("outgoing")
+	go func() {
+		errChan <- transactionrelay.IgnoreRelayedTransactions(incomingRoute, outgoingRoute)
+	}()
+
+	// Announced transactions are ignored, and transaction requests are answered with not found
+	transactionID := externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{1})
+	for _, message := range []appmessage.Message{
+		appmessage.NewMsgInvTransaction([]*externalapi.DomainTransactionID{transactionID}),
+		appmessage.NewMsgTransactionNotFound(transactionID),
+		appmessage.NewMsgRequestTransactions([]*externalapi.DomainTransactionID{transactionID}),
+	} {
+		err := incomingRoute.Enqueue(message)
+		if err != nil {
+			t.Fatalf("Enqueue: %+v", err)
+		}
+	}
+	message, err = outgoingRoute.DequeueWithTimeout(time.Second)
+	if err != nil {
+		t.Fatalf("DequeueWithTimeout: %+v", err)
+	}
+	msgTransactionNotFound, ok := message.(*appmessage.MsgTransactionNotFound)
+	if !ok {
+		t.Fatalf("expected MsgTransactionNotFound, got %s", message.Command())
+	}
+	if !msgTransactionNotFound.ID.Equal(transactionID) {
+		t.Fatalf("expected transaction %s not to be found, got %s", transactionID, msgTransactionNotFound.ID)
+	}
+	checkFlowStoppedByClosedRoute(t, incomingRoute, errChan)
+}
+
+func checkFlowStoppedByClosedRoute(t *testing.T, incomingRoute *router.Route, errChan chan error) {
+	incomingRoute.Close()
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, router.ErrRouteClosed) {
+			t.Fatalf("expected the flow to stop because of the closed route, got: %+v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out after %s", time.Second)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/kobradag/kobrad/domain/consensus"
 	"github.com/kobradag/kobrad/domain/consensus/utils/testutils"
 	"github.com/kobradag/kobrad/infrastructure/network/addressmanager"
+	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 )
 
@@ -24,7 +25,7 @@ func TestReceiveAddressesErrors(t *testing.T) {
 	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
 		incomingRoute := router.NewRoute("incoming")
 		outgoingRoute := router.NewRoute("outgoing")
-		peer := peerpkg.New(nil)
+		peer := peerpkg.New(nil, &connmanager.PeerScore{})
 		errChan := make(chan error)
 		go func() {
 			errChan <- addressexchange.ReceiveAddresses(fakeReceiveAddressesContext{}, incomingRoute, outgoingRoute, peer)
@@ -0,0 +1,31 @@
+package transactionrelay
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// IgnoreRelayedTransactions handles transaction relay messages from block-relay-only peers.
+// Peers that don't know about block-relay-only mode may still announce transactions, which
+// are ignored. Transaction requests are answered with appmessage.MsgTransactionNotFound, so
+// that the requesting peer doesn't wait for them.
+func IgnoreRelayedTransactions(incomingRoute *router.Route, outgoingRoute *router.Route) error {
+	for {
+		message, err := incomingRoute.Dequeue()
+		if err != nil {
+			return err
+		}
+
+		msgRequestTransactions, ok := message.(*appmessage.MsgRequestTransactions)
+		if !ok {
+			continue
+		}
+
+		for _, transactionID := range msgRequestTransactions.IDs {
+			err := outgoingRoute.Enqueue(appmessage.NewMsgTransactionNotFound(transactionID))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
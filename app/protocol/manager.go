@@ -15,6 +15,7 @@ import (
 
 	"github.com/kobradag/kobrad/app/protocol/flowcontext"
 	peerpkg "github.com/kobradag/kobrad/app/protocol/peer"
+	"github.com/kobradag/kobrad/app/snapshot"
 	"github.com/kobradag/kobrad/infrastructure/config"
 	"github.com/kobradag/kobrad/infrastructure/network/addressmanager"
 	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
@@ -68,6 +69,42 @@ func (m *Manager) AddTransaction(tx *externalapi.DomainTransaction, allowOrphan
 	return m.context.AddTransaction(tx, allowOrphan)
 }
 
+// AddReplacementTransaction adds transaction to the mempool, replacing the mempool transactions
+// it conflicts with if it pays a higher fee, and propagates it. The replaced transactions are returned.
+func (m *Manager) AddReplacementTransaction(tx *externalapi.DomainTransaction, allowOrphan bool) (
+	replacedTransactions []*externalapi.DomainTransaction, err error) {
+
+	return m.context.AddReplacementTransaction(tx, allowOrphan)
+}
+
+// SaveMempool saves all the transactions in the mempool to the mempool file in the app directory
+func (m *Manager) SaveMempool() (savedTransactionCount int, err error) {
+	return m.context.SaveMempool()
+}
+
+// SaveAnchorPeers saves the long-lived and well-behaved outbound peers as anchors,
+// which are reconnected to first on the next startup
+func (m *Manager) SaveAnchorPeers() (savedAnchorCount int, err error) {
+	return m.context.SaveAnchorPeers()
+}
+
+// ExportPruningPointSnapshot writes a snapshot of the current pruning point and its UTXO set to the given path,
+// which must be relative to the app directory
+func (m *Manager) ExportPruningPointSnapshot(path string) (*snapshot.Summary, error) {
+	return m.context.ExportPruningPointSnapshot(path)
+}
+
+// ImportPruningPointSnapshot replaces the current consensus with the snapshot in the given path
+func (m *Manager) ImportPruningPointSnapshot(path string) (*snapshot.Summary, error) {
+	return m.context.ImportPruningPointSnapshot(path)
+}
+
+// LoadMempool adds the transactions in the mempool file in the app directory to the
+// mempool, and propagates the accepted ones. It returns the number of accepted transactions.
+func (m *Manager) LoadMempool() (acceptedTransactionCount int, err error) {
+	return m.context.LoadMempool()
+}
+
 // AddBlock adds the given block to the DAG and propagates it.
 func (m *Manager) AddBlock(block *externalapi.DomainBlock) error {
 	return m.context.AddBlock(block)
@@ -0,0 +1,38 @@
+package peer
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+// MaxInFlightIBDBlockBodiesRequests is the maximum number of IBDBlockBodiesRequests
+// that may be pending for a single peer at any time
+const MaxInFlightIBDBlockBodiesRequests = 2
+
+// IBDBlockBodiesRequest is a request from the flow that runs IBD to the IBD flow of another
+// peer, asking it to download the bodies of the given blocks from its peer
+type IBDBlockBodiesRequest struct {
+	Hashes []*externalapi.DomainHash
+
+	// Responses is where the IBDBlockBodiesResponse is sent to
+	Responses chan<- *IBDBlockBodiesResponse
+	// Cancel is closed once the response is no longer needed
+	Cancel <-chan struct{}
+}
+
+// IsCancelled returns whether the response to this request is no longer needed
+func (r *IBDBlockBodiesRequest) IsCancelled() bool {
+	select {
+	case <-r.Cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// IBDBlockBodiesResponse is the response to an IBDBlockBodiesRequest. Blocks are
+// in the same order as the requested hashes
+type IBDBlockBodiesResponse struct {
+	Request *IBDBlockBodiesRequest
+	Blocks  []*externalapi.DomainBlock
+	Err     error
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
 
 	"github.com/kobradag/kobrad/app/appmessage"
@@ -22,6 +23,7 @@ type Peer struct {
 	advertisedProtocolVerion uint32 // protocol version advertised by remote
 	protocolVersion          uint32 // negotiated protocol version
 	disableRelayTx           bool
+	isBlockRelayOnly         bool // Whether only blocks are relayed over the connection, as negotiated in the handshake
 	subnetworkID             *externalapi.DomainSubnetworkID
 
 	timeOffset        time.Duration
@@ -33,14 +35,25 @@ type Peer struct {
 	lastPingDuration time.Duration // Time for last ping to return
 
 	ibdRequestChannel chan *externalapi.DomainBlock // A channel used to communicate IBD requests between flows
+
+	// A channel used by the flow that runs IBD to ask this peer's IBD flow to download block bodies on its behalf
+	ibdBlockBodiesRequestChannel chan *IBDBlockBodiesRequest
+
+	lastRelayedBlockLock sync.RWMutex
+	lastRelayedBlockHash *externalapi.DomainHash // The hash of the last block this peer announced
+
+	score *connmanager.PeerScore // Shared by all the connections from the same IP
 }
 
-// New returns a new Peer
-func New(connection *netadapter.NetConnection) *Peer {
+// New returns a new Peer, whose ban score and reputation are kept in the given score
+func New(connection *netadapter.NetConnection, score *connmanager.PeerScore) *Peer {
 	return &Peer{
 		connection:        connection,
+		score:             score,
 		connectionStarted: time.Now(),
 		ibdRequestChannel: make(chan *externalapi.DomainBlock),
+
+		ibdBlockBodiesRequestChannel: make(chan *IBDBlockBodiesRequest, MaxInFlightIBDBlockBodiesRequests),
 	}
 }
 
@@ -90,6 +103,12 @@ func (p *Peer) IsOutbound() bool {
 	return p.connection.IsOutbound()
 }
 
+// IsBlockRelayOnly returns whether only blocks are relayed to and from the peer. This is
+// the case if either side asked for it in its version message.
+func (p *Peer) IsBlockRelayOnly() bool {
+	return p.isBlockRelayOnly
+}
+
 // UpdateFieldsFromMsgVersion updates the peer with the data from the version message.
 func (p *Peer) UpdateFieldsFromMsgVersion(msg *appmessage.MsgVersion, maxProtocolVersion uint32) {
 	// Negotiate the protocol version.
@@ -106,6 +125,7 @@ func (p *Peer) UpdateFieldsFromMsgVersion(msg *appmessage.MsgVersion, maxProtoco
 	p.userAgent = msg.UserAgent
 
 	p.disableRelayTx = msg.DisableRelayTx
+	p.isBlockRelayOnly = msg.BlockRelayOnly || p.connection.IsBlockRelayOnly()
 	p.subnetworkID = msg.SubnetworkID
 
 	p.timeOffset = mstime.Since(msg.Timestamp)
@@ -151,3 +171,26 @@ func (p *Peer) LastPingDuration() time.Duration {
 func (p *Peer) IBDRequestChannel() chan *externalapi.DomainBlock {
 	return p.ibdRequestChannel
 }
+
+// IBDBlockBodiesRequestChannel returns the channel used by the flow that runs IBD in order to ask
+// this peer's IBD flow to download block bodies on its behalf
+func (p *Peer) IBDBlockBodiesRequestChannel() chan *IBDBlockBodiesRequest {
+	return p.ibdBlockBodiesRequestChannel
+}
+
+// SetLastRelayedBlockHash sets the hash of the last block this peer announced
+func (p *Peer) SetLastRelayedBlockHash(blockHash *externalapi.DomainHash) {
+	p.lastRelayedBlockLock.Lock()
+	defer p.lastRelayedBlockLock.Unlock()
+
+	p.lastRelayedBlockHash = blockHash
+}
+
+// LastRelayedBlockHash returns the hash of the last block this peer announced,
+// or nil if it hasn't announced any block yet
+func (p *Peer) LastRelayedBlockHash() *externalapi.DomainHash {
+	p.lastRelayedBlockLock.RLock()
+	defer p.lastRelayedBlockLock.RUnlock()
+
+	return p.lastRelayedBlockHash
+}
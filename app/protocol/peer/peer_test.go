@@ -0,0 +1,32 @@
+package peer
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
+)
+
+func TestIsBlockRelayOnly(t *testing.T) {
+	tests := []struct {
+		name                     string
+		remoteAskedForBlockRelay bool
+		expectedIsBlockRelayOnly bool
+	}{
+		{name: "regular connection", remoteAskedForBlockRelay: false, expectedIsBlockRelayOnly: false},
+		{name: "remote asked for block relay only", remoteAskedForBlockRelay: true, expectedIsBlockRelayOnly: true},
+	}
+
+	for _, test := range tests {
+		peer := New(&netadapter.NetConnection{}, &connmanager.PeerScore{})
+		msgVersion := appmessage.NewMsgVersion(nil, nil, "", nil, 5)
+		msgVersion.BlockRelayOnly = test.remoteAskedForBlockRelay
+
+		peer.UpdateFieldsFromMsgVersion(msgVersion, 5)
+		if peer.IsBlockRelayOnly() != test.expectedIsBlockRelayOnly {
+			t.Errorf("%s: expected IsBlockRelayOnly to be %t, got %t",
+				test.name, test.expectedIsBlockRelayOnly, peer.IsBlockRelayOnly())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package peer
+
+// Misbehavior is a kind of peer misbehavior, along with the points it adds to the
+// ban score of the peer
+type Misbehavior struct {
+	Reason string
+	Points float64
+}
+
+// The misbehaviors a peer is scored for. A peer is banned once its ban score reaches
+// the ban threshold, which by default is reached by a single protocol violation.
+var (
+	// MisbehaviorProtocolViolation is a violation of the protocol, such as sending an invalid block
+	MisbehaviorProtocolViolation = Misbehavior{Reason: "protocol violation", Points: 100}
+
+	// MisbehaviorUnexpectedMessage is sending a message that wasn't requested or isn't expected at
+	// that point of the protocol, in places where honest peers are known to occasionally do so
+	MisbehaviorUnexpectedMessage = Misbehavior{Reason: "unexpected message", Points: 20}
+
+	// MisbehaviorSlowResponse is not responding to a request in time
+	MisbehaviorSlowResponse = Misbehavior{Reason: "slow response", Points: 10}
+
+	// MisbehaviorExcessiveOrphans is sending an orphan block while the orphan pool is full
+	MisbehaviorExcessiveOrphans = Misbehavior{Reason: "excessive orphans", Points: 10}
+)
+
+// GoodBehavior is a kind of useful peer behavior, along with the points it adds to the
+// reputation of the peer
+type GoodBehavior struct {
+	Reason string
+	Points float64
+}
+
+// The good behaviors a peer is rewarded for
+var (
+	// GoodBehaviorNewBlock is being the first peer to relay a block that was then accepted
+	GoodBehaviorNewBlock = GoodBehavior{Reason: "new block", Points: 1}
+
+	// GoodBehaviorFastIBDResponse is responding quickly to an IBD request
+	GoodBehaviorFastIBDResponse = GoodBehavior{Reason: "fast IBD response", Points: 1}
+)
+
+// AddMisbehavior adds the points of the given misbehavior to the ban score of
+// the peer, and returns the new ban score
+func (p *Peer) AddMisbehavior(misbehavior Misbehavior) float64 {
+	banScore := p.score.AddBanScore(misbehavior.Points)
+	log.Debugf("Peer %s misbehaved (%s). Its ban score is now %.1f", p, misbehavior.Reason, banScore)
+	return banScore
+}
+
+// AddGoodBehavior adds the points of the given good behavior to the reputation of the peer
+func (p *Peer) AddGoodBehavior(goodBehavior GoodBehavior) {
+	p.score.AddReputation(goodBehavior.Points)
+}
+
+// BanScore returns the accumulated misbehavior points of the peer, after decay
+func (p *Peer) BanScore() float64 {
+	return p.score.BanScore()
+}
+
+// Reputation returns the accumulated good behavior points of the peer minus its
+// ban score, after decay. It's used to prefer reputable peers for outgoing connections.
+func (p *Peer) Reputation() float64 {
+	return p.score.Reputation()
+}
@@ -0,0 +1,24 @@
+package peer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kobradag/kobrad/infrastructure/network/connmanager"
+)
+
+func TestReputation(t *testing.T) {
+	peer := New(nil, &connmanager.PeerScore{})
+	for i := 0; i < 5; i++ {
+		peer.AddGoodBehavior(GoodBehaviorNewBlock)
+	}
+	banScore := peer.AddMisbehavior(MisbehaviorSlowResponse)
+
+	if banScore < MisbehaviorSlowResponse.Points-1e-3 || banScore > MisbehaviorSlowResponse.Points {
+		t.Fatalf("expected a ban score of about %f, got %f", MisbehaviorSlowResponse.Points, banScore)
+	}
+	expectedReputation := 5*GoodBehaviorNewBlock.Points - MisbehaviorSlowResponse.Points
+	if reputation := peer.Reputation(); math.Abs(reputation-expectedReputation) > 1e-3 {
+		t.Fatalf("expected a reputation of about %f, got %f", expectedReputation, reputation)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 
 	"github.com/kobradag/kobrad/app/protocol/common"
+	"github.com/kobradag/kobrad/app/protocol/flowcontext"
 	"github.com/kobradag/kobrad/app/protocol/flows/ready"
 	v5 "github.com/kobradag/kobrad/app/protocol/flows/v5"
 
@@ -77,8 +78,10 @@ func (m *Manager) routerInitializer(router *routerpkg.Router, netConnection *net
 		var flows []*common.Flow
 		log.Infof("Registering p2p flows for peer %s for protocol version %d", peer, peer.ProtocolVersion())
 		switch peer.ProtocolVersion() {
-		case 5:
-			flows = v5.Register(m, router, errChan, &isStopping)
+		// Version 6 only adds compact block relay, which the v5 flows use according to the
+		// protocol version of the peer
+		case 5, 6:
+			flows = v5.Register(m, router, errChan, &isStopping, peer)
 		default:
 			panic(errors.Errorf("no way to handle protocol version %d", peer.ProtocolVersion()))
 		}
@@ -105,9 +108,13 @@ func (m *Manager) routerInitializer(router *routerpkg.Router, netConnection *net
 	})
 }
 
+// handleError disconnects from the peer that caused the given error. Protocol errors are
+// added to the ban score of the peer, which is kept per IP across connections, and the peer
+// is banned if banning is enabled and its ban score has reached the ban threshold.
 func (m *Manager) handleError(err error, netConnection *netadapter.NetConnection, outgoingRoute *routerpkg.Route) {
 	if protocolErr := (protocolerrors.ProtocolError{}); errors.As(err, &protocolErr) {
-		if m.context.Config().EnableBanning && protocolErr.ShouldBan {
+		hasReachedBanThreshold := m.addMisbehavior(err, protocolErr, netConnection)
+		if m.context.Config().EnableBanning && hasReachedBanThreshold {
 			log.Warnf("Banning %s (reason: %s)", netConnection, protocolErr.Cause)
 
 			err := m.context.ConnectionManager().Ban(netConnection)
@@ -120,12 +127,11 @@ func (m *Manager) handleError(err error, netConnection *netadapter.NetConnection
 				panic(err)
 			}
 		}
-		log.Infof("Disconnecting from %s (reason: %s)", netConnection, protocolErr.Cause)
-		netConnection.Disconnect()
-		return
-	}
-	if errors.Is(err, routerpkg.ErrTimeout) {
-		log.Warnf("Got timeout from %s. Disconnecting...", netConnection)
+		if errors.Is(err, routerpkg.ErrTimeout) {
+			log.Warnf("Got timeout from %s. Disconnecting...", netConnection)
+		} else {
+			log.Infof("Disconnecting from %s (reason: %s)", netConnection, protocolErr.Cause)
+		}
 		netConnection.Disconnect()
 		return
 	}
@@ -135,6 +141,34 @@ func (m *Manager) handleError(err error, netConnection *netadapter.NetConnection
 	panic(err)
 }
 
+// addMisbehavior adds the misbehavior the given protocol error indicates to the ban score
+// of the peer, and returns whether the ban score has reached the ban threshold
+func (m *Manager) addMisbehavior(err error, protocolErr protocolerrors.ProtocolError,
+	netConnection *netadapter.NetConnection) bool {
+
+	if m.context.IsWhitelisted(netConnection) {
+		return false
+	}
+
+	var misbehavior *peerpkg.Misbehavior
+	switch {
+	case protocolErr.ShouldBan:
+		misbehavior = &peerpkg.MisbehaviorProtocolViolation
+	case errors.Is(err, protocolerrors.ErrUnexpectedMessage):
+		misbehavior = &peerpkg.MisbehaviorUnexpectedMessage
+	case errors.Is(err, routerpkg.ErrTimeout), errors.Is(err, flowcontext.ErrPingTimeout):
+		misbehavior = &peerpkg.MisbehaviorSlowResponse
+	}
+
+	score := m.context.ConnectionManager().PeerScore(netConnection)
+	if misbehavior == nil {
+		return m.context.HasReachedBanThreshold(score.BanScore())
+	}
+	banScore := score.AddBanScore(misbehavior.Points)
+	log.Debugf("%s misbehaved (%s). Its ban score is now %.1f", netConnection, misbehavior.Reason, banScore)
+	return m.context.HasReachedBanThreshold(banScore)
+}
+
 // RegisterFlow registers a flow to the given router.
 func (m *Manager) RegisterFlow(name string, router *routerpkg.Router, messageTypes []appmessage.MessageCommand, isStopping *uint32,
 	errChan chan error, initializeFunc common.FlowInitializeFunc) *common.Flow {
@@ -5,6 +5,12 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ErrUnexpectedMessage is the cause of protocol errors about messages that weren't
+// requested or aren't expected at that point of the protocol, in places where honest
+// peers are known to occasionally send them. Such errors add to the ban score of the
+// peer instead of banning it right away.
+var ErrUnexpectedMessage = errors.New("unexpected message")
+
 // ProtocolError is an error that signifies a violation
 // of the peer-to-peer protocol
 type ProtocolError struct {
@@ -51,6 +51,12 @@ var handlers = map[appmessage.MessageCommand]handler{
 	appmessage.CmdNotifyNewBlockTemplateRequestMessage:                      rpchandlers.HandleNotifyNewBlockTemplate,
 	appmessage.CmdGetCoinSupplyRequestMessage:                               rpchandlers.HandleGetCoinSupply,
 	appmessage.CmdGetMempoolEntriesByAddressesRequestMessage:                rpchandlers.HandleGetMempoolEntriesByAddresses,
+	appmessage.CmdGetTransactionRequestMessage:                              rpchandlers.HandleGetTransaction,
+	appmessage.CmdGetFeeEstimateRequestMessage:                              rpchandlers.HandleGetFeeEstimate,
+	appmessage.CmdSaveMempoolRequestMessage:                                 rpchandlers.HandleSaveMempool,
+	appmessage.CmdLoadMempoolRequestMessage:                                 rpchandlers.HandleLoadMempool,
+	appmessage.CmdExportPruningPointSnapshotRequestMessage:                  rpchandlers.HandleExportPruningPointSnapshot,
+	appmessage.CmdGetNetTotalsRequestMessage:                                rpchandlers.HandleGetNetTotals,
 }
 
 func (m *Manager) routerInitializer(router *router.Router, netConnection *netadapter.NetConnection) {
@@ -0,0 +1,40 @@
+package rpchandlers
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// HandleExportPruningPointSnapshot handles the respectively named RPC command
+func HandleExportPruningPointSnapshot(context *rpccontext.Context, _ *router.Router, request appmessage.Message) (appmessage.Message, error) {
+	if context.Config.SafeRPC {
+		log.Warn("ExportPruningPointSnapshot RPC command called while node in safe RPC mode -- ignoring.")
+		errorMessage := &appmessage.ExportPruningPointSnapshotResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("ExportPruningPointSnapshot RPC command called while node in safe RPC mode")
+		return errorMessage, nil
+	}
+
+	exportPruningPointSnapshotRequest := request.(*appmessage.ExportPruningPointSnapshotRequestMessage)
+	if exportPruningPointSnapshotRequest.Path == "" {
+		errorMessage := &appmessage.ExportPruningPointSnapshotResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("A snapshot file path is required")
+		return errorMessage, nil
+	}
+
+	if context.ProtocolManager.IsIBDRunning() {
+		errorMessage := &appmessage.ExportPruningPointSnapshotResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Cannot export a snapshot while the node is in IBD")
+		return errorMessage, nil
+	}
+
+	summary, err := context.ProtocolManager.ExportPruningPointSnapshot(exportPruningPointSnapshotRequest.Path)
+	if err != nil {
+		errorMessage := &appmessage.ExportPruningPointSnapshotResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Could not export the snapshot: %s", err)
+		return errorMessage, nil
+	}
+
+	return appmessage.NewExportPruningPointSnapshotResponseMessage(summary.PruningPointHash.String(),
+		uint64(summary.HeaderCount), uint64(summary.BlockCount), uint64(summary.UTXOCount)), nil
+}
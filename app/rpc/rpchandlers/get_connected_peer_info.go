@@ -1,6 +1,8 @@
 package rpchandlers
 
 import (
+	"encoding/hex"
+
 	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/app/rpc/rpccontext"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
@@ -12,6 +14,8 @@ func HandleGetConnectedPeerInfo(context *rpccontext.Context, _ *router.Router, _
 	ibdPeer := context.ProtocolManager.IBDPeer()
 	infos := make([]*appmessage.GetConnectedPeerInfoMessage, 0, len(peers))
 	for _, peer := range peers {
+		bandwidthCounter := peer.Connection().BandwidthCounter()
+		bandwidthTotals := bandwidthCounter.Totals()
 		info := &appmessage.GetConnectedPeerInfoMessage{
 			ID:                        peer.ID().String(),
 			Address:                   peer.Address(),
@@ -22,6 +26,14 @@ func HandleGetConnectedPeerInfo(context *rpccontext.Context, _ *router.Router, _
 			AdvertisedProtocolVersion: peer.AdvertisedProtocolVersion(),
 			TimeConnected:             peer.TimeConnected().Milliseconds(),
 			IsIBDPeer:                 peer == ibdPeer,
+			BanScore:                  peer.BanScore(),
+			Reputation:                peer.Reputation(),
+			BytesSent:                 bandwidthTotals.BytesSent,
+			BytesReceived:             bandwidthTotals.BytesReceived,
+			BandwidthByMessageType:    messageTypeBandwidths(bandwidthCounter),
+			IsBlockRelayOnly:          peer.IsBlockRelayOnly(),
+			IsEncrypted:               peer.Connection().IsEncrypted(),
+			IdentityKey:               hex.EncodeToString(peer.Connection().IdentityKey()),
 		}
 		infos = append(infos, info)
 	}
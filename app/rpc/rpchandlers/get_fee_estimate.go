@@ -0,0 +1,30 @@
+package rpchandlers
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// HandleGetFeeEstimate handles the respectively named RPC command
+func HandleGetFeeEstimate(context *rpccontext.Context, _ *router.Router, _ appmessage.Message) (appmessage.Message, error) {
+	estimate, err := context.Domain.MiningManager().GetFeeEstimate()
+	if err != nil {
+		return nil, err
+	}
+
+	return appmessage.NewGetFeeEstimateResponseMessage(&appmessage.RPCFeeEstimate{
+		PriorityBucket: &appmessage.RPCFeeRateBucket{
+			FeeRate:          estimate.PriorityBucket.FeeRate,
+			EstimatedSeconds: estimate.PriorityBucket.EstimatedSeconds,
+		},
+		NormalBucket: &appmessage.RPCFeeRateBucket{
+			FeeRate:          estimate.NormalBucket.FeeRate,
+			EstimatedSeconds: estimate.NormalBucket.EstimatedSeconds,
+		},
+		LowBucket: &appmessage.RPCFeeRateBucket{
+			FeeRate:          estimate.LowBucket.FeeRate,
+			EstimatedSeconds: estimate.LowBucket.EstimatedSeconds,
+		},
+	}), nil
+}
@@ -0,0 +1,33 @@
+package rpchandlers
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// HandleGetNetTotals handles the respectively named RPC command
+func HandleGetNetTotals(context *rpccontext.Context, _ *router.Router, _ appmessage.Message) (appmessage.Message, error) {
+	bandwidthCounter := context.NetAdapter.P2PBandwidthCounter()
+	totals := bandwidthCounter.Totals()
+	response := appmessage.NewGetNetTotalsResponseMessage(totals.BytesSent, totals.BytesReceived,
+		context.Config.MaxUploadRate*1024, context.Config.MaxPeerUploadRate*1024,
+		messageTypeBandwidths(bandwidthCounter))
+	return response, nil
+}
+
+func messageTypeBandwidths(bandwidthCounter *bandwidth.Counter) []*appmessage.MessageTypeBandwidth {
+	messageTypeTotals := bandwidthCounter.MessageTypeTotals()
+	bandwidths := make([]*appmessage.MessageTypeBandwidth, len(messageTypeTotals))
+	for i, totals := range messageTypeTotals {
+		bandwidths[i] = &appmessage.MessageTypeBandwidth{
+			Command:          totals.Command,
+			BytesSent:        totals.BytesSent,
+			BytesReceived:    totals.BytesReceived,
+			MessagesSent:     totals.MessagesSent,
+			MessagesReceived: totals.MessagesReceived,
+		}
+	}
+	return bandwidths
+}
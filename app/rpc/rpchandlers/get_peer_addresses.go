@@ -14,8 +14,8 @@ func HandleGetPeerAddresses(context *rpccontext.Context, _ *router.Router, _ app
 	netAddresses := context.AddressManager.Addresses()
 	addressMessages := make([]*appmessage.GetPeerAddressesKnownAddressMessage, len(netAddresses))
 	for i, netAddress := range netAddresses {
-		addressWithPort := net.JoinHostPort(netAddress.IP.String(), strconv.FormatUint(uint64(netAddress.Port), 10))
-		addressMessages[i] = &appmessage.GetPeerAddressesKnownAddressMessage{Addr: addressWithPort}
+		// String keeps the host name of onion addresses
+		addressMessages[i] = &appmessage.GetPeerAddressesKnownAddressMessage{Addr: netAddress.String()}
 	}
 
 	bannedAddresses := context.AddressManager.BannedAddresses()
@@ -0,0 +1,100 @@
+package rpchandlers
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/consensus/utils/transactionid"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// HandleGetTransaction handles the respectively named RPC command
+func HandleGetTransaction(context *rpccontext.Context, _ *router.Router, request appmessage.Message) (appmessage.Message, error) {
+	if !context.Config.TXIndex {
+		errorMessage := &appmessage.GetTransactionResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Method unavailable when kobrad is run without --txindex")
+		return errorMessage, nil
+	}
+
+	getTransactionRequest := request.(*appmessage.GetTransactionRequestMessage)
+
+	transactionID, err := transactionid.FromString(getTransactionRequest.TransactionID)
+	if err != nil {
+		errorMessage := &appmessage.GetTransactionResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Transaction ID could not be parsed: %s", err)
+		return errorMessage, nil
+	}
+
+	location, found, err := context.TXIndex.TransactionLocation(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		errorMessage := &appmessage.GetTransactionResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Transaction %s was not found", transactionID)
+		return errorMessage, nil
+	}
+
+	includingBlockHashes := make([]string, len(location.IncludingBlockHashes))
+	for i, includingBlockHash := range location.IncludingBlockHashes {
+		includingBlockHashes[i] = includingBlockHash.String()
+	}
+
+	var acceptingBlockHash string
+	var confirmations uint64
+	if location.IsAccepted() {
+		acceptingBlockHash = location.AcceptingBlockHash.String()
+
+		virtualSelectedParent, err := context.Domain.Consensus().GetVirtualSelectedParent()
+		if err != nil {
+			return nil, err
+		}
+		virtualSelectedParentInfo, err := context.Domain.Consensus().GetBlockInfo(virtualSelectedParent)
+		if err != nil {
+			return nil, err
+		}
+		if virtualSelectedParentInfo.BlueScore >= location.AcceptingBlockBlueScore {
+			confirmations = virtualSelectedParentInfo.BlueScore - location.AcceptingBlockBlueScore + 1
+		}
+	}
+
+	var rpcTransaction *appmessage.RPCTransaction
+	if getTransactionRequest.IncludeTransaction {
+		rpcTransaction, err = findRPCTransaction(context, transactionID, location.IncludingBlockHashes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return appmessage.NewGetTransactionResponseMessage(rpcTransaction, includingBlockHashes,
+		acceptingBlockHash, location.AcceptingBlockBlueScore, confirmations), nil
+}
+
+// findRPCTransaction looks for the transaction in the bodies of its including blocks.
+// It returns nil if all of them were pruned.
+func findRPCTransaction(context *rpccontext.Context, transactionID *externalapi.DomainTransactionID,
+	includingBlockHashes []*externalapi.DomainHash) (*appmessage.RPCTransaction, error) {
+
+	for _, includingBlockHash := range includingBlockHashes {
+		block, found, err := context.Domain.Consensus().GetBlock(includingBlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		for _, transaction := range block.Transactions {
+			if !consensushashing.TransactionID(transaction).Equal(transactionID) {
+				continue
+			}
+			rpcTransaction := appmessage.DomainTransactionToRPCTransaction(transaction)
+			err := context.PopulateTransactionWithVerboseData(rpcTransaction, block.Header)
+			if err != nil {
+				return nil, err
+			}
+			return rpcTransaction, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,26 @@
+package rpchandlers
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// HandleLoadMempool handles the respectively named RPC command
+func HandleLoadMempool(context *rpccontext.Context, _ *router.Router, _ appmessage.Message) (appmessage.Message, error) {
+	if context.Config.SafeRPC {
+		log.Warn("LoadMempool RPC command called while node in safe RPC mode -- ignoring.")
+		errorMessage := &appmessage.LoadMempoolResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("LoadMempool RPC command called while node in safe RPC mode")
+		return errorMessage, nil
+	}
+
+	acceptedTransactionCount, err := context.ProtocolManager.LoadMempool()
+	if err != nil {
+		errorMessage := &appmessage.LoadMempoolResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Could not load the mempool: %s", err)
+		return errorMessage, nil
+	}
+
+	return appmessage.NewLoadMempoolResponseMessage(uint64(acceptedTransactionCount)), nil
+}
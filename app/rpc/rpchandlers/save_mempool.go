@@ -0,0 +1,26 @@
+package rpchandlers
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+)
+
+// HandleSaveMempool handles the respectively named RPC command
+func HandleSaveMempool(context *rpccontext.Context, _ *router.Router, _ appmessage.Message) (appmessage.Message, error) {
+	if context.Config.SafeRPC {
+		log.Warn("SaveMempool RPC command called while node in safe RPC mode -- ignoring.")
+		errorMessage := &appmessage.SaveMempoolResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("SaveMempool RPC command called while node in safe RPC mode")
+		return errorMessage, nil
+	}
+
+	savedTransactionCount, err := context.ProtocolManager.SaveMempool()
+	if err != nil {
+		errorMessage := &appmessage.SaveMempoolResponseMessage{}
+		errorMessage.Error = appmessage.RPCErrorf("Could not save the mempool: %s", err)
+		return errorMessage, nil
+	}
+
+	return appmessage.NewSaveMempoolResponseMessage(uint64(savedTransactionCount)), nil
+}
@@ -3,6 +3,7 @@ package rpchandlers
 import (
 	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/app/rpc/rpccontext"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
 	"github.com/kobradag/kobrad/domain/miningmanager/mempool"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
@@ -21,7 +22,13 @@ func HandleSubmitTransaction(context *rpccontext.Context, _ *router.Router, requ
 	}
 
 	transactionID := consensushashing.TransactionID(domainTransaction)
-	err = context.ProtocolManager.AddTransaction(domainTransaction, submitTransactionRequest.AllowOrphan)
+	var replacedTransactions []*externalapi.DomainTransaction
+	if submitTransactionRequest.AllowReplacement {
+		replacedTransactions, err = context.ProtocolManager.AddReplacementTransaction(domainTransaction,
+			submitTransactionRequest.AllowOrphan)
+	} else {
+		err = context.ProtocolManager.AddTransaction(domainTransaction, submitTransactionRequest.AllowOrphan)
+	}
 	if err != nil {
 		if !errors.As(err, &mempool.RuleError{}) {
 			return nil, err
@@ -34,5 +41,10 @@ func HandleSubmitTransaction(context *rpccontext.Context, _ *router.Router, requ
 	}
 
 	response := appmessage.NewSubmitTransactionResponseMessage(transactionID.String())
+	for _, replacedTransaction := range replacedTransactions {
+		replacedTransactionID := consensushashing.TransactionID(replacedTransaction)
+		log.Debugf("Transaction %s replaced transaction %s", transactionID, replacedTransactionID)
+		response.ReplacedTransactionIDs = append(response.ReplacedTransactionIDs, replacedTransactionID.String())
+	}
 	return response, nil
 }
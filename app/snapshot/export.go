@@ -0,0 +1,262 @@
+package snapshot
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/app/protocol/common"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/ruleerrors"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+	"github.com/kobradag/kobrad/infrastructure/logger"
+	"github.com/pkg/errors"
+)
+
+// headersBatchSize is the maximum number of headers in a single headers record.
+// GetHashesBetween requires it to be at least MergeSetSizeLimit + 1
+const headersBatchSize = 1 << 10
+
+// utxoSetChunkSize is the number of UTXOs in a single UTXO set chunk record
+const utxoSetChunkSize = 1000
+
+// Summary describes the contents of a snapshot file
+type Summary struct {
+	PruningPointHash *externalapi.DomainHash
+	HeaderCount      int
+	BlockCount       int
+	UTXOCount        int
+}
+
+// Export writes the current pruning point, its proof, the past pruning points, the pruning point
+// anticone, the headers and blocks of the pruning point future and the pruning point UTXO set
+// to a snapshot file in the given path
+func Export(consensus externalapi.Consensus, params *dagconfig.Params, path string) (*Summary, error) {
+	onEnd := logger.LogAndMeasureExecutionTime(log, "snapshot.Export")
+	defer onEnd()
+
+	pruningPoint, err := consensus.PruningPoint()
+	if err != nil {
+		return nil, err
+	}
+	if pruningPoint.Equal(params.GenesisHash) {
+		return nil, errors.Errorf("cannot export a snapshot while the pruning point is the genesis")
+	}
+
+	writer, err := createFile(path)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := export(consensus, params, writer, pruningPoint)
+	if err != nil {
+		writer.discard()
+		return nil, err
+	}
+	err = writer.commit(path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Exported a snapshot of pruning point %s with %d headers, %d blocks and %d UTXOs to %s",
+		summary.PruningPointHash, summary.HeaderCount, summary.BlockCount, summary.UTXOCount, path)
+	return summary, nil
+}
+
+func export(consensus externalapi.Consensus, params *dagconfig.Params, writer *fileWriter,
+	pruningPoint *externalapi.DomainHash) (*Summary, error) {
+
+	log.Infof("Exporting the pruning point proof of %s", pruningPoint)
+	pruningPointProof, err := consensus.BuildPruningPointProof()
+	if err != nil {
+		return nil, err
+	}
+	err = writeSection(writer, appmessage.DomainPruningPointProofToMsgPruningPointProof(pruningPointProof))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Exporting the past pruning points and the pruning point anticone")
+	err = exportPruningPointsAndPruningPointAnticone(consensus, params, writer, pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Exporting the headers of the pruning point future")
+	pruningPointFuture, err := exportPruningPointFutureHeaders(consensus, writer, pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Exporting the pruning point UTXO set")
+	utxoCount, err := exportPruningPointUTXOSet(consensus, writer, pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Exporting the blocks of the pruning point future")
+	blockCount, err := exportPruningPointFutureBlocks(consensus, writer, pruningPointFuture)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pruning point might have moved while exporting, in which case the
+	// exported data is a mix of the two pruning points
+	currentPruningPoint, err := consensus.PruningPoint()
+	if err != nil {
+		return nil, err
+	}
+	if !currentPruningPoint.Equal(pruningPoint) {
+		return nil, errors.Errorf("the pruning point changed from %s to %s during the export",
+			pruningPoint, currentPruningPoint)
+	}
+
+	return &Summary{
+		PruningPointHash: pruningPoint,
+		HeaderCount:      len(pruningPointFuture),
+		BlockCount:       blockCount,
+		UTXOCount:        utxoCount,
+	}, nil
+}
+
+func writeSection(writer *fileWriter, message appmessage.Message) error {
+	err := writer.writeMessage(message)
+	if err != nil {
+		return err
+	}
+	return writer.endSection()
+}
+
+func exportPruningPointsAndPruningPointAnticone(consensus externalapi.Consensus, params *dagconfig.Params,
+	writer *fileWriter, pruningPoint *externalapi.DomainHash) error {
+
+	pruningPointHeaders, err := consensus.PruningPointHeaders()
+	if err != nil {
+		return err
+	}
+	msgPruningPointHeaders := make([]*appmessage.MsgBlockHeader, len(pruningPointHeaders))
+	for i, header := range pruningPointHeaders {
+		msgPruningPointHeaders[i] = appmessage.DomainBlockHeaderToBlockHeader(header)
+	}
+	err = writeSection(writer, appmessage.NewMsgPruningPoints(msgPruningPointHeaders))
+	if err != nil {
+		return err
+	}
+
+	trustedData, err := common.BuildPruningPointAndItsAnticoneTrustedData(consensus, params)
+	if err != nil {
+		return err
+	}
+	if !trustedData.PointAndItsAnticone[0].Equal(pruningPoint) {
+		return errors.Errorf("the pruning point changed from %s to %s during the export",
+			pruningPoint, trustedData.PointAndItsAnticone[0])
+	}
+	err = writeSection(writer, trustedData.TrustedData)
+	if err != nil {
+		return err
+	}
+
+	for _, blockHash := range trustedData.PointAndItsAnticone {
+		block, found, err := consensus.GetBlock(blockHash)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.Errorf("pruning point anticone block %s not found", blockHash)
+		}
+		err = writer.writeMessage(trustedData.BlockWithTrustedData(block))
+		if err != nil {
+			return err
+		}
+	}
+	return writer.endSection()
+}
+
+// exportPruningPointFutureHeaders writes the headers of all the blocks between the pruning point
+// and the headers selected tip, and returns their hashes in the order they were written
+func exportPruningPointFutureHeaders(consensus externalapi.Consensus, writer *fileWriter,
+	pruningPoint *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
+
+	headersSelectedTip, err := consensus.GetHeadersSelectedTip()
+	if err != nil {
+		return nil, err
+	}
+
+	pruningPointFuture := make([]*externalapi.DomainHash, 0)
+	lowHash := pruningPoint
+	for !lowHash.Equal(headersSelectedTip) {
+		blockHashes, _, err := consensus.GetHashesBetween(lowHash, headersSelectedTip, headersBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		blockHeaders := make([]*appmessage.MsgBlockHeader, len(blockHashes))
+		for i, blockHash := range blockHashes {
+			blockHeader, err := consensus.GetBlockHeader(blockHash)
+			if err != nil {
+				return nil, err
+			}
+			blockHeaders[i] = appmessage.DomainBlockHeaderToBlockHeader(blockHeader)
+		}
+		err = writer.writeMessage(appmessage.NewBlockHeadersMessage(blockHeaders))
+		if err != nil {
+			return nil, err
+		}
+
+		pruningPointFuture = append(pruningPointFuture, blockHashes...)
+		lowHash = blockHashes[len(blockHashes)-1]
+	}
+	return pruningPointFuture, writer.endSection()
+}
+
+func exportPruningPointUTXOSet(consensus externalapi.Consensus, writer *fileWriter,
+	pruningPoint *externalapi.DomainHash) (int, error) {
+
+	utxoCount := 0
+	var fromOutpoint *externalapi.DomainOutpoint
+	for {
+		pruningPointUTXOs, err := consensus.GetPruningPointUTXOs(pruningPoint, fromOutpoint, utxoSetChunkSize)
+		if err != nil {
+			if errors.Is(err, ruleerrors.ErrWrongPruningPointHash) {
+				return 0, errors.Errorf("the pruning point changed during the export of its UTXO set")
+			}
+			return 0, err
+		}
+		if len(pruningPointUTXOs) == 0 {
+			break
+		}
+
+		outpointAndUTXOEntryPairs :=
+			appmessage.DomainOutpointAndUTXOEntryPairsToOutpointAndUTXOEntryPairs(pruningPointUTXOs)
+		err = writer.writeMessage(appmessage.NewMsgPruningPointUTXOSetChunk(outpointAndUTXOEntryPairs))
+		if err != nil {
+			return 0, err
+		}
+		utxoCount += len(pruningPointUTXOs)
+
+		if len(pruningPointUTXOs) < utxoSetChunkSize {
+			break
+		}
+		fromOutpoint = pruningPointUTXOs[len(pruningPointUTXOs)-1].Outpoint
+	}
+	return utxoCount, writer.endSection()
+}
+
+// exportPruningPointFutureBlocks writes the blocks of the pruning point future that have bodies
+func exportPruningPointFutureBlocks(consensus externalapi.Consensus, writer *fileWriter,
+	pruningPointFuture []*externalapi.DomainHash) (int, error) {
+
+	blockCount := 0
+	for _, blockHash := range pruningPointFuture {
+		block, found, err := consensus.GetBlock(blockHash)
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			continue
+		}
+
+		err = writer.writeMessage(appmessage.DomainBlockToMsgBlock(block))
+		if err != nil {
+			return 0, err
+		}
+		blockCount++
+	}
+	return blockCount, writer.endSection()
+}
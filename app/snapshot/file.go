@@ -0,0 +1,218 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver/protowire"
+	"github.com/pkg/errors"
+)
+
+// A snapshot file starts with fileMagic and the file format version, followed by sections
+// of records. Every record is a little-endian uint32 length followed by a serialized
+// protowire.HarbidMessage, and every section ends with an empty record. The sections are,
+// in order, the same messages a syncer sends during IBD with a headers proof:
+//  1. The pruning point proof
+//  2. The past pruning points
+//  3. The trusted data of the pruning point and its anticone
+//  4. The pruning point and its anticone, as blocks with trusted data
+//  5. The headers of the pruning point future, in topological order
+//  6. The pruning point UTXO set, in chunks
+//  7. The blocks of the pruning point future that have bodies, in topological order
+var fileMagic = []byte("kobrasnp")
+
+// fileVersion is the version of the snapshot file format. It should be bumped whenever
+// the format changes, so that old files are rejected rather than misinterpreted.
+const fileVersion = 1
+
+// maxRecordSize is the maximum size of a single record in a snapshot file. Anything bigger
+// indicates a corrupt file.
+const maxRecordSize = 1 << 30
+
+// fileWriter writes the records of a snapshot file
+type fileWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// createFile creates a snapshot file in a temporary path next to the given path. The
+// file is moved to the given path only once it's complete, so that a failed export
+// never leaves a partial file behind.
+func createFile(path string) (*fileWriter, error) {
+	file, err := os.Create(temporaryPath(path))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	writer := &fileWriter{
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}
+	_, err = writer.writer.Write(fileMagic)
+	if err != nil {
+		writer.discard()
+		return nil, errors.WithStack(err)
+	}
+	err = binary.Write(writer.writer, binary.LittleEndian, uint32(fileVersion))
+	if err != nil {
+		writer.discard()
+		return nil, errors.WithStack(err)
+	}
+	return writer, nil
+}
+
+func temporaryPath(path string) string {
+	return path + ".tmp"
+}
+
+// writeMessage writes the given message as a single record
+func (w *fileWriter) writeMessage(message appmessage.Message) error {
+	harbidMessage, err := protowire.FromAppMessage(message)
+	if err != nil {
+		return err
+	}
+	serializedMessage, err := proto.Marshal(harbidMessage)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(serializedMessage) == 0 || len(serializedMessage) > maxRecordSize {
+		return errors.Errorf("cannot write a %s message of %d bytes to a snapshot file",
+			message.Command(), len(serializedMessage))
+	}
+
+	err = binary.Write(w.writer, binary.LittleEndian, uint32(len(serializedMessage)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.writer.Write(serializedMessage)
+	return errors.WithStack(err)
+}
+
+// endSection writes the empty record that ends the current section
+func (w *fileWriter) endSection() error {
+	return errors.WithStack(binary.Write(w.writer, binary.LittleEndian, uint32(0)))
+}
+
+// commit flushes the file to the disk and moves it to the given path
+func (w *fileWriter) commit(path string) error {
+	err := w.writer.Flush()
+	if err != nil {
+		w.discard()
+		return errors.WithStack(err)
+	}
+	err = w.file.Sync()
+	if err != nil {
+		w.discard()
+		return errors.WithStack(err)
+	}
+	err = w.file.Close()
+	if err != nil {
+		os.Remove(w.file.Name())
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(w.file.Name(), path))
+}
+
+// discard closes and removes the temporary file
+func (w *fileWriter) discard() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// fileReader reads the records of a snapshot file
+type fileReader struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// openFile opens the snapshot file in the given path and validates its magic and version
+func openFile(path string) (*fileReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	reader := &fileReader{
+		file:   file,
+		reader: bufio.NewReader(file),
+	}
+	magic := make([]byte, len(fileMagic))
+	_, err = io.ReadFull(reader.reader, magic)
+	if err != nil || !bytes.Equal(magic, fileMagic) {
+		file.Close()
+		return nil, errors.Errorf("%s is not a snapshot file", path)
+	}
+	var version uint32
+	err = binary.Read(reader.reader, binary.LittleEndian, &version)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrapf(err, "failed to read the snapshot file version")
+	}
+	if version != fileVersion {
+		file.Close()
+		return nil, errors.Errorf("unsupported snapshot file version %d", version)
+	}
+	return reader, nil
+}
+
+// readMessage reads the next record. It returns a nil message at the end of a section
+func (r *fileReader) readMessage() (appmessage.Message, error) {
+	var length uint32
+	err := binary.Read(r.reader, binary.LittleEndian, &length)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read a snapshot file record")
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	if length > maxRecordSize {
+		return nil, errors.Errorf("snapshot file record has a size of %d bytes, which is more than "+
+			"the maximum of %d", length, maxRecordSize)
+	}
+
+	serializedMessage := make([]byte, length)
+	_, err = io.ReadFull(r.reader, serializedMessage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read a snapshot file record")
+	}
+	harbidMessage := &protowire.HarbidMessage{}
+	err = proto.Unmarshal(serializedMessage, harbidMessage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to deserialize a snapshot file record")
+	}
+	return harbidMessage.ToAppMessage()
+}
+
+// readSingleMessage reads a section that consists of a single message
+func (r *fileReader) readSingleMessage() (appmessage.Message, error) {
+	message, err := r.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if message == nil {
+		return nil, errors.Errorf("unexpected empty section in the snapshot file")
+	}
+	endOfSection, err := r.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if endOfSection != nil {
+		return nil, errors.Errorf("unexpected %s message at the end of a snapshot file section",
+			endOfSection.Command())
+	}
+	return message, nil
+}
+
+func (r *fileReader) close() error {
+	return errors.WithStack(r.file.Close())
+}
+
+func unexpectedMessageError(expected appmessage.MessageCommand, message appmessage.Message) error {
+	return errors.Errorf("unexpected message in the snapshot file. expected: %s, got: %s",
+		expected, message.Command())
+}
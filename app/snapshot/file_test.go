@@ -0,0 +1,124 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.dat")
+
+	pruningPoints := appmessage.NewMsgPruningPoints([]*appmessage.MsgBlockHeader{})
+	utxoSetChunk := appmessage.NewMsgPruningPointUTXOSetChunk([]*appmessage.OutpointAndUTXOEntryPair{
+		{
+			Outpoint: &appmessage.Outpoint{
+				TxID:  *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+				Index: 2,
+			},
+			UTXOEntry: &appmessage.UTXOEntry{
+				Amount:          3,
+				ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{4, 5}, Version: 0},
+				BlockDAAScore:   6,
+				IsCoinbase:      true,
+			},
+		},
+	})
+
+	writer, err := createFile(path)
+	if err != nil {
+		t.Fatalf("createFile: %+v", err)
+	}
+	err = writeSection(writer, pruningPoints)
+	if err != nil {
+		t.Fatalf("writeSection: %+v", err)
+	}
+	err = writer.writeMessage(utxoSetChunk)
+	if err != nil {
+		t.Fatalf("writeMessage: %+v", err)
+	}
+	err = writer.writeMessage(utxoSetChunk)
+	if err != nil {
+		t.Fatalf("writeMessage: %+v", err)
+	}
+	err = writer.endSection()
+	if err != nil {
+		t.Fatalf("endSection: %+v", err)
+	}
+	err = writer.commit(path)
+	if err != nil {
+		t.Fatalf("commit: %+v", err)
+	}
+	if _, err := os.Stat(temporaryPath(path)); !os.IsNotExist(err) {
+		t.Fatalf("the temporary file was not removed by commit")
+	}
+
+	reader, err := openFile(path)
+	if err != nil {
+		t.Fatalf("openFile: %+v", err)
+	}
+	defer reader.close()
+
+	message, err := reader.readSingleMessage()
+	if err != nil {
+		t.Fatalf("readSingleMessage: %+v", err)
+	}
+	if _, ok := message.(*appmessage.MsgPruningPoints); !ok {
+		t.Fatalf("expected %s, got %s", appmessage.CmdPruningPoints, message.Command())
+	}
+
+	readChunkCount := 0
+	for {
+		message, err := reader.readMessage()
+		if err != nil {
+			t.Fatalf("readMessage: %+v", err)
+		}
+		if message == nil {
+			break
+		}
+		readChunk, ok := message.(*appmessage.MsgPruningPointUTXOSetChunk)
+		if !ok {
+			t.Fatalf("expected %s, got %s", appmessage.CmdPruningPointUTXOSetChunk, message.Command())
+		}
+		if !reflect.DeepEqual(readChunk.OutpointAndUTXOEntryPairs, utxoSetChunk.OutpointAndUTXOEntryPairs) {
+			t.Fatalf("the read UTXO set chunk is different from the written one")
+		}
+		readChunkCount++
+	}
+	if readChunkCount != 2 {
+		t.Fatalf("expected 2 UTXO set chunks, got %d", readChunkCount)
+	}
+
+	_, err = reader.readMessage()
+	if err == nil {
+		t.Fatalf("expected an error when reading past the end of the file")
+	}
+}
+
+func TestOpenFileRejectsInvalidFiles(t *testing.T) {
+	directory := t.TempDir()
+
+	notSnapshotPath := filepath.Join(directory, "mempool.dat")
+	err := os.WriteFile(notSnapshotPath, []byte("not a snapshot file"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	_, err = openFile(notSnapshotPath)
+	if err == nil {
+		t.Fatalf("expected openFile to reject a file with a wrong magic")
+	}
+
+	futureVersionPath := filepath.Join(directory, "future.dat")
+	err = os.WriteFile(futureVersionPath, append(append([]byte{}, fileMagic...), fileVersion+1, 0, 0, 0), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	_, err = openFile(futureVersionPath)
+	if err == nil {
+		t.Fatalf("expected openFile to reject an unsupported version")
+	}
+}
@@ -0,0 +1,412 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/domain"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/ruleerrors"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+	"github.com/kobradag/kobrad/infrastructure/logger"
+	"github.com/pkg/errors"
+)
+
+// ErrPruningPointAlreadyKnown is returned from Import when the pruning point
+// of the snapshot file is already in the DAG
+var ErrPruningPointAlreadyKnown = errors.New("the pruning point of the snapshot is already known")
+
+// Import reads the snapshot file in the given path and inserts it into a staging consensus the same
+// way IBD with a headers proof does, so the snapshot is validated exactly like data received from a
+// peer. Once the pruning point and its UTXO set are validated the staging consensus replaces the
+// current one, and the blocks of the pruning point future are inserted into it.
+func Import(domain domain.Domain, params *dagconfig.Params, path string) (*Summary, error) {
+	onEnd := logger.LogAndMeasureExecutionTime(log, "snapshot.Import")
+	defer onEnd()
+
+	reader, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.close()
+
+	message, err := reader.readSingleMessage()
+	if err != nil {
+		return nil, err
+	}
+	msgPruningPointProof, ok := message.(*appmessage.MsgPruningPointProof)
+	if !ok {
+		return nil, unexpectedMessageError(appmessage.CmdPruningPointProof, message)
+	}
+	pruningPointProof := appmessage.MsgPruningPointProofToDomainPruningPointProof(msgPruningPointProof)
+	if len(pruningPointProof.Headers) == 0 || len(pruningPointProof.Headers[0]) == 0 {
+		return nil, errors.Errorf("the snapshot pruning point proof is empty")
+	}
+	pruningPoint := consensushashing.HeaderHash(pruningPointProof.Headers[0][len(pruningPointProof.Headers[0])-1])
+
+	pruningPointInfo, err := domain.Consensus().GetBlockInfo(pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+	if pruningPointInfo.Exists {
+		return nil, errors.Wrapf(ErrPruningPointAlreadyKnown, "pruning point %s", pruningPoint)
+	}
+
+	err = domain.InitStagingConsensusWithoutGenesis()
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := importIntoStagingConsensus(domain, params, reader, pruningPointProof, pruningPoint)
+	if err != nil {
+		log.Infof("Importing the snapshot was unsuccessful. Deleting the staging consensus. (%s)", err)
+		deleteStagingConsensusErr := domain.DeleteStagingConsensus()
+		if deleteStagingConsensusErr != nil {
+			return nil, deleteStagingConsensusErr
+		}
+		return nil, err
+	}
+
+	log.Infof("The snapshot pruning point %s was imported successfully. Committing the staging consensus "+
+		"and deleting the previous obsolete one if such exists.", pruningPoint)
+	err = domain.CommitStagingConsensus()
+	if err != nil {
+		return nil, err
+	}
+
+	summary.BlockCount, err = importPruningPointFutureBlocks(domain.Consensus(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Imported a snapshot of pruning point %s with %d headers, %d blocks and %d UTXOs from %s",
+		summary.PruningPointHash, summary.HeaderCount, summary.BlockCount, summary.UTXOCount, path)
+	return summary, nil
+}
+
+func importIntoStagingConsensus(domain domain.Domain, params *dagconfig.Params, reader *fileReader,
+	pruningPointProof *externalapi.PruningPointProof, pruningPoint *externalapi.DomainHash) (*Summary, error) {
+
+	log.Infof("Validating the snapshot pruning point proof of %s", pruningPoint)
+	err := domain.Consensus().ValidatePruningPointProof(pruningPointProof)
+	if err != nil {
+		return nil, err
+	}
+	err = domain.StagingConsensus().ApplyPruningPointProof(pruningPointProof)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Importing the past pruning points and the pruning point anticone")
+	err = importPruningPoints(domain, reader, pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+	err = importPruningPointAnticone(domain.StagingConsensus(), reader, pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: Remove this condition once there's more proper way to check finality violation
+	// in the headers proof.
+	if pruningPoint.Equal(params.GenesisHash) {
+		return nil, errors.Errorf("the genesis pruning point violates finality")
+	}
+
+	log.Infof("Importing the headers of the pruning point future")
+	headerCount, err := importPruningPointFutureHeaders(domain.StagingConsensus(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validatePruningPointFutureHeaderTimestamps(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Checking if the snapshot pruning point %s is compatible to the node DAG", pruningPoint)
+	isValid, err := domain.StagingConsensus().IsValidPruningPoint(pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+	if !isValid {
+		return nil, errors.Errorf("invalid pruning point %s", pruningPoint)
+	}
+
+	log.Infof("Importing the pruning point UTXO set")
+	utxoCount, err := importPruningPointUTXOSet(domain.StagingConsensus(), reader, pruningPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Summary{
+		PruningPointHash: pruningPoint,
+		HeaderCount:      headerCount,
+		UTXOCount:        utxoCount,
+	}, nil
+}
+
+func importPruningPoints(domain domain.Domain, reader *fileReader, pruningPoint *externalapi.DomainHash) error {
+	currentPruningPoint, err := domain.Consensus().PruningPoint()
+	if err != nil {
+		return err
+	}
+	if currentPruningPoint.Equal(pruningPoint) {
+		return errors.Errorf("the snapshot pruning point is the same as the current pruning point")
+	}
+
+	message, err := reader.readSingleMessage()
+	if err != nil {
+		return err
+	}
+	msgPruningPoints, ok := message.(*appmessage.MsgPruningPoints)
+	if !ok {
+		return unexpectedMessageError(appmessage.CmdPruningPoints, message)
+	}
+	if len(msgPruningPoints.Headers) == 0 {
+		return errors.Errorf("the snapshot has no pruning points")
+	}
+
+	headers := make([]externalapi.BlockHeader, len(msgPruningPoints.Headers))
+	for i, header := range msgPruningPoints.Headers {
+		headers[i] = appmessage.BlockHeaderToDomainBlockHeader(header)
+	}
+
+	arePruningPointsViolatingFinality, err := domain.Consensus().ArePruningPointsViolatingFinality(headers)
+	if err != nil {
+		return err
+	}
+	if arePruningPointsViolatingFinality {
+		return errors.Errorf("the snapshot pruning points are violating finality")
+	}
+
+	lastPruningPoint := consensushashing.HeaderHash(headers[len(headers)-1])
+	if !lastPruningPoint.Equal(pruningPoint) {
+		return errors.Errorf("the proof pruning point is not equal to the last pruning point in the snapshot")
+	}
+
+	return domain.StagingConsensus().ImportPruningPoints(headers)
+}
+
+func importPruningPointAnticone(consensus externalapi.Consensus, reader *fileReader,
+	pruningPoint *externalapi.DomainHash) error {
+
+	message, err := reader.readSingleMessage()
+	if err != nil {
+		return err
+	}
+	msgTrustedData, ok := message.(*appmessage.MsgTrustedData)
+	if !ok {
+		return unexpectedMessageError(appmessage.CmdTrustedData, message)
+	}
+
+	blockCount := 0
+	for ; ; blockCount++ {
+		message, err := reader.readMessage()
+		if err != nil {
+			return err
+		}
+		if message == nil {
+			break
+		}
+		msgBlockWithTrustedData, ok := message.(*appmessage.MsgBlockWithTrustedDataV4)
+		if !ok {
+			return unexpectedMessageError(appmessage.CmdBlockWithTrustedDataV4, message)
+		}
+		if blockCount == 0 && !msgBlockWithTrustedData.Block.Header.BlockHash().Equal(pruningPoint) {
+			return errors.Errorf("the first block with trusted data in the snapshot is not the pruning point")
+		}
+
+		blockWithTrustedData :=
+			appmessage.BlockWithTrustedDataV4ToDomainBlockWithTrustedData(msgBlockWithTrustedData, msgTrustedData)
+		err = consensus.ValidateAndInsertBlockWithTrustedData(blockWithTrustedData, false)
+		if err != nil {
+			return errors.Wrapf(err, "failed validating block with trusted data %s",
+				consensushashing.BlockHash(blockWithTrustedData.Block))
+		}
+	}
+	if blockCount == 0 {
+		return errors.Errorf("the snapshot doesn't contain the pruning point")
+	}
+
+	log.Infof("Imported the pruning point and its anticone. Total blocks: %d", blockCount)
+	return nil
+}
+
+func importPruningPointFutureHeaders(consensus externalapi.Consensus, reader *fileReader) (int, error) {
+	headerCount := 0
+	for {
+		message, err := reader.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		if message == nil {
+			break
+		}
+		blockHeadersMessage, ok := message.(*appmessage.BlockHeadersMessage)
+		if !ok {
+			return 0, unexpectedMessageError(appmessage.CmdBlockHeaders, message)
+		}
+
+		for _, msgBlockHeader := range blockHeadersMessage.BlockHeaders {
+			err := insertHeader(consensus, appmessage.BlockHeaderToDomainBlockHeader(msgBlockHeader))
+			if err != nil {
+				return 0, err
+			}
+		}
+		headerCount += len(blockHeadersMessage.BlockHeaders)
+		log.Infof("Imported %d block headers", headerCount)
+	}
+	return headerCount, nil
+}
+
+func insertHeader(consensus externalapi.Consensus, header externalapi.BlockHeader) error {
+	block := &externalapi.DomainBlock{
+		Header:       header,
+		Transactions: nil,
+	}
+
+	blockHash := consensushashing.BlockHash(block)
+	blockInfo, err := consensus.GetBlockInfo(blockHash)
+	if err != nil {
+		return err
+	}
+	if blockInfo.Exists {
+		log.Debugf("Block header %s is already in the DAG. Skipping...", blockHash)
+		return nil
+	}
+	err = consensus.ValidateAndInsertBlock(block, false)
+	if err != nil {
+		if errors.Is(err, ruleerrors.ErrDuplicateBlock) {
+			log.Debugf("Skipping block header %s as it is a duplicate", blockHash)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to import block header %s", blockHash)
+	}
+	return nil
+}
+
+// validatePruningPointFutureHeaderTimestamps makes sure the imported headers are meaningfully
+// ahead of the headers of the current consensus, so that the import never rolls the node back
+func validatePruningPointFutureHeaderTimestamps(domain domain.Domain) error {
+	headerSelectedTipHash, err := domain.StagingConsensus().GetHeadersSelectedTip()
+	if err != nil {
+		return err
+	}
+	headerSelectedTipHeader, err := domain.StagingConsensus().GetBlockHeader(headerSelectedTipHash)
+	if err != nil {
+		return err
+	}
+	headerSelectedTipTimestamp := headerSelectedTipHeader.TimeInMilliseconds()
+
+	currentSelectedTipHash, err := domain.Consensus().GetHeadersSelectedTip()
+	if err != nil {
+		return err
+	}
+	currentSelectedTipHeader, err := domain.Consensus().GetBlockHeader(currentSelectedTipHash)
+	if err != nil {
+		return err
+	}
+	currentSelectedTipTimestamp := currentSelectedTipHeader.TimeInMilliseconds()
+
+	if headerSelectedTipTimestamp < currentSelectedTipTimestamp {
+		return errors.Errorf("the timestamp of the snapshot selected tip is smaller than the current selected tip")
+	}
+
+	minTimestampDifferenceInMilliseconds := (10 * time.Minute).Milliseconds()
+	if headerSelectedTipTimestamp-currentSelectedTipTimestamp < minTimestampDifferenceInMilliseconds {
+		return errors.Errorf("difference between the timestamps of the current selected tip and the " +
+			"snapshot selected tip is too small")
+	}
+	return nil
+}
+
+func importPruningPointUTXOSet(consensus externalapi.Consensus, reader *fileReader,
+	pruningPoint *externalapi.DomainHash) (int, error) {
+
+	defer func() {
+		err := consensus.ClearImportedPruningPointData()
+		if err != nil {
+			panic(fmt.Sprintf("failed to clear imported pruning point data: %s", err))
+		}
+	}()
+
+	utxoCount := 0
+	for {
+		message, err := reader.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		if message == nil {
+			break
+		}
+		msgPruningPointUTXOSetChunk, ok := message.(*appmessage.MsgPruningPointUTXOSetChunk)
+		if !ok {
+			return 0, unexpectedMessageError(appmessage.CmdPruningPointUTXOSetChunk, message)
+		}
+
+		domainOutpointAndUTXOEntryPairs := appmessage.OutpointAndUTXOEntryPairsToDomainOutpointAndUTXOEntryPairs(
+			msgPruningPointUTXOSetChunk.OutpointAndUTXOEntryPairs)
+		err = consensus.AppendImportedPruningPointUTXOs(domainOutpointAndUTXOEntryPairs)
+		if err != nil {
+			return 0, err
+		}
+		utxoCount += len(domainOutpointAndUTXOEntryPairs)
+	}
+	log.Infof("Imported %d UTXOs. Validating the pruning point UTXO set", utxoCount)
+
+	err := consensus.ValidateAndInsertImportedPruningPoint(pruningPoint)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error with the snapshot pruning point UTXO set")
+	}
+	return utxoCount, nil
+}
+
+// importPruningPointFutureBlocks inserts the block bodies of the pruning point future,
+// and then resolves the virtual
+func importPruningPointFutureBlocks(consensus externalapi.Consensus, reader *fileReader) (int, error) {
+	blockCount := 0
+	for {
+		message, err := reader.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		if message == nil {
+			break
+		}
+		msgBlock, ok := message.(*appmessage.MsgBlock)
+		if !ok {
+			return 0, unexpectedMessageError(appmessage.CmdBlock, message)
+		}
+
+		block := appmessage.MsgBlockToDomainBlock(msgBlock)
+		err = consensus.ValidateAndInsertBlock(block, false)
+		if err != nil {
+			if errors.Is(err, ruleerrors.ErrDuplicateBlock) {
+				log.Debugf("Skipping block %s as it has already been added to the DAG",
+					consensushashing.BlockHash(block))
+				continue
+			}
+			return 0, errors.Wrapf(err, "failed to import block %s", consensushashing.BlockHash(block))
+		}
+
+		blockCount++
+		if blockCount%1000 == 0 {
+			log.Infof("Imported %d blocks", blockCount)
+		}
+	}
+	if blockCount == 0 {
+		return 0, nil
+	}
+
+	err := consensus.ResolveVirtual(func(virtualDAAScoreStart uint64, virtualDAAScore uint64) {
+		log.Infof("Resolving virtual. Current DAA score: %d", virtualDAAScore)
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.Infof("Resolved virtual")
+	return blockCount, nil
+}
@@ -0,0 +1,7 @@
+package snapshot
+
+import (
+	"github.com/kobradag/kobrad/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("SNAP")
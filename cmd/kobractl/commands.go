@@ -34,6 +34,12 @@ var commandTypes = []reflect.Type{
 	reflect.TypeOf(protowire.HarbidMessage_GetMempoolEntriesByAddressesRequest{}),
 
 	reflect.TypeOf(protowire.HarbidMessage_SubmitTransactionRequest{}),
+	reflect.TypeOf(protowire.HarbidMessage_GetTransactionRequest{}),
+	reflect.TypeOf(protowire.HarbidMessage_GetFeeEstimateRequest{}),
+	reflect.TypeOf(protowire.HarbidMessage_SaveMempoolRequest{}),
+	reflect.TypeOf(protowire.HarbidMessage_LoadMempoolRequest{}),
+	reflect.TypeOf(protowire.HarbidMessage_ExportPruningPointSnapshotRequest{}),
+	reflect.TypeOf(protowire.HarbidMessage_GetNetTotalsRequest{}),
 
 	reflect.TypeOf(protowire.HarbidMessage_GetUtxosByAddressesRequest{}),
 	reflect.TypeOf(protowire.HarbidMessage_GetBalanceByAddressRequest{}),
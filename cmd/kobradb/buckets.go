@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/kobradag/kobrad/domain/prefixmanager"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+)
+
+// bucketSeparator separates the buckets in a database key. See database.MakeBucket
+const bucketSeparator = '/'
+
+// noPrefix marks keys that don't belong to any consensus instance
+const noPrefix = -1
+
+// noLevel marks keys that aren't stored per block level
+const noLevel = -1
+
+// bucketStores maps the bucket and key names in the database to the packages that own them.
+// Consensus stores are in domain/consensus/datastructures
+var bucketStores = map[string]string{
+	"blocks":                                "blockstore",
+	"blocks-count":                          "blockstore",
+	"block-headers":                         "blockheaderstore",
+	"block-headers-count":                   "blockheaderstore",
+	"block-statuses":                        "blockstatusstore",
+	"block-relations":                       "blockrelationstore",
+	"block-ghostdag-data":                   "ghostdagdatastore",
+	"block-with-trusted-data-ghostdag-data": "ghostdagdatastore",
+	"reachability-data":                     "reachabilitydatastore",
+	"reachability-reindex-root":             "reachabilitydatastore",
+	"multisets":                             "multisetstore",
+	"utxo-diffs":                            "utxodiffstore",
+	"utxo-diff-children":                    "utxodiffstore",
+	"acceptance-data":                       "acceptancedatastore",
+	"finality-points":                       "finalitystore",
+	"merge-depth-roots":                     "mergedepthrootstore",
+	"daa-window":                            "daawindowstore",
+	"daa-score":                             "daablocksstore",
+	"daa-added-blocks":                      "daablocksstore",
+	"tips":                                  "consensusstatestore",
+	"virtual-utxo-set":                      "consensusstatestore",
+	"importing-pruning-point-utxo-set":      "consensusstatestore",
+	"headers-selected-tip":                  "headersselectedtipstore",
+	"chain-block-hash-by-index":             "headersselectedchainstore",
+	"chain-block-index-by-hash":             "headersselectedchainstore",
+	"highest-chain-block-index":             "headersselectedchainstore",
+	"pruning-block-index":                   "pruningstore",
+	"candidate-pruning-point-hash":          "pruningstore",
+	"pruning-point-utxo-set":                "pruningstore",
+	"updating-pruning-point-utxo-set":       "pruningstore",
+	"pruning-point-by-index":                "pruningstore",
+	"imported-pruning-point-utxos":          "pruningstore",
+	"imported-pruning-point-multiset":       "pruningstore",
+	"pruningProofManager":                   "pruningproofmanager",
+	"TMP":                                   "pruningproofmanager",
+	"active-prefix":                         "prefixmanager",
+	"inactive-prefix":                       "prefixmanager",
+	"utxo-index":                            "utxoindex",
+	"utxo-index-virtual-parents":            "utxoindex",
+	"utxo-index-circulating-supply":         "utxoindex",
+	"tx-index-accepting-block":              "txindex",
+	"tx-index-including-blocks":             "txindex",
+	"tx-index-selected-tip":                 "txindex",
+}
+
+// bucketID identifies the bucket a database key belongs to
+type bucketID struct {
+	prefix int
+	level  int
+	name   string
+}
+
+type bucketStatistics struct {
+	keyCount   uint64
+	keyBytes   uint64
+	valueBytes uint64
+}
+
+// keyBucket returns the bucket of the given database key. Consensus keys start with
+// the prefix of their consensus instance, and stores that are kept per block level
+// add the level right after it.
+func keyBucket(key []byte) bucketID {
+	id := bucketID{prefix: noPrefix, level: noLevel}
+	if len(key) > 2 && key[0] <= 1 && key[1] == bucketSeparator {
+		id.prefix = int(key[0])
+		key = key[2:]
+		switch {
+		case len(key) > 1 && key[0] == bucketSeparator:
+			// MakeBucket doesn't add a separator after a level that equals the separator itself
+			id.level = bucketSeparator
+			key = key[1:]
+		case len(key) > 2 && key[1] == bucketSeparator:
+			id.level = int(key[0])
+			key = key[2:]
+		}
+	}
+
+	separatorIndex := bytes.IndexByte(key, bucketSeparator)
+	if separatorIndex >= 0 {
+		key = key[:separatorIndex]
+	}
+	id.name = string(key)
+	return id
+}
+
+func buckets(conf *bucketsConfig) error {
+	db, err := openDatabase(&conf.databaseFlags, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statistics, err := collectBucketStatistics(db)
+	if err != nil {
+		return err
+	}
+
+	prefixNames, err := consensusPrefixNames(db)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]bucketID, 0, len(statistics))
+	for id := range statistics {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].prefix != ids[j].prefix {
+			return ids[i].prefix < ids[j].prefix
+		}
+		if ids[i].name != ids[j].name {
+			return ids[i].name < ids[j].name
+		}
+		return ids[i].level < ids[j].level
+	})
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PREFIX\tLEVEL\tBUCKET\tSTORE\tKEYS\tKEY BYTES\tVALUE BYTES\t")
+	total := bucketStatistics{}
+	for _, id := range ids {
+		prefixName := "-"
+		if id.prefix != noPrefix {
+			prefixName = fmt.Sprintf("%d", id.prefix)
+			if name, ok := prefixNames[id.prefix]; ok {
+				prefixName = name
+			}
+		}
+		levelName := "-"
+		if id.level != noLevel {
+			levelName = fmt.Sprintf("%d", id.level)
+		}
+		store, ok := bucketStores[id.name]
+		if !ok {
+			store = "unknown"
+		}
+
+		bucket := statistics[id]
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t\n", prefixName, levelName, id.name, store,
+			bucket.keyCount, bucket.keyBytes, bucket.valueBytes)
+		total.keyCount += bucket.keyCount
+		total.keyBytes += bucket.keyBytes
+		total.valueBytes += bucket.valueBytes
+	}
+	fmt.Fprintf(writer, "total\t\t\t\t%d\t%d\t%d\t\n", total.keyCount, total.keyBytes, total.valueBytes)
+	return writer.Flush()
+}
+
+func collectBucketStatistics(db database.Database) (map[bucketID]*bucketStatistics, error) {
+	cursor, err := db.Cursor(database.MakeBucket(nil))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	statistics := make(map[bucketID]*bucketStatistics)
+	for cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return nil, err
+		}
+		value, err := cursor.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		keyBytes := key.Bytes()
+		id := keyBucket(keyBytes)
+		bucket, ok := statistics[id]
+		if !ok {
+			bucket = &bucketStatistics{}
+			statistics[id] = bucket
+		}
+		bucket.keyCount++
+		bucket.keyBytes += uint64(len(keyBytes))
+		bucket.valueBytes += uint64(len(value))
+	}
+	return statistics, nil
+}
+
+// consensusPrefixNames returns the names of the database prefixes of the active and inactive consensus
+func consensusPrefixNames(db database.Database) (map[int]string, error) {
+	prefixNames := make(map[int]string)
+	activePrefix, exists, err := prefixmanager.ActivePrefix(db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		prefixNames[int(activePrefix.Serialize()[0])] = fmt.Sprintf("%d (active)", activePrefix.Serialize()[0])
+	}
+	inactivePrefix, exists, err := prefixmanager.InactivePrefix(db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		prefixNames[int(inactivePrefix.Serialize()[0])] = fmt.Sprintf("%d (inactive)", inactivePrefix.Serialize()[0])
+	}
+	return prefixNames, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+)
+
+func TestKeyBucket(t *testing.T) {
+	prefixBucket := database.MakeBucket([]byte{1})
+	tests := []struct {
+		key      *database.Key
+		expected bucketID
+	}{
+		{
+			key:      database.MakeBucket(nil).Key([]byte("active-prefix")),
+			expected: bucketID{prefix: noPrefix, level: noLevel, name: "active-prefix"},
+		},
+		{
+			key:      database.MakeBucket([]byte("utxo-index")).Key([]byte{0, '/', 2}),
+			expected: bucketID{prefix: noPrefix, level: noLevel, name: "utxo-index"},
+		},
+		{
+			key:      prefixBucket.Key([]byte("tips")),
+			expected: bucketID{prefix: 1, level: noLevel, name: "tips"},
+		},
+		{
+			key:      prefixBucket.Bucket([]byte("blocks")).Key([]byte{'/', 1, 2}),
+			expected: bucketID{prefix: 1, level: noLevel, name: "blocks"},
+		},
+		{
+			key:      prefixBucket.Bucket([]byte{3}).Bucket([]byte("block-relations")).Key([]byte{1, 2}),
+			expected: bucketID{prefix: 1, level: 3, name: "block-relations"},
+		},
+		{
+			key:      prefixBucket.Bucket([]byte{bucketSeparator}).Bucket([]byte("block-relations")).Key([]byte{1, 2}),
+			expected: bucketID{prefix: 1, level: bucketSeparator, name: "block-relations"},
+		},
+		{
+			key:      prefixBucket.Bucket([]byte{3}).Key([]byte("reachability-reindex-root")),
+			expected: bucketID{prefix: 1, level: 3, name: "reachability-reindex-root"},
+		},
+	}
+
+	for _, test := range tests {
+		id := keyBucket(test.key.Bytes())
+		if id != test.expected {
+			t.Errorf("key %q: expected bucket %+v but got %+v", test.key.Bytes(), test.expected, id)
+		}
+	}
+}
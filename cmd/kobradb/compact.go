@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func compact(conf *compactConfig) error {
+	db, err := openDatabase(&conf.databaseFlags, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fmt.Println("Compacting the database. This may take a while")
+	start := time.Now()
+	err = db.Compact()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Compacted the database in %s\n", time.Since(start).Round(time.Second))
+	return nil
+}
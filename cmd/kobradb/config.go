@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/kobradag/kobrad/infrastructure/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	bucketsSubCmd = "buckets"
+	dumpSubCmd    = "dump"
+	stateSubCmd   = "state"
+	compactSubCmd = "compact"
+	verifySubCmd  = "verify"
+)
+
+const (
+	recordBlock        = "block"
+	recordHeader       = "header"
+	recordGHOSTDAG     = "ghostdag"
+	recordReachability = "reachability"
+)
+
+type configFlags struct{}
+
+// databaseFlags are the flags that select the database to open. They are shared by all sub-commands
+type databaseFlags struct {
+	AppDir string `short:"b" long:"appdir" description:"The app directory of the node whose database should be opened"`
+	DbType string `long:"dbtype" description:"The database backend of the node: leveldb or pebble"`
+	config.NetworkFlags
+}
+
+type bucketsConfig struct {
+	databaseFlags
+}
+
+type dumpConfig struct {
+	Record         string `long:"record" short:"r" description:"The record to dump: block, header, ghostdag or reachability" required:"true"`
+	Hash           string `long:"hash" description:"The hash of the block whose record should be dumped" required:"true"`
+	Level          int    `long:"level" description:"The block level of the ghostdag or reachability record"`
+	Trusted        bool   `long:"trusted" description:"Dump the ghostdag record of a block that was received with trusted data"`
+	InactivePrefix bool   `long:"inactive-prefix" description:"Read from the inactive consensus instead of the active one"`
+	databaseFlags
+}
+
+type stateConfig struct {
+	InactivePrefix bool `long:"inactive-prefix" description:"Read from the inactive consensus instead of the active one"`
+	databaseFlags
+}
+
+type compactConfig struct {
+	databaseFlags
+}
+
+type verifyConfig struct {
+	SkipUTXOSets   bool `long:"skip-utxo-sets" description:"Skip verifying the virtual and pruning point UTXO sets, which requires reading them entirely"`
+	InactivePrefix bool `long:"inactive-prefix" description:"Verify the inactive consensus instead of the active one"`
+	databaseFlags
+}
+
+func newDatabaseFlags() databaseFlags {
+	return databaseFlags{
+		AppDir: config.DefaultAppDir,
+		DbType: config.DbTypeLevelDB,
+	}
+}
+
+func parseCommandLine() (subCommand string, config interface{}) {
+	cfg := &configFlags{}
+	parser := flags.NewParser(cfg, flags.PrintErrors|flags.HelpFlag)
+
+	bucketsConf := &bucketsConfig{databaseFlags: newDatabaseFlags()}
+	parser.AddCommand(bucketsSubCmd, "Lists the database buckets",
+		"Lists the buckets of every store in the database, along with their key counts and sizes", bucketsConf)
+
+	dumpConf := &dumpConfig{databaseFlags: newDatabaseFlags()}
+	parser.AddCommand(dumpSubCmd, "Dumps a record of a block",
+		"Dumps the block, header, ghostdag or reachability record of the block with the given hash", dumpConf)
+
+	stateConf := &stateConfig{databaseFlags: newDatabaseFlags()}
+	parser.AddCommand(stateSubCmd, "Prints the consensus state",
+		"Prints the pruning point, the tips and the virtual block state", stateConf)
+
+	compactConf := &compactConfig{databaseFlags: newDatabaseFlags()}
+	parser.AddCommand(compactSubCmd, "Compacts the database",
+		"Compacts the whole database. This is the only sub-command that writes to the database", compactConf)
+
+	verifyConf := &verifyConfig{databaseFlags: newDatabaseFlags()}
+	parser.AddCommand(verifySubCmd, "Verifies the consistency of the database",
+		"Verifies that the stores of the consensus are consistent with each other, and that the virtual and pruning "+
+			"point UTXO sets match their commitments. Inconsistencies are reported, and never fixed", verifyConf)
+
+	_, err := parser.Parse()
+	if err != nil {
+		var flagsErr *flags.Error
+		if ok := errors.As(err, &flagsErr); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			os.Exit(1)
+		}
+		return "", nil
+	}
+
+	switch parser.Command.Active.Name {
+	case bucketsSubCmd:
+		err := bucketsConf.resolve(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = bucketsConf
+	case dumpSubCmd:
+		err := dumpConf.resolve(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		err = validateDumpConfig(dumpConf)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = dumpConf
+	case stateSubCmd:
+		err := stateConf.resolve(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = stateConf
+	case compactSubCmd:
+		err := compactConf.resolve(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = compactConf
+	case verifySubCmd:
+		err := verifyConf.resolve(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = verifyConf
+	}
+
+	return parser.Command.Active.Name, config
+}
+
+func (dbFlags *databaseFlags) resolve(parser *flags.Parser) error {
+	err := dbFlags.ResolveNetwork(parser)
+	if err != nil {
+		return err
+	}
+
+	switch dbFlags.DbType {
+	case config.DbTypeLevelDB, config.DbTypePebble:
+	default:
+		return errors.Errorf("unknown database type '%s'. Supported types are %s and %s",
+			dbFlags.DbType, config.DbTypeLevelDB, config.DbTypePebble)
+	}
+	return nil
+}
+
+// networkAppDir returns the app directory of the selected network, the same way kobrad resolves it
+func (dbFlags *databaseFlags) networkAppDir() string {
+	return filepath.Join(dbFlags.AppDir, dbFlags.NetParams().Name)
+}
+
+func validateDumpConfig(conf *dumpConfig) error {
+	switch conf.Record {
+	case recordBlock, recordHeader, recordGHOSTDAG, recordReachability:
+	default:
+		return errors.Errorf("unknown record '%s'. Supported records are %s, %s, %s and %s",
+			conf.Record, recordBlock, recordHeader, recordGHOSTDAG, recordReachability)
+	}
+
+	if conf.Level < 0 {
+		return errors.Errorf("--level must not be negative")
+	}
+	if conf.Level != 0 && conf.Record != recordGHOSTDAG && conf.Record != recordReachability {
+		return errors.Errorf("--level is only supported by the %s and %s records", recordGHOSTDAG, recordReachability)
+	}
+	if conf.Trusted && conf.Record != recordGHOSTDAG {
+		return errors.Errorf("--trusted is only supported by the %s record", recordGHOSTDAG)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/domain/consensus/model"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/hashes"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+// printableGHOSTDAGData is the JSON representation of externalapi.BlockGHOSTDAGData
+type printableGHOSTDAGData struct {
+	BlueScore          uint64
+	BlueWork           string
+	SelectedParent     string
+	MergeSetBlues      []string
+	MergeSetReds       []string
+	BluesAnticoneSizes map[string]externalapi.KType
+}
+
+// printableReachabilityData is the JSON representation of model.ReachabilityData
+type printableReachabilityData struct {
+	Parent            string
+	Children          []string
+	IntervalStart     uint64
+	IntervalEnd       uint64
+	FutureCoveringSet []string
+}
+
+func dump(conf *dumpConfig) error {
+	blockHash, err := externalapi.NewDomainHashFromString(conf.Hash)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse --hash")
+	}
+	if conf.Level > conf.NetParams().MaxBlockLevel {
+		return errors.Errorf("--level must not be greater than %d", conf.NetParams().MaxBlockLevel)
+	}
+
+	db, err := openDatabase(&conf.databaseFlags, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbPrefix, err := consensusPrefix(db, conf.InactivePrefix)
+	if err != nil {
+		return err
+	}
+	stores, err := newConsensusStores(db, dbPrefix)
+	if err != nil {
+		return err
+	}
+
+	var record interface{}
+	switch conf.Record {
+	case recordBlock:
+		record, err = dumpBlock(stores, blockHash)
+	case recordHeader:
+		record, err = dumpHeader(stores, blockHash)
+	case recordGHOSTDAG:
+		record, err = dumpGHOSTDAGData(stores, blockHash, conf.Level, conf.Trusted)
+	case recordReachability:
+		record, err = dumpReachabilityData(stores, blockHash, conf.Level)
+	}
+	if database.IsNotFoundError(err) {
+		return errors.Errorf("block %s has no %s record", blockHash, conf.Record)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(record)
+}
+
+func dumpBlock(stores *consensusStores, blockHash *externalapi.DomainHash) (interface{}, error) {
+	block, err := stores.blockStore.Block(stores.dbContext, model.NewStagingArea(), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return appmessage.DomainBlockToRPCBlock(block), nil
+}
+
+func dumpHeader(stores *consensusStores, blockHash *externalapi.DomainHash) (interface{}, error) {
+	header, err := stores.blockHeaderStore.BlockHeader(stores.dbContext, model.NewStagingArea(), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return appmessage.DomainBlockToRPCBlock(&externalapi.DomainBlock{Header: header}).Header, nil
+}
+
+func dumpGHOSTDAGData(stores *consensusStores, blockHash *externalapi.DomainHash,
+	level int, isTrustedData bool) (interface{}, error) {
+
+	ghostdagData, err := stores.ghostdagDataStore(level).Get(
+		stores.dbContext, model.NewStagingArea(), blockHash, isTrustedData)
+	if err != nil {
+		return nil, err
+	}
+	return toPrintableGHOSTDAGData(ghostdagData), nil
+}
+
+func toPrintableGHOSTDAGData(ghostdagData *externalapi.BlockGHOSTDAGData) *printableGHOSTDAGData {
+	bluesAnticoneSizes := make(map[string]externalapi.KType, len(ghostdagData.BluesAnticoneSizes()))
+	for blockHash, anticoneSize := range ghostdagData.BluesAnticoneSizes() {
+		bluesAnticoneSizes[blockHash.String()] = anticoneSize
+	}
+
+	selectedParent := ""
+	if ghostdagData.SelectedParent() != nil {
+		selectedParent = ghostdagData.SelectedParent().String()
+	}
+
+	return &printableGHOSTDAGData{
+		BlueScore:          ghostdagData.BlueScore(),
+		BlueWork:           ghostdagData.BlueWork().Text(16),
+		SelectedParent:     selectedParent,
+		MergeSetBlues:      hashes.ToStrings(ghostdagData.MergeSetBlues()),
+		MergeSetReds:       hashes.ToStrings(ghostdagData.MergeSetReds()),
+		BluesAnticoneSizes: bluesAnticoneSizes,
+	}
+}
+
+func dumpReachabilityData(stores *consensusStores, blockHash *externalapi.DomainHash, level int) (interface{}, error) {
+	reachabilityData, err := stores.reachabilityDataStore(level).ReachabilityData(stores.dbContext, model.NewStagingArea(), blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := ""
+	if reachabilityData.Parent() != nil {
+		parent = reachabilityData.Parent().String()
+	}
+
+	return &printableReachabilityData{
+		Parent:            parent,
+		Children:          hashes.ToStrings(reachabilityData.Children()),
+		IntervalStart:     reachabilityData.Interval().Start,
+		IntervalEnd:       reachabilityData.Interval().End,
+		FutureCoveringSet: hashes.ToStrings(reachabilityData.FutureCoveringSet()),
+	}, nil
+}
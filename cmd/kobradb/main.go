@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kobradag/kobrad/app"
+	"github.com/kobradag/kobrad/domain/prefixmanager"
+	"github.com/kobradag/kobrad/domain/prefixmanager/prefix"
+	"github.com/kobradag/kobrad/infrastructure/config"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/kobradag/kobrad/infrastructure/db/database/ldb"
+	"github.com/kobradag/kobrad/infrastructure/db/database/pebbledb"
+	"github.com/pkg/errors"
+)
+
+const cacheSizeMiB = 64
+
+func main() {
+	subCmd, config := parseCommandLine()
+
+	var err error
+	switch subCmd {
+	case bucketsSubCmd:
+		err = buckets(config.(*bucketsConfig))
+	case dumpSubCmd:
+		err = dump(config.(*dumpConfig))
+	case stateSubCmd:
+		err = state(config.(*stateConfig))
+	case compactSubCmd:
+		err = compact(config.(*compactConfig))
+	case verifySubCmd:
+		err = verify(config.(*verifyConfig))
+	default:
+		err = errors.Errorf("Unknown sub-command '%s'\n", subCmd)
+	}
+
+	if err != nil {
+		printErrorAndExit(err)
+	}
+}
+
+func printErrorAndExit(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
+
+// openDatabase opens the database selected by the given flags. Unless writable is set, the
+// database is opened read-only, so that inspecting it can never modify or repair it.
+// kobrad must not be running while its database is opened.
+func openDatabase(dbFlags *databaseFlags, writable bool) (database.Database, error) {
+	path := app.DatabasePath(dbFlags.networkAppDir(), dbFlags.DbType)
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.Wrapf(err, "could not find a %s database in %s", dbFlags.DbType, path)
+	}
+
+	switch {
+	case dbFlags.DbType == config.DbTypePebble && writable:
+		return pebbledb.NewPebbleDB(path, cacheSizeMiB)
+	case dbFlags.DbType == config.DbTypePebble:
+		return pebbledb.NewReadOnlyPebbleDB(path, cacheSizeMiB)
+	case writable:
+		return ldb.NewLevelDB(path, cacheSizeMiB)
+	default:
+		return ldb.NewReadOnlyLevelDB(path, cacheSizeMiB)
+	}
+}
+
+// consensusPrefix returns the database prefix of the active consensus, or of the
+// inactive one if isInactive is set
+func consensusPrefix(db database.Database, isInactive bool) (*prefix.Prefix, error) {
+	getPrefix, name := prefixmanager.ActivePrefix, "active"
+	if isInactive {
+		getPrefix, name = prefixmanager.InactivePrefix, "inactive"
+	}
+
+	dbPrefix, exists, err := getPrefix(db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.Errorf("the database has no %s consensus", name)
+	}
+	return dbPrefix, nil
+}
+
+func printJSON(value interface{}) error {
+	output, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(output))
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kobradag/kobrad/domain/consensus/model"
+	"github.com/kobradag/kobrad/domain/consensus/utils/hashes"
+)
+
+// printableState is the consensus state printed by the state sub-command
+type printableState struct {
+	Prefix                  string
+	PruningPoint            string
+	PruningPointIndex       uint64
+	HeadersSelectedTip      string
+	Tips                    []string
+	VirtualParents          []string
+	VirtualDAAScore         uint64
+	VirtualGHOSTDAGData     *printableGHOSTDAGData
+	BlockCount              uint64
+	BlockHeaderCount        uint64
+	IsImportingPruningPoint bool
+}
+
+func state(conf *stateConfig) error {
+	db, err := openDatabase(&conf.databaseFlags, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbPrefix, err := consensusPrefix(db, conf.InactivePrefix)
+	if err != nil {
+		return err
+	}
+	stores, err := newConsensusStores(db, dbPrefix)
+	if err != nil {
+		return err
+	}
+
+	stagingArea := model.NewStagingArea()
+	pruningPoint, err := stores.pruningStore.PruningPoint(stores.dbContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	pruningPointIndex, err := stores.pruningStore.CurrentPruningPointIndex(stores.dbContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	headersSelectedTip, err := stores.headersSelectedTipStore.HeadersSelectedTip(stores.dbContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	tips, err := stores.consensusStateStore.Tips(stagingArea, stores.dbContext)
+	if err != nil {
+		return err
+	}
+	virtualRelations, err := stores.blockRelationStore(0).BlockRelation(
+		stores.dbContext, stagingArea, model.VirtualBlockHash)
+	if err != nil {
+		return err
+	}
+	virtualDAAScore, err := stores.daaBlocksStore.DAAScore(stores.dbContext, stagingArea, model.VirtualBlockHash)
+	if err != nil {
+		return err
+	}
+	virtualGHOSTDAGData, err := stores.ghostdagDataStore(0).Get(
+		stores.dbContext, stagingArea, model.VirtualBlockHash, false)
+	if err != nil {
+		return err
+	}
+	isImportingPruningPoint, err := stores.consensusStateStore.HadStartedImportingPruningPointUTXOSet(stores.dbContext)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(&printableState{
+		Prefix:                  fmt.Sprintf("%d", dbPrefix.Serialize()[0]),
+		PruningPoint:            pruningPoint.String(),
+		PruningPointIndex:       pruningPointIndex,
+		HeadersSelectedTip:      headersSelectedTip.String(),
+		Tips:                    hashes.ToStrings(tips),
+		VirtualParents:          hashes.ToStrings(virtualRelations.Parents),
+		VirtualDAAScore:         virtualDAAScore,
+		VirtualGHOSTDAGData:     toPrintableGHOSTDAGData(virtualGHOSTDAGData),
+		BlockCount:              stores.blockStore.Count(stagingArea),
+		BlockHeaderCount:        stores.blockHeaderStore.Count(stagingArea),
+		IsImportingPruningPoint: isImportingPruningPoint,
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	consensusdatabase "github.com/kobradag/kobrad/domain/consensus/database"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/blockheaderstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/blockrelationstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/blockstatusstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/blockstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/consensusstatestore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/daablocksstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/ghostdagdatastore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/headersselectedtipstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/multisetstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/pruningstore"
+	"github.com/kobradag/kobrad/domain/consensus/datastructures/reachabilitydatastore"
+	"github.com/kobradag/kobrad/domain/consensus/model"
+	"github.com/kobradag/kobrad/domain/prefixmanager/prefix"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+)
+
+// storeCacheSize is the cache size of every store. Every record is read at most a few
+// times, so there's no point in caching much
+const storeCacheSize = 10
+
+// consensusStores are the stores of a single consensus instance in the database
+type consensusStores struct {
+	dbContext               model.DBManager
+	prefixBucket            model.DBBucket
+	blockStore              model.BlockStore
+	blockHeaderStore        model.BlockHeaderStore
+	blockStatusStore        model.BlockStatusStore
+	multisetStore           model.MultisetStore
+	consensusStateStore     model.ConsensusStateStore
+	pruningStore            model.PruningStore
+	headersSelectedTipStore model.HeaderSelectedTipStore
+	daaBlocksStore          model.DAABlocksStore
+
+	// isOldReachabilityInitialized is set for databases that keep reachability data per level
+	isOldReachabilityInitialized bool
+}
+
+func newConsensusStores(db database.Database, dbPrefix *prefix.Prefix) (*consensusStores, error) {
+	dbManager := consensusdatabase.New(db)
+	prefixBucket := consensusdatabase.MakeBucket(dbPrefix.Serialize())
+
+	blockStore, err := blockstore.New(dbManager, prefixBucket, storeCacheSize, false)
+	if err != nil {
+		return nil, err
+	}
+	blockHeaderStore, err := blockheaderstore.New(dbManager, prefixBucket, storeCacheSize, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Databases that were created before reachability data was shared by all levels still keep
+	// it per level, and the same check as in domain/consensus/factory.go is used to tell them apart
+	oldReachabilityDataStore := reachabilitydatastore.New(
+		prefixBucket.Bucket([]byte{0}), storeCacheSize, false)
+	isOldReachabilityInitialized, err := oldReachabilityDataStore.HasReachabilityData(
+		dbManager, model.NewStagingArea(), model.VirtualGenesisBlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &consensusStores{
+		dbContext:               dbManager,
+		prefixBucket:            prefixBucket,
+		blockStore:              blockStore,
+		blockHeaderStore:        blockHeaderStore,
+		blockStatusStore:        blockstatusstore.New(prefixBucket, storeCacheSize, false),
+		multisetStore:           multisetstore.New(prefixBucket, storeCacheSize, false),
+		consensusStateStore:     consensusstatestore.New(prefixBucket, storeCacheSize, false),
+		pruningStore:            pruningstore.New(prefixBucket, storeCacheSize, false),
+		headersSelectedTipStore: headersselectedtipstore.New(prefixBucket),
+		daaBlocksStore:          daablocksstore.New(prefixBucket, storeCacheSize, storeCacheSize, false),
+
+		isOldReachabilityInitialized: isOldReachabilityInitialized,
+	}, nil
+}
+
+// levelBucket returns the bucket of the stores that are kept per block level. See dagStores in
+// domain/consensus/factory.go
+func (stores *consensusStores) levelBucket(level int) model.DBBucket {
+	return stores.prefixBucket.Bucket([]byte{byte(level)})
+}
+
+func (stores *consensusStores) ghostdagDataStore(level int) model.GHOSTDAGDataStore {
+	return ghostdagdatastore.New(stores.levelBucket(level), storeCacheSize, false)
+}
+
+func (stores *consensusStores) blockRelationStore(level int) model.BlockRelationStore {
+	return blockrelationstore.New(stores.levelBucket(level), storeCacheSize, false)
+}
+
+// reachabilityDataStore returns the reachability data store of the given level
+func (stores *consensusStores) reachabilityDataStore(level int) model.ReachabilityDataStore {
+	if stores.isOldReachabilityInitialized {
+		return reachabilitydatastore.New(stores.levelBucket(level), storeCacheSize, false)
+	}
+	return reachabilitydatastore.New(stores.prefixBucket, storeCacheSize, false)
+}
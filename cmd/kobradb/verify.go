@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kobradag/kobrad/domain/consensus/model"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/multiset"
+	"github.com/kobradag/kobrad/domain/consensus/utils/pow"
+	"github.com/kobradag/kobrad/domain/consensus/utils/utxo"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+// progressInterval is the number of verified blocks or UTXOs between progress reports
+const progressInterval = 100_000
+
+// verifier checks the invariants that hold between the stores of a single consensus instance.
+// It only reads from the database, and reports every inconsistency it finds instead of stopping
+// at the first one
+type verifier struct {
+	stores        *consensusStores
+	maxBlockLevel int
+	findingCount  int
+}
+
+func verify(conf *verifyConfig) error {
+	db, err := openDatabase(&conf.databaseFlags, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbPrefix, err := consensusPrefix(db, conf.InactivePrefix)
+	if err != nil {
+		return err
+	}
+	stores, err := newConsensusStores(db, dbPrefix)
+	if err != nil {
+		return err
+	}
+
+	v := &verifier{
+		stores:        stores,
+		maxBlockLevel: conf.NetParams().MaxBlockLevel,
+	}
+	err = v.verifyBlockHeaders()
+	if err != nil {
+		return err
+	}
+	err = v.verifyBlockBodies()
+	if err != nil {
+		return err
+	}
+	err = v.verifyConsensusStateBlocks()
+	if err != nil {
+		return err
+	}
+	if !conf.SkipUTXOSets {
+		err = v.verifyVirtualUTXOSet()
+		if err != nil {
+			return err
+		}
+		err = v.verifyPruningPointUTXOSet()
+		if err != nil {
+			return err
+		}
+	}
+
+	if v.findingCount > 0 {
+		return errors.Errorf("Found %d inconsistencies in the database", v.findingCount)
+	}
+	fmt.Println("The database is consistent")
+	return nil
+}
+
+func (v *verifier) report(format string, args ...interface{}) {
+	v.findingCount++
+	fmt.Printf("INCONSISTENCY: "+format+"\n", args...)
+}
+
+// verifyBlockHeaders checks that every block header has GHOSTDAG data and reachability data, that every
+// block whose status requires a body has one, and that the multiset of every block whose UTXO state was
+// resolved matches its UTXO commitment
+func (v *verifier) verifyBlockHeaders() error {
+	fmt.Println("Verifying the block headers")
+	iterator, err := v.stores.blockHeaderStore.AllBlockHeaderHashesIterator(v.stores.dbContext)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	verifiedCount := 0
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		blockHash, err := iterator.Get()
+		if err != nil {
+			return err
+		}
+		err = v.verifyBlockHeader(blockHash)
+		if err != nil {
+			return err
+		}
+
+		verifiedCount++
+		if verifiedCount%progressInterval == 0 {
+			fmt.Printf("Verified %d block headers\n", verifiedCount)
+		}
+	}
+	fmt.Printf("Verified %d block headers\n", verifiedCount)
+	return nil
+}
+
+func (v *verifier) verifyBlockHeader(blockHash *externalapi.DomainHash) error {
+	stagingArea := model.NewStagingArea()
+	header, err := v.stores.blockHeaderStore.BlockHeader(v.stores.dbContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+
+	// Blocks that were received as part of the pruning point proof only have GHOSTDAG data in the
+	// levels they belong to, and blocks that were received with trusted data may only have trusted
+	// GHOSTDAG data
+	hasTrustedGHOSTDAGData, err := v.hasGHOSTDAGData(stagingArea, blockHash, 0, true)
+	if err != nil {
+		return err
+	}
+	blockLevel := pow.BlockLevel(header, v.maxBlockLevel)
+	hasGHOSTDAGData := false
+	for level := 0; level <= blockLevel && !hasGHOSTDAGData; level++ {
+		hasGHOSTDAGData, err = v.hasGHOSTDAGData(stagingArea, blockHash, level, false)
+		if err != nil {
+			return err
+		}
+	}
+	if !hasGHOSTDAGData && !hasTrustedGHOSTDAGData {
+		v.report("block %s has a header but no GHOSTDAG data in any of its levels", blockHash)
+	}
+
+	// Only blocks that are part of the DAG in some level have reachability data
+	if hasGHOSTDAGData {
+		hasReachabilityData := false
+		for level := 0; level <= blockLevel && !hasReachabilityData; level++ {
+			hasReachabilityData, err = v.stores.reachabilityDataStore(level).HasReachabilityData(
+				v.stores.dbContext, stagingArea, blockHash)
+			if err != nil {
+				return err
+			}
+		}
+		if !hasReachabilityData {
+			v.report("block %s has GHOSTDAG data but no reachability data", blockHash)
+		}
+	}
+
+	err = v.verifyBlockStatus(stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+
+	blockMultiset, err := v.stores.multisetStore.Get(v.stores.dbContext, stagingArea, blockHash)
+	if database.IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !blockMultiset.Hash().Equal(header.UTXOCommitment()) {
+		v.report("the multiset of block %s hashes to %s, but its header commits to %s",
+			blockHash, blockMultiset.Hash(), header.UTXOCommitment())
+	}
+	return nil
+}
+
+// verifyBlockStatus checks that the given block has a body if its status requires one. Blocks that
+// were pruned, or that were received as headers only, have a header-only status. Blocks that were
+// received with trusted data might have no status at all
+func (v *verifier) verifyBlockStatus(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) error {
+	status, err := v.stores.blockStatusStore.Get(v.stores.dbContext, stagingArea, blockHash)
+	if database.IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !statusRequiresBody(status) {
+		return nil
+	}
+
+	hasBlock, err := v.stores.blockStore.HasBlock(v.stores.dbContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	if !hasBlock {
+		v.report("block %s has status %s but no body", blockHash, status)
+	}
+	return nil
+}
+
+func statusRequiresBody(status externalapi.BlockStatus) bool {
+	switch status {
+	case externalapi.StatusUTXOValid, externalapi.StatusUTXOPendingVerification, externalapi.StatusDisqualifiedFromChain:
+		return true
+	default:
+		return false
+	}
+}
+
+func (v *verifier) hasGHOSTDAGData(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash,
+	level int, isTrustedData bool) (bool, error) {
+
+	_, err := v.stores.ghostdagDataStore(level).Get(v.stores.dbContext, stagingArea, blockHash, isTrustedData)
+	if database.IsNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyBlockBodies checks that every stored block body has a header and a status
+func (v *verifier) verifyBlockBodies() error {
+	fmt.Println("Verifying the block bodies")
+	iterator, err := v.stores.blockStore.AllBlockHashesIterator(v.stores.dbContext)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	verifiedCount := 0
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		blockHash, err := iterator.Get()
+		if err != nil {
+			return err
+		}
+		err = v.verifyBlockBody(blockHash)
+		if err != nil {
+			return err
+		}
+
+		verifiedCount++
+		if verifiedCount%progressInterval == 0 {
+			fmt.Printf("Verified %d block bodies\n", verifiedCount)
+		}
+	}
+	fmt.Printf("Verified %d block bodies\n", verifiedCount)
+	return nil
+}
+
+func (v *verifier) verifyBlockBody(blockHash *externalapi.DomainHash) error {
+	stagingArea := model.NewStagingArea()
+	hasHeader, err := v.stores.blockHeaderStore.HasBlockHeader(v.stores.dbContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	if !hasHeader {
+		v.report("block %s has a body but no header", blockHash)
+	}
+
+	// Archival nodes keep the bodies of pruned blocks, so a header-only status doesn't
+	// necessarily mean that the body should have been deleted
+	hasStatus, err := v.stores.blockStatusStore.Exists(v.stores.dbContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	if !hasStatus {
+		v.report("block %s has a body but no status", blockHash)
+	}
+	return nil
+}
+
+// verifyConsensusStateBlocks checks that the blocks the consensus state refers to, namely the tips,
+// the virtual parents and the pruning point, have a status that requires a body
+func (v *verifier) verifyConsensusStateBlocks() error {
+	stagingArea := model.NewStagingArea()
+	tips, err := v.stores.consensusStateStore.Tips(stagingArea, v.stores.dbContext)
+	if err != nil {
+		return err
+	}
+	virtualRelations, err := v.stores.blockRelationStore(0).BlockRelation(
+		v.stores.dbContext, stagingArea, model.VirtualBlockHash)
+	if err != nil {
+		return err
+	}
+	pruningPoint, err := v.stores.pruningStore.PruningPoint(v.stores.dbContext, stagingArea)
+	if err != nil {
+		return err
+	}
+
+	blockHashes := append(append(tips, virtualRelations.Parents...), pruningPoint)
+	for _, blockHash := range blockHashes {
+		status, err := v.stores.blockStatusStore.Get(v.stores.dbContext, stagingArea, blockHash)
+		if database.IsNotFoundError(err) {
+			v.report("block %s is referenced by the consensus state but has no status", blockHash)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !statusRequiresBody(status) {
+			v.report("block %s is referenced by the consensus state but has status %s", blockHash, status)
+		}
+	}
+	return nil
+}
+
+// verifyVirtualUTXOSet checks that the multiset of the virtual UTXO set matches the multiset
+// that's stored for the virtual block
+func (v *verifier) verifyVirtualUTXOSet() error {
+	hadStartedImportingPruningPointUTXOSet, err :=
+		v.stores.consensusStateStore.HadStartedImportingPruningPointUTXOSet(v.stores.dbContext)
+	if err != nil {
+		return err
+	}
+	if hadStartedImportingPruningPointUTXOSet {
+		fmt.Println("Skipping the virtual UTXO set, since it's in the middle of being imported")
+		return nil
+	}
+
+	fmt.Println("Verifying the virtual UTXO set")
+	stagingArea := model.NewStagingArea()
+	iterator, err := v.stores.consensusStateStore.VirtualUTXOSetIterator(v.stores.dbContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	utxoSetHash, err := v.utxoSetHash(iterator)
+	if err != nil {
+		return err
+	}
+
+	virtualMultiset, err := v.stores.multisetStore.Get(v.stores.dbContext, stagingArea, model.VirtualBlockHash)
+	if err != nil {
+		return err
+	}
+	if !virtualMultiset.Hash().Equal(utxoSetHash) {
+		v.report("the virtual UTXO set hashes to %s, but the multiset of the virtual block hashes to %s",
+			utxoSetHash, virtualMultiset.Hash())
+	}
+	return nil
+}
+
+// verifyPruningPointUTXOSet checks that the pruning point UTXO set matches the UTXO commitment
+// of the pruning point, the same way --enable-sanity-check-pruning-utxo does when the pruning point moves
+func (v *verifier) verifyPruningPointUTXOSet() error {
+	hadStartedUpdatingPruningPointUTXOSet, err :=
+		v.stores.pruningStore.HadStartedUpdatingPruningPointUTXOSet(v.stores.dbContext)
+	if err != nil {
+		return err
+	}
+	if hadStartedUpdatingPruningPointUTXOSet {
+		fmt.Println("Skipping the pruning point UTXO set, since it's in the middle of being updated")
+		return nil
+	}
+
+	fmt.Println("Verifying the pruning point UTXO set")
+	stagingArea := model.NewStagingArea()
+	iterator, err := v.stores.pruningStore.PruningPointUTXOIterator(v.stores.dbContext)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	utxoSetHash, err := v.utxoSetHash(iterator)
+	if err != nil {
+		return err
+	}
+
+	pruningPoint, err := v.stores.pruningStore.PruningPoint(v.stores.dbContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	pruningPointHeader, err := v.stores.blockHeaderStore.BlockHeader(v.stores.dbContext, stagingArea, pruningPoint)
+	if err != nil {
+		return err
+	}
+	if !pruningPointHeader.UTXOCommitment().Equal(utxoSetHash) {
+		v.report("the pruning point UTXO set hashes to %s, but the pruning point %s commits to %s",
+			utxoSetHash, pruningPoint, pruningPointHeader.UTXOCommitment())
+	}
+	return nil
+}
+
+func (v *verifier) utxoSetHash(iterator externalapi.ReadOnlyUTXOSetIterator) (*externalapi.DomainHash, error) {
+	utxoSetMultiset := multiset.New()
+	utxoCount := 0
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		outpoint, entry, err := iterator.Get()
+		if err != nil {
+			return nil, err
+		}
+		serializedUTXO, err := utxo.SerializeUTXO(entry, outpoint)
+		if err != nil {
+			return nil, err
+		}
+		utxoSetMultiset.Add(serializedUTXO)
+
+		utxoCount++
+		if utxoCount%progressInterval == 0 {
+			fmt.Printf("Hashed %d UTXOs\n", utxoCount)
+		}
+	}
+	fmt.Printf("Hashed %d UTXOs\n", utxoCount)
+	return utxoSetMultiset.Hash(), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus"
+	"github.com/kobradag/kobrad/domain/consensus/model"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+	"github.com/kobradag/kobrad/domain/prefixmanager/prefix"
+)
+
+func TestVerify(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.MainnetParams}
+	consensusConfig.SkipProofOfWork = true
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestVerify")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	tipHash := consensusConfig.GenesisHash
+	const blockCount = 5
+	for i := 0; i < blockCount; i++ {
+		tipHash, _, err = tc.AddBlock([]*externalapi.DomainHash{tipHash}, nil, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+	}
+
+	stores, err := newConsensusStores(tc.Database(), &prefix.Prefix{})
+	if err != nil {
+		t.Fatalf("newConsensusStores: %+v", err)
+	}
+
+	iterator, err := stores.blockHeaderStore.AllBlockHeaderHashesIterator(stores.dbContext)
+	if err != nil {
+		t.Fatalf("AllBlockHeaderHashesIterator: %+v", err)
+	}
+	headerCount := 0
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		_, err := iterator.Get()
+		if err != nil {
+			t.Fatalf("Get: %+v", err)
+		}
+		headerCount++
+	}
+	// The genesis is stored along with the added blocks
+	if headerCount != blockCount+1 {
+		t.Fatalf("expected %d block headers but got %d", blockCount+1, headerCount)
+	}
+	err = iterator.Close()
+	if err != nil {
+		t.Fatalf("Close: %+v", err)
+	}
+	err = iterator.Close()
+	if err == nil {
+		t.Fatalf("expected closing the iterator twice to fail")
+	}
+	func() {
+		defer func() {
+			r := recover()
+			if r == nil || !strings.Contains(fmt.Sprint(r), "closed AllBlockHeaderHashesIterator") {
+				t.Fatalf("expected using a closed iterator to panic, got %v", r)
+			}
+		}()
+		iterator.First()
+	}()
+
+	runVerifier := func() int {
+		v := &verifier{stores: stores, maxBlockLevel: consensusConfig.MaxBlockLevel}
+		for _, verify := range []func() error{v.verifyBlockHeaders, v.verifyBlockBodies,
+			v.verifyConsensusStateBlocks, v.verifyVirtualUTXOSet, v.verifyPruningPointUTXOSet} {
+
+			err := verify()
+			if err != nil {
+				t.Fatalf("verify: %+v", err)
+			}
+		}
+		return v.findingCount
+	}
+
+	findingCount := runVerifier()
+	if findingCount != 0 {
+		t.Fatalf("expected a consistent database, but found %d inconsistencies", findingCount)
+	}
+
+	// Deleting the body of the tip leaves a block whose status requires a body, which is also
+	// referenced by the consensus state
+	stagingArea := model.NewStagingArea()
+	stores.blockStore.Delete(stagingArea, tipHash)
+	dbTx, err := stores.dbContext.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %+v", err)
+	}
+	err = stagingArea.Commit(dbTx)
+	if err != nil {
+		t.Fatalf("Commit: %+v", err)
+	}
+	err = dbTx.Commit()
+	if err != nil {
+		t.Fatalf("Commit: %+v", err)
+	}
+
+	findingCount = runVerifier()
+	if findingCount != 1 {
+		t.Fatalf("expected a single inconsistency, but found %d", findingCount)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/kobradag/kobrad/infrastructure/config"
+	"github.com/pkg/errors"
+)
+
+const defaultBatchSize = 10_000
+
+type configFlags struct {
+	AppDir    string `short:"b" long:"appdir" description:"The app directory of the node whose database should be converted"`
+	BatchSize int    `long:"batch-size" description:"Number of entries written to the new database in each transaction"`
+	config.NetworkFlags
+}
+
+func parseConfig() (*configFlags, error) {
+	cfg := &configFlags{
+		AppDir:    config.DefaultAppDir,
+		BatchSize: defaultBatchSize,
+	}
+	parser := flags.NewParser(cfg, flags.HelpFlag)
+	parser.Usage = "[OPTIONS]\n\nConverts the leveldb database of a stopped kobrad into a pebble database."
+	_, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.ResolveNetwork(parser)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.BatchSize <= 0 {
+		return nil, errors.Errorf("--batch-size must be positive")
+	}
+
+	return cfg, nil
+}
+
+// networkAppDir returns the app directory of the selected network, the same way kobrad resolves it
+func (cfg *configFlags) networkAppDir() string {
+	return filepath.Join(cfg.AppDir, cfg.NetParams().Name)
+}
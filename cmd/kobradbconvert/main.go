@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kobradag/kobrad/app"
+	"github.com/kobradag/kobrad/infrastructure/config"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/kobradag/kobrad/infrastructure/db/database/ldb"
+	"github.com/kobradag/kobrad/infrastructure/db/database/pebbledb"
+	"github.com/pkg/errors"
+)
+
+const (
+	cacheSizeMiB = 256
+
+	// progressInterval is the number of copied entries between progress reports
+	progressInterval = 1_000_000
+)
+
+func main() {
+	cfg, err := parseConfig()
+	if err != nil {
+		printErrorAndExit(fmt.Sprintf("error parsing command-line arguments: %s", err))
+	}
+
+	err = convert(cfg)
+	if err != nil {
+		printErrorAndExit(fmt.Sprintf("error converting the database: %s", err))
+	}
+}
+
+func printErrorAndExit(message string) {
+	fmt.Fprintf(os.Stderr, "%s\n", message)
+	os.Exit(1)
+}
+
+func convert(cfg *configFlags) error {
+	appDir := cfg.networkAppDir()
+	sourcePath := app.DatabasePath(appDir, config.DbTypeLevelDB)
+	destinationPath := app.DatabasePath(appDir, config.DbTypePebble)
+
+	if _, err := os.Stat(sourcePath); err != nil {
+		return errors.Wrapf(err, "could not find a leveldb database in %s", sourcePath)
+	}
+	if _, err := os.Stat(destinationPath); err == nil {
+		return errors.Errorf("%s already exists. Remove it in order to convert the leveldb database again",
+			destinationPath)
+	}
+
+	// The database is written to a temporary directory and renamed once it's complete,
+	// so that kobrad never opens a partially converted database
+	temporaryPath := destinationPath + "-converting"
+	err := os.RemoveAll(temporaryPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Printf("Converting the leveldb database in %s into a pebble database in %s\n", sourcePath, destinationPath)
+	source, err := ldb.NewLevelDB(sourcePath, cacheSizeMiB)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := pebbledb.NewPebbleDB(temporaryPath, cacheSizeMiB)
+	if err != nil {
+		return err
+	}
+	copiedCount, err := copyDatabase(source, destination, cfg.BatchSize)
+	if err != nil {
+		destination.Close()
+		return err
+	}
+	err = destination.Close()
+	if err != nil {
+		return err
+	}
+
+	err = copyFile(filepath.Join(sourcePath, "version"), filepath.Join(temporaryPath, "version"))
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temporaryPath, destinationPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Printf("Converted %d entries. Start kobrad with --dbtype=%s to use the new database.\n"+
+		"The leveldb database in %s is left untouched, and may be removed once the new one works.\n",
+		copiedCount, config.DbTypePebble, sourcePath)
+	return nil
+}
+
+// copyDatabase copies all the entries in source to destination, committing
+// a transaction every batchSize entries. It returns the number of copied entries
+func copyDatabase(source database.Database, destination database.Database, batchSize int) (int, error) {
+	cursor, err := source.Cursor(database.MakeBucket(nil))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close()
+
+	copiedCount := 0
+	transaction, err := destination.Begin()
+	if err != nil {
+		return 0, err
+	}
+	// transaction is replaced after every batch, so it has to be evaluated only when returning
+	defer func() {
+		transaction.RollbackUnlessClosed()
+	}()
+
+	for cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return 0, err
+		}
+		value, err := cursor.Value()
+		if err != nil {
+			return 0, err
+		}
+		err = transaction.Put(key, value)
+		if err != nil {
+			return 0, err
+		}
+
+		copiedCount++
+		if copiedCount%batchSize == 0 {
+			err = transaction.Commit()
+			if err != nil {
+				return 0, err
+			}
+			transaction, err = destination.Begin()
+			if err != nil {
+				return 0, err
+			}
+		}
+		if copiedCount%progressInterval == 0 {
+			fmt.Printf("Copied %d entries\n", copiedCount)
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return copiedCount, nil
+}
+
+func copyFile(sourcePath string, destinationPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer source.Close()
+
+	destination, err := os.Create(destinationPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = io.Copy(destination, source)
+	if err != nil {
+		destination.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(destination.Close())
+}
@@ -22,6 +22,8 @@ const (
 	newAddressSubCmd                = "new-address"
 	dumpUnencryptedDataSubCmd       = "dump-unencrypted-data"
 	startDaemonSubCmd               = "start-daemon"
+	historySubCmd                   = "history"
+	listUnspentSubCmd               = "list-unspent"
 )
 
 const (
@@ -51,16 +53,34 @@ type balanceConfig struct {
 	config.NetworkFlags
 }
 
+type historyConfig struct {
+	DaemonAddress string `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	config.NetworkFlags
+}
+
+type listUnspentConfig struct {
+	DaemonAddress string   `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	Addresses     []string `long:"address" short:"a" description:"List only the UTXOs of this wallet address. Use multiple times to list several addresses"`
+	config.NetworkFlags
+}
+
 type sendConfig struct {
-	KeysFile                 string   `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kobrawallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Caswallet\\key.json (Windows))"`
-	Password                 string   `long:"password" short:"p" description:"Wallet password"`
-	DaemonAddress            string   `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
-	ToAddress                string   `long:"to-address" short:"t" description:"The public address to send Pyrin to" required:"true"`
-	FromAddresses            []string `long:"from-address" short:"a" description:"Specific public address to send Pyrin from. Use multiple times to accept several addresses" required:"false"`
-	SendAmount               float64  `long:"send-amount" short:"v" description:"An amount to send in Pyrin (e.g. 1234.12345678)"`
-	IsSendAll                bool     `long:"send-all" description:"Send all the Pyrin in the wallet (mutually exclusive with --send-amount)"`
-	UseExistingChangeAddress bool     `long:"use-existing-change-address" short:"u" description:"Will use an existing change address (in case no change address was ever used, it will use a new one)"`
-	Verbose                  bool     `long:"show-serialized" short:"s" description:"Show a list of hex encoded sent transactions"`
+	KeysFile                 string    `long:"keys-file" short:"f" description:"Keys file location (default: ~/.kobrawallet/keys.json (*nix), %USERPROFILE%\\AppData\\Local\\Caswallet\\key.json (Windows))"`
+	Password                 string    `long:"password" short:"p" description:"Wallet password"`
+	DaemonAddress            string    `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	ToAddress                string    `long:"to-address" short:"t" description:"The public address to send Pyrin to"`
+	ToAddresses              []string  `long:"to" description:"A public address to pay. Use multiple times, each with an '--amount', to pay several recipients"`
+	Amounts                  []float64 `long:"amount" description:"An amount in Pyrin to pay the '--to' address in the same position"`
+	RecipientsFile           string    `long:"recipients-file" description:"A CSV file with an address and an amount in Pyrin to pay on every line"`
+	FromAddresses            []string  `long:"from-address" short:"a" description:"Specific public address to send Pyrin from. Use multiple times to accept several addresses" required:"false"`
+	SendAmount               float64   `long:"send-amount" short:"v" description:"An amount to send in Pyrin (e.g. 1234.12345678)"`
+	IsSendAll                bool      `long:"send-all" description:"Send all the Pyrin in the wallet (mutually exclusive with --send-amount)"`
+	UseExistingChangeAddress bool      `long:"use-existing-change-address" short:"u" description:"Will use an existing change address (in case no change address was ever used, it will use a new one)"`
+	FeeRate                  float64   `long:"fee-rate" description:"Fee rate to pay in leor per gram (mutually exclusive with --priority)"`
+	Priority                 string    `long:"priority" description:"Fee priority, which selects the fee rate from the node's fee estimate (default: normal)" choice:"low" choice:"normal" choice:"high"`
+	UTXOSelection            string    `long:"utxo-selection" description:"How to select the UTXOs to spend (default: largest-first)" choice:"largest-first" choice:"smallest-first" choice:"branch-and-bound" choice:"avoid-address-mixing"`
+	UTXOs                    []string  `long:"utxo" description:"A UTXO to spend, as txid:index. Use multiple times to spend several UTXOs (mutually exclusive with --from-address and --utxo-selection)"`
+	Verbose                  bool      `long:"show-serialized" short:"s" description:"Show a list of hex encoded sent transactions"`
 	config.NetworkFlags
 }
 
@@ -71,12 +91,19 @@ type sweepConfig struct {
 }
 
 type createUnsignedTransactionConfig struct {
-	DaemonAddress            string   `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
-	ToAddress                string   `long:"to-address" short:"t" description:"The public address to send Pyrin to" required:"true"`
-	FromAddresses            []string `long:"from-address" short:"a" description:"Specific public address to send Pyrin from. Use multiple times to accept several addresses" required:"false"`
-	SendAmount               float64  `long:"send-amount" short:"v" description:"An amount to send in Pyrin (e.g. 1234.12345678)"`
-	IsSendAll                bool     `long:"send-all" description:"Send all the Pyrin in the wallet (mutually exclusive with --send-amount)"`
-	UseExistingChangeAddress bool     `long:"use-existing-change-address" short:"u" description:"Will use an existing change address (in case no change address was ever used, it will use a new one)"`
+	DaemonAddress            string    `long:"daemonaddress" short:"d" description:"Wallet daemon server to connect to"`
+	ToAddress                string    `long:"to-address" short:"t" description:"The public address to send Pyrin to"`
+	ToAddresses              []string  `long:"to" description:"A public address to pay. Use multiple times, each with an '--amount', to pay several recipients"`
+	Amounts                  []float64 `long:"amount" description:"An amount in Pyrin to pay the '--to' address in the same position"`
+	RecipientsFile           string    `long:"recipients-file" description:"A CSV file with an address and an amount in Pyrin to pay on every line"`
+	FromAddresses            []string  `long:"from-address" short:"a" description:"Specific public address to send Pyrin from. Use multiple times to accept several addresses" required:"false"`
+	SendAmount               float64   `long:"send-amount" short:"v" description:"An amount to send in Pyrin (e.g. 1234.12345678)"`
+	IsSendAll                bool      `long:"send-all" description:"Send all the Pyrin in the wallet (mutually exclusive with --send-amount)"`
+	UseExistingChangeAddress bool      `long:"use-existing-change-address" short:"u" description:"Will use an existing change address (in case no change address was ever used, it will use a new one)"`
+	FeeRate                  float64   `long:"fee-rate" description:"Fee rate to pay in leor per gram (mutually exclusive with --priority)"`
+	Priority                 string    `long:"priority" description:"Fee priority, which selects the fee rate from the node's fee estimate (default: normal)" choice:"low" choice:"normal" choice:"high"`
+	UTXOSelection            string    `long:"utxo-selection" description:"How to select the UTXOs to spend (default: largest-first)" choice:"largest-first" choice:"smallest-first" choice:"branch-and-bound" choice:"avoid-address-mixing"`
+	UTXOs                    []string  `long:"utxo" description:"A UTXO to spend, as txid:index. Use multiple times to spend several UTXOs (mutually exclusive with --from-address and --utxo-selection)"`
 	config.NetworkFlags
 }
 
@@ -141,6 +168,16 @@ func parseCommandLine() (subCommand string, config interface{}) {
 	parser.AddCommand(balanceSubCmd, "Shows the balance of a public address",
 		"Shows the balance for a public address in Pyrin", balanceConf)
 
+	historyConf := &historyConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(historySubCmd, "Shows the transaction history of the wallet",
+		"Shows the transactions that were received or sent by the wallet since the daemon started "+
+			"tracking them, along with their amounts, fees, counterparty addresses and confirmations", historyConf)
+
+	listUnspentConf := &listUnspentConfig{DaemonAddress: defaultListen}
+	parser.AddCommand(listUnspentSubCmd, "Lists the UTXOs of the wallet",
+		"Lists the UTXOs of the wallet with their amounts, confirmations and maturity, "+
+			"which can be spent explicitly with the --utxo flag of send", listUnspentConf)
+
 	sendConf := &sendConfig{DaemonAddress: defaultListen}
 	parser.AddCommand(sendSubCmd, "Sends a Pyrin transaction to a public address",
 		"Sends a Pyrin transaction to a public address", sendConf)
@@ -212,6 +249,20 @@ func parseCommandLine() (subCommand string, config interface{}) {
 			printErrorAndExit(err)
 		}
 		config = balanceConf
+	case historySubCmd:
+		combineNetworkFlags(&historyConf.NetworkFlags, &cfg.NetworkFlags)
+		err := historyConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = historyConf
+	case listUnspentSubCmd:
+		combineNetworkFlags(&listUnspentConf.NetworkFlags, &cfg.NetworkFlags)
+		err := listUnspentConf.ResolveNetwork(parser)
+		if err != nil {
+			printErrorAndExit(err)
+		}
+		config = listUnspentConf
 	case sendSubCmd:
 		combineNetworkFlags(&sendConf.NetworkFlags, &cfg.NetworkFlags)
 		err := sendConf.ResolveNetwork(parser)
@@ -296,19 +347,35 @@ func parseCommandLine() (subCommand string, config interface{}) {
 }
 
 func validateCreateUnsignedTransactionConf(conf *createUnsignedTransactionConfig) error {
-	if (!conf.IsSendAll && conf.SendAmount == 0) ||
-		(conf.IsSendAll && conf.SendAmount > 0) {
-
-		return errors.New("exactly one of '--send-amount' or '--all' must be specified")
+	err := validateRecipientFlags(conf.ToAddress, conf.ToAddresses, conf.Amounts, conf.RecipientsFile,
+		conf.SendAmount, conf.IsSendAll)
+	if err != nil {
+		return err
 	}
-	return nil
+	if len(conf.UTXOs) > 0 && (len(conf.FromAddresses) > 0 || conf.UTXOSelection != "") {
+		return errors.New("'--utxo' can't be combined with '--from-address' or '--utxo-selection'")
+	}
+	return validateFeeFlags(conf.FeeRate, conf.Priority)
 }
 
 func validateSendConfig(conf *sendConfig) error {
-	if (!conf.IsSendAll && conf.SendAmount == 0) ||
-		(conf.IsSendAll && conf.SendAmount > 0) {
+	err := validateRecipientFlags(conf.ToAddress, conf.ToAddresses, conf.Amounts, conf.RecipientsFile,
+		conf.SendAmount, conf.IsSendAll)
+	if err != nil {
+		return err
+	}
+	if len(conf.UTXOs) > 0 && (len(conf.FromAddresses) > 0 || conf.UTXOSelection != "") {
+		return errors.New("'--utxo' can't be combined with '--from-address' or '--utxo-selection'")
+	}
+	return validateFeeFlags(conf.FeeRate, conf.Priority)
+}
 
-		return errors.New("exactly one of '--send-amount' or '--all' must be specified")
+func validateFeeFlags(feeRate float64, priority string) error {
+	if feeRate < 0 {
+		return errors.New("'--fee-rate' must be positive")
+	}
+	if feeRate > 0 && priority != "" {
+		return errors.New("at most one of '--fee-rate' or '--priority' may be specified")
 	}
 	return nil
 }
@@ -7,7 +7,7 @@ import (
 
 	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/client"
 	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
-	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/utils"
 )
 
 func createUnsignedTransaction(conf *createUnsignedTransactionConfig) error {
@@ -20,19 +20,35 @@ func createUnsignedTransaction(conf *createUnsignedTransactionConfig) error {
 	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
 	defer cancel()
 
-	sendAmountLeor := uint64(conf.SendAmount * constants.LeorPerPyrin)
+	payments, err := recipientPayments(conf.ToAddresses, conf.Amounts, conf.RecipientsFile)
+	if err != nil {
+		return err
+	}
+
+	utxos, err := parseOutpoints(conf.UTXOs)
+	if err != nil {
+		return err
+	}
+
+	sendAmountLeor := toLeor(conf.SendAmount)
 	response, err := daemonClient.CreateUnsignedTransactions(ctx, &pb.CreateUnsignedTransactionsRequest{
 		From:                     conf.FromAddresses,
 		Address:                  conf.ToAddress,
 		Amount:                   sendAmountLeor,
+		Payments:                 payments,
 		IsSendAll:                conf.IsSendAll,
 		UseExistingChangeAddress: conf.UseExistingChangeAddress,
+		FeeRate:                  conf.FeeRate,
+		FeePriority:              conf.Priority,
+		UtxoSelection:            conf.UTXOSelection,
+		Utxos:                    utxos,
 	})
 	if err != nil {
 		return err
 	}
 
 	fmt.Fprintln(os.Stderr, "Created unsigned transaction")
+	fmt.Fprintf(os.Stderr, "Fee: %s KODA (%.2f leor/gram)\n", utils.FormatKas(response.Fee), response.FeeRate)
 	fmt.Println(encodeTransactionsToHex(response.UnsignedTransactions))
 
 	return nil
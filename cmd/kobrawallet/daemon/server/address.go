@@ -42,6 +42,12 @@ func (s *server) changeAddress(useExisting bool, fromAddresses []*walletAddress)
 	if err != nil {
 		return nil, nil, err
 	}
+	// The change address is tracked right away, so that its UTXOs are known to be
+	// the wallet's as soon as the transaction is accepted
+	err = s.addDerivedAddress(address.String(), walletAddr)
+	if err != nil {
+		return nil, nil, err
+	}
 	return address, walletAddr, nil
 }
 
@@ -99,6 +105,10 @@ func (s *server) NewAddress(_ context.Context, request *pb.NewAddressRequest) (*
 	if err != nil {
 		return nil, err
 	}
+	err = s.addDerivedAddress(address.String(), walletAddr)
+	if err != nil {
+		return nil, err
+	}
 
 	return &pb.NewAddressResponse{Address: address.String()}, nil
 }
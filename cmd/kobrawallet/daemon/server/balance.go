@@ -23,6 +23,10 @@ func (s *server) GetBalance(_ context.Context, _ *pb.GetBalanceRequest) (*pb.Get
 
 	balancesMap := make(balancesMapType, 0)
 	for _, entry := range s.utxosSortedByAmount {
+		// UTXOs that are spent by transactions that weren't accepted yet aren't part of the balance
+		if s.isOutpointLocked(entry.Outpoint) {
+			continue
+		}
 		amount := entry.UTXOEntry.Amount()
 		address := entry.address
 		balances, ok := balancesMap[address]
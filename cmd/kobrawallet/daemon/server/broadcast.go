@@ -7,6 +7,7 @@ import (
 	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet"
 	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet/serialization"
 	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
 	"github.com/kobradag/kobrad/infrastructure/network/rpcclient"
 	"github.com/pkg/errors"
 	"time"
@@ -30,6 +31,13 @@ func (s *server) broadcast(transactions [][]byte, isDomain bool) ([]string, erro
 	var tx *externalapi.DomainTransaction
 	var err error
 
+	// Transactions may spend outputs of transactions that were broadcast before them in
+	// the same batch, so the outputs of each are added to the known amounts as well
+	knownAmounts := make(map[externalapi.DomainOutpoint]uint64, len(s.utxosSortedByAmount))
+	for _, utxo := range s.utxosSortedByAmount {
+		knownAmounts[*utxo.Outpoint] = utxo.UTXOEntry.Amount()
+	}
+
 	for i, transaction := range transactions {
 
 		if isDomain {
@@ -52,11 +60,16 @@ func (s *server) broadcast(transactions [][]byte, isDomain bool) ([]string, erro
 		for _, input := range tx.Inputs {
 			s.usedOutpoints[input.PreviousOutpoint] = time.Now()
 		}
-	}
 
-	err = s.refreshUTXOs()
-	if err != nil {
-		return nil, err
+		err = s.recordOutgoingTransaction(txIDs[i], tx, knownAmounts)
+		if err != nil {
+			// The transaction was already submitted, so failing to record it isn't returned
+			log.Errorf("Error recording transaction %s in the transaction history: %s", txIDs[i], err)
+		}
+		transactionID := consensushashing.TransactionID(tx)
+		for j, output := range tx.Outputs {
+			knownAmounts[externalapi.DomainOutpoint{TransactionID: *transactionID, Index: uint32(j)}] = output.Value
+		}
 	}
 
 	return txIDs, nil
@@ -3,18 +3,32 @@ package server
 import (
 	"context"
 	"fmt"
-	"time"
+	"math"
 
+	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
 	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet/serialization"
 	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
 	"github.com/kobradag/kobrad/util"
 	"github.com/pkg/errors"
-	"golang.org/x/exp/slices"
 )
 
-// TODO: Implement a better fee estimation mechanism
-const feePerInput = 10000
+// defaultFeePerInput is the fee per input UTXOs are selected with at the minimum fee
+// rate. The fee that's actually paid is computed from the mass of the transaction
+// once its inputs are selected.
+const defaultFeePerInput = 10000
+
+// minimumFeeRate is the mempool's default minimum fee rate in leor per gram,
+// which defaultFeePerInput is assumed to pay for
+const minimumFeeRate = 1.0
+
+// Fee priorities, which select the fee rate from the node's fee estimate
+const (
+	feePriorityLow    = "low"
+	feePriorityNormal = "normal"
+	feePriorityHigh   = "high"
+)
 
 func (s *server) CreateUnsignedTransactions(_ context.Context, request *pb.CreateUnsignedTransactionsRequest) (
 	*pb.CreateUnsignedTransactionsResponse, error,
@@ -22,28 +36,86 @@ func (s *server) CreateUnsignedTransactions(_ context.Context, request *pb.Creat
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	unsignedTransactions, err := s.createUnsignedTransactions(request.Address, request.Amount, request.IsSendAll,
-		request.From, request.UseExistingChangeAddress)
+	feeRate, err := s.feeRate(request.FeeRate, request.FeePriority)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.requestedPayments(request.Address, request.Amount, request.IsSendAll, request.Payments)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.CreateUnsignedTransactionsResponse{UnsignedTransactions: unsignedTransactions}, nil
+	selection, err := requestedUTXOSelection(request.UtxoSelection, request.Utxos, request.From, request.IsSendAll)
+	if err != nil {
+		return nil, err
+	}
+
+	unsignedTransactions, err := s.createUnsignedTransactions(payments, request.IsSendAll,
+		request.From, request.UseExistingChangeAddress, feeRate, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := unsignedTransactionsFee(unsignedTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CreateUnsignedTransactionsResponse{
+		UnsignedTransactions: unsignedTransactions,
+		Fee:                  fee,
+		FeeRate:              feeRate,
+	}, nil
 }
 
-func (s *server) createUnsignedTransactions(address string, amount uint64, isSendAll bool, fromAddressesString []string, useExistingChangeAddress bool) ([][]byte, error) {
+// requestedPayments returns the payments of a request, which either pays amount to address,
+// or pays each of requestPayments. Decoding the addresses here also makes sure they're correct
+// before proceeding to a potentially long UTXO refreshment operation.
+func (s *server) requestedPayments(address string, amount uint64, isSendAll bool, requestPayments []*pb.Payment) (
+	[]*libkobrawallet.Payment, error) {
+
+	if len(requestPayments) == 0 {
+		requestPayments = []*pb.Payment{{Address: address, Amount: amount}}
+	} else if address != "" || amount != 0 || isSendAll {
+		return nil, errors.Errorf("payments can't be combined with an address, an amount or send all")
+	}
+
+	payments := make([]*libkobrawallet.Payment, len(requestPayments))
+	for i, requestPayment := range requestPayments {
+		toAddress, err := util.DecodeAddress(requestPayment.Address, s.params.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		if !isSendAll && requestPayment.Amount == 0 {
+			return nil, errors.Errorf("the payment to %s has no amount", requestPayment.Address)
+		}
+		payments[i] = &libkobrawallet.Payment{
+			Address: toAddress,
+			Amount:  requestPayment.Amount,
+		}
+	}
+	return payments, nil
+}
+
+// createUnsignedTransactions creates the transactions that make the given payments. If isSendAll
+// is set, there's a single payment, and it's paid all the funds instead of its amount.
+func (s *server) createUnsignedTransactions(payments []*libkobrawallet.Payment, isSendAll bool,
+	fromAddressesString []string, useExistingChangeAddress bool, feeRate float64, selection *utxoSelection) (
+	[][]byte, error) {
+
 	if !s.isSynced() {
 //my-add		return nil, errors.Errorf("wallet daemon is not synced yet, %s", s.formatSyncStateReport())
 	}
 
-	// make sure address string is correct before proceeding to a
-	// potentially long UTXO refreshment operation
-	toAddress, err := util.DecodeAddress(address, s.params.Prefix)
-	if err != nil {
-		return nil, err
+	var amount uint64
+	for _, payment := range payments {
+		amount += payment.Amount
 	}
 
-	err = s.refreshUTXOs()
+	// The UTXO set is kept up to date by the UTXOs changed notifications, and the outpoints
+	// of transactions that weren't accepted yet are skipped by usedOutpoints
+	err := s.lockMempoolSpentOutpoints()
 	if err != nil {
 		return nil, err
 	}
@@ -57,86 +129,266 @@ func (s *server) createUnsignedTransactions(address string, amount uint64, isSen
 		fromAddresses = append(fromAddresses, fromAddress)
 	}
 
-	selectedUTXOs, spendValue, changeLeor, err := s.selectUTXOs(amount, isSendAll, feePerInput, fromAddresses)
+	selectUTXOs := func(feePerInput uint64) ([]*libkobrawallet.UTXO, error) {
+		selectedUTXOs, _, _, err := s.selectUTXOs(amount, isSendAll, feePerInput, fromAddresses, selection)
+		if err != nil {
+			return nil, err
+		}
+		if len(selectedUTXOs) == 0 {
+			return nil, errors.Errorf("couldn't find funds to spend")
+		}
+		return selectedUTXOs, nil
+	}
+	selectedUTXOs, err := selectUTXOs(feePerInputForRate(feeRate))
 	if err != nil {
 		return nil, err
 	}
 
-	if len(selectedUTXOs) == 0 {
-		return nil, errors.Errorf("couldn't find funds to spend")
-	}
-
 	changeAddress, changeWalletAddress, err := s.changeAddress(useExistingChangeAddress, fromAddresses)
 	if err != nil {
 		return nil, err
 	}
 
-	payments := []*libkobrawallet.Payment{{
-		Address: toAddress,
-		Amount:  spendValue,
-	}}
-	if changeLeor > 0 {
-		payments = append(payments, &libkobrawallet.Payment{
-			Address: changeAddress,
-			Amount:  changeLeor,
-		})
-	}
-	unsignedTransaction, err := libkobrawallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
-		s.keysFile.MinimumSignatures,
-		payments, selectedUTXOs)
+	unsignedTransaction, err := s.createUnsignedTransactionSelectingUTXOs(payments, changeAddress, isSendAll,
+		selectedUTXOs, feeRate, selection, selectUTXOs)
 	if err != nil {
 		return nil, err
 	}
 
-	unsignedTransactions, err := s.maybeAutoCompoundTransaction(unsignedTransaction, toAddress, changeAddress, changeWalletAddress)
+	unsignedTransactions, err := s.maybeAutoCompoundTransaction(unsignedTransaction, payments, changeAddress,
+		changeWalletAddress, feeRate, fromAddresses, selection)
 	if err != nil {
 		return nil, err
 	}
 	return unsignedTransactions, nil
 }
 
-func (s *server) selectUTXOs(spendAmount uint64, isSendAll bool, feePerInput uint64, fromAddresses []*walletAddress) (
-	selectedUTXOs []*libkobrawallet.UTXO, totalReceived uint64, changeLeor uint64, err error) {
+// createUnsignedTransactionSelectingUTXOs creates a transaction that makes the given payments and
+// pays feeRate for its mass. The given UTXOs were selected with the fee per input of feeRate, and
+// as long as they don't cover the fee for the mass of the transaction, UTXOs are selected again
+// with selectUTXOs, with at least the fee per input the transaction requires.
+func (s *server) createUnsignedTransactionSelectingUTXOs(payments []*libkobrawallet.Payment,
+	changeAddress util.Address, isSendAll bool, selectedUTXOs []*libkobrawallet.UTXO, feeRate float64,
+	selection *utxoSelection, selectUTXOs func(feePerInput uint64) ([]*libkobrawallet.UTXO, error)) ([]byte, error) {
 
-	selectedUTXOs = []*libkobrawallet.UTXO{}
-	totalValue := uint64(0)
+	feePerInput := feePerInputForRate(feeRate)
+	for {
+		unsignedTransaction, requiredFee, err := s.createUnsignedTransactionPayingFeeRate(payments, changeAddress,
+			isSendAll, selectedUTXOs, feeRate, selection.maxChangeAddedToFee(feePerInput))
+		if err != nil {
+			return nil, err
+		}
+		if unsignedTransaction != nil {
+			return unsignedTransaction, nil
+		}
+
+		// The selected UTXOs don't cover the fee for the mass of the transaction, so UTXOs
+		// are selected again with at least the fee per input this transaction requires
+		feePerInput = requiredFee/uint64(len(selectedUTXOs)) + 1
+		selectedUTXOs, err = selectUTXOs(feePerInput)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// createUnsignedTransactionPayingFeeRate creates a transaction that spends the given UTXOs
+// and pays the given fee rate for its mass. If the UTXOs don't cover the payments along with
+// the fee, it returns a nil transaction and the fee that's required. Change of up to
+// maxChangeAddedToFee is paid as part of the fee instead of to a change output.
+func (s *server) createUnsignedTransactionPayingFeeRate(payments []*libkobrawallet.Payment, changeAddress util.Address,
+	isSendAll bool, selectedUTXOs []*libkobrawallet.UTXO, feeRate float64, maxChangeAddedToFee uint64) (
+	unsignedTransaction []byte, requiredFee uint64, err error) {
+
+	var totalValue, amount uint64
+	for _, utxo := range selectedUTXOs {
+		totalValue += utxo.UTXOEntry.Amount()
+	}
+	for _, payment := range payments {
+		amount += payment.Amount
+	}
+
+	// The mass doesn't depend on the output amounts, so it's estimated with a change output
+	// regardless of its amount. Dropping it later only lowers the mass
+	var massEstimationPayments []*libkobrawallet.Payment
+	if isSendAll {
+		massEstimationPayments = []*libkobrawallet.Payment{{Address: payments[0].Address, Amount: totalValue}}
+	} else {
+		massEstimationPayments = append(payments[:len(payments):len(payments)],
+			&libkobrawallet.Payment{Address: changeAddress, Amount: 0})
+	}
+	transactionBytes, err := libkobrawallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
+		s.keysFile.MinimumSignatures, massEstimationPayments, selectedUTXOs)
+	if err != nil {
+		return nil, 0, err
+	}
+	transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+	mass, err := s.estimateMassAfterSignatures(transaction)
+	if err != nil {
+		return nil, 0, err
+	}
+	fee := feeForMass(mass, feeRate)
+
+	if isSendAll {
+		if totalValue <= fee {
+			return nil, 0, errors.Errorf("Insufficient funds for send: the fee of %f is higher than the "+
+				"%f available", float64(fee)/constants.LeorPerPyrin, float64(totalValue)/constants.LeorPerPyrin)
+		}
+		payments = []*libkobrawallet.Payment{{Address: payments[0].Address, Amount: totalValue - fee}}
+	} else {
+		if totalValue < amount+fee {
+			return nil, fee, nil
+		}
+		if changeLeor := totalValue - amount - fee; changeLeor > maxChangeAddedToFee {
+			payments = append(payments[:len(payments):len(payments)],
+				&libkobrawallet.Payment{Address: changeAddress, Amount: changeLeor})
+		}
+	}
+
+	unsignedTransaction, err = libkobrawallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
+		s.keysFile.MinimumSignatures, payments, selectedUTXOs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return unsignedTransaction, fee, nil
+}
+
+// feeRate returns the fee rate in leor per gram to pay. A requested fee rate of 0 means
+// the node's fee estimate for the given priority is used, which falls back to the minimum
+// fee rate when it's unavailable.
+func (s *server) feeRate(requestedFeeRate float64, feePriority string) (float64, error) {
+	if requestedFeeRate != 0 {
+		if requestedFeeRate < minimumFeeRate {
+			return 0, errors.Errorf("fee rate %f is below the minimum fee rate of %f leor per gram",
+				requestedFeeRate, minimumFeeRate)
+		}
+		return requestedFeeRate, nil
+	}
+
+	switch feePriority {
+	case "", feePriorityLow, feePriorityNormal, feePriorityHigh:
+	default:
+		return 0, errors.Errorf("unknown fee priority %s, expected one of %s, %s or %s",
+			feePriority, feePriorityLow, feePriorityNormal, feePriorityHigh)
+	}
+
+	response, err := s.rpcClient.GetFeeEstimate()
+	if err != nil {
+		log.Warnf("Could not get a fee estimate from the node, using the minimum fee rate: %s", err)
+		return minimumFeeRate, nil
+	}
+	return feeRateForPriority(response.Estimate, feePriority), nil
+}
+
+// feeRateForPriority returns the fee rate of the given fee estimate for the given priority,
+// which is at least the minimum fee rate
+func feeRateForPriority(estimate *appmessage.RPCFeeEstimate, feePriority string) float64 {
+	if estimate == nil {
+		return minimumFeeRate
+	}
+	var bucket *appmessage.RPCFeeRateBucket
+	switch feePriority {
+	case feePriorityLow:
+		bucket = estimate.LowBucket
+	case feePriorityHigh:
+		bucket = estimate.PriorityBucket
+	default:
+		bucket = estimate.NormalBucket
+	}
+	if bucket == nil {
+		return minimumFeeRate
+	}
+	return math.Max(bucket.FeeRate, minimumFeeRate)
+}
+
+// feePerInputForRate returns the fee per input UTXOs are selected with for the given fee rate
+func feePerInputForRate(feeRate float64) uint64 {
+	return uint64(math.Ceil(defaultFeePerInput * feeRate / minimumFeeRate))
+}
+
+func feeForMass(mass uint64, feeRate float64) uint64 {
+	return uint64(math.Ceil(float64(mass) * feeRate))
+}
+
+// unsignedTransactionsFee returns the total fee the given transactions pay
+func unsignedTransactionsFee(unsignedTransactions [][]byte) (uint64, error) {
+	var fee uint64
+	for _, unsignedTransaction := range unsignedTransactions {
+		transaction, err := serialization.DeserializePartiallySignedTransaction(unsignedTransaction)
+		if err != nil {
+			return 0, err
+		}
+		var inputsValue, outputsValue uint64
+		for _, input := range transaction.PartiallySignedInputs {
+			inputsValue += input.PrevOutput.Value
+		}
+		for _, output := range transaction.Tx.Outputs {
+			outputsValue += output.Value
+		}
+		fee += inputsValue - outputsValue
+	}
+	return fee, nil
+}
+
+func (s *server) selectUTXOs(spendAmount uint64, isSendAll bool, feePerInput uint64, fromAddresses []*walletAddress,
+	selection *utxoSelection) (selectedUTXOs []*libkobrawallet.UTXO, totalReceived uint64, changeLeor uint64, err error) {
 
 	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
 	if err != nil {
 		return nil, 0, 0, err
 	}
-	coinbaseMaturity := s.params.BlockCoinbaseMaturity
-	if dagInfo.NetworkName == "kobra-testnet" {
-		coinbaseMaturity = 1000
-	}
+	coinbaseMaturity := s.coinbaseMaturity(dagInfo.NetworkName)
 
-	for _, utxo := range s.utxosSortedByAmount {
-		if (fromAddresses != nil && !slices.Contains(fromAddresses, utxo.address)) ||
-			!isUTXOSpendable(utxo, dagInfo.VirtualDAAScore, coinbaseMaturity) {
-			continue
+	var utxos []*walletUTXO
+	if len(selection.outpoints) > 0 {
+		utxos, err = s.explicitUTXOs(selection.outpoints, dagInfo.VirtualDAAScore, coinbaseMaturity)
+		if err != nil {
+			return nil, 0, 0, err
 		}
+	} else {
+		utxos = s.spendableUTXOs(fromAddresses, dagInfo.VirtualDAAScore, coinbaseMaturity)
+	}
 
-		if broadcastTime, ok := s.usedOutpoints[*utxo.Outpoint]; ok {
-			if time.Since(broadcastTime) > time.Minute {
-				delete(s.usedOutpoints, *utxo.Outpoint)
+	if !isSendAll && len(selection.outpoints) == 0 {
+		switch selection.strategy {
+		case utxoSelectionSmallestFirst:
+			smallestFirst := make([]*walletUTXO, len(utxos))
+			for i, utxo := range utxos {
+				smallestFirst[len(utxos)-1-i] = utxo
+			}
+			utxos = selectUTXOsInOrder(smallestFirst, spendAmount, feePerInput)
+		case utxoSelectionBranchAndBound:
+			changelessUTXOs := selectUTXOsBranchAndBound(utxos, spendAmount, feePerInput,
+				selection.maxChangeAddedToFee(feePerInput))
+			if changelessUTXOs != nil {
+				utxos = changelessUTXOs
 			} else {
-				continue
+				utxos = selectUTXOsInOrder(utxos, spendAmount, feePerInput)
+			}
+		case utxoSelectionAvoidAddressMixing:
+			singleAddressUTXOs := selectUTXOsFromSingleAddress(utxos, spendAmount, feePerInput)
+			if singleAddressUTXOs == nil {
+				return nil, 0, 0, errors.Errorf("no single address has enough funds to send %f without "+
+					"mixing addresses", float64(spendAmount)/constants.LeorPerPyrin)
 			}
+			utxos = singleAddressUTXOs
+		default:
+			utxos = selectUTXOsInOrder(utxos, spendAmount, feePerInput)
 		}
+	}
 
-		selectedUTXOs = append(selectedUTXOs, &libkobrawallet.UTXO{
+	selectedUTXOs = make([]*libkobrawallet.UTXO, len(utxos))
+	totalValue := uint64(0)
+	for i, utxo := range utxos {
+		selectedUTXOs[i] = &libkobrawallet.UTXO{
 			Outpoint:       utxo.Outpoint,
 			UTXOEntry:      utxo.UTXOEntry,
 			DerivationPath: s.walletAddressPath(utxo.address),
-		})
-
-		totalValue += utxo.UTXOEntry.Amount()
-
-		fee := feePerInput * uint64(len(selectedUTXOs))
-		totalSpend := spendAmount + fee
-		if !isSendAll && totalValue >= totalSpend {
-			break
 		}
+		totalValue += utxo.UTXOEntry.Amount()
 	}
 
 	fee := feePerInput * uint64(len(selectedUTXOs))
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/keys"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet/serialization"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
+	"github.com/kobradag/kobrad/domain/consensus/utils/txscript"
+	"github.com/kobradag/kobrad/domain/consensus/utils/utxo"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+	"github.com/kobradag/kobrad/util"
+	"github.com/kobradag/kobrad/util/txmass"
+)
+
+func TestRequestedPayments(t *testing.T) {
+	params := &dagconfig.SimnetParams
+	serverInstance := &server{params: params}
+
+	addresses := make([]string, 2)
+	for i := range addresses {
+		address, err := util.NewAddressPublicKey(make([]byte, 32), params.Prefix)
+		if err != nil {
+			t.Fatalf("NewAddressPublicKey: %s", err)
+		}
+		addresses[i] = address.String()
+	}
+
+	payments, err := serverInstance.requestedPayments(addresses[0], 100, false, nil)
+	if err != nil {
+		t.Fatalf("requestedPayments: %s", err)
+	}
+	if len(payments) != 1 || payments[0].Address.String() != addresses[0] || payments[0].Amount != 100 {
+		t.Fatalf("unexpected payments for a single recipient: %+v", payments)
+	}
+
+	requestPayments := []*pb.Payment{
+		{Address: addresses[0], Amount: 100},
+		{Address: addresses[1], Amount: 200},
+	}
+	payments, err = serverInstance.requestedPayments("", 0, false, requestPayments)
+	if err != nil {
+		t.Fatalf("requestedPayments: %s", err)
+	}
+	if len(payments) != len(requestPayments) {
+		t.Fatalf("expected %d payments but got %d", len(requestPayments), len(payments))
+	}
+	for i, payment := range payments {
+		if payment.Address.String() != requestPayments[i].Address || payment.Amount != requestPayments[i].Amount {
+			t.Fatalf("payment %d is %s:%d, expected %s:%d", i, payment.Address, payment.Amount,
+				requestPayments[i].Address, requestPayments[i].Amount)
+		}
+	}
+
+	if _, err := serverInstance.requestedPayments(addresses[0], 0, false, requestPayments); err == nil {
+		t.Fatalf("expected an error when payments are combined with an address")
+	}
+	if _, err := serverInstance.requestedPayments("", 0, true, requestPayments); err == nil {
+		t.Fatalf("expected an error when payments are combined with send all")
+	}
+	if _, err := serverInstance.requestedPayments("", 0, false,
+		[]*pb.Payment{{Address: addresses[0], Amount: 0}}); err == nil {
+
+		t.Fatalf("expected an error for a payment with no amount")
+	}
+	if _, err := serverInstance.requestedPayments("", 0, false,
+		[]*pb.Payment{{Address: "invalid", Amount: 1}}); err == nil {
+
+		t.Fatalf("expected an error for an invalid address")
+	}
+}
+
+func TestFeeRate(t *testing.T) {
+	serverInstance := &server{}
+
+	feeRate, err := serverInstance.feeRate(2.5, feePriorityLow)
+	if err != nil {
+		t.Fatalf("feeRate: %s", err)
+	}
+	if feeRate != 2.5 {
+		t.Fatalf("expected the requested fee rate to take precedence over the priority, got %f", feeRate)
+	}
+	if _, err := serverInstance.feeRate(minimumFeeRate/2, ""); err == nil {
+		t.Fatalf("expected an error for a fee rate below the minimum")
+	}
+	if _, err := serverInstance.feeRate(0, "urgent"); err == nil {
+		t.Fatalf("expected an error for an unknown fee priority")
+	}
+
+	estimate := &appmessage.RPCFeeEstimate{
+		LowBucket:      &appmessage.RPCFeeRateBucket{FeeRate: minimumFeeRate / 2},
+		NormalBucket:   &appmessage.RPCFeeRateBucket{FeeRate: 3},
+		PriorityBucket: &appmessage.RPCFeeRateBucket{FeeRate: 10},
+	}
+	tests := []struct {
+		feePriority     string
+		expectedFeeRate float64
+	}{
+		{feePriority: feePriorityLow, expectedFeeRate: minimumFeeRate},
+		{feePriority: "", expectedFeeRate: 3},
+		{feePriority: feePriorityNormal, expectedFeeRate: 3},
+		{feePriority: feePriorityHigh, expectedFeeRate: 10},
+	}
+	for _, test := range tests {
+		feeRate := feeRateForPriority(estimate, test.feePriority)
+		if feeRate != test.expectedFeeRate {
+			t.Fatalf("expected the fee rate of priority %q to be %f, got %f",
+				test.feePriority, test.expectedFeeRate, feeRate)
+		}
+	}
+	if feeRate := feeRateForPriority(&appmessage.RPCFeeEstimate{}, feePriorityHigh); feeRate != minimumFeeRate {
+		t.Fatalf("expected the minimum fee rate for a missing bucket, got %f", feeRate)
+	}
+}
+
+// testFeeServer returns a server of a single key wallet that doesn't need a node,
+// along with its change address
+func testFeeServer(t *testing.T) (*server, util.Address) {
+	params := &dagconfig.MainnetParams
+	mnemonic, err := libkobrawallet.CreateMnemonic()
+	if err != nil {
+		t.Fatalf("CreateMnemonic: %s", err)
+	}
+	extendedPublicKey, err := libkobrawallet.MasterPublicKeyFromMnemonic(params, mnemonic, false)
+	if err != nil {
+		t.Fatalf("MasterPublicKeyFromMnemonic: %s", err)
+	}
+	serverInstance := &server{
+		params:           params,
+		keysFile:         &keys.File{ExtendedPublicKeys: []string{extendedPublicKey}, MinimumSignatures: 1},
+		addressSet:       make(walletAddressSet),
+		txMassCalculator: txmass.NewCalculator(params.MassPerTxByte, params.MassPerScriptPubKeyByte, params.MassPerSigOp),
+	}
+	changeAddress, err := libkobrawallet.Address(params, serverInstance.keysFile.ExtendedPublicKeys, 1,
+		serverInstance.walletAddressPath(&walletAddress{keyChain: libkobrawallet.InternalKeychain}), false)
+	if err != nil {
+		t.Fatalf("Address: %s", err)
+	}
+	return serverInstance, changeAddress
+}
+
+// testFeeUTXOs returns UTXOs of the first external address of the wallet of the given server
+func testFeeUTXOs(t *testing.T, serverInstance *server, amounts ...uint64) []*libkobrawallet.UTXO {
+	path := serverInstance.walletAddressPath(&walletAddress{keyChain: libkobrawallet.ExternalKeychain})
+	address, err := libkobrawallet.Address(serverInstance.params, serverInstance.keysFile.ExtendedPublicKeys, 1, path, false)
+	if err != nil {
+		t.Fatalf("Address: %s", err)
+	}
+	scriptPublicKey, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %s", err)
+	}
+	utxos := make([]*libkobrawallet.UTXO, len(amounts))
+	for i, amount := range amounts {
+		utxos[i] = &libkobrawallet.UTXO{
+			Outpoint: &externalapi.DomainOutpoint{
+				TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{byte(i + 1)}),
+			},
+			UTXOEntry:      utxo.NewUTXOEntry(amount, scriptPublicKey, false, constants.UnacceptedDAAScore),
+			DerivationPath: path,
+		}
+	}
+	return utxos
+}
+
+// testTransactionFee returns the fee the given transaction pays and its estimated mass
+func testTransactionFee(t *testing.T, serverInstance *server, transaction *serialization.PartiallySignedTransaction) (
+	fee uint64, mass uint64) {
+
+	for _, input := range transaction.PartiallySignedInputs {
+		fee += input.PrevOutput.Value
+	}
+	for _, output := range transaction.Tx.Outputs {
+		fee -= output.Value
+	}
+	mass, err := serverInstance.estimateMassAfterSignatures(transaction)
+	if err != nil {
+		t.Fatalf("estimateMassAfterSignatures: %s", err)
+	}
+	return fee, mass
+}
+
+func TestCreateUnsignedTransactionSelectingUTXOs(t *testing.T) {
+	serverInstance, changeAddress := testFeeServer(t)
+	const feeRate = 20.0
+	const amount = 1_000_000
+	utxos := testFeeUTXOs(t, serverInstance, amount+1, 10*amount)
+	payments := []*libkobrawallet.Payment{{Address: changeAddress, Amount: amount}}
+
+	// The first UTXO doesn't cover the fee for the mass of the transaction, so UTXOs are
+	// selected again with the fee per input the transaction requires
+	var reselectionFeesPerInput []uint64
+	selectUTXOs := func(feePerInput uint64) ([]*libkobrawallet.UTXO, error) {
+		reselectionFeesPerInput = append(reselectionFeesPerInput, feePerInput)
+		return utxos, nil
+	}
+	unsignedTransactionBytes, err := serverInstance.createUnsignedTransactionSelectingUTXOs(payments, changeAddress,
+		false, utxos[:1], feeRate, &utxoSelection{}, selectUTXOs)
+	if err != nil {
+		t.Fatalf("createUnsignedTransactionSelectingUTXOs: %s", err)
+	}
+	if len(reselectionFeesPerInput) != 1 || reselectionFeesPerInput[0] <= uint64(feeRate) {
+		t.Fatalf("expected the UTXOs to be selected again once with the required fee per input, got %v",
+			reselectionFeesPerInput)
+	}
+
+	unsignedTransaction, err := serialization.DeserializePartiallySignedTransaction(unsignedTransactionBytes)
+	if err != nil {
+		t.Fatalf("DeserializePartiallySignedTransaction: %s", err)
+	}
+	if len(unsignedTransaction.Tx.Inputs) != len(utxos) || len(unsignedTransaction.Tx.Outputs) != 2 {
+		t.Fatalf("expected a transaction spending %d UTXOs into a payment and change, got %d inputs and %d outputs",
+			len(utxos), len(unsignedTransaction.Tx.Inputs), len(unsignedTransaction.Tx.Outputs))
+	}
+	fee, mass := testTransactionFee(t, serverInstance, unsignedTransaction)
+	if fee != feeForMass(mass, feeRate) {
+		t.Fatalf("expected the fee to be %d for a mass of %d, got %d", feeForMass(mass, feeRate), mass, fee)
+	}
+
+	// Sending all the funds pays the fee out of the payment
+	unsignedTransactionBytes, err = serverInstance.createUnsignedTransactionSelectingUTXOs(payments, changeAddress,
+		true, utxos, feeRate, &utxoSelection{}, selectUTXOs)
+	if err != nil {
+		t.Fatalf("createUnsignedTransactionSelectingUTXOs: %s", err)
+	}
+	unsignedTransaction, err = serialization.DeserializePartiallySignedTransaction(unsignedTransactionBytes)
+	if err != nil {
+		t.Fatalf("DeserializePartiallySignedTransaction: %s", err)
+	}
+	fee, mass = testTransactionFee(t, serverInstance, unsignedTransaction)
+	if len(unsignedTransaction.Tx.Outputs) != 1 || fee != feeForMass(mass, feeRate) {
+		t.Fatalf("expected a single output paying a fee of %d, got %d outputs paying %d",
+			feeForMass(mass, feeRate), len(unsignedTransaction.Tx.Outputs), fee)
+	}
+}
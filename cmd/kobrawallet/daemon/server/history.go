@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// historyEntry is a transaction that moved funds into or out of the wallet
+type historyEntry struct {
+	TransactionID          string   `json:"transactionId"`
+	Timestamp              int64    `json:"timestamp"` // Unix milliseconds of when the transaction was first seen
+	Received               uint64   `json:"received"`
+	Sent                   uint64   `json:"sent"`
+	Fee                    uint64   `json:"fee"`
+	CounterpartyAddresses  []string `json:"counterpartyAddresses"`
+	IsAccepted             bool     `json:"isAccepted"`
+	AcceptingBlockHash     string   `json:"acceptingBlockHash"`
+	AcceptingBlockDAAScore uint64   `json:"acceptingBlockDaaScore"`
+
+	// ReceivedOutpoints are the outputs of the transaction that were paid to the wallet,
+	// and SpentOutpoints are the wallet UTXOs it spends, if it was sent by this wallet.
+	// They're used to avoid counting outputs twice, and to detect the acceptance of
+	// the transaction
+	ReceivedOutpoints []string `json:"receivedOutpoints"`
+	SpentOutpoints    []string `json:"spentOutpoints,omitempty"`
+}
+
+func (e *historyEntry) isOutgoing() bool {
+	return len(e.SpentOutpoints) > 0
+}
+
+// accept marks the transaction of the entry as accepted. It returns false if it
+// already was.
+func (e *historyEntry) accept(acceptingBlockDAAScore uint64) bool {
+	if e.IsAccepted {
+		return false
+	}
+	e.IsAccepted = true
+	e.AcceptingBlockDAAScore = acceptingBlockDAAScore
+	return true
+}
+
+// confirmations returns the number of confirmations of the entry given the
+// virtual DAA score, or 0 if the transaction wasn't accepted yet
+func (e *historyEntry) confirmations(virtualDAAScore uint64) uint64 {
+	if !e.IsAccepted || virtualDAAScore < e.AcceptingBlockDAAScore {
+		return 0
+	}
+	return virtualDAAScore - e.AcceptingBlockDAAScore + 1
+}
+
+func (e *historyEntry) addCounterpartyAddress(address string) {
+	for _, counterpartyAddress := range e.CounterpartyAddresses {
+		if counterpartyAddress == address {
+			return
+		}
+	}
+	e.CounterpartyAddresses = append(e.CounterpartyAddresses, address)
+}
+
+func historyOutpointKey(transactionID string, index uint32) string {
+	return fmt.Sprintf("%s:%d", transactionID, index)
+}
+
+// transactionHistory is the transaction history of a wallet. It's kept next to the
+// keys file of the wallet. Sent transactions are saved right away, while changes that
+// come from the node are marked as unsaved, and saved periodically by the sync loop.
+type transactionHistory struct {
+	path    string
+	entries []*historyEntry
+
+	hasUnsavedChanges bool
+
+	entriesByTransactionID map[string]*historyEntry
+	entriesBySpentOutpoint map[string]*historyEntry
+
+	// acceptingBlockLookups counts the attempts to look up the accepting block of
+	// each transaction in the node, which fail if the node doesn't run with --txindex
+	acceptingBlockLookups map[string]int
+}
+
+// historyFilePath returns the path of the transaction history of the keys file
+// in the given path, e.g. ~/.kobrawallet/keys-history.json for ~/.kobrawallet/keys.json
+func historyFilePath(keysFilePath string) string {
+	return strings.TrimSuffix(keysFilePath, filepath.Ext(keysFilePath)) + "-history.json"
+}
+
+// loadTransactionHistory reads the transaction history in the given path. A history
+// that doesn't exist yet is created empty.
+func loadTransactionHistory(path string) (*transactionHistory, error) {
+	history := &transactionHistory{
+		path:                   path,
+		entriesByTransactionID: make(map[string]*historyEntry),
+		entriesBySpentOutpoint: make(map[string]*historyEntry),
+		acceptingBlockLookups:  make(map[string]int),
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []*historyEntry
+	err = json.NewDecoder(file).Decode(&entries)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		history.add(entry)
+	}
+	return history, nil
+}
+
+func (h *transactionHistory) add(entry *historyEntry) {
+	h.entries = append(h.entries, entry)
+	h.entriesByTransactionID[entry.TransactionID] = entry
+	for _, outpoint := range entry.SpentOutpoints {
+		h.entriesBySpentOutpoint[outpoint] = entry
+	}
+}
+
+// markChanged marks the history as changed, to be written by the next saveIfChanged
+func (h *transactionHistory) markChanged() {
+	h.hasUnsavedChanges = true
+}
+
+// saveIfChanged writes the history if it has changes that weren't saved yet
+func (h *transactionHistory) saveIfChanged() error {
+	if !h.hasUnsavedChanges {
+		return nil
+	}
+	return h.save()
+}
+
+// save writes the history to a temporary file and renames it over the previous
+// history, so that a crash in the middle of a write doesn't corrupt it
+func (h *transactionHistory) save() error {
+	err := os.MkdirAll(filepath.Dir(h.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := h.path + ".tmp"
+	file, err := os.OpenFile(temporaryPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(file).Encode(h.entries)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temporaryPath, h.path)
+	if err != nil {
+		return err
+	}
+	h.hasUnsavedChanges = false
+	return nil
+}
+
+// recordOutgoing adds a transaction that was sent by the wallet. It returns false
+// if the transaction is already in the history.
+func (h *transactionHistory) recordOutgoing(entry *historyEntry) bool {
+	if _, ok := h.entriesByTransactionID[entry.TransactionID]; ok {
+		return false
+	}
+	h.add(entry)
+	return true
+}
+
+// recordReceivedOutput records a UTXO of the wallet that was created by the given
+// transaction. Outputs of transactions that weren't sent by the wallet are counted as
+// received funds, while the rest are change. Since a UTXO only exists once its
+// transaction is accepted, the transaction is marked as accepted as well.
+// It returns whether the history changed.
+func (h *transactionHistory) recordReceivedOutput(transactionID string, outpoint string, amount uint64,
+	blockDAAScore uint64) bool {
+
+	entry, ok := h.entriesByTransactionID[transactionID]
+	if !ok {
+		entry = &historyEntry{
+			TransactionID: transactionID,
+			Timestamp:     time.Now().UnixMilli(),
+		}
+		h.add(entry)
+	}
+
+	for _, receivedOutpoint := range entry.ReceivedOutpoints {
+		if receivedOutpoint == outpoint {
+			return false
+		}
+	}
+	entry.ReceivedOutpoints = append(entry.ReceivedOutpoints, outpoint)
+	if !entry.isOutgoing() {
+		entry.Received += amount
+	}
+	entry.accept(blockDAAScore)
+	return true
+}
+
+// spendingEntry returns the entry of the outgoing transaction that spends the given
+// outpoint, or nil if there's none
+func (h *transactionHistory) spendingEntry(outpoint string) *historyEntry {
+	return h.entriesBySpentOutpoint[outpoint]
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+)
+
+func TestTransactionHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys-history.json")
+	history, err := loadTransactionHistory(path)
+	if err != nil {
+		t.Fatalf("loadTransactionHistory: %s", err)
+	}
+
+	// An incoming transaction that pays the wallet twice
+	if !history.recordReceivedOutput("incoming", historyOutpointKey("incoming", 0), 100, 10) {
+		t.Fatalf("recordReceivedOutput: expected the history to change")
+	}
+	history.recordReceivedOutput("incoming", historyOutpointKey("incoming", 1), 50, 10)
+	if history.recordReceivedOutput("incoming", historyOutpointKey("incoming", 1), 50, 10) {
+		t.Fatalf("recordReceivedOutput: an output was recorded twice")
+	}
+
+	// An outgoing transaction, whose output to the wallet is change
+	outgoing := &historyEntry{
+		TransactionID:  "outgoing",
+		Sent:           120,
+		Fee:            1,
+		SpentOutpoints: []string{historyOutpointKey("incoming", 0), historyOutpointKey("incoming", 1)},
+	}
+	if !history.recordOutgoing(outgoing) {
+		t.Fatalf("recordOutgoing: expected the transaction to be recorded")
+	}
+	if history.recordOutgoing(&historyEntry{TransactionID: "outgoing"}) {
+		t.Fatalf("recordOutgoing: a transaction was recorded twice")
+	}
+	if history.spendingEntry(historyOutpointKey("incoming", 1)) != outgoing {
+		t.Fatalf("spendingEntry: expected the outgoing transaction")
+	}
+	if outgoing.confirmations(100) != 0 {
+		t.Fatalf("confirmations: a pending transaction has confirmations")
+	}
+	history.recordReceivedOutput("outgoing", historyOutpointKey("outgoing", 1), 29, 20)
+
+	err = history.save()
+	if err != nil {
+		t.Fatalf("save: %s", err)
+	}
+	history, err = loadTransactionHistory(path)
+	if err != nil {
+		t.Fatalf("loadTransactionHistory: %s", err)
+	}
+	if len(history.entries) != 2 {
+		t.Fatalf("loadTransactionHistory: expected 2 entries, got %d", len(history.entries))
+	}
+
+	incoming := history.entries[0]
+	if incoming.Received != 150 || incoming.isOutgoing() || incoming.confirmations(19) != 10 {
+		t.Fatalf("unexpected incoming entry %+v", incoming)
+	}
+	outgoing = history.entries[1]
+	if outgoing.Received != 0 || outgoing.Sent != 120 || !outgoing.IsAccepted || outgoing.AcceptingBlockDAAScore != 20 {
+		t.Fatalf("unexpected outgoing entry %+v", outgoing)
+	}
+	if history.spendingEntry(historyOutpointKey("incoming", 0)) != outgoing {
+		t.Fatalf("spendingEntry: expected the outgoing transaction after loading the history")
+	}
+}
+
+func TestHistoryFilePath(t *testing.T) {
+	historyPath := historyFilePath(filepath.Join("wallet", "keys.json"))
+	if historyPath != filepath.Join("wallet", "keys-history.json") {
+		t.Fatalf("historyFilePath: unexpected path %s", historyPath)
+	}
+}
+
+func TestRecordUTXOsChangedIgnoresUnknownAddresses(t *testing.T) {
+	history, err := loadTransactionHistory(filepath.Join(t.TempDir(), "keys-history.json"))
+	if err != nil {
+		t.Fatalf("loadTransactionHistory: %s", err)
+	}
+	serverInstance := &server{
+		addressSet: walletAddressSet{"address": &walletAddress{}},
+		history:    history,
+	}
+
+	serverInstance.recordUTXOsChanged([]*appmessage.UTXOsByAddressesEntry{
+		testUTXOsByAddressesEntry("address", "1", 10),
+		testUTXOsByAddressesEntry("unknown", "2", 20),
+	}, []*appmessage.UTXOsByAddressesEntry{
+		testUTXOsByAddressesEntry("unknown", "1", 10),
+	}, 100)
+	if len(history.entries) != 1 || history.entries[0].Received != 10 {
+		t.Fatalf("recordUTXOsChanged: expected only the UTXO of the wallet address to be recorded, got %+v",
+			history.entries)
+	}
+
+	// Notifications don't rewrite the history, which is only saved by the sync loop
+	if _, err := os.Stat(history.path); !os.IsNotExist(err) {
+		t.Fatalf("recordUTXOsChanged: expected the history not to be saved yet, got %v", err)
+	}
+	err = serverInstance.saveHistoryWithLock()
+	if err != nil {
+		t.Fatalf("saveHistoryWithLock: %s", err)
+	}
+	savedHistory, err := loadTransactionHistory(history.path)
+	if err != nil {
+		t.Fatalf("loadTransactionHistory: %s", err)
+	}
+	if len(savedHistory.entries) != 1 || history.hasUnsavedChanges {
+		t.Fatalf("saveHistoryWithLock: expected the recorded UTXO to be saved, got %+v", savedHistory.entries)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/pkg/errors"
+)
+
+func (s *server) ListUnspent(_ context.Context, request *pb.ListUnspentRequest) (*pb.ListUnspentResponse, error) {
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var addresses map[string]struct{}
+	if len(request.Addresses) > 0 {
+		addresses = make(map[string]struct{}, len(request.Addresses))
+		for _, address := range request.Addresses {
+			if _, ok := s.addressSet[address]; !ok {
+				return nil, errors.Errorf("address %s isn't an address of the wallet", address)
+			}
+			addresses[address] = struct{}{}
+		}
+	}
+
+	coinbaseMaturity := s.coinbaseMaturity(dagInfo.NetworkName)
+
+	addressStrings := make(map[*walletAddress]string)
+	var utxos []*pb.UnspentOutput
+	for _, utxo := range s.utxosSortedByAmount {
+		address, ok := addressStrings[utxo.address]
+		if !ok {
+			address, err = s.walletAddressString(utxo.address)
+			if err != nil {
+				return nil, err
+			}
+			addressStrings[utxo.address] = address
+		}
+		if addresses != nil {
+			if _, ok := addresses[address]; !ok {
+				continue
+			}
+		}
+
+		blockDAAScore := utxo.UTXOEntry.BlockDAAScore()
+		var confirmations uint64
+		if dagInfo.VirtualDAAScore >= blockDAAScore {
+			confirmations = dagInfo.VirtualDAAScore - blockDAAScore + 1
+		}
+		maturityDAAScore := blockDAAScore
+		if utxo.UTXOEntry.IsCoinbase() {
+			maturityDAAScore = blockDAAScore + coinbaseMaturity + 1
+		}
+
+		utxos = append(utxos, &pb.UnspentOutput{
+			Address: address,
+			Outpoint: &pb.Outpoint{
+				TransactionId: utxo.Outpoint.TransactionID.String(),
+				Index:         utxo.Outpoint.Index,
+			},
+			Amount:           utxo.UTXOEntry.Amount(),
+			BlockDaaScore:    blockDAAScore,
+			IsCoinbase:       utxo.UTXOEntry.IsCoinbase(),
+			Confirmations:    confirmations,
+			IsMature:         isUTXOSpendable(utxo, dagInfo.VirtualDAAScore, coinbaseMaturity),
+			MaturityDaaScore: maturityDAAScore,
+			IsLocked:         s.isOutpointLocked(utxo.Outpoint),
+		})
+	}
+
+	return &pb.ListUnspentResponse{
+		Utxos:           utxos,
+		VirtualDaaScore: dagInfo.VirtualDAAScore,
+	}, nil
+}
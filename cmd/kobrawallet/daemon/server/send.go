@@ -10,9 +10,29 @@ func (s *server) Send(_ context.Context, request *pb.SendRequest) (*pb.SendRespo
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	unsignedTransactions, err := s.createUnsignedTransactions(request.ToAddress, request.Amount, request.IsSendAll,
-		request.From, request.UseExistingChangeAddress)
+	feeRate, err := s.feeRate(request.FeeRate, request.FeePriority)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.requestedPayments(request.ToAddress, request.Amount, request.IsSendAll, request.Payments)
+	if err != nil {
+		return nil, err
+	}
+
+	selection, err := requestedUTXOSelection(request.UtxoSelection, request.Utxos, request.From, request.IsSendAll)
+	if err != nil {
+		return nil, err
+	}
+
+	unsignedTransactions, err := s.createUnsignedTransactions(payments, request.IsSendAll,
+		request.From, request.UseExistingChangeAddress, feeRate, selection)
+
+	if err != nil {
+		return nil, err
+	}
 
+	fee, err := unsignedTransactionsFee(unsignedTransactions)
 	if err != nil {
 		return nil, err
 	}
@@ -27,5 +47,5 @@ func (s *server) Send(_ context.Context, request *pb.SendRequest) (*pb.SendRespo
 		return nil, err
 	}
 
-	return &pb.SendResponse{TxIDs: txIDs, SignedTransactions: signedTransactions}, nil
+	return &pb.SendResponse{TxIDs: txIDs, SignedTransactions: signedTransactions, Fee: fee}, nil
 }
@@ -38,6 +38,13 @@ type server struct {
 	addressSet          walletAddressSet
 	txMassCalculator    *txmass.Calculator
 	usedOutpoints       map[externalapi.DomainOutpoint]time.Time
+	history             *transactionHistory
+	subscribedAddresses map[string]struct{} // Addresses that are registered for UTXOsChanged notifications, nil until the first registration
+
+	// hasReconnected and isUTXOSetOverridden are set from the RPC client's goroutines
+	// when the UTXO set has to be refreshed, and are accessed atomically
+	hasReconnected      uint32
+	isUTXOSetOverridden uint32
 
 	isLogFinalProgressLineShown bool
 	maxUsedAddressesForLog      uint32
@@ -82,6 +89,11 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 		return err
 	}
 
+	history, err := loadTransactionHistory(historyFilePath(keysFile.Path()))
+	if err != nil {
+		return errors.Wrapf(err, "Error reading the transaction history of %s", keysFilePath)
+	}
+
 	serverInstance := &server{
 		rpcClient:                   rpcClient,
 		params:                      params,
@@ -92,11 +104,14 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 		addressSet:                  make(walletAddressSet),
 		txMassCalculator:            txmass.NewCalculator(params.MassPerTxByte, params.MassPerScriptPubKeyByte, params.MassPerSigOp),
 		usedOutpoints:               map[externalapi.DomainOutpoint]time.Time{},
+		history:                     history,
 		isLogFinalProgressLineShown: false,
 		maxUsedAddressesForLog:      0,
 		maxProcessedAddressesForLog: 0,
 	}
 
+	rpcClient.SetOnReconnectedHandler(serverInstance.onReconnected)
+
 	log.Infof("Read, syncing the wallet...")
 	spawn("serverInstance.sync", func() {
 		err := serverInstance.sync()
@@ -134,6 +149,11 @@ func Start(params *dagconfig.Params, listen, rpcServer string, keysFilePath stri
 		}
 	}
 
+	err = serverInstance.saveHistoryWithLock()
+	if err != nil {
+		log.Errorf("Error saving the transaction history: %s", err)
+	}
+
 	return nil
 }
 
@@ -18,16 +18,19 @@ import (
 // transaction.
 // If it is - the transaction is split into multiple transactions, each with a portion of the inputs and a single output
 // into a change address.
-// An additional `mergeTransaction` is generated - which merges the outputs of the above splits into a single output
-// paying to the original transaction's payee.
-func (s *server) maybeAutoCompoundTransaction(transactionBytes []byte, toAddress util.Address,
-	changeAddress util.Address, changeWalletAddress *walletAddress) ([][]byte, error) {
+// An additional `mergeTransaction` is generated - which merges the outputs of the above splits into outputs
+// paying the original transaction's payees.
+// If the merge transaction needs more funds, they're only taken from fromAddresses and according to selection.
+func (s *server) maybeAutoCompoundTransaction(transactionBytes []byte, payments []*libkobrawallet.Payment,
+	changeAddress util.Address, changeWalletAddress *walletAddress, feeRate float64, fromAddresses []*walletAddress,
+	selection *utxoSelection) ([][]byte, error) {
 	transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	splitTransactions, err := s.maybeSplitAndMergeTransaction(transaction, toAddress, changeAddress, changeWalletAddress)
+	splitTransactions, err := s.maybeSplitAndMergeTransaction(transaction, payments, changeAddress, changeWalletAddress,
+		feeRate, fromAddresses, selection, make(map[externalapi.DomainOutpoint]struct{}))
 	if err != nil {
 		return nil, err
 	}
@@ -41,24 +44,44 @@ func (s *server) maybeAutoCompoundTransaction(transactionBytes []byte, toAddress
 	return splitTransactionsBytes, nil
 }
 
+// mergeTransaction creates the transaction that spends the outputs of the split transactions
+// to make the payments of the original transaction, paying feeRate for its mass. If the outputs
+// of the split transactions don't cover the fee, UTXOs of fromAddresses that aren't in
+// spentOutpoints are added according to selection
 func (s *server) mergeTransaction(
 	splitTransactions []*serialization.PartiallySignedTransaction,
 	originalTransaction *serialization.PartiallySignedTransaction,
-	toAddress util.Address,
+	payments []*libkobrawallet.Payment,
 	changeAddress util.Address,
 	changeWalletAddress *walletAddress,
+	feeRate float64,
+	fromAddresses []*walletAddress,
+	selection *utxoSelection,
+	spentOutpoints map[externalapi.DomainOutpoint]struct{},
 ) (*serialization.PartiallySignedTransaction, error) {
+	numPayments := len(payments)
 	numOutputs := len(originalTransaction.Tx.Outputs)
-	if numOutputs > 2 || numOutputs == 0 {
-		// This is a sanity check to make sure originalTransaction has either 1 or 2 outputs:
-		// 1. For the payment itself
+	if numOutputs != numPayments && numOutputs != numPayments+1 {
+		// This is a sanity check to make sure originalTransaction has the following outputs:
+		// 1. One for each payment
 		// 2. (optional) for change
-		return nil, errors.Errorf("original transaction has %d outputs, while 1 or 2 are expected",
-			len(originalTransaction.Tx.Outputs))
+		return nil, errors.Errorf("original transaction has %d outputs, while %d or %d are expected",
+			numOutputs, numPayments, numPayments+1)
+	}
+
+	// The payments are made with the amounts of the original transaction's outputs, which
+	// differ from the requested ones when sending all the funds
+	mergePayments := make([]*libkobrawallet.Payment, numPayments)
+	sentValue := uint64(0)
+	for i, payment := range payments {
+		mergePayments[i] = &libkobrawallet.Payment{
+			Address: payment.Address,
+			Amount:  originalTransaction.Tx.Outputs[i].Value,
+		}
+		sentValue += mergePayments[i].Amount
 	}
 
 	totalValue := uint64(0)
-	sentValue := originalTransaction.Tx.Outputs[0].Value
 	utxos := make([]*libkobrawallet.UTXO, len(splitTransactions))
 	for i, splitTransaction := range splitTransactions {
 		output := splitTransaction.Tx.Outputs[0]
@@ -71,42 +94,39 @@ func (s *server) mergeTransaction(
 			DerivationPath: s.walletAddressPath(changeWalletAddress),
 		}
 		totalValue += output.Value
-		totalValue -= feePerInput
 	}
 
-	if totalValue < sentValue {
+	for {
+		mergeTransactionBytes, requiredFee, err := s.createUnsignedTransactionPayingFeeRate(mergePayments, changeAddress,
+			false, utxos, feeRate, 0)
+		if err != nil {
+			return nil, err
+		}
+		if mergeTransactionBytes != nil {
+			return serialization.DeserializePartiallySignedTransaction(mergeTransactionBytes)
+		}
+
 		// sometimes the fees from compound transactions make the total output higher than what's available from selected
-		// utxos, in such cases - find one more UTXO and use it.
-		additionalUTXOs, totalValueAdded, err := s.moreUTXOsForMergeTransaction(utxos, sentValue-totalValue)
+		// utxos, in such cases - find more UTXOs and use them.
+		additionalUTXOs, totalValueAdded, err := s.moreUTXOsForMergeTransaction(utxos, spentOutpoints,
+			sentValue+requiredFee-totalValue, feePerInputForRate(feeRate), fromAddresses, selection)
 		if err != nil {
 			return nil, err
 		}
 		utxos = append(utxos, additionalUTXOs...)
 		totalValue += totalValueAdded
 	}
-
-	payments := []*libkobrawallet.Payment{{
-		Address: toAddress,
-		Amount:  sentValue,
-	}}
-	if totalValue > sentValue {
-		payments = append(payments, &libkobrawallet.Payment{
-			Address: changeAddress,
-			Amount:  totalValue - sentValue,
-		})
-	}
-
-	mergeTransactionBytes, err := libkobrawallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
-		s.keysFile.MinimumSignatures, payments, utxos)
-	if err != nil {
-		return nil, err
-	}
-
-	return serialization.DeserializePartiallySignedTransaction(mergeTransactionBytes)
 }
 
-func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.PartiallySignedTransaction, toAddress util.Address,
-	changeAddress util.Address, changeWalletAddress *walletAddress) ([]*serialization.PartiallySignedTransaction, error) {
+// maybeSplitAndMergeTransaction splits the given transaction if it's too large, and merges the
+// outputs of the split transactions. spentOutpoints accumulates the outpoints spent by the
+// transactions of all the recursion levels, so that merge transactions never add UTXOs that
+// are already spent by the split transactions of a previous level.
+func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.PartiallySignedTransaction,
+	payments []*libkobrawallet.Payment, changeAddress util.Address, changeWalletAddress *walletAddress,
+	feeRate float64, fromAddresses []*walletAddress, selection *utxoSelection,
+	spentOutpoints map[externalapi.DomainOutpoint]struct{}) (
+	[]*serialization.PartiallySignedTransaction, error) {
 
 	transactionMass, err := s.estimateMassAfterSignatures(transaction)
 	if err != nil {
@@ -117,6 +137,19 @@ func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.Partia
 		return []*serialization.PartiallySignedTransaction{transaction}, nil
 	}
 
+	// The merge transaction has the outputs of this transaction and spends at least two split
+	// transactions, so if they don't fit in a standard transaction, splitting would never end
+	transactionWithoutInputs := transaction.Tx.Clone()
+	transactionWithoutInputs.Inputs = []*externalapi.DomainTransactionInput{}
+	massWithoutInputs := s.txMassCalculator.CalculateTransactionMass(transactionWithoutInputs)
+	massPerInput := (transactionMass - massWithoutInputs) / uint64(len(transaction.Tx.Inputs))
+	if massWithoutInputs+2*massPerInput >= mempool.MaximumStandardTransactionMass {
+		return nil, errors.Errorf("the %d payments don't fit in a single standard transaction, "+
+			"split them into several sends", len(payments))
+	}
+
+	addSpentOutpoints(spentOutpoints, transaction)
+
 	splitCount, inputCountPerSplit, err := s.splitAndInputPerSplitCounts(transaction, transactionMass, changeAddress)
 	if err != nil {
 		return nil, err
@@ -127,19 +160,21 @@ func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.Partia
 		startIndex := i * inputCountPerSplit
 		endIndex := startIndex + inputCountPerSplit
 		var err error
-		splitTransactions[i], err = s.createSplitTransaction(transaction, changeAddress, startIndex, endIndex)
+		splitTransactions[i], err = s.createSplitTransaction(transaction, changeAddress, startIndex, endIndex, feeRate)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if len(splitTransactions) > 1 {
-		mergeTransaction, err := s.mergeTransaction(splitTransactions, transaction, toAddress, changeAddress, changeWalletAddress)
+		mergeTransaction, err := s.mergeTransaction(splitTransactions, transaction, payments, changeAddress,
+			changeWalletAddress, feeRate, fromAddresses, selection, spentOutpoints)
 		if err != nil {
 			return nil, err
 		}
 		// Recursion will be 2-3 iterations deep even in the rarest` cases, so considered safe..
-		splitMergeTransaction, err := s.maybeSplitAndMergeTransaction(mergeTransaction, toAddress, changeAddress, changeWalletAddress)
+		splitMergeTransaction, err := s.maybeSplitAndMergeTransaction(mergeTransaction, payments, changeAddress,
+			changeWalletAddress, feeRate, fromAddresses, selection, spentOutpoints)
 		if err != nil {
 			return nil, err
 		}
@@ -150,6 +185,15 @@ func (s *server) maybeSplitAndMergeTransaction(transaction *serialization.Partia
 	return splitTransactions, nil
 }
 
+// addSpentOutpoints adds the outpoints spent by the given transaction to spentOutpoints
+func addSpentOutpoints(spentOutpoints map[externalapi.DomainOutpoint]struct{},
+	transaction *serialization.PartiallySignedTransaction) {
+
+	for _, input := range transaction.Tx.Inputs {
+		spentOutpoints[input.PreviousOutpoint] = struct{}{}
+	}
+}
+
 // splitAndInputPerSplitCounts calculates the number of splits to create, and the number of inputs to assign per split.
 func (s *server) splitAndInputPerSplitCounts(transaction *serialization.PartiallySignedTransaction, transactionMass uint64,
 	changeAddress util.Address) (splitCount, inputsPerSplitCount int, err error) {
@@ -172,7 +216,7 @@ func (s *server) splitAndInputPerSplitCounts(transaction *serialization.Partiall
 
 	// Create another dummy transaction, this time one similar to the split transactions we wish to generate,
 	// but with 0 inputs, to calculate how much mass for inputs do we have available in the split transactions
-	splitTransactionWithoutInputs, err := s.createSplitTransaction(transaction, changeAddress, 0, 0)
+	splitTransactionWithoutInputs, err := s.createSplitTransactionPaying(nil, changeAddress, 0)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -189,8 +233,12 @@ func (s *server) splitAndInputPerSplitCounts(transaction *serialization.Partiall
 	return splitCount, inputsPerSplitCount, nil
 }
 
+// createSplitTransaction creates a transaction that spends the inputs of the given transaction
+// in the range [startIndex, endIndex) into a single output to the change address, paying
+// feeRate for its mass
 func (s *server) createSplitTransaction(transaction *serialization.PartiallySignedTransaction,
-	changeAddress util.Address, startIndex int, endIndex int) (*serialization.PartiallySignedTransaction, error) {
+	changeAddress util.Address, startIndex int, endIndex int, feeRate float64) (
+	*serialization.PartiallySignedTransaction, error) {
 
 	selectedUTXOs := make([]*libkobrawallet.UTXO, 0, endIndex-startIndex)
 	totalLeor := uint64(0)
@@ -206,13 +254,34 @@ func (s *server) createSplitTransaction(transaction *serialization.PartiallySign
 		})
 
 		totalLeor += selectedUTXOs[i-startIndex].UTXOEntry.Amount()
-		totalLeor -= feePerInput
 	}
+
+	// The mass doesn't depend on the output amount, so it's estimated before the fee is known
+	splitTransaction, err := s.createSplitTransactionPaying(selectedUTXOs, changeAddress, totalLeor)
+	if err != nil {
+		return nil, err
+	}
+	mass, err := s.estimateMassAfterSignatures(splitTransaction)
+	if err != nil {
+		return nil, err
+	}
+	fee := feeForMass(mass, feeRate)
+	if totalLeor <= fee {
+		return nil, errors.Errorf("Insufficient funds for split transaction: the fee of %f is higher than the "+
+			"%f its inputs hold", float64(fee)/constants.LeorPerPyrin, float64(totalLeor)/constants.LeorPerPyrin)
+	}
+
+	return s.createSplitTransactionPaying(selectedUTXOs, changeAddress, totalLeor-fee)
+}
+
+func (s *server) createSplitTransactionPaying(selectedUTXOs []*libkobrawallet.UTXO, changeAddress util.Address,
+	amount uint64) (*serialization.PartiallySignedTransaction, error) {
+
 	unsignedTransactionBytes, err := libkobrawallet.CreateUnsignedTransaction(s.keysFile.ExtendedPublicKeys,
 		s.keysFile.MinimumSignatures,
 		[]*libkobrawallet.Payment{{
 			Address: changeAddress,
-			Amount:  totalLeor,
+			Amount:  amount,
 		}}, selectedUTXOs)
 	if err != nil {
 		return nil, err
@@ -248,35 +317,68 @@ func (s *server) estimateMassAfterSignatures(transaction *serialization.Partiall
 	return s.txMassCalculator.CalculateTransactionMass(transactionWithSignatures), nil
 }
 
-func (s *server) moreUTXOsForMergeTransaction(alreadySelectedUTXOs []*libkobrawallet.UTXO, requiredAmount uint64) (
+// moreUTXOsForMergeTransaction selects UTXOs of fromAddresses, or of all addresses if none are given, that
+// aren't selected already nor in spentOutpoints, and add at least requiredAmount to the merge transaction after paying for their
+// own inputs at feePerInput. It returns the total value of the selected UTXOs.
+// UTXOs are never added when the UTXOs to spend were given explicitly, or when addresses must not be
+// mixed, since the merge transaction would then spend UTXOs the user didn't choose.
+func (s *server) moreUTXOsForMergeTransaction(alreadySelectedUTXOs []*libkobrawallet.UTXO,
+	spentOutpoints map[externalapi.DomainOutpoint]struct{}, requiredAmount uint64, feePerInput uint64, fromAddresses []*walletAddress, selection *utxoSelection) (
 	additionalUTXOs []*libkobrawallet.UTXO, totalValueAdded uint64, err error) {
 
+	if len(selection.outpoints) > 0 {
+		return nil, 0, errors.Errorf("Insufficient funds for merge transaction: the given UTXOs don't cover " +
+			"the fees of the split and merge transactions")
+	}
+	if selection.strategy == utxoSelectionAvoidAddressMixing {
+		return nil, 0, errors.Errorf("Insufficient funds for merge transaction: covering the fees of the split "+
+			"and merge transactions requires mixing addresses, which %s doesn't allow", utxoSelectionAvoidAddressMixing)
+	}
+
 	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
 	if err != nil {
 		return nil, 0, err
 	}
-	alreadySelectedUTXOsMap := make(map[externalapi.DomainOutpoint]struct{}, len(alreadySelectedUTXOs))
+	utxos := s.spendableUTXOs(fromAddresses, dagInfo.VirtualDAAScore, s.coinbaseMaturity(dagInfo.NetworkName))
+	return s.selectUTXOsForMergeTransaction(utxos, alreadySelectedUTXOs, spentOutpoints, requiredAmount, feePerInput)
+}
+
+// selectUTXOsForMergeTransaction selects the UTXOs out of the given ones that aren't selected already
+// nor in spentOutpoints, and add at least requiredAmount after paying for their own inputs at feePerInput. UTXOs that aren't
+// worth more than feePerInput are skipped. It returns the total value of the selected UTXOs.
+func (s *server) selectUTXOsForMergeTransaction(utxos []*walletUTXO, alreadySelectedUTXOs []*libkobrawallet.UTXO,
+	spentOutpoints map[externalapi.DomainOutpoint]struct{}, requiredAmount uint64, feePerInput uint64) (
+	additionalUTXOs []*libkobrawallet.UTXO, totalValueAdded uint64, err error) {
+
+	alreadySelectedUTXOsMap := make(map[externalapi.DomainOutpoint]struct{}, len(alreadySelectedUTXOs)+len(spentOutpoints))
 	for _, alreadySelectedUTXO := range alreadySelectedUTXOs {
 		alreadySelectedUTXOsMap[*alreadySelectedUTXO.Outpoint] = struct{}{}
 	}
+	// The spent outpoints are spent by the split transactions, and aren't marked as
+	// used until they're broadcast, so they're excluded here to avoid a double spend
+	for outpoint := range spentOutpoints {
+		alreadySelectedUTXOsMap[outpoint] = struct{}{}
+	}
 
-	for _, utxo := range s.utxosSortedByAmount {
+	netValueAdded := uint64(0)
+	for _, utxo := range utxos {
 		if _, ok := alreadySelectedUTXOsMap[*utxo.Outpoint]; ok {
 			continue
 		}
-		if !isUTXOSpendable(utxo, dagInfo.VirtualDAAScore, s.params.BlockCoinbaseMaturity) {
+		if utxo.UTXOEntry.Amount() <= feePerInput {
 			continue
 		}
 		additionalUTXOs = append(additionalUTXOs, &libkobrawallet.UTXO{
 			Outpoint:       utxo.Outpoint,
 			UTXOEntry:      utxo.UTXOEntry,
 			DerivationPath: s.walletAddressPath(utxo.address)})
-		totalValueAdded += utxo.UTXOEntry.Amount() - feePerInput
-		if totalValueAdded >= requiredAmount {
+		totalValueAdded += utxo.UTXOEntry.Amount()
+		netValueAdded += utxo.UTXOEntry.Amount() - feePerInput
+		if netValueAdded >= requiredAmount {
 			break
 		}
 	}
-	if totalValueAdded < requiredAmount {
+	if netValueAdded < requiredAmount {
 		return nil, 0, errors.Errorf("Insufficient funds for merge transaction")
 	}
 
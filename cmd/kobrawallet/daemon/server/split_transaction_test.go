@@ -150,3 +150,131 @@ func testEstimateMassIncreaseForSignaturesSetUp(t *testing.T, consensusConfig *c
 
 	return unsignedTransaction, mnemonics, params, teardown
 }
+
+func TestCreateSplitTransactionFee(t *testing.T) {
+	serverInstance, changeAddress := testFeeServer(t)
+	const feeRate = 3.0
+
+	createTransaction := func(amounts ...uint64) *serialization.PartiallySignedTransaction {
+		transactionBytes, err := libkobrawallet.CreateUnsignedTransaction(serverInstance.keysFile.ExtendedPublicKeys,
+			serverInstance.keysFile.MinimumSignatures, []*libkobrawallet.Payment{{Address: changeAddress, Amount: 1}},
+			testFeeUTXOs(t, serverInstance, amounts...))
+		if err != nil {
+			t.Fatalf("CreateUnsignedTransaction: %s", err)
+		}
+		transaction, err := serialization.DeserializePartiallySignedTransaction(transactionBytes)
+		if err != nil {
+			t.Fatalf("DeserializePartiallySignedTransaction: %s", err)
+		}
+		return transaction
+	}
+
+	transaction := createTransaction(100_000, 200_000, 300_000)
+	splitTransaction, err := serverInstance.createSplitTransaction(transaction, changeAddress, 1, 3, feeRate)
+	if err != nil {
+		t.Fatalf("createSplitTransaction: %s", err)
+	}
+	if len(splitTransaction.Tx.Inputs) != 2 || len(splitTransaction.Tx.Outputs) != 1 {
+		t.Fatalf("expected a split transaction with 2 inputs and a single output, got %d inputs and %d outputs",
+			len(splitTransaction.Tx.Inputs), len(splitTransaction.Tx.Outputs))
+	}
+	fee, mass := testTransactionFee(t, serverInstance, splitTransaction)
+	if fee != feeForMass(mass, feeRate) {
+		t.Fatalf("expected the fee to be %d for a mass of %d, got %d", feeForMass(mass, feeRate), mass, fee)
+	}
+
+	// Inputs that don't cover the fee of their split transaction fail it instead of underflowing its output
+	transaction = createTransaction(1, 2)
+	_, err = serverInstance.createSplitTransaction(transaction, changeAddress, 0, 2, feeRate)
+	if err == nil {
+		t.Fatalf("expected an error for inputs that don't cover the fee")
+	}
+}
+
+func TestMoreUTXOsForMergeTransactionCoinControl(t *testing.T) {
+	serverInstance, _ := testFeeServer(t)
+	fromAddress := &walletAddress{index: 1, keyChain: libkobrawallet.ExternalKeychain}
+	otherAddress := &walletAddress{index: 2, keyChain: libkobrawallet.ExternalKeychain}
+	for i, address := range []*walletAddress{otherAddress, fromAddress, fromAddress} {
+		serverInstance.utxosSortedByAmount = append(serverInstance.utxosSortedByAmount, &walletUTXO{
+			Outpoint:  &externalapi.DomainOutpoint{Index: uint32(i)},
+			UTXOEntry: utxo.NewUTXOEntry(uint64(300-100*i), &externalapi.ScriptPublicKey{}, false, 0),
+			address:   address,
+		})
+	}
+
+	// The merge transaction never spends UTXOs the user didn't choose, so explicit UTXOs
+	// and avoiding address mixing fail it instead of adding UTXOs
+	explicitSelection := &utxoSelection{outpoints: []*externalapi.DomainOutpoint{{Index: 1}}}
+	_, _, err := serverInstance.moreUTXOsForMergeTransaction(nil, nil, 50, 1, nil, explicitSelection)
+	if err == nil {
+		t.Fatalf("expected an error for a merge transaction of explicit UTXOs")
+	}
+	avoidAddressMixingSelection := &utxoSelection{strategy: utxoSelectionAvoidAddressMixing}
+	_, _, err = serverInstance.moreUTXOsForMergeTransaction(nil, nil, 50, 1, nil, avoidAddressMixingSelection)
+	if err == nil {
+		t.Fatalf("expected an error for a merge transaction that avoids address mixing")
+	}
+
+	// With from addresses, only their UTXOs are added, even though a larger one exists
+	utxos := serverInstance.spendableUTXOs([]*walletAddress{fromAddress}, 0, 0)
+	additionalUTXOs, totalValueAdded, err := serverInstance.selectUTXOsForMergeTransaction(utxos, nil, nil, 250, 1)
+	if err != nil {
+		t.Fatalf("selectUTXOsForMergeTransaction: %s", err)
+	}
+	if len(additionalUTXOs) != 2 || totalValueAdded != 300 {
+		t.Fatalf("expected the 2 UTXOs of the from address with a total of 300 to be added, got %d with a total of %d",
+			len(additionalUTXOs), totalValueAdded)
+	}
+	_, _, err = serverInstance.selectUTXOsForMergeTransaction(utxos, nil, nil, 300, 1)
+	if err == nil {
+		t.Fatalf("expected an error when the from address doesn't cover the merge transaction")
+	}
+}
+
+func TestSelectUTXOsForMergeTransactionSkipsSpentOutpoints(t *testing.T) {
+	serverInstance, _ := testFeeServer(t)
+	address := &walletAddress{index: 1, keyChain: libkobrawallet.ExternalKeychain}
+	var utxos []*walletUTXO
+	for i := 0; i < 4; i++ {
+		utxos = append(utxos, &walletUTXO{
+			Outpoint:  &externalapi.DomainOutpoint{Index: uint32(i)},
+			UTXOEntry: utxo.NewUTXOEntry(uint64(400-100*i), &externalapi.ScriptPublicKey{}, false, 0),
+			address:   address,
+		})
+	}
+	transactionSpending := func(outpoints ...*externalapi.DomainOutpoint) *serialization.PartiallySignedTransaction {
+		transaction := &serialization.PartiallySignedTransaction{Tx: &externalapi.DomainTransaction{}}
+		for _, outpoint := range outpoints {
+			transaction.Tx.Inputs = append(transaction.Tx.Inputs,
+				&externalapi.DomainTransactionInput{PreviousOutpoint: *outpoint})
+		}
+		return transaction
+	}
+
+	// The original transaction is split, and so is its merge transaction, which added a UTXO of
+	// its own. The merge transaction of the nested split mustn't spend the UTXOs of either level.
+	splitOutpoint := &externalapi.DomainOutpoint{Index: 100}
+	spentOutpoints := make(map[externalapi.DomainOutpoint]struct{})
+	addSpentOutpoints(spentOutpoints, transactionSpending(utxos[0].Outpoint))
+	addSpentOutpoints(spentOutpoints, transactionSpending(splitOutpoint, utxos[1].Outpoint))
+
+	additionalUTXOs, totalValueAdded, err := serverInstance.selectUTXOsForMergeTransaction(utxos, nil, spentOutpoints, 250, 1)
+	if err != nil {
+		t.Fatalf("selectUTXOsForMergeTransaction: %s", err)
+	}
+	if len(additionalUTXOs) != 2 || totalValueAdded != 300 {
+		t.Fatalf("expected the 2 UTXOs that aren't spent by the split transactions to be added, "+
+			"got %d with a total of %d", len(additionalUTXOs), totalValueAdded)
+	}
+	for _, additionalUTXO := range additionalUTXOs {
+		if _, ok := spentOutpoints[*additionalUTXO.Outpoint]; ok {
+			t.Fatalf("selectUTXOsForMergeTransaction: added the already spent UTXO %s", additionalUTXO.Outpoint)
+		}
+	}
+
+	_, _, err = serverInstance.selectUTXOsForMergeTransaction(utxos, nil, spentOutpoints, 350, 1)
+	if err == nil {
+		t.Fatalf("expected an error when only the spent UTXOs cover the merge transaction")
+	}
+}
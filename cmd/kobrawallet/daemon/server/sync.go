@@ -3,11 +3,13 @@ package server
 import (
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet"
 
 	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/pkg/errors"
 )
 
@@ -23,44 +25,56 @@ func (was walletAddressSet) strings() []string {
 	return addresses
 }
 
+// sync keeps the wallet in sync with the node. The addresses of the wallet are scanned
+// once, up to numIndexesToQueryForRecentAddresses past the last used one, and the UTXO set
+// is then kept up to date by UTXOsChanged notifications of the wallet addresses, which are
+// subscribed to as the wallet derives new addresses. The whole UTXO set is only fetched
+// again after reconnecting to the node, or after the node overrides its pruning point UTXO
+// set. Addresses past the scanned ones, which may be used by another instance of the same
+// wallet, are looked for every farAddressesScanInterval.
 func (s *server) sync() error {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
+	farAddressesTicker := time.NewTicker(farAddressesScanInterval)
+	defer farAddressesTicker.Stop()
 
 	err := s.collectRecentAddresses()
 	if err != nil {
 		return err
 	}
 
-	err = s.refreshExistingUTXOsWithLock()
+	err = s.registerForNotificationsWithLock()
 	if err != nil {
 		return err
 	}
 
-	for range ticker.C {
-		err = s.collectFarAddresses()
-		if err != nil {
-			return err
-		}
+	for {
+		select {
+		case <-ticker.C:
+			err = s.syncUTXOsWithLock()
+			if err != nil {
+				return err
+			}
 
-		err = s.collectRecentAddresses()
-		if err != nil {
-			return err
-		}
+			s.lookUpAcceptingBlocks()
 
-		err = s.refreshExistingUTXOsWithLock()
-		if err != nil {
-			return err
+			err = s.saveHistoryWithLock()
+			if err != nil {
+				log.Errorf("Error saving the transaction history: %s", err)
+			}
+		case <-farAddressesTicker.C:
+			err = s.collectFarAddresses()
+			if err != nil {
+				log.Errorf("Error scanning for addresses past the scanned ones: %s", err)
+			}
 		}
 	}
-
-	return nil
 }
 
-const (
-	numIndexesToQueryForFarAddresses    = 100
-	numIndexesToQueryForRecentAddresses = 1000
-)
+const numIndexesToQueryForRecentAddresses = 1000
+
+// farAddressesScanInterval is how often the addresses past the scanned ones are scanned
+const farAddressesScanInterval = time.Minute
 
 // addressesToQuery scans the addresses in the given range. Because
 // each cosigner in a multisig has its own unique path for generating
@@ -88,20 +102,6 @@ func (s *server) addressesToQuery(start, end uint32) (walletAddressSet, error) {
 	return addresses, nil
 }
 
-// collectFarAddresses collects numIndexesToQueryForFarAddresses addresses
-// from the last point it stopped in the previous call.
-func (s *server) collectFarAddresses() error {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-	err := s.collectAddresses(s.nextSyncStartIndex, s.nextSyncStartIndex+numIndexesToQueryForFarAddresses)
-	if err != nil {
-		return err
-	}
-
-	s.nextSyncStartIndex += numIndexesToQueryForFarAddresses
-	return nil
-}
-
 func (s *server) maxUsedIndexWithLock() uint32 {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -145,6 +145,56 @@ func (s *server) collectRecentAddresses() error {
 	return nil
 }
 
+// collectFarAddresses scans the numIndexesToQueryForRecentAddresses addresses past the scanned
+// ones. If any of them is used, they're all added to the wallet addresses and subscribed to, and
+// the UTXO set is refreshed to pick up their UTXOs. Otherwise they're left out, so that the
+// subscribed addresses don't grow with every scan.
+func (s *server) collectFarAddresses() error {
+	s.lock.RLock()
+	start := s.nextSyncStartIndex
+	s.lock.RUnlock()
+
+	// The addresses are derived and queried without holding the lock, since both take a while
+	addressSet, err := s.addressesToQuery(start, start+numIndexesToQueryForRecentAddresses)
+	if err != nil {
+		return err
+	}
+	getBalancesByAddressesResponse, err := s.rpcClient.GetBalancesByAddresses(addressSet.strings())
+	if err != nil {
+		return err
+	}
+	if !hasBalance(getBalancesByAddressesResponse) {
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err = s.updateAddressesAndLastUsedIndexes(addressSet, getBalancesByAddressesResponse)
+	if err != nil {
+		return err
+	}
+	if start+numIndexesToQueryForRecentAddresses > s.nextSyncStartIndex {
+		s.nextSyncStartIndex = start + numIndexesToQueryForRecentAddresses
+	}
+	log.Infof("Found used addresses past the scanned ones, scanned %d addresses", s.nextSyncStartIndex)
+
+	err = s.subscribeToAddresses(addressSet.strings())
+	if err != nil {
+		return err
+	}
+	return s.refreshUTXOs()
+}
+
+func hasBalance(getBalancesByAddressesResponse *appmessage.GetBalancesByAddressesResponseMessage) bool {
+	for _, entry := range getBalancesByAddressesResponse.Entries {
+		if entry.Balance > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *server) collectAddressesWithLock(start, end uint32) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -171,11 +221,23 @@ func (s *server) collectAddresses(start, end uint32) error {
 	return nil
 }
 
+// updateAddressesAndLastUsedIndexes adds all the scanned addresses to the address set, whatever
+// their balance, so that payments to addresses that were handed out but are empty are still
+// picked up by the UTXOsChanged notifications. The last used indexes are only advanced by
+// addresses with a balance.
 func (s *server) updateAddressesAndLastUsedIndexes(requestedAddressSet walletAddressSet,
 	getBalancesByAddressesResponse *appmessage.GetBalancesByAddressesResponseMessage) error {
 	lastUsedExternalIndex := s.keysFile.LastUsedExternalIndex()
 	lastUsedInternalIndex := s.keysFile.LastUsedInternalIndex()
 
+	for address, walletAddress := range requestedAddressSet {
+		// The UTXOs of the address refer to the walletAddress that's already in the
+		// address set, so it's kept rather than replaced by the rescanned one
+		if _, ok := s.addressSet[address]; !ok {
+			s.addressSet[address] = walletAddress
+		}
+	}
+
 	for _, entry := range getBalancesByAddressesResponse.Entries {
 		walletAddress, ok := requestedAddressSet[entry.Address]
 		if !ok {
@@ -186,8 +248,6 @@ func (s *server) updateAddressesAndLastUsedIndexes(requestedAddressSet walletAdd
 			continue
 		}
 
-		s.addressSet[entry.Address] = walletAddress
-
 		if walletAddress.keyChain == libkobrawallet.ExternalKeychain {
 			if walletAddress.index > lastUsedExternalIndex {
 				lastUsedExternalIndex = walletAddress.index
@@ -208,13 +268,198 @@ func (s *server) updateAddressesAndLastUsedIndexes(requestedAddressSet walletAdd
 	return s.keysFile.SetLastUsedInternalIndex(lastUsedInternalIndex)
 }
 
-func (s *server) refreshExistingUTXOsWithLock() error {
+func (s *server) registerForNotificationsWithLock() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	return s.registerForNotifications()
+}
+
+// registerForNotifications registers for the notifications that keep the UTXO set up to
+// date, and refreshes the whole UTXO set. It's called on startup and after reconnecting
+// to the node, since the registrations are lost along with the connection.
+func (s *server) registerForNotifications() error {
+	s.subscribedAddresses = make(map[string]struct{})
+
+	err := s.rpcClient.RegisterPruningPointUTXOSetNotifications(s.onPruningPointUTXOSetOverride)
+	if err != nil {
+		return err
+	}
+
+	// The addresses are subscribed to before the UTXO set is fetched, so that
+	// no change is missed in between
+	err = s.subscribeToAddresses(s.addressSet.strings())
+	if err != nil {
+		return err
+	}
+
 	return s.refreshUTXOs()
 }
 
+func (s *server) onReconnected() {
+	atomic.StoreUint32(&s.hasReconnected, 1)
+}
+
+func (s *server) onPruningPointUTXOSetOverride() {
+	atomic.StoreUint32(&s.isUTXOSetOverridden, 1)
+}
+
+// syncUTXOsWithLock refreshes the whole UTXO set if the notifications can't be relied on,
+// which is the case after reconnecting to the node or after the node overrode its pruning
+// point UTXO set
+func (s *server) syncUTXOsWithLock() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if atomic.CompareAndSwapUint32(&s.hasReconnected, 1, 0) {
+		log.Infof("Reconnected to the node, refreshing the UTXO set")
+		atomic.StoreUint32(&s.isUTXOSetOverridden, 0)
+		return s.registerForNotifications()
+	}
+	if atomic.CompareAndSwapUint32(&s.isUTXOSetOverridden, 1, 0) {
+		log.Infof("The pruning point UTXO set of the node was overridden, refreshing the UTXO set")
+		return s.refreshUTXOs()
+	}
+	return nil
+}
+
+// addDerivedAddress adds an address that was just derived by the wallet to the address
+// set, and registers for its UTXOsChanged notifications right away. Derived addresses
+// are beyond the scanned ones, so they're counted as scanned as well.
+func (s *server) addDerivedAddress(address string, walletAddr *walletAddress) error {
+	if _, ok := s.addressSet[address]; !ok {
+		s.addressSet[address] = walletAddr
+	}
+	if walletAddr.index >= s.nextSyncStartIndex {
+		s.nextSyncStartIndex = walletAddr.index + 1
+	}
+
+	// Until the notifications are registered for, which happens once the initial
+	// scan is done, there's nothing to add the address to
+	if s.subscribedAddresses == nil {
+		return nil
+	}
+	return s.subscribeToAddresses([]string{address})
+}
+
+// subscribeToAddresses registers for UTXOsChanged notifications of the given addresses,
+// skipping the ones that were already registered for. Registering with no addresses
+// subscribes to the UTXO changes of all addresses, so the notifications handler is only
+// registered along with the first addresses of the wallet.
+func (s *server) subscribeToAddresses(addresses []string) error {
+	var newAddresses []string
+	for _, address := range addresses {
+		if _, ok := s.subscribedAddresses[address]; !ok {
+			newAddresses = append(newAddresses, address)
+		}
+	}
+	if len(newAddresses) == 0 {
+		return nil
+	}
+
+	var err error
+	if len(s.subscribedAddresses) == 0 {
+		err = s.rpcClient.RegisterForUTXOsChangedNotifications(newAddresses, s.onUTXOsChanged)
+	} else {
+		err = s.rpcClient.AddUTXOsChangedNotificationAddresses(newAddresses)
+	}
+	if err != nil {
+		return err
+	}
+	for _, address := range newAddresses {
+		s.subscribedAddresses[address] = struct{}{}
+	}
+	return nil
+}
+
+// onUTXOsChanged is the handler of the UTXOsChanged notifications of the wallet addresses
+func (s *server) onUTXOsChanged(notification *appmessage.UTXOsChangedNotificationMessage) {
+	// The removed UTXO entries carry the DAA scores of the blocks that created them, so the
+	// virtual DAA score is used as the DAA score of the transactions that spent them. It's
+	// fetched before taking the lock, so that the lock isn't held during the RPC call
+	var virtualDAAScore uint64
+	if len(notification.Removed) > 0 {
+		dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+		if err != nil {
+			log.Errorf("Error getting the virtual DAA score for the transaction history: %s", err)
+		} else {
+			virtualDAAScore = dagInfo.VirtualDAAScore
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.applyUTXOsChanged(notification.Added, notification.Removed)
+	if err != nil {
+		log.Errorf("Error applying UTXOs changed notification: %s", err)
+		return
+	}
+
+	s.recordUTXOsChanged(notification.Added, notification.Removed, virtualDAAScore)
+}
+
+// applyUTXOsChanged updates the UTXO set with the given added and removed UTXOs.
+// Changes that are already reflected in the UTXO set, which happens when a notification
+// races with a refresh, are ignored, and so are added UTXOs of addresses that aren't in
+// the wallet.
+func (s *server) applyUTXOsChanged(added, removed []*appmessage.UTXOsByAddressesEntry) error {
+	removedOutpoints := make(map[externalapi.DomainOutpoint]struct{}, len(removed))
+	for _, entry := range removed {
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return err
+		}
+		removedOutpoints[*outpoint] = struct{}{}
+		delete(s.usedOutpoints, *outpoint)
+	}
+
+	utxos := make([]*walletUTXO, 0, len(s.utxosSortedByAmount)+len(added))
+	existingOutpoints := make(map[externalapi.DomainOutpoint]struct{}, len(s.utxosSortedByAmount))
+	for _, utxo := range s.utxosSortedByAmount {
+		if _, ok := removedOutpoints[*utxo.Outpoint]; ok {
+			continue
+		}
+		existingOutpoints[*utxo.Outpoint] = struct{}{}
+		utxos = append(utxos, utxo)
+	}
+
+	for _, entry := range added {
+		outpoint, err := appmessage.RPCOutpointToDomainOutpoint(entry.Outpoint)
+		if err != nil {
+			return err
+		}
+		if _, ok := existingOutpoints[*outpoint]; ok {
+			continue
+		}
+		if _, ok := removedOutpoints[*outpoint]; ok {
+			continue
+		}
+
+		// A notification may carry UTXOs of other addresses, which aren't the wallet's
+		address, ok := s.addressSet[entry.Address]
+		if !ok {
+			continue
+		}
+		utxoEntry, err := appmessage.RPCUTXOEntryToUTXOEntry(entry.UTXOEntry)
+		if err != nil {
+			return err
+		}
+		existingOutpoints[*outpoint] = struct{}{}
+		utxos = append(utxos, &walletUTXO{
+			Outpoint:  outpoint,
+			UTXOEntry: utxoEntry,
+			address:   address,
+		})
+	}
+
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].UTXOEntry.Amount() > utxos[j].UTXOEntry.Amount() })
+
+	s.utxosSortedByAmount = utxos
+
+	return nil
+}
+
 // updateUTXOSet clears the current UTXO set, and re-fills it with the given entries
 func (s *server) updateUTXOSet(entries []*appmessage.UTXOsByAddressesEntry, mempoolEntries []*appmessage.MempoolEntryByAddress) error {
 	utxos := make([]*walletUTXO, 0, len(entries))
@@ -277,6 +522,10 @@ func (s *server) refreshUTXOs() error {
 		return err
 	}
 
+	// UTXOs whose notifications were missed, e.g. while reconnecting, are added
+	// to the history here
+	s.recordUTXOsChanged(getUTXOsByAddressesResponse.Entries, nil, 0)
+
 	return s.updateUTXOSet(getUTXOsByAddressesResponse.Entries, mempoolEntriesByAddresses.Entries)
 }
 
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/keys"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+func testUTXOsByAddressesEntry(address string, transactionIDDigit string, amount uint64) *appmessage.UTXOsByAddressesEntry {
+	return &appmessage.UTXOsByAddressesEntry{
+		Address: address,
+		Outpoint: &appmessage.RPCOutpoint{
+			TransactionID: strings.Repeat(transactionIDDigit, externalapi.DomainHashSize*2),
+			Index:         0,
+		},
+		UTXOEntry: &appmessage.RPCUTXOEntry{
+			Amount:          amount,
+			ScriptPublicKey: &appmessage.RPCScriptPublicKey{Script: "51"},
+		},
+	}
+}
+
+func TestApplyUTXOsChanged(t *testing.T) {
+	serverInstance := &server{
+		addressSet:          walletAddressSet{"address": &walletAddress{}},
+		utxosSortedByAmount: []*walletUTXO{},
+		usedOutpoints:       map[externalapi.DomainOutpoint]time.Time{},
+	}
+
+	added := []*appmessage.UTXOsByAddressesEntry{
+		testUTXOsByAddressesEntry("address", "1", 10),
+		testUTXOsByAddressesEntry("address", "2", 30),
+		testUTXOsByAddressesEntry("address", "3", 20),
+	}
+	err := serverInstance.applyUTXOsChanged(added, nil)
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+
+	// Applying the same change twice, as happens when a notification races with a refresh, is a no-op
+	err = serverInstance.applyUTXOsChanged(added[:1], nil)
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	if len(serverInstance.utxosSortedByAmount) != 3 {
+		t.Fatalf("applyUTXOsChanged: expected 3 UTXOs, got %d", len(serverInstance.utxosSortedByAmount))
+	}
+	for i, expectedAmount := range []uint64{30, 20, 10} {
+		if amount := serverInstance.utxosSortedByAmount[i].UTXOEntry.Amount(); amount != expectedAmount {
+			t.Fatalf("applyUTXOsChanged: UTXO %d has amount %d instead of %d", i, amount, expectedAmount)
+		}
+	}
+
+	err = serverInstance.applyUTXOsChanged(nil, added[1:2])
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	if len(serverInstance.utxosSortedByAmount) != 2 || serverInstance.utxosSortedByAmount[0].UTXOEntry.Amount() != 20 {
+		t.Fatalf("applyUTXOsChanged: the removed UTXO is still in the UTXO set")
+	}
+
+	// UTXOs of addresses that aren't in the wallet are skipped without failing the rest of the change
+	err = serverInstance.applyUTXOsChanged([]*appmessage.UTXOsByAddressesEntry{
+		testUTXOsByAddressesEntry("unknown", "4", 40),
+		testUTXOsByAddressesEntry("address", "5", 50),
+	}, nil)
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	if len(serverInstance.utxosSortedByAmount) != 3 || serverInstance.utxosSortedByAmount[0].UTXOEntry.Amount() != 50 {
+		t.Fatalf("applyUTXOsChanged: expected only the UTXO of the wallet address to be added")
+	}
+}
+
+func TestAddDerivedAddress(t *testing.T) {
+	serverInstance := &server{addressSet: walletAddressSet{}}
+
+	// Before the notifications are registered for, derived addresses are only added to the address set
+	err := serverInstance.addDerivedAddress("address", &walletAddress{index: 5})
+	if err != nil {
+		t.Fatalf("addDerivedAddress: %s", err)
+	}
+	if _, ok := serverInstance.addressSet["address"]; !ok {
+		t.Fatalf("addDerivedAddress: the address wasn't added to the address set")
+	}
+	if serverInstance.nextSyncStartIndex != 6 {
+		t.Fatalf("addDerivedAddress: expected the next sync start index to be 6, got %d",
+			serverInstance.nextSyncStartIndex)
+	}
+
+	// Addresses that are already subscribed to aren't subscribed to again
+	serverInstance.subscribedAddresses = map[string]struct{}{"address": {}}
+	err = serverInstance.addDerivedAddress("address", &walletAddress{index: 1})
+	if err != nil {
+		t.Fatalf("addDerivedAddress: %s", err)
+	}
+	if serverInstance.nextSyncStartIndex != 6 {
+		t.Fatalf("addDerivedAddress: the next sync start index went back to %d", serverInstance.nextSyncStartIndex)
+	}
+}
+
+func TestUpdateAddressesKeepsExistingWalletAddresses(t *testing.T) {
+	existingAddress := &walletAddress{keyChain: libkobrawallet.ExternalKeychain}
+	serverInstance := &server{
+		addressSet: walletAddressSet{"address": existingAddress},
+		keysFile:   &keys.File{},
+	}
+
+	// Rescanning derives a new walletAddress for the same address, which mustn't replace the
+	// one the UTXOs of the address refer to
+	err := serverInstance.updateAddressesAndLastUsedIndexes(
+		walletAddressSet{
+			"address":     &walletAddress{keyChain: libkobrawallet.ExternalKeychain},
+			"new-address": &walletAddress{keyChain: libkobrawallet.InternalKeychain},
+		},
+		&appmessage.GetBalancesByAddressesResponseMessage{Entries: []*appmessage.BalancesByAddressesEntry{
+			{Address: "address", Balance: 1},
+			{Address: "new-address", Balance: 1},
+		}})
+	if err != nil {
+		t.Fatalf("updateAddressesAndLastUsedIndexes: %s", err)
+	}
+	if serverInstance.addressSet["address"] != existingAddress {
+		t.Fatalf("updateAddressesAndLastUsedIndexes: the existing walletAddress was replaced")
+	}
+	if _, ok := serverInstance.addressSet["new-address"]; !ok {
+		t.Fatalf("updateAddressesAndLastUsedIndexes: the new address wasn't added")
+	}
+}
+
+func TestEmptyScannedAddressReceivesPayment(t *testing.T) {
+	serverInstance := &server{
+		addressSet:          walletAddressSet{},
+		keysFile:            &keys.File{},
+		utxosSortedByAmount: []*walletUTXO{},
+		usedOutpoints:       map[externalapi.DomainOutpoint]time.Time{},
+	}
+
+	// An address that was used before but is empty on startup is still added to the
+	// address set, so that it's subscribed to along with the rest of the wallet addresses
+	err := serverInstance.updateAddressesAndLastUsedIndexes(
+		walletAddressSet{
+			"used-address":  &walletAddress{index: 0, keyChain: libkobrawallet.ExternalKeychain},
+			"empty-address": &walletAddress{index: 1, keyChain: libkobrawallet.ExternalKeychain},
+		},
+		&appmessage.GetBalancesByAddressesResponseMessage{Entries: []*appmessage.BalancesByAddressesEntry{
+			{Address: "used-address", Balance: 1},
+			{Address: "empty-address", Balance: 0},
+		}})
+	if err != nil {
+		t.Fatalf("updateAddressesAndLastUsedIndexes: %s", err)
+	}
+	if _, ok := serverInstance.addressSet["empty-address"]; !ok {
+		t.Fatalf("updateAddressesAndLastUsedIndexes: the empty address wasn't added to the address set")
+	}
+
+	// A payment to the empty address after startup is added to the UTXO set
+	err = serverInstance.applyUTXOsChanged([]*appmessage.UTXOsByAddressesEntry{
+		testUTXOsByAddressesEntry("empty-address", "1", 10),
+	}, nil)
+	if err != nil {
+		t.Fatalf("applyUTXOsChanged: %s", err)
+	}
+	if len(serverInstance.utxosSortedByAmount) != 1 ||
+		serverInstance.utxosSortedByAmount[0].address != serverInstance.addressSet["empty-address"] {
+		t.Fatalf("applyUTXOsChanged: the payment to the empty address was missed")
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/txscript"
+)
+
+const (
+	// maxAcceptingBlockLookups is the number of times the accepting block of a transaction
+	// is looked up before giving up on it
+	maxAcceptingBlockLookups = 3
+
+	// maxAcceptingBlockLookupsPerSync bounds the lookups done in a single sync iteration,
+	// so that the first sync of a wallet with a long history doesn't flood the node with requests
+	maxAcceptingBlockLookupsPerSync = 10
+)
+
+func (s *server) GetTransactionHistory(_ context.Context, _ *pb.GetTransactionHistoryRequest) (
+	*pb.GetTransactionHistoryResponse, error) {
+
+	dagInfo, err := s.rpcClient.GetBlockDAGInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entries := make([]*pb.TransactionHistoryEntry, len(s.history.entries))
+	for i, entry := range s.history.entries {
+		entries[i] = &pb.TransactionHistoryEntry{
+			TransactionId:          entry.TransactionID,
+			Timestamp:              entry.Timestamp,
+			Received:               entry.Received,
+			Sent:                   entry.Sent,
+			Fee:                    entry.Fee,
+			CounterpartyAddresses:  entry.CounterpartyAddresses,
+			IsAccepted:             entry.IsAccepted,
+			AcceptingBlockHash:     entry.AcceptingBlockHash,
+			AcceptingBlockDaaScore: entry.AcceptingBlockDAAScore,
+			Confirmations:          entry.confirmations(dagInfo.VirtualDAAScore),
+		}
+	}
+
+	return &pb.GetTransactionHistoryResponse{Entries: entries}, nil
+}
+
+// recordOutgoingTransaction adds a transaction that was broadcast by the wallet to the
+// history. knownAmounts holds the amounts of the outpoints the transaction may spend.
+func (s *server) recordOutgoingTransaction(transactionID string, transaction *externalapi.DomainTransaction,
+	knownAmounts map[externalapi.DomainOutpoint]uint64) error {
+
+	entry := &historyEntry{
+		TransactionID:  transactionID,
+		Timestamp:      time.Now().UnixMilli(),
+		SpentOutpoints: make([]string, len(transaction.Inputs)),
+	}
+
+	var inputsAmount uint64
+	areAllInputAmountsKnown := true
+	for i, input := range transaction.Inputs {
+		outpoint := input.PreviousOutpoint
+		entry.SpentOutpoints[i] = historyOutpointKey(outpoint.TransactionID.String(), outpoint.Index)
+		amount, ok := knownAmounts[outpoint]
+		if !ok {
+			areAllInputAmountsKnown = false
+			continue
+		}
+		inputsAmount += amount
+	}
+
+	var outputsAmount uint64
+	for _, output := range transaction.Outputs {
+		outputsAmount += output.Value
+		_, address, err := txscript.ExtractScriptPubKeyAddress(output.ScriptPublicKey, s.params)
+		if err != nil {
+			return err
+		}
+		if address != nil {
+			if _, ok := s.addressSet[address.String()]; ok {
+				continue
+			}
+			entry.addCounterpartyAddress(address.String())
+		}
+		entry.Sent += output.Value
+	}
+
+	if areAllInputAmountsKnown && inputsAmount >= outputsAmount {
+		entry.Fee = inputsAmount - outputsAmount
+	}
+
+	if !s.history.recordOutgoing(entry) {
+		return nil
+	}
+	return s.history.save()
+}
+
+// recordUTXOsChanged updates the history with UTXOs of the wallet that were added and
+// removed. Added UTXOs record the transactions that created them, and removed UTXOs mark
+// the outgoing transactions that spent them as accepted at the given virtual DAA score.
+// UTXOs of addresses that aren't in the wallet are ignored. The changes are saved by the
+// next saveHistoryWithLock.
+func (s *server) recordUTXOsChanged(added, removed []*appmessage.UTXOsByAddressesEntry, virtualDAAScore uint64) {
+	changed := false
+	for _, entry := range added {
+		if entry.Outpoint == nil || entry.UTXOEntry == nil {
+			continue
+		}
+		if _, ok := s.addressSet[entry.Address]; !ok {
+			continue
+		}
+		outpoint := historyOutpointKey(entry.Outpoint.TransactionID, entry.Outpoint.Index)
+		if s.history.recordReceivedOutput(entry.Outpoint.TransactionID, outpoint,
+			entry.UTXOEntry.Amount, entry.UTXOEntry.BlockDAAScore) {
+
+			changed = true
+		}
+	}
+
+	for _, entry := range removed {
+		if entry.Outpoint == nil {
+			continue
+		}
+		if _, ok := s.addressSet[entry.Address]; !ok {
+			continue
+		}
+		spendingEntry := s.history.spendingEntry(historyOutpointKey(entry.Outpoint.TransactionID, entry.Outpoint.Index))
+		if spendingEntry != nil && spendingEntry.accept(virtualDAAScore) {
+			changed = true
+		}
+	}
+
+	if changed {
+		s.history.markChanged()
+	}
+}
+
+// saveHistoryWithLock writes the changes to the history that weren't saved yet. It's
+// called periodically by the sync loop, so that notifications don't rewrite the whole
+// history each time
+func (s *server) saveHistoryWithLock() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.history.saveIfChanged()
+}
+
+// acceptingBlockLookup is the result of looking up the accepting block of a transaction
+// in the history, along with the senders and the fee of an incoming transaction
+type acceptingBlockLookup struct {
+	transactionID      string
+	isIncoming         bool
+	acceptingBlockHash string
+	senderAddresses    []string
+	fee                uint64
+	isFeeKnown         bool
+}
+
+// lookUpAcceptingBlocks fills the accepting block hashes of accepted transactions in the
+// history, along with the senders and fees of incoming transactions. This requires the
+// node to run with --txindex, and is skipped for transactions that can't be found.
+// Since looking up the senders of a transaction takes an RPC call per input, the lookups
+// are done without holding the lock, and only their results are applied under it.
+func (s *server) lookUpAcceptingBlocks() {
+	lookups := s.pendingAcceptingBlockLookupsWithLock()
+	if len(lookups) == 0 {
+		return
+	}
+
+	for _, lookup := range lookups {
+		s.lookUpAcceptingBlock(lookup)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	changed := false
+	for _, lookup := range lookups {
+		if lookup.acceptingBlockHash == "" {
+			continue
+		}
+		entry, ok := s.history.entriesByTransactionID[lookup.transactionID]
+		if !ok || entry.AcceptingBlockHash != "" {
+			continue
+		}
+		entry.AcceptingBlockHash = lookup.acceptingBlockHash
+		for _, address := range lookup.senderAddresses {
+			if _, ok := s.addressSet[address]; !ok {
+				entry.addCounterpartyAddress(address)
+			}
+		}
+		if lookup.isFeeKnown {
+			entry.Fee = lookup.fee
+		}
+		changed = true
+	}
+
+	if changed {
+		s.history.markChanged()
+	}
+}
+
+// pendingAcceptingBlockLookupsWithLock returns the lookups to do in this sync iteration,
+// and counts them as attempted
+func (s *server) pendingAcceptingBlockLookupsWithLock() []*acceptingBlockLookup {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var lookups []*acceptingBlockLookup
+	for _, entry := range s.history.entries {
+		if len(lookups) >= maxAcceptingBlockLookupsPerSync {
+			break
+		}
+		if !entry.IsAccepted || entry.AcceptingBlockHash != "" ||
+			s.history.acceptingBlockLookups[entry.TransactionID] >= maxAcceptingBlockLookups {
+			continue
+		}
+		s.history.acceptingBlockLookups[entry.TransactionID]++
+		lookups = append(lookups, &acceptingBlockLookup{
+			transactionID: entry.TransactionID,
+			isIncoming:    !entry.isOutgoing(),
+		})
+	}
+	return lookups
+}
+
+// lookUpAcceptingBlock fills the given lookup from the node
+func (s *server) lookUpAcceptingBlock(lookup *acceptingBlockLookup) {
+	response, err := s.rpcClient.GetTransaction(lookup.transactionID, lookup.isIncoming)
+	if err != nil {
+		log.Debugf("Could not look up the accepting block of transaction %s: %s", lookup.transactionID, err)
+		return
+	}
+	if response.AcceptingBlockHash == "" {
+		return
+	}
+	lookup.acceptingBlockHash = response.AcceptingBlockHash
+	if lookup.isIncoming && response.Transaction != nil {
+		s.lookUpIncomingTransactionDetails(lookup, response.Transaction)
+	}
+}
+
+// lookUpIncomingTransactionDetails sets the sender addresses and the fee of an incoming
+// transaction by looking up the outputs its inputs spend
+func (s *server) lookUpIncomingTransactionDetails(lookup *acceptingBlockLookup, transaction *appmessage.RPCTransaction) {
+	var inputsAmount uint64
+	areAllInputsFound := true
+	for _, input := range transaction.Inputs {
+		previousOutpoint := input.PreviousOutpoint
+		response, err := s.rpcClient.GetTransaction(previousOutpoint.TransactionID, true)
+		if err != nil || response.Transaction == nil ||
+			int(previousOutpoint.Index) >= len(response.Transaction.Outputs) {
+
+			areAllInputsFound = false
+			continue
+		}
+		output := response.Transaction.Outputs[previousOutpoint.Index]
+		inputsAmount += output.Amount
+		if output.VerboseData == nil || output.VerboseData.ScriptPublicKeyAddress == "" {
+			continue
+		}
+		lookup.senderAddresses = append(lookup.senderAddresses, output.VerboseData.ScriptPublicKeyAddress)
+	}
+
+	var outputsAmount uint64
+	for _, output := range transaction.Outputs {
+		outputsAmount += output.Amount
+	}
+	if len(transaction.Inputs) > 0 && areAllInputsFound && inputsAmount >= outputsAmount {
+		lookup.fee = inputsAmount - outputsAmount
+		lookup.isFeeKnown = true
+	}
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+// UTXO selection strategies
+const (
+	// utxoSelectionLargestFirst spends the largest UTXOs first, which keeps transactions small
+	utxoSelectionLargestFirst = "largest-first"
+
+	// utxoSelectionSmallestFirst spends the smallest UTXOs first, which consolidates the wallet UTXOs
+	utxoSelectionSmallestFirst = "smallest-first"
+
+	// utxoSelectionBranchAndBound looks for UTXOs that pay the amount without a change output,
+	// and falls back to largest-first if there are none
+	utxoSelectionBranchAndBound = "branch-and-bound"
+
+	// utxoSelectionAvoidAddressMixing only spends UTXOs of a single address, so that the
+	// transaction doesn't link the addresses of the wallet together
+	utxoSelectionAvoidAddressMixing = "avoid-address-mixing"
+)
+
+// maxBranchAndBoundTries bounds the search of utxoSelectionBranchAndBound
+const maxBranchAndBoundTries = 100000
+
+// usedOutpointLockDuration is for how long the UTXOs spent by a broadcast transaction aren't
+// selected again, unless they're removed from the UTXO set earlier
+const usedOutpointLockDuration = time.Minute
+
+// utxoSelection is how the UTXOs a transaction spends are selected: either by a strategy,
+// or explicitly by their outpoints
+type utxoSelection struct {
+	strategy  string
+	outpoints []*externalapi.DomainOutpoint
+}
+
+// requestedUTXOSelection returns the UTXO selection of a request
+func requestedUTXOSelection(strategy string, outpoints []*pb.Outpoint, fromAddresses []string, isSendAll bool) (
+	*utxoSelection, error) {
+
+	switch strategy {
+	case "", utxoSelectionLargestFirst, utxoSelectionSmallestFirst, utxoSelectionBranchAndBound:
+	case utxoSelectionAvoidAddressMixing:
+		if isSendAll {
+			return nil, errors.Errorf("can't send all the funds with %s, use from addresses instead",
+				utxoSelectionAvoidAddressMixing)
+		}
+	default:
+		return nil, errors.Errorf("unknown UTXO selection %s, expected one of %s, %s, %s or %s", strategy,
+			utxoSelectionLargestFirst, utxoSelectionSmallestFirst, utxoSelectionBranchAndBound,
+			utxoSelectionAvoidAddressMixing)
+	}
+
+	if len(outpoints) == 0 {
+		return &utxoSelection{strategy: strategy}, nil
+	}
+	if strategy != "" || len(fromAddresses) > 0 {
+		return nil, errors.Errorf("explicit UTXOs can't be combined with a UTXO selection or from addresses")
+	}
+
+	selection := &utxoSelection{outpoints: make([]*externalapi.DomainOutpoint, len(outpoints))}
+	seen := make(map[externalapi.DomainOutpoint]struct{}, len(outpoints))
+	for i, outpoint := range outpoints {
+		transactionID, err := externalapi.NewDomainTransactionIDFromString(outpoint.TransactionId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid transaction ID %s", outpoint.TransactionId)
+		}
+		domainOutpoint := externalapi.NewDomainOutpoint(transactionID, outpoint.Index)
+		if _, ok := seen[*domainOutpoint]; ok {
+			return nil, errors.Errorf("UTXO %s:%d is given more than once", outpoint.TransactionId, outpoint.Index)
+		}
+		seen[*domainOutpoint] = struct{}{}
+		selection.outpoints[i] = domainOutpoint
+	}
+	return selection, nil
+}
+
+// maxChangeAddedToFee returns the change below which no change output is created, and the
+// change is paid as part of the fee instead
+func (u *utxoSelection) maxChangeAddedToFee(feePerInput uint64) uint64 {
+	if u.strategy != utxoSelectionBranchAndBound {
+		return 0
+	}
+	// Spending a change output later costs about as much as an input does
+	return feePerInput
+}
+
+// coinbaseMaturity returns the DAA score difference after which coinbase UTXOs can be spent
+func (s *server) coinbaseMaturity(networkName string) uint64 {
+	if networkName == "kobra-testnet" {
+		return 1000
+	}
+	return s.params.BlockCoinbaseMaturity
+}
+
+// isOutpointLocked returns whether the given outpoint is spent by a transaction that was
+// broadcast recently, or that was in the mempool when transactions were last created
+func (s *server) isOutpointLocked(outpoint *externalapi.DomainOutpoint) bool {
+	broadcastTime, ok := s.usedOutpoints[*outpoint]
+	return ok && time.Since(broadcastTime) <= usedOutpointLockDuration
+}
+
+// lockMempoolSpentOutpoints locks the outpoints that are spent by transactions in the node's
+// mempool, whether they were broadcast by this daemon or by another client with the same keys.
+// It's called whenever transactions are created, so the outpoints of transactions that wait in
+// the mempool for longer than usedOutpointLockDuration stay locked, while the ones of transactions
+// that were dropped from the mempool expire.
+func (s *server) lockMempoolSpentOutpoints() error {
+	// The transaction pool is only included when it isn't filtered out
+	mempoolEntriesByAddresses, err := s.rpcClient.GetMempoolEntriesByAddresses(s.addressSet.strings(), true, false)
+	if err != nil {
+		return err
+	}
+	return s.lockOutpointsSpentByMempoolEntries(mempoolEntriesByAddresses.Entries, time.Now())
+}
+
+func (s *server) lockOutpointsSpentByMempoolEntries(mempoolEntries []*appmessage.MempoolEntryByAddress,
+	lockTime time.Time) error {
+
+	for _, entriesByAddress := range mempoolEntries {
+		for _, entry := range entriesByAddress.Sending {
+			for _, input := range entry.Transaction.Inputs {
+				outpoint, err := appmessage.RPCOutpointToDomainOutpoint(input.PreviousOutpoint)
+				if err != nil {
+					return err
+				}
+				s.usedOutpoints[*outpoint] = lockTime
+			}
+		}
+	}
+	return nil
+}
+
+// spendableUTXOs returns the wallet UTXOs of the given addresses, or of all addresses if none
+// are given, which can be spent, ordered by descending amount
+func (s *server) spendableUTXOs(fromAddresses []*walletAddress, virtualDAAScore uint64,
+	coinbaseMaturity uint64) []*walletUTXO {
+
+	var utxos []*walletUTXO
+	for _, utxo := range s.utxosSortedByAmount {
+		if (fromAddresses != nil && !slices.Contains(fromAddresses, utxo.address)) ||
+			!isUTXOSpendable(utxo, virtualDAAScore, coinbaseMaturity) {
+			continue
+		}
+
+		if _, ok := s.usedOutpoints[*utxo.Outpoint]; ok {
+			if s.isOutpointLocked(utxo.Outpoint) {
+				continue
+			}
+			delete(s.usedOutpoints, *utxo.Outpoint)
+		}
+
+		utxos = append(utxos, utxo)
+	}
+	return utxos
+}
+
+// explicitUTXOs returns the wallet UTXOs of the given outpoints, and fails if any of them
+// can't be spent
+func (s *server) explicitUTXOs(outpoints []*externalapi.DomainOutpoint, virtualDAAScore uint64,
+	coinbaseMaturity uint64) ([]*walletUTXO, error) {
+
+	utxosByOutpoint := make(map[externalapi.DomainOutpoint]*walletUTXO, len(s.utxosSortedByAmount))
+	for _, utxo := range s.utxosSortedByAmount {
+		utxosByOutpoint[*utxo.Outpoint] = utxo
+	}
+
+	utxos := make([]*walletUTXO, len(outpoints))
+	for i, outpoint := range outpoints {
+		utxo, ok := utxosByOutpoint[*outpoint]
+		if !ok {
+			return nil, errors.Errorf("UTXO %s isn't a UTXO of the wallet",
+				historyOutpointKey(outpoint.TransactionID.String(), outpoint.Index))
+		}
+		if !isUTXOSpendable(utxo, virtualDAAScore, coinbaseMaturity) {
+			return nil, errors.Errorf("UTXO %s is an immature coinbase UTXO",
+				historyOutpointKey(outpoint.TransactionID.String(), outpoint.Index))
+		}
+		if s.isOutpointLocked(outpoint) {
+			return nil, errors.Errorf("UTXO %s is spent by a recently broadcast transaction",
+				historyOutpointKey(outpoint.TransactionID.String(), outpoint.Index))
+		}
+		utxos[i] = utxo
+	}
+	return utxos, nil
+}
+
+// selectUTXOsInOrder selects UTXOs in the given order until they cover spendAmount along
+// with feePerInput for each of them. If they can't, all of them are returned.
+func selectUTXOsInOrder(utxos []*walletUTXO, spendAmount uint64, feePerInput uint64) []*walletUTXO {
+	totalValue := uint64(0)
+	for i, utxo := range utxos {
+		totalValue += utxo.UTXOEntry.Amount()
+		if totalValue >= spendAmount+feePerInput*uint64(i+1) {
+			return utxos[:i+1]
+		}
+	}
+	return utxos
+}
+
+// selectUTXOsBranchAndBound searches for UTXOs whose amounts, less feePerInput for each,
+// add up to between spendAmount and spendAmount+maxExcess, so that no change output is
+// needed. Of the selections it finds, the one with the least excess is returned, or nil
+// if there's none. The given UTXOs must be ordered by descending amount.
+func selectUTXOsBranchAndBound(utxos []*walletUTXO, spendAmount uint64, feePerInput uint64,
+	maxExcess uint64) []*walletUTXO {
+
+	var candidates []*walletUTXO
+	var effectiveValues []uint64
+	for _, utxo := range utxos {
+		if utxo.UTXOEntry.Amount() <= feePerInput {
+			continue
+		}
+		candidates = append(candidates, utxo)
+		effectiveValues = append(effectiveValues, utxo.UTXOEntry.Amount()-feePerInput)
+	}
+
+	// remainingValues[i] is the total effective value of the candidates from i onwards,
+	// used to prune branches that can't reach spendAmount
+	remainingValues := make([]uint64, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remainingValues[i] = remainingValues[i+1] + effectiveValues[i]
+	}
+
+	var best []int
+	bestExcess := maxExcess + 1
+	tries := 0
+	var selected []int
+	var search func(i int, totalValue uint64)
+	search = func(i int, totalValue uint64) {
+		if tries >= maxBranchAndBoundTries || bestExcess == 0 {
+			return
+		}
+		tries++
+
+		if totalValue >= spendAmount {
+			// Adding more candidates only increases the excess
+			if excess := totalValue - spendAmount; excess < bestExcess {
+				best = append(best[:0], selected...)
+				bestExcess = excess
+			}
+			return
+		}
+		if i == len(candidates) || totalValue+remainingValues[i] < spendAmount {
+			return
+		}
+
+		if totalValue+effectiveValues[i] <= spendAmount+maxExcess {
+			selected = append(selected, i)
+			search(i+1, totalValue+effectiveValues[i])
+			selected = selected[:len(selected)-1]
+		}
+		search(i+1, totalValue)
+	}
+	search(0, 0)
+
+	if best == nil {
+		return nil
+	}
+	result := make([]*walletUTXO, len(best))
+	for i, candidateIndex := range best {
+		result[i] = candidates[candidateIndex]
+	}
+	return result
+}
+
+// selectUTXOsFromSingleAddress selects UTXOs of a single address that cover spendAmount along
+// with feePerInput for each of them. It prefers the address that needs the fewest UTXOs, and
+// then the one that leaves the least change. It returns nil if no address has enough funds.
+// The given UTXOs must be ordered by descending amount.
+func selectUTXOsFromSingleAddress(utxos []*walletUTXO, spendAmount uint64, feePerInput uint64) []*walletUTXO {
+	var addresses []*walletAddress
+	utxosByAddress := make(map[*walletAddress][]*walletUTXO)
+	for _, utxo := range utxos {
+		if _, ok := utxosByAddress[utxo.address]; !ok {
+			addresses = append(addresses, utxo.address)
+		}
+		utxosByAddress[utxo.address] = append(utxosByAddress[utxo.address], utxo)
+	}
+
+	var best []*walletUTXO
+	var bestTotalValue uint64
+	for _, address := range addresses {
+		selected := selectUTXOsInOrder(utxosByAddress[address], spendAmount, feePerInput)
+		totalValue := uint64(0)
+		for _, utxo := range selected {
+			totalValue += utxo.UTXOEntry.Amount()
+		}
+		if totalValue < spendAmount+feePerInput*uint64(len(selected)) {
+			continue
+		}
+		if best == nil || len(selected) < len(best) ||
+			(len(selected) == len(best) && totalValue < bestTotalValue) {
+
+			best = selected
+			bestTotalValue = totalValue
+		}
+	}
+	return best
+}
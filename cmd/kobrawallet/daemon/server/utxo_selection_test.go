@@ -0,0 +1,200 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/utxo"
+)
+
+// testWalletUTXOs returns UTXOs with the given amounts, ordered by descending amount as
+// they're kept in utxosSortedByAmount, all paying to address
+func testWalletUTXOs(address *walletAddress, amounts ...uint64) []*walletUTXO {
+	utxos := make([]*walletUTXO, len(amounts))
+	for i, amount := range amounts {
+		utxos[i] = &walletUTXO{
+			Outpoint:  &externalapi.DomainOutpoint{Index: uint32(i)},
+			UTXOEntry: utxo.NewUTXOEntry(amount, &externalapi.ScriptPublicKey{}, false, 0),
+			address:   address,
+		}
+	}
+	return utxos
+}
+
+func testUTXOsTotal(utxos []*walletUTXO) uint64 {
+	total := uint64(0)
+	for _, utxo := range utxos {
+		total += utxo.UTXOEntry.Amount()
+	}
+	return total
+}
+
+func TestSelectUTXOsInOrder(t *testing.T) {
+	utxos := testWalletUTXOs(&walletAddress{}, 50, 30, 20, 10)
+
+	selected := selectUTXOsInOrder(utxos, 60, 1)
+	if len(selected) != 2 || testUTXOsTotal(selected) != 80 {
+		t.Fatalf("expected the two largest UTXOs to be selected, got %d with a total of %d",
+			len(selected), testUTXOsTotal(selected))
+	}
+
+	// The fee per input is part of the amount that must be covered
+	selected = selectUTXOsInOrder(utxos, 79, 1)
+	if len(selected) != 3 {
+		t.Fatalf("expected 3 UTXOs to be selected, got %d", len(selected))
+	}
+
+	selected = selectUTXOsInOrder(utxos, 1000, 1)
+	if len(selected) != len(utxos) {
+		t.Fatalf("expected all UTXOs to be returned when they don't cover the amount, got %d", len(selected))
+	}
+}
+
+func TestSelectUTXOsBranchAndBound(t *testing.T) {
+	utxos := testWalletUTXOs(&walletAddress{}, 61, 31, 21, 11)
+
+	// 31+21 less a fee of 1 per input pays exactly 50
+	selected := selectUTXOsBranchAndBound(utxos, 50, 1, 0)
+	if len(selected) != 2 || testUTXOsTotal(selected) != 52 {
+		t.Fatalf("expected the UTXOs of 31 and 21 to be selected, got %d with a total of %d",
+			len(selected), testUTXOsTotal(selected))
+	}
+
+	// The selection with the least excess is preferred: 31+21 leaves an excess of 10 over 40,
+	// while 31+11 leaves none
+	selected = selectUTXOsBranchAndBound(utxos, 40, 1, 10)
+	if len(selected) != 2 || testUTXOsTotal(selected) != 42 {
+		t.Fatalf("expected the UTXOs of 31 and 11 to be selected, got %d with a total of %d",
+			len(selected), testUTXOsTotal(selected))
+	}
+
+	if selected := selectUTXOsBranchAndBound(utxos, 46, 1, 1); selected != nil {
+		t.Fatalf("expected no changeless selection, got %d UTXOs", len(selected))
+	}
+	if selected := selectUTXOsBranchAndBound(utxos, 1000, 1, 10); selected != nil {
+		t.Fatalf("expected no selection when the UTXOs don't cover the amount, got %d UTXOs", len(selected))
+	}
+}
+
+func TestSelectUTXOsFromSingleAddress(t *testing.T) {
+	address1 := &walletAddress{index: 1}
+	address2 := &walletAddress{index: 2}
+	utxos := append(testWalletUTXOs(address1, 40), testWalletUTXOs(address2, 30, 25)...)
+	utxos = append(utxos, testWalletUTXOs(address1, 20)...)
+
+	// Both addresses cover 50 with two UTXOs, and address2 leaves less change
+	selected := selectUTXOsFromSingleAddress(utxos, 50, 1)
+	if len(selected) != 2 || testUTXOsTotal(selected) != 55 {
+		t.Fatalf("expected the UTXOs of address2 to be selected, got %d with a total of %d",
+			len(selected), testUTXOsTotal(selected))
+	}
+	for _, utxo := range selected {
+		if utxo.address != address2 {
+			t.Fatalf("expected all selected UTXOs to belong to a single address")
+		}
+	}
+
+	// address1 covers 35 with a single UTXO
+	selected = selectUTXOsFromSingleAddress(utxos, 35, 1)
+	if len(selected) != 1 || selected[0].address != address1 {
+		t.Fatalf("expected a single UTXO of address1 to be selected, got %d", len(selected))
+	}
+
+	if selected := selectUTXOsFromSingleAddress(utxos, 70, 1); selected != nil {
+		t.Fatalf("expected no selection when no single address covers the amount, got %d UTXOs", len(selected))
+	}
+}
+
+func TestRequestedUTXOSelection(t *testing.T) {
+	transactionID := strings.Repeat("ab", externalapi.DomainHashSize)
+
+	selection, err := requestedUTXOSelection(utxoSelectionSmallestFirst, nil, nil, false)
+	if err != nil {
+		t.Fatalf("requestedUTXOSelection: %s", err)
+	}
+	if selection.strategy != utxoSelectionSmallestFirst {
+		t.Fatalf("expected strategy %s, got %s", utxoSelectionSmallestFirst, selection.strategy)
+	}
+
+	selection, err = requestedUTXOSelection("", []*pb.Outpoint{{TransactionId: transactionID, Index: 1}}, nil, false)
+	if err != nil {
+		t.Fatalf("requestedUTXOSelection: %s", err)
+	}
+	if len(selection.outpoints) != 1 || selection.outpoints[0].TransactionID.String() != transactionID ||
+		selection.outpoints[0].Index != 1 {
+
+		t.Fatalf("unexpected outpoints %v", selection.outpoints)
+	}
+
+	invalidRequests := []struct {
+		name          string
+		strategy      string
+		outpoints     []*pb.Outpoint
+		fromAddresses []string
+		isSendAll     bool
+	}{
+		{name: "unknown strategy", strategy: "random"},
+		{name: "send all without mixing addresses", strategy: utxoSelectionAvoidAddressMixing, isSendAll: true},
+		{name: "invalid transaction ID", outpoints: []*pb.Outpoint{{TransactionId: "invalid"}}},
+		{
+			name:      "duplicate outpoint",
+			outpoints: []*pb.Outpoint{{TransactionId: transactionID}, {TransactionId: transactionID}},
+		},
+		{
+			name:      "outpoints with a strategy",
+			strategy:  utxoSelectionLargestFirst,
+			outpoints: []*pb.Outpoint{{TransactionId: transactionID}},
+		},
+		{
+			name:          "outpoints with from addresses",
+			outpoints:     []*pb.Outpoint{{TransactionId: transactionID}},
+			fromAddresses: []string{"address"},
+		},
+	}
+	for _, test := range invalidRequests {
+		_, err := requestedUTXOSelection(test.strategy, test.outpoints, test.fromAddresses, test.isSendAll)
+		if err == nil {
+			t.Fatalf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestLockOutpointsSpentByMempoolEntries(t *testing.T) {
+	address := &walletAddress{}
+	serverInstance := &server{
+		utxosSortedByAmount: testWalletUTXOs(address, 30, 20, 10),
+		usedOutpoints:       map[externalapi.DomainOutpoint]time.Time{},
+	}
+	spentOutpoint := serverInstance.utxosSortedByAmount[0].Outpoint
+	mempoolEntries := []*appmessage.MempoolEntryByAddress{{
+		Sending: []*appmessage.MempoolEntry{{
+			Transaction: &appmessage.RPCTransaction{
+				Inputs: []*appmessage.RPCTransactionInput{{
+					PreviousOutpoint: &appmessage.RPCOutpoint{
+						TransactionID: spentOutpoint.TransactionID.String(),
+						Index:         spentOutpoint.Index,
+					},
+				}},
+			},
+		}},
+	}}
+
+	// A transaction that waits in the mempool for longer than the lock duration
+	// is locked again once transactions are created
+	serverInstance.usedOutpoints[*spentOutpoint] = time.Now().Add(-2 * usedOutpointLockDuration)
+	err := serverInstance.lockOutpointsSpentByMempoolEntries(mempoolEntries, time.Now())
+	if err != nil {
+		t.Fatalf("lockOutpointsSpentByMempoolEntries: %s", err)
+	}
+	if !serverInstance.isOutpointLocked(spentOutpoint) {
+		t.Fatalf("expected the outpoint spent in the mempool to be locked")
+	}
+	utxos := serverInstance.spendableUTXOs(nil, 0, 0)
+	if len(utxos) != 2 || testUTXOsTotal(utxos) != 30 {
+		t.Fatalf("expected the UTXO spent in the mempool not to be spendable")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/client"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/utils"
+)
+
+func history(conf *historyConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+	response, err := daemonClient.GetTransactionHistory(ctx, &pb.GetTransactionHistoryRequest{})
+	if err != nil {
+		return err
+	}
+
+	if len(response.Entries) == 0 {
+		fmt.Println("No transactions")
+		return nil
+	}
+
+	for _, entry := range response.Entries {
+		timestamp := time.UnixMilli(entry.Timestamp).Format("2006-01-02 15:04:05")
+		fmt.Printf("%s %s\n", timestamp, entry.TransactionId)
+		if entry.Received > 0 {
+			fmt.Printf("\tReceived:     %s KODA\n", utils.FormatKas(entry.Received))
+		}
+		if entry.Sent > 0 {
+			fmt.Printf("\tSent:         %s KODA\n", utils.FormatKas(entry.Sent))
+		}
+		if entry.Fee > 0 {
+			fmt.Printf("\tFee:          %s KODA\n", utils.FormatKas(entry.Fee))
+		}
+		if len(entry.CounterpartyAddresses) > 0 {
+			counterpartyLabel := "From:"
+			if entry.Sent > 0 {
+				counterpartyLabel = "To:"
+			}
+			fmt.Printf("\t%-13s %s\n", counterpartyLabel, strings.Join(entry.CounterpartyAddresses, "\n\t              "))
+		}
+		if !entry.IsAccepted {
+			fmt.Println("\tStatus:       pending")
+			continue
+		}
+		fmt.Printf("\tStatus:       %d confirmations\n", entry.Confirmations)
+		if entry.AcceptingBlockHash != "" {
+			fmt.Printf("\tAccepted in:  %s\n", entry.AcceptingBlockHash)
+		}
+	}
+
+	return nil
+}
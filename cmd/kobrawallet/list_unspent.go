@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/client"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/utils"
+	"github.com/pkg/errors"
+)
+
+func listUnspent(conf *listUnspentConfig) error {
+	daemonClient, tearDown, err := client.Connect(conf.DaemonAddress)
+	if err != nil {
+		return err
+	}
+	defer tearDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), daemonTimeout)
+	defer cancel()
+	response, err := daemonClient.ListUnspent(ctx, &pb.ListUnspentRequest{Addresses: conf.Addresses})
+	if err != nil {
+		return err
+	}
+
+	if len(response.Utxos) == 0 {
+		fmt.Println("No UTXOs")
+		return nil
+	}
+
+	for _, utxo := range response.Utxos {
+		fmt.Printf("%s:%d\n", utxo.Outpoint.TransactionId, utxo.Outpoint.Index)
+		fmt.Printf("\tAddress:        %s\n", utxo.Address)
+		fmt.Printf("\tAmount:         %s KODA\n", utils.FormatKas(utxo.Amount))
+		fmt.Printf("\tConfirmations:  %d\n", utxo.Confirmations)
+		switch {
+		case !utxo.IsMature:
+			fmt.Printf("\tStatus:         immature coinbase, spendable at DAA score %d\n", utxo.MaturityDaaScore)
+		case utxo.IsLocked:
+			fmt.Println("\tStatus:         spent by a recently broadcast transaction")
+		case utxo.IsCoinbase:
+			fmt.Println("\tStatus:         spendable coinbase")
+		default:
+			fmt.Println("\tStatus:         spendable")
+		}
+	}
+
+	return nil
+}
+
+// parseOutpoints parses outpoints given as txid:index
+func parseOutpoints(outpointStrings []string) ([]*pb.Outpoint, error) {
+	outpoints := make([]*pb.Outpoint, len(outpointStrings))
+	for i, outpointString := range outpointStrings {
+		separatorIndex := strings.LastIndex(outpointString, ":")
+		if separatorIndex == -1 {
+			return nil, errors.Errorf("UTXO %s isn't of the form txid:index", outpointString)
+		}
+		index, err := strconv.ParseUint(outpointString[separatorIndex+1:], 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid index in UTXO %s", outpointString)
+		}
+		outpoints[i] = &pb.Outpoint{
+			TransactionId: outpointString[:separatorIndex],
+			Index:         uint32(index),
+		}
+	}
+	return outpoints, nil
+}
@@ -11,6 +11,10 @@ func main() {
 		err = create(config.(*createConfig))
 	case balanceSubCmd:
 		err = balance(config.(*balanceConfig))
+	case historySubCmd:
+		err = history(config.(*historyConfig))
+	case listUnspentSubCmd:
+		err = listUnspent(config.(*listUnspentConfig))
 	case sendSubCmd:
 		err = send(config.(*sendConfig))
 	case createUnsignedTransactionSubCmd:
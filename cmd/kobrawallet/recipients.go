@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
+	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
+	"github.com/pkg/errors"
+)
+
+// toLeor converts an amount in Pyrin, as given on the command line, to leor
+func toLeor(amount float64) uint64 {
+	return uint64(amount * constants.LeorPerPyrin)
+}
+
+// recipientPayments returns the payments given by the repeated '--to' and '--amount'
+// flags, or by the recipients file if one is given
+func recipientPayments(toAddresses []string, amounts []float64, recipientsFile string) ([]*pb.Payment, error) {
+	if recipientsFile != "" {
+		return readRecipientsFile(recipientsFile)
+	}
+
+	payments := make([]*pb.Payment, len(toAddresses))
+	for i, toAddress := range toAddresses {
+		payments[i] = &pb.Payment{
+			Address: toAddress,
+			Amount:  toLeor(amounts[i]),
+		}
+	}
+	return payments, nil
+}
+
+// readRecipientsFile reads payments from a CSV file with an address and an amount in
+// Pyrin on every line. Lines starting with '#' are ignored.
+func readRecipientsFile(path string) ([]*pb.Payment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var payments []*pb.Payment
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading recipients file %s", path)
+		}
+
+		line, _ := reader.FieldPos(0)
+		amount, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid amount in line %d of %s", line, path)
+		}
+		if amount <= 0 {
+			return nil, errors.Errorf("the amount in line %d of %s must be positive", line, path)
+		}
+		payments = append(payments, &pb.Payment{
+			Address: strings.TrimSpace(record[0]),
+			Amount:  toLeor(amount),
+		})
+	}
+
+	if len(payments) == 0 {
+		return nil, errors.Errorf("recipients file %s has no recipients", path)
+	}
+	return payments, nil
+}
+
+// validateRecipientFlags checks that the recipients are given in exactly one way: a single
+// '--to-address', '--to' and '--amount' pairs, or '--recipients-file'
+func validateRecipientFlags(toAddress string, toAddresses []string, amounts []float64, recipientsFile string,
+	sendAmount float64, isSendAll bool) error {
+
+	modes := 0
+	for _, isUsed := range []bool{toAddress != "", len(toAddresses) > 0 || len(amounts) > 0, recipientsFile != ""} {
+		if isUsed {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return errors.New("exactly one of '--to-address', '--to' with '--amount' or '--recipients-file' must be specified")
+	}
+
+	if toAddress != "" {
+		if (!isSendAll && sendAmount == 0) || (isSendAll && sendAmount > 0) {
+			return errors.New("exactly one of '--send-amount' or '--all' must be specified")
+		}
+		return nil
+	}
+
+	if isSendAll || sendAmount != 0 {
+		return errors.New("'--send-amount' and '--send-all' can only be used with '--to-address'")
+	}
+	if len(toAddresses) != len(amounts) {
+		return errors.Errorf("got %d '--to' addresses but %d '--amount' values, every '--to' needs an '--amount'",
+			len(toAddresses), len(amounts))
+	}
+	for i, amount := range amounts {
+		if amount <= 0 {
+			return errors.Errorf("the amount paid to %s must be positive", toAddresses[i])
+		}
+	}
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/kobradag/kobrad/cmd/kobrawallet/daemon/pb"
 	"github.com/kobradag/kobrad/cmd/kobrawallet/keys"
 	"github.com/kobradag/kobrad/cmd/kobrawallet/libkobrawallet"
-	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
+	"github.com/kobradag/kobrad/cmd/kobrawallet/utils"
 	"github.com/pkg/errors"
 )
 
@@ -35,7 +35,17 @@ func send(conf *sendConfig) error {
 
 	var sendAmountLeor uint64
 	if !conf.IsSendAll {
-		sendAmountLeor = uint64(conf.SendAmount * constants.LeorPerPyrin)
+		sendAmountLeor = toLeor(conf.SendAmount)
+	}
+
+	payments, err := recipientPayments(conf.ToAddresses, conf.Amounts, conf.RecipientsFile)
+	if err != nil {
+		return err
+	}
+
+	utxos, err := parseOutpoints(conf.UTXOs)
+	if err != nil {
+		return err
 	}
 
 	createUnsignedTransactionsResponse, err :=
@@ -43,13 +53,21 @@ func send(conf *sendConfig) error {
 			From:                     conf.FromAddresses,
 			Address:                  conf.ToAddress,
 			Amount:                   sendAmountLeor,
+			Payments:                 payments,
 			IsSendAll:                conf.IsSendAll,
 			UseExistingChangeAddress: conf.UseExistingChangeAddress,
+			FeeRate:                  conf.FeeRate,
+			FeePriority:              conf.Priority,
+			UtxoSelection:            conf.UTXOSelection,
+			Utxos:                    utxos,
 		})
 	if err != nil {
 		return err
 	}
 
+	fmt.Printf("Fee: %s KODA (%.2f leor/gram)\n", utils.FormatKas(createUnsignedTransactionsResponse.Fee),
+		createUnsignedTransactionsResponse.FeeRate)
+
 	if len(conf.Password) == 0 {
 		conf.Password = keys.GetPassword("Password:")
 	}
@@ -666,6 +666,15 @@ func (s *consensus) AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPairs []
 	return s.pruningManager.AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPairs)
 }
 
+// ImportedPruningPointUTXOsCursor returns the outpoint of the last UTXO that was imported with
+// AppendImportedPruningPointUTXOs, or nil if there are no imported UTXOs
+func (s *consensus) ImportedPruningPointUTXOsCursor() (*externalapi.DomainOutpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.pruningManager.ImportedPruningPointUTXOsCursor()
+}
+
 func (s *consensus) ValidateAndInsertImportedPruningPoint(newPruningPoint *externalapi.DomainHash) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -7,6 +7,7 @@ import (
 	"github.com/kobradag/kobrad/domain/consensus/utils/lrucache"
 	"github.com/kobradag/kobrad/util/staging"
 	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
 )
 
 var bucketName = []byte("block-headers")
@@ -190,3 +191,59 @@ func (bhs *blockHeaderStore) serializeHeaderCount(count uint64) ([]byte, error)
 	dbBlockHeaderCount := &serialization.DbBlockHeaderCount{Count: count}
 	return proto.Marshal(dbBlockHeaderCount)
 }
+
+type allBlockHeaderHashesIterator struct {
+	cursor   model.DBCursor
+	isClosed bool
+}
+
+func (a *allBlockHeaderHashesIterator) First() bool {
+	if a.isClosed {
+		panic("Tried using a closed AllBlockHeaderHashesIterator")
+	}
+	return a.cursor.First()
+}
+
+func (a *allBlockHeaderHashesIterator) Next() bool {
+	if a.isClosed {
+		panic("Tried using a closed AllBlockHeaderHashesIterator")
+	}
+	return a.cursor.Next()
+}
+
+func (a *allBlockHeaderHashesIterator) Get() (*externalapi.DomainHash, error) {
+	if a.isClosed {
+		return nil, errors.New("Tried using a closed AllBlockHeaderHashesIterator")
+	}
+	key, err := a.cursor.Key()
+	if err != nil {
+		return nil, err
+	}
+
+	blockHashBytes := key.Suffix()
+	return externalapi.NewDomainHashFromByteSlice(blockHashBytes)
+}
+
+func (a *allBlockHeaderHashesIterator) Close() error {
+	if a.isClosed {
+		return errors.New("Tried using a closed AllBlockHeaderHashesIterator")
+	}
+	a.isClosed = true
+	err := a.cursor.Close()
+	if err != nil {
+		return err
+	}
+	a.cursor = nil
+	return nil
+}
+
+// AllBlockHeaderHashesIterator returns an iterator over the hashes of all the block headers in the database.
+// Staged block headers are not included
+func (bhs *blockHeaderStore) AllBlockHeaderHashesIterator(dbContext model.DBReader) (model.BlockIterator, error) {
+	cursor, err := dbContext.Cursor(bhs.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &allBlockHeaderHashesIterator{cursor: cursor}, nil
+}
@@ -10,6 +10,7 @@ import (
 
 var importedPruningPointUTXOsBucketName = []byte("imported-pruning-point-utxos")
 var importedPruningPointMultisetKeyName = []byte("imported-pruning-point-multiset")
+var importedPruningPointCursorKeyName = []byte("imported-pruning-point-cursor")
 
 func (ps *pruningStore) ClearImportedPruningPointUTXOs(dbContext model.DBWriter) error {
 	cursor, err := dbContext.Cursor(ps.importedPruningPointUTXOsBucket)
@@ -28,7 +29,7 @@ func (ps *pruningStore) ClearImportedPruningPointUTXOs(dbContext model.DBWriter)
 			return err
 		}
 	}
-	return nil
+	return dbContext.Delete(ps.importedPruningPointCursorKey)
 }
 
 func (ps *pruningStore) AppendImportedPruningPointUTXOs(dbTx model.DBTransaction,
@@ -49,7 +50,34 @@ func (ps *pruningStore) AppendImportedPruningPointUTXOs(dbTx model.DBTransaction
 		}
 	}
 
-	return nil
+	if len(outpointAndUTXOEntryPairs) == 0 {
+		return nil
+	}
+	// The last appended outpoint is kept along with the UTXOs, so that
+	// an interrupted import could be resumed right after it
+	serializedCursor, err := serializeOutpoint(outpointAndUTXOEntryPairs[len(outpointAndUTXOEntryPairs)-1].Outpoint)
+	if err != nil {
+		return err
+	}
+	return dbTx.Put(ps.importedPruningPointCursorKey, serializedCursor)
+}
+
+func (ps *pruningStore) HasImportedPruningPointUTXO(dbContext model.DBReader,
+	outpoint *externalapi.DomainOutpoint) (bool, error) {
+
+	key, err := ps.importedPruningPointUTXOKey(outpoint)
+	if err != nil {
+		return false, err
+	}
+	return dbContext.Has(key)
+}
+
+func (ps *pruningStore) ImportedPruningPointCursor(dbContext model.DBReader) (*externalapi.DomainOutpoint, error) {
+	serializedCursor, err := dbContext.Get(ps.importedPruningPointCursorKey)
+	if err != nil {
+		return nil, err
+	}
+	return deserializeOutpoint(serializedCursor)
 }
 
 func (ps *pruningStore) ImportedPruningPointUTXOIterator(dbContext model.DBReader) (externalapi.ReadOnlyUTXOSetIterator, error) {
@@ -32,6 +32,7 @@ type pruningStore struct {
 	updatingPruningPointUTXOSetKey  model.DBKey
 	importedPruningPointUTXOsBucket model.DBBucket
 	importedPruningPointMultisetKey model.DBKey
+	importedPruningPointCursorKey   model.DBKey
 	pruningPointByIndexBucket       model.DBBucket
 }
 
@@ -46,6 +47,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Pru
 		importedPruningPointUTXOsBucket: prefixBucket.Bucket(importedPruningPointUTXOsBucketName),
 		updatingPruningPointUTXOSetKey:  prefixBucket.Key(updatingPruningPointUTXOSetKeyName),
 		importedPruningPointMultisetKey: prefixBucket.Key(importedPruningPointMultisetKeyName),
+		importedPruningPointCursorKey:   prefixBucket.Key(importedPruningPointCursorKeyName),
 		pruningPointByIndexBucket:       prefixBucket.Bucket(pruningPointByIndexBucketName),
 	}
 }
@@ -251,6 +253,8 @@ func (ps *pruningStore) PruningPointUTXOs(dbContext model.DBReader,
 		if err != nil {
 			return nil, err
 		}
+		// Seek positions the cursor on fromOutpoint, and the first call to Next below
+		// moves past it, so fromOutpoint itself is not returned
 		seekKey := ps.pruningPointUTXOSetBucket.Key(serializedFromOutpoint)
 		err = cursor.Seek(seekKey)
 		if err != nil {
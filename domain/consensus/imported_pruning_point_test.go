@@ -0,0 +1,125 @@
+package consensus
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/multiset"
+	"github.com/kobradag/kobrad/domain/consensus/utils/utxo"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+	"github.com/kobradag/kobrad/domain/prefixmanager/prefix"
+	"github.com/kobradag/kobrad/infrastructure/db/database/ldb"
+)
+
+func TestResumeImportedPruningPointUTXOs(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestResumeImportedPruningPointUTXOs")
+	if err != nil {
+		t.Fatalf("TempDir: %+v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := ldb.NewLevelDB(tmpDir, 8)
+	if err != nil {
+		t.Fatalf("NewLevelDB: %+v", err)
+	}
+	defer db.Close()
+
+	config := &Config{Params: dagconfig.TestnetParams, SkipAddingGenesis: true}
+	tc, _, err := NewFactory().NewConsensus(config, db, &prefix.Prefix{}, nil)
+	if err != nil {
+		t.Fatalf("NewConsensus: %+v", err)
+	}
+
+	pairs := make([]*externalapi.OutpointAndUTXOEntryPair, 10)
+	for i := range pairs {
+		pairs[i] = &externalapi.OutpointAndUTXOEntryPair{
+			Outpoint: &externalapi.DomainOutpoint{
+				TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{byte(i)}),
+				Index:         uint32(i),
+			},
+			UTXOEntry: utxo.NewUTXOEntry(uint64(i+1), &externalapi.ScriptPublicKey{Script: []byte{byte(i)}}, false, 0),
+		}
+	}
+
+	cursor, err := tc.ImportedPruningPointUTXOsCursor()
+	if err != nil {
+		t.Fatalf("ImportedPruningPointUTXOsCursor: %+v", err)
+	}
+	if cursor != nil {
+		t.Fatalf("expected no cursor before importing any UTXO, got %s", cursor)
+	}
+
+	err = tc.AppendImportedPruningPointUTXOs(pairs[:6])
+	if err != nil {
+		t.Fatalf("AppendImportedPruningPointUTXOs: %+v", err)
+	}
+	cursor, err = tc.ImportedPruningPointUTXOsCursor()
+	if err != nil {
+		t.Fatalf("ImportedPruningPointUTXOsCursor: %+v", err)
+	}
+	if cursor == nil || !cursor.Equal(pairs[5].Outpoint) {
+		t.Fatalf("expected the cursor to be %s, got %s", pairs[5].Outpoint, cursor)
+	}
+
+	// Resuming from a peer that sends some of the UTXOs again must not change the imported multiset
+	err = tc.AppendImportedPruningPointUTXOs(pairs[4:])
+	if err != nil {
+		t.Fatalf("AppendImportedPruningPointUTXOs: %+v", err)
+	}
+	cursor, err = tc.ImportedPruningPointUTXOsCursor()
+	if err != nil {
+		t.Fatalf("ImportedPruningPointUTXOsCursor: %+v", err)
+	}
+	if cursor == nil || !cursor.Equal(pairs[9].Outpoint) {
+		t.Fatalf("expected the cursor to be %s, got %s", pairs[9].Outpoint, cursor)
+	}
+
+	c := tc.(*consensus)
+	importedMultiset, err := c.pruningStore.ImportedPruningPointMultiset(c.databaseContext)
+	if err != nil {
+		t.Fatalf("ImportedPruningPointMultiset: %+v", err)
+	}
+	expectedMultiset := multiset.New()
+	for _, pair := range pairs {
+		serializedUTXO, err := utxo.SerializeUTXO(pair.UTXOEntry, pair.Outpoint)
+		if err != nil {
+			t.Fatalf("SerializeUTXO: %+v", err)
+		}
+		expectedMultiset.Add(serializedUTXO)
+	}
+	if !importedMultiset.Hash().Equal(expectedMultiset.Hash()) {
+		t.Fatalf("the imported multiset doesn't match the imported UTXOs")
+	}
+
+	// A peer resuming from a cursor gets the UTXOs that follow it, excluding the cursor itself
+	err = c.pruningStore.CommitImportedPruningPointUTXOSet(c.databaseContext)
+	if err != nil {
+		t.Fatalf("CommitImportedPruningPointUTXOSet: %+v", err)
+	}
+	pruningPointUTXOs, err := c.pruningStore.PruningPointUTXOs(c.databaseContext, pairs[5].Outpoint, len(pairs))
+	if err != nil {
+		t.Fatalf("PruningPointUTXOs: %+v", err)
+	}
+	if len(pruningPointUTXOs) != len(pairs[6:]) {
+		t.Fatalf("expected %d UTXOs after the cursor, got %d", len(pairs[6:]), len(pruningPointUTXOs))
+	}
+	for i, pair := range pruningPointUTXOs {
+		if !pair.Outpoint.Equal(pairs[6+i].Outpoint) {
+			t.Fatalf("expected UTXO %d after the cursor to be %s, got %s", i, pairs[6+i].Outpoint, pair.Outpoint)
+		}
+	}
+
+	err = tc.ClearImportedPruningPointData()
+	if err != nil {
+		t.Fatalf("ClearImportedPruningPointData: %+v", err)
+	}
+	cursor, err = tc.ImportedPruningPointUTXOsCursor()
+	if err != nil {
+		t.Fatalf("ImportedPruningPointUTXOsCursor: %+v", err)
+	}
+	if cursor != nil {
+		t.Fatalf("expected no cursor after clearing the imported data, got %s", cursor)
+	}
+}
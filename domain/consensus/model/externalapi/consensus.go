@@ -31,6 +31,7 @@ type Consensus interface {
 	PruningPointAndItsAnticone() ([]*DomainHash, error)
 	ClearImportedPruningPointData() error
 	AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPairs []*OutpointAndUTXOEntryPair) error
+	ImportedPruningPointUTXOsCursor() (*DomainOutpoint, error)
 	ValidateAndInsertImportedPruningPoint(newPruningPoint *DomainHash) error
 	GetVirtualSelectedParent() (*DomainHash, error)
 	CreateBlockLocatorFromPruningPoint(highHash *DomainHash, limit uint32) (BlockLocator, error)
@@ -25,6 +25,8 @@ type PruningStore interface {
 	ClearImportedPruningPointUTXOs(dbContext DBWriter) error
 	AppendImportedPruningPointUTXOs(dbTx DBTransaction, outpointAndUTXOEntryPairs []*externalapi.OutpointAndUTXOEntryPair) error
 	ImportedPruningPointUTXOIterator(dbContext DBReader) (externalapi.ReadOnlyUTXOSetIterator, error)
+	HasImportedPruningPointUTXO(dbContext DBReader, outpoint *externalapi.DomainOutpoint) (bool, error)
+	ImportedPruningPointCursor(dbContext DBReader) (*externalapi.DomainOutpoint, error)
 	ClearImportedPruningPointMultiset(dbContext DBWriter) error
 	ImportedPruningPointMultiset(dbContext DBReader) (Multiset, error)
 	UpdateImportedPruningPointMultiset(dbTx DBTransaction, multiset Multiset) error
@@ -946,7 +946,30 @@ func (pm *pruningManager) AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPa
 		}
 		importedMultiset = multiset.New()
 	}
+	// A peer that resumes an interrupted import sends the UTXOs after the last imported one, but a peer
+	// that doesn't support resuming sends the whole UTXO set again. The UTXOs of a chunk are in the same
+	// order as the imported ones, so only a chunk that starts with an already imported UTXO has to be
+	// deduplicated, in order not to add its UTXOs to the multiset twice
+	shouldSkipImported := false
+	if len(outpointAndUTXOEntryPairs) > 0 {
+		shouldSkipImported, err = pm.pruningStore.HasImportedPruningPointUTXO(dbTx, outpointAndUTXOEntryPairs[0].Outpoint)
+		if err != nil {
+			return err
+		}
+	}
+	newOutpointAndUTXOEntryPairs := make([]*externalapi.OutpointAndUTXOEntryPair, 0, len(outpointAndUTXOEntryPairs))
 	for _, outpointAndUTXOEntryPair := range outpointAndUTXOEntryPairs {
+		if shouldSkipImported {
+			isAlreadyImported, err := pm.pruningStore.HasImportedPruningPointUTXO(dbTx, outpointAndUTXOEntryPair.Outpoint)
+			if err != nil {
+				return err
+			}
+			if isAlreadyImported {
+				continue
+			}
+		}
+		newOutpointAndUTXOEntryPairs = append(newOutpointAndUTXOEntryPairs, outpointAndUTXOEntryPair)
+
 		serializedUTXO, err := utxo.SerializeUTXO(outpointAndUTXOEntryPair.UTXOEntry, outpointAndUTXOEntryPair.Outpoint)
 		if err != nil {
 			return err
@@ -958,7 +981,7 @@ func (pm *pruningManager) AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPa
 		return err
 	}
 
-	err = pm.pruningStore.AppendImportedPruningPointUTXOs(dbTx, outpointAndUTXOEntryPairs)
+	err = pm.pruningStore.AppendImportedPruningPointUTXOs(dbTx, newOutpointAndUTXOEntryPairs)
 	if err != nil {
 		return err
 	}
@@ -966,6 +989,17 @@ func (pm *pruningManager) AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPa
 	return dbTx.Commit()
 }
 
+func (pm *pruningManager) ImportedPruningPointUTXOsCursor() (*externalapi.DomainOutpoint, error) {
+	cursor, err := pm.pruningStore.ImportedPruningPointCursor(pm.databaseContext)
+	if err != nil {
+		if database.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cursor, nil
+}
+
 func (pm *pruningManager) UpdatePruningPointIfRequired() error {
 	hadStartedUpdatingPruningPointUTXOSet, err := pm.pruningStore.HadStartedUpdatingPruningPointUTXOSet(pm.databaseContext)
 	if err != nil {
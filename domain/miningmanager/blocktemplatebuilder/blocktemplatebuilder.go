@@ -5,6 +5,7 @@ import (
 	"sort"
 
 	"github.com/kobradag/kobrad/domain/consensus/processes/coinbasemanager"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
 	"github.com/kobradag/kobrad/domain/consensus/utils/merkle"
 	"github.com/kobradag/kobrad/domain/consensus/utils/transactionhelper"
 	"github.com/kobradag/kobrad/domain/consensusreference"
@@ -24,6 +25,9 @@ type candidateTx struct {
 	txValue  float64
 	gasLimit uint64
 
+	// packageFeeRate is only set when selecting transactions by package fee rate
+	packageFeeRate float64
+
 	p     float64
 	start float64
 	end   float64
@@ -42,16 +46,25 @@ type blockTemplateBuilder struct {
 
 // New creates a new blockTemplateBuilder
 func New(consensusReference consensusreference.ConsensusReference, mempool miningmanagerapi.Mempool,
-	blockMaxMass uint64, coinbasePayloadScriptPublicKeyMaxLength uint8) miningmanagerapi.BlockTemplateBuilder {
+	blockMaxMass uint64, coinbasePayloadScriptPublicKeyMaxLength uint8,
+	transactionSelectionMode miningmanagerapi.TransactionSelectionMode) miningmanagerapi.BlockTemplateBuilder {
 	return &blockTemplateBuilder{
 		consensusReference: consensusReference,
 		mempool:            mempool,
-		policy:             policy{BlockMaxMass: blockMaxMass},
+		policy: policy{
+			BlockMaxMass:             blockMaxMass,
+			TransactionSelectionMode: transactionSelectionMode,
+		},
 
 		coinbasePayloadScriptPublicKeyMaxLength: coinbasePayloadScriptPublicKeyMaxLength,
 	}
 }
 
+// BlockMaxMass returns the maximum mass of the transactions in the block templates built by this builder
+func (btb *blockTemplateBuilder) BlockMaxMass() uint64 {
+	return btb.policy.BlockMaxMass
+}
+
 // BuildBlockTemplate creates a block template for a miner to consume
 // BuildBlockTemplate returns a new block template that is ready to be solved
 // using the transactions from the passed transaction source pool and a coinbase
@@ -142,7 +155,22 @@ func (btb *blockTemplateBuilder) BuildBlockTemplate(
 	log.Debugf("Considering %d transactions for inclusion to new block",
 		len(candidateTxs))
 
-	blockTxs := btb.selectTransactions(candidateTxs)
+	var blockTxs selectedTransactions
+	switch btb.policy.TransactionSelectionMode {
+	case miningmanagerapi.TransactionSelectionPackage:
+		packageFeeRates := btb.mempool.BlockCandidatePackageFeeRates()
+		for _, candidateTx := range candidateTxs {
+			packageFeeRate, ok := packageFeeRates[*consensushashing.TransactionID(candidateTx.DomainTransaction)]
+			if !ok {
+				// The transaction was removed from the mempool in the meantime
+				packageFeeRate = float64(candidateTx.Fee) / float64(candidateTx.Mass)
+			}
+			candidateTx.packageFeeRate = packageFeeRate
+		}
+		blockTxs = btb.selectTransactionsByPackageFeeRate(candidateTxs)
+	default:
+		blockTxs = btb.selectTransactions(candidateTxs)
+	}
 	blockTemplate, err := btb.consensusReference.Consensus().BuildBlockTemplate(coinbaseData, blockTxs.selectedTxs)
 
 	invalidTxsErr := ruleerrors.ErrInvalidTransactionsInNewBlock{}
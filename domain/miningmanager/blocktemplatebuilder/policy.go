@@ -4,6 +4,10 @@
 
 package blocktemplatebuilder
 
+import (
+	miningmanagerapi "github.com/kobradag/kobrad/domain/miningmanager/model"
+)
+
 // policy houses the policy (configuration parameters) which is used to control
 // the generation of block templates. See the documentation for
 // NewBlockTemplate for more details on each of these parameters are used.
@@ -11,4 +15,8 @@ type policy struct {
 	// BlockMaxMass is the maximum block mass to be used when generating a
 	// block template.
 	BlockMaxMass uint64
+
+	// TransactionSelectionMode determines how transactions are selected
+	// when generating a block template.
+	TransactionSelectionMode miningmanagerapi.TransactionSelectionMode
 }
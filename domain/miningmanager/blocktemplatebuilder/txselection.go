@@ -152,15 +152,82 @@ func (btb *blockTemplateBuilder) selectTransactions(candidateTxs []*candidateTx)
 		markCandidateTxForDeletion(selectedTx)
 	}
 
+	txsForBlockTemplate.addSortedBySubnetwork(selectedTxs)
+	return txsForBlockTemplate
+}
+
+// selectTransactionsByPackageFeeRate greedily selects the candidate transactions with the
+// highest package fee rates, skipping the ones that don't fit into the block anymore.
+// Candidates with equal package fee rates are ordered by their own fee rate.
+func (btb *blockTemplateBuilder) selectTransactionsByPackageFeeRate(candidateTxs []*candidateTx) selectedTransactions {
+	txsForBlockTemplate := selectedTransactions{
+		selectedTxs: make([]*consensusexternalapi.DomainTransaction, 0, len(candidateTxs)),
+		txMasses:    make([]uint64, 0, len(candidateTxs)),
+		txFees:      make([]uint64, 0, len(candidateTxs)),
+		totalMass:   0,
+		totalFees:   0,
+	}
+	gasUsageMap := make(map[consensusexternalapi.DomainSubnetworkID]uint64)
+
+	sortedCandidateTxs := make([]*candidateTx, len(candidateTxs))
+	copy(sortedCandidateTxs, candidateTxs)
+	sort.SliceStable(sortedCandidateTxs, func(i, j int) bool {
+		if sortedCandidateTxs[i].packageFeeRate != sortedCandidateTxs[j].packageFeeRate {
+			return sortedCandidateTxs[i].packageFeeRate > sortedCandidateTxs[j].packageFeeRate
+		}
+		return sortedCandidateTxs[i].txValue > sortedCandidateTxs[j].txValue
+	})
+
+	selectedTxs := make([]*candidateTx, 0)
+	for _, candidateTx := range sortedCandidateTxs {
+		tx := candidateTx.DomainTransaction
+
+		// Skip transactions that exceed the maximum transaction mass
+		// per block. Also check for overflow.
+		if txsForBlockTemplate.totalMass+candidateTx.Mass < txsForBlockTemplate.totalMass ||
+			txsForBlockTemplate.totalMass+candidateTx.Mass > btb.policy.BlockMaxMass {
+			log.Tracef("Tx %s would exceed the max block mass. "+
+				"As such, skipping it.", consensushashing.TransactionID(tx))
+			continue
+		}
+
+		// Skip transactions that exceed the maximum gas per subnetwork
+		// per block. Also check for overflow.
+		if !subnetworks.IsBuiltInOrNative(tx.SubnetworkID) {
+			gasUsage := gasUsageMap[tx.SubnetworkID]
+			if gasUsage+tx.Gas < gasUsage || gasUsage+tx.Gas > candidateTx.gasLimit {
+				log.Tracef("Tx %s would exceed the gas limit in "+
+					"subnetwork %s. As such, skipping it.",
+					consensushashing.TransactionID(tx), tx.SubnetworkID)
+				continue
+			}
+			gasUsageMap[tx.SubnetworkID] = gasUsage + tx.Gas
+		}
+
+		selectedTxs = append(selectedTxs, candidateTx)
+		txsForBlockTemplate.totalMass += candidateTx.Mass
+		txsForBlockTemplate.totalFees += candidateTx.Fee
+
+		log.Tracef("Adding tx %s (feePerMegaGram %d, packageFeePerMegaGram %d)",
+			consensushashing.TransactionID(tx), candidateTx.Fee*1e6/candidateTx.Mass,
+			uint64(candidateTx.packageFeeRate*1e6))
+	}
+
+	txsForBlockTemplate.addSortedBySubnetwork(selectedTxs)
+	return txsForBlockTemplate
+}
+
+// addSortedBySubnetwork adds the given transactions to selectedTxs, along with
+// their masses and fees, ordered by subnetwork
+func (st *selectedTransactions) addSortedBySubnetwork(selectedTxs []*candidateTx) {
 	sort.Slice(selectedTxs, func(i, j int) bool {
 		return subnetworks.Less(selectedTxs[i].SubnetworkID, selectedTxs[j].SubnetworkID)
 	})
 	for _, selectedTx := range selectedTxs {
-		txsForBlockTemplate.selectedTxs = append(txsForBlockTemplate.selectedTxs, selectedTx.DomainTransaction)
-		txsForBlockTemplate.txMasses = append(txsForBlockTemplate.txMasses, selectedTx.Mass)
-		txsForBlockTemplate.txFees = append(txsForBlockTemplate.txFees, selectedTx.Fee)
+		st.selectedTxs = append(st.selectedTxs, selectedTx.DomainTransaction)
+		st.txMasses = append(st.txMasses, selectedTx.Mass)
+		st.txFees = append(st.txFees, selectedTx.Fee)
 	}
-	return txsForBlockTemplate
 }
 
 func rebalanceCandidates(oldCandidateTxs []*candidateTx, isFirstRun bool) (
@@ -0,0 +1,194 @@
+package blocktemplatebuilder
+
+import (
+	"math/rand"
+	"testing"
+
+	consensusexternalapi "github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/subnetworks"
+	miningmanagerapi "github.com/kobradag/kobrad/domain/miningmanager/model"
+)
+
+func newTestTransaction(lockTime uint64, fee uint64, mass uint64) *consensusexternalapi.DomainTransaction {
+	return &consensusexternalapi.DomainTransaction{
+		Outputs: []*consensusexternalapi.DomainTransactionOutput{
+			{Value: 1, ScriptPublicKey: &consensusexternalapi.ScriptPublicKey{}},
+		},
+		LockTime:     lockTime,
+		SubnetworkID: subnetworks.SubnetworkIDNative,
+		Fee:          fee,
+		Mass:         mass,
+	}
+}
+
+func TestSelectTransactionsByPackageFeeRate(t *testing.T) {
+	btb := &blockTemplateBuilder{policy: policy{BlockMaxMass: 3000}}
+
+	lowFeeParent := newTestTransaction(1, 1000, 1000)
+	standalone := newTestTransaction(2, 3000, 1000)
+	tooHeavy := newTestTransaction(3, 100000, 2500)
+	cheap := newTestTransaction(4, 1000, 1000)
+	candidateTxs := []*candidateTx{
+		{DomainTransaction: lowFeeParent, txValue: btb.calcTxValue(lowFeeParent), packageFeeRate: 10},
+		{DomainTransaction: standalone, txValue: btb.calcTxValue(standalone), packageFeeRate: 3},
+		{DomainTransaction: tooHeavy, txValue: btb.calcTxValue(tooHeavy), packageFeeRate: 40},
+		{DomainTransaction: cheap, txValue: btb.calcTxValue(cheap), packageFeeRate: 1},
+	}
+
+	// tooHeavy has the best package fee rate, and leaves no room for the others
+	selected := btb.selectTransactionsByPackageFeeRate(candidateTxs)
+	if selected.totalMass != 2500 || len(selected.selectedTxs) != 1 || selected.selectedTxs[0] != tooHeavy {
+		t.Fatalf("unexpected selected transactions %v", selected.selectedTxs)
+	}
+
+	// Once tooHeavy doesn't fit, lowFeeParent is selected thanks to its package
+	// even though its own fee rate is as low as cheap's
+	btb.policy.BlockMaxMass = 2000
+	selected = btb.selectTransactionsByPackageFeeRate(candidateTxs)
+	if len(selected.selectedTxs) != 2 {
+		t.Fatalf("expected 2 selected transactions but got %d", len(selected.selectedTxs))
+	}
+	for _, expected := range []*consensusexternalapi.DomainTransaction{lowFeeParent, standalone} {
+		found := false
+		for _, selectedTx := range selected.selectedTxs {
+			if selectedTx == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("transaction with lock time %d wasn't selected", expected.LockTime)
+		}
+	}
+	if selected.totalFees != 4000 {
+		t.Fatalf("expected total fees of 4000 but got %d", selected.totalFees)
+	}
+}
+
+// simulatedTransaction is a mempool transaction along with the mempool transactions it spends
+type simulatedTransaction struct {
+	transaction *consensusexternalapi.DomainTransaction
+	parents     []*simulatedTransaction
+	isIncluded  bool
+}
+
+// generateSimulatedMempool generates a mempool in which a third of the transactions are
+// standalone, and the rest are pairs of a low-fee parent and a high-fee child
+func generateSimulatedMempool(random *rand.Rand, count int) []*simulatedTransaction {
+	transactions := make([]*simulatedTransaction, 0, count)
+	newTransaction := func(feeRate uint64, parents ...*simulatedTransaction) *simulatedTransaction {
+		mass := uint64(2000 + random.Intn(8000))
+		transaction := &simulatedTransaction{
+			transaction: newTestTransaction(uint64(len(transactions)), feeRate*mass, mass),
+			parents:     parents,
+		}
+		transactions = append(transactions, transaction)
+		return transaction
+	}
+
+	for len(transactions) < count {
+		if random.Intn(3) == 0 {
+			newTransaction(uint64(2 + random.Intn(8)))
+			continue
+		}
+		parent := newTransaction(1)
+		newTransaction(uint64(20+random.Intn(10)), parent)
+	}
+	return transactions
+}
+
+// simulatePackageFeeRates returns the package fee rate of every ready transaction,
+// the same way the mempool calculates it
+func simulatePackageFeeRates(transactions []*simulatedTransaction) map[*simulatedTransaction]float64 {
+	packageFeeRates := make(map[*simulatedTransaction]float64)
+	for _, transaction := range transactions {
+		if transaction.isIncluded {
+			continue
+		}
+		packageFee, packageMass := transaction.transaction.Fee, transaction.transaction.Mass
+		var readyAncestors []*simulatedTransaction
+		for _, parent := range transaction.parents {
+			if parent.isIncluded {
+				continue
+			}
+			packageFee += parent.transaction.Fee
+			packageMass += parent.transaction.Mass
+			readyAncestors = append(readyAncestors, parent)
+		}
+		if len(readyAncestors) == 0 {
+			readyAncestors = append(readyAncestors, transaction)
+		}
+		packageFeeRate := float64(packageFee) / float64(packageMass)
+		for _, readyAncestor := range readyAncestors {
+			if packageFeeRate > packageFeeRates[readyAncestor] {
+				packageFeeRates[readyAncestor] = packageFeeRate
+			}
+		}
+	}
+	return packageFeeRates
+}
+
+// simulateBlockTemplates builds blockCount consecutive block templates out of the given
+// transactions, and returns the total fees they collect
+func simulateBlockTemplates(btb *blockTemplateBuilder, transactions []*simulatedTransaction, blockCount int) uint64 {
+	for _, transaction := range transactions {
+		transaction.isIncluded = false
+	}
+
+	totalFees := uint64(0)
+	for i := 0; i < blockCount; i++ {
+		packageFeeRates := simulatePackageFeeRates(transactions)
+		candidateTxs := make([]*candidateTx, 0, len(packageFeeRates))
+		candidateTransactions := make(map[*consensusexternalapi.DomainTransaction]*simulatedTransaction)
+		for transaction, packageFeeRate := range packageFeeRates {
+			candidateTxs = append(candidateTxs, &candidateTx{
+				DomainTransaction: transaction.transaction,
+				txValue:           btb.calcTxValue(transaction.transaction),
+				packageFeeRate:    packageFeeRate,
+			})
+			candidateTransactions[transaction.transaction] = transaction
+		}
+
+		var selected selectedTransactions
+		switch btb.policy.TransactionSelectionMode {
+		case miningmanagerapi.TransactionSelectionPackage:
+			selected = btb.selectTransactionsByPackageFeeRate(candidateTxs)
+		default:
+			selected = btb.selectTransactions(candidateTxs)
+		}
+		for _, selectedTx := range selected.selectedTxs {
+			candidateTransactions[selectedTx].isIncluded = true
+		}
+		totalFees += selected.totalFees
+	}
+	return totalFees
+}
+
+func benchmarkTransactionSelection(b *testing.B, btb *blockTemplateBuilder) {
+	const (
+		transactionCount = 3000
+		blockCount       = 3
+	)
+	// The mempool holds roughly 6 blocks worth of transactions
+	btb.policy.BlockMaxMass = transactionCount * 6000 / 6
+	transactions := generateSimulatedMempool(rand.New(rand.NewSource(0)), transactionCount)
+
+	b.ResetTimer()
+	totalFees := uint64(0)
+	for i := 0; i < b.N; i++ {
+		totalFees += simulateBlockTemplates(btb, transactions, blockCount)
+	}
+	b.ReportMetric(float64(totalFees)/float64(b.N*blockCount), "fees/template")
+}
+
+func BenchmarkProbabilisticTransactionSelection(b *testing.B) {
+	benchmarkTransactionSelection(b, &blockTemplateBuilder{
+		policy: policy{TransactionSelectionMode: miningmanagerapi.TransactionSelectionProbabilistic},
+	})
+}
+
+func BenchmarkPackageTransactionSelection(b *testing.B) {
+	benchmarkTransactionSelection(b, &blockTemplateBuilder{
+		policy: policy{TransactionSelectionMode: miningmanagerapi.TransactionSelectionPackage},
+	})
+}
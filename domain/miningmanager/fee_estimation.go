@@ -0,0 +1,117 @@
+package miningmanager
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	miningmanagermodel "github.com/kobradag/kobrad/domain/miningmanager/model"
+)
+
+const (
+	// normalFeeEstimateTarget and lowFeeEstimateTarget are the inclusion times the
+	// normal and low fee estimates aim for. The priority estimate aims for the next block.
+	normalFeeEstimateTarget = time.Minute
+	lowFeeEstimateTarget    = time.Hour
+
+	// priorityAcceptedFeeRatePercentile and normalAcceptedFeeRatePercentile are the percentiles
+	// of recently accepted fee rates below which the priority and normal estimates don't go
+	priorityAcceptedFeeRatePercentile = 0.75
+	normalAcceptedFeeRatePercentile   = 0.5
+)
+
+// GetFeeEstimate estimates the fee rates (in leor per gram) a new transaction should pay in order to be
+// included in a block within different time frames.
+//
+// The estimate is based on the amount of transaction mass waiting in the mempool with a higher fee rate,
+// assuming that every block takes the transactions with the highest fee rates up to the block template
+// mass limit. Since the mempool may be momentarily empty while other nodes still see competition, the
+// priority and normal estimates never go below the fee rates that were recently accepted into blocks.
+func (mm *miningManager) GetFeeEstimate() (*miningmanagermodel.FeeEstimate, error) {
+	blockMaxMass := mm.blockTemplateBuilder.BlockMaxMass()
+	normalTargetBlocks := mm.blocksInDuration(normalFeeEstimateTarget)
+	lowTargetBlocks := mm.blocksInDuration(lowFeeEstimateTarget)
+
+	statistics := mm.mempool.FeeRateStatistics(lowTargetBlocks * blockMaxMass)
+	estimator := &feeEstimator{
+		statistics:         statistics,
+		blockMaxMass:       blockMaxMass,
+		targetTimePerBlock: mm.targetTimePerBlock,
+	}
+
+	sortedAcceptedFeeRates := make([]float64, len(statistics.RecentlyAcceptedFeeRates))
+	copy(sortedAcceptedFeeRates, statistics.RecentlyAcceptedFeeRates)
+	sort.Float64s(sortedAcceptedFeeRates)
+
+	priorityFeeRate := math.Max(estimator.feeRateForBlocks(1),
+		percentile(sortedAcceptedFeeRates, priorityAcceptedFeeRatePercentile))
+	normalFeeRate := math.Min(priorityFeeRate, math.Max(estimator.feeRateForBlocks(normalTargetBlocks),
+		percentile(sortedAcceptedFeeRates, normalAcceptedFeeRatePercentile)))
+	lowFeeRate := math.Min(normalFeeRate, estimator.feeRateForBlocks(lowTargetBlocks))
+
+	return &miningmanagermodel.FeeEstimate{
+		PriorityBucket: estimator.bucket(priorityFeeRate),
+		NormalBucket:   estimator.bucket(normalFeeRate),
+		LowBucket:      estimator.bucket(lowFeeRate),
+	}, nil
+}
+
+// blocksInDuration returns the number of blocks expected to be created within the given duration
+func (mm *miningManager) blocksInDuration(duration time.Duration) uint64 {
+	blocks := uint64(duration / mm.targetTimePerBlock)
+	if blocks == 0 {
+		return 1
+	}
+	return blocks
+}
+
+type feeEstimator struct {
+	statistics         *miningmanagermodel.FeeRateStatistics
+	blockMaxMass       uint64
+	targetTimePerBlock time.Duration
+}
+
+// feeRateForBlocks returns the fee rate a transaction has to exceed in order to be included
+// within the given number of blocks, or the minimum fee rate if there's room for everything
+func (fe *feeEstimator) feeRateForBlocks(blocks uint64) float64 {
+	availableMass := blocks * fe.blockMaxMass
+	accumulatedMass := uint64(0)
+	for _, pendingTransaction := range fe.statistics.PendingTransactions {
+		accumulatedMass += pendingTransaction.Mass
+		if accumulatedMass > availableMass {
+			return math.Max(pendingTransaction.FeeRate, fe.statistics.MinimumFeeRate)
+		}
+	}
+	return fe.statistics.MinimumFeeRate
+}
+
+// bucket returns a bucket for the given fee rate, along with the time it's expected to take
+// until all the pending transactions paying a higher fee rate are included
+func (fe *feeEstimator) bucket(feeRate float64) miningmanagermodel.FeeRateBucket {
+	massAhead := uint64(0)
+	for _, pendingTransaction := range fe.statistics.PendingTransactions {
+		if pendingTransaction.FeeRate <= feeRate {
+			break
+		}
+		massAhead += pendingTransaction.Mass
+	}
+	blocks := massAhead/fe.blockMaxMass + 1
+
+	return miningmanagermodel.FeeRateBucket{
+		FeeRate:          feeRate,
+		EstimatedSeconds: float64(blocks) * fe.targetTimePerBlock.Seconds(),
+	}
+}
+
+// percentile returns the value at the given percentile of the given sorted values,
+// or 0 if there are none
+func percentile(sortedValues []float64, p float64) float64 {
+	if len(sortedValues) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(p*float64(len(sortedValues)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sortedValues[index]
+}
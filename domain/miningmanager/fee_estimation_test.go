@@ -0,0 +1,78 @@
+package miningmanager
+
+import (
+	"testing"
+	"time"
+
+	miningmanagermodel "github.com/kobradag/kobrad/domain/miningmanager/model"
+)
+
+func TestFeeEstimator(t *testing.T) {
+	estimator := &feeEstimator{
+		statistics: &miningmanagermodel.FeeRateStatistics{
+			PendingTransactions: []miningmanagermodel.TransactionFeeRate{
+				{FeeRate: 10, Mass: 600},
+				{FeeRate: 8, Mass: 600},
+				{FeeRate: 5, Mass: 600},
+				{FeeRate: 3, Mass: 600},
+			},
+			MinimumFeeRate: 1,
+		},
+		blockMaxMass:       1000,
+		targetTimePerBlock: time.Second,
+	}
+
+	tests := []struct {
+		blocks          uint64
+		expectedFeeRate float64
+	}{
+		{blocks: 1, expectedFeeRate: 8},
+		{blocks: 2, expectedFeeRate: 3},
+		{blocks: 3, expectedFeeRate: 1},
+	}
+	for _, test := range tests {
+		feeRate := estimator.feeRateForBlocks(test.blocks)
+		if feeRate != test.expectedFeeRate {
+			t.Errorf("expected fee rate %f for %d blocks but got %f", test.expectedFeeRate, test.blocks, feeRate)
+		}
+	}
+
+	bucketTests := []struct {
+		feeRate                  float64
+		expectedEstimatedSeconds float64
+	}{
+		{feeRate: 11, expectedEstimatedSeconds: 1},
+		{feeRate: 8, expectedEstimatedSeconds: 1},
+		{feeRate: 5, expectedEstimatedSeconds: 2},
+		{feeRate: 1, expectedEstimatedSeconds: 3},
+	}
+	for _, test := range bucketTests {
+		bucket := estimator.bucket(test.feeRate)
+		if bucket.EstimatedSeconds != test.expectedEstimatedSeconds {
+			t.Errorf("expected %f seconds for fee rate %f but got %f",
+				test.expectedEstimatedSeconds, test.feeRate, bucket.EstimatedSeconds)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4}
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{p: 0, expected: 1},
+		{p: 0.5, expected: 2},
+		{p: 0.75, expected: 3},
+		{p: 1, expected: 4},
+	}
+	for _, test := range tests {
+		result := percentile(values, test.p)
+		if result != test.expected {
+			t.Errorf("expected percentile %f to be %f but got %f", test.p, test.expected, result)
+		}
+	}
+	if percentile(nil, 0.5) != 0 {
+		t.Errorf("expected the percentile of no values to be 0")
+	}
+}
@@ -1,13 +1,16 @@
 package mempool
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
+	"github.com/pkg/errors"
 
 	"github.com/kobradag/kobrad/util"
 
 	"github.com/kobradag/kobrad/domain/dagconfig"
+	miningmanagermodel "github.com/kobradag/kobrad/domain/miningmanager/model"
 )
 
 const (
@@ -27,6 +30,10 @@ const (
 	// the mempool and relayed. It is specified in leor per 1kg (or 1000 grams) of transaction mass.
 	defaultMinimumRelayTransactionFee = util.Amount(1000)
 
+	// defaultMaximumReplacedTransactionCount is the maximum number of mempool transactions
+	// (including their redeemers) a single transaction may replace
+	defaultMaximumReplacedTransactionCount = 100
+
 	// Standard transaction version range might be different from what consensus accepts, therefore
 	// we define separate values in mempool.
 	// However, currently there's exactly one transaction version, so mempool accepts the same version
@@ -35,6 +42,47 @@ const (
 	defaultMaximumStandardTransactionVersion = constants.MaxTransactionVersion
 )
 
+// ReplaceByFeePolicy determines which transactions are allowed to replace
+// conflicting mempool transactions by paying a higher fee
+type ReplaceByFeePolicy uint8
+
+const (
+	// ReplaceByFeeDisabled never allows a transaction to replace mempool transactions
+	ReplaceByFeeDisabled ReplaceByFeePolicy = iota
+
+	// ReplaceByFeeOptIn allows replacement only for transactions whose submitter
+	// explicitly asked for it
+	ReplaceByFeeOptIn
+
+	// ReplaceByFeeAll allows replacement for all transactions, including the ones
+	// relayed by peers
+	ReplaceByFeeAll
+)
+
+var replaceByFeePolicyStrings = map[ReplaceByFeePolicy]string{
+	ReplaceByFeeDisabled: "disabled",
+	ReplaceByFeeOptIn:    "optin",
+	ReplaceByFeeAll:      "all",
+}
+
+// String returns the ReplaceByFeePolicy in human-readable form.
+func (policy ReplaceByFeePolicy) String() string {
+	if s, ok := replaceByFeePolicyStrings[policy]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown ReplaceByFeePolicy (%d)", uint8(policy))
+}
+
+// ParseReplaceByFeePolicy returns the ReplaceByFeePolicy represented by the given string
+func ParseReplaceByFeePolicy(policyString string) (ReplaceByFeePolicy, error) {
+	for policy, s := range replaceByFeePolicyStrings {
+		if s == policyString {
+			return policy, nil
+		}
+	}
+	return 0, errors.Errorf("unknown replace-by-fee policy %s", policyString)
+}
+
 // Config represents a mempool configuration
 type Config struct {
 	MaximumTransactionCount               uint64
@@ -50,6 +98,9 @@ type Config struct {
 	MinimumRelayTransactionFee            util.Amount
 	MinimumStandardTransactionVersion     uint16
 	MaximumStandardTransactionVersion     uint16
+	ReplaceByFeePolicy                    ReplaceByFeePolicy
+	MaximumReplacedTransactionCount       uint64
+	TransactionSelectionMode              miningmanagermodel.TransactionSelectionMode
 }
 
 // DefaultConfig returns the default mempool configuration
@@ -70,5 +121,8 @@ func DefaultConfig(dagParams *dagconfig.Params) *Config {
 		MinimumRelayTransactionFee:            defaultMinimumRelayTransactionFee,
 		MinimumStandardTransactionVersion:     defaultMinimumStandardTransactionVersion,
 		MaximumStandardTransactionVersion:     defaultMaximumStandardTransactionVersion,
+		ReplaceByFeePolicy:                    ReplaceByFeeOptIn,
+		MaximumReplacedTransactionCount:       defaultMaximumReplacedTransactionCount,
+		TransactionSelectionMode:              miningmanagermodel.TransactionSelectionProbabilistic,
 	}
 }
@@ -0,0 +1,86 @@
+package mempool
+
+import (
+	"time"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	miningmanagermodel "github.com/kobradag/kobrad/domain/miningmanager/model"
+)
+
+const (
+	// maxRecentlyAcceptedFeeRates is the number of fee rates of recently
+	// accepted transactions that are kept for fee estimation
+	maxRecentlyAcceptedFeeRates = 1000
+
+	// recentlyAcceptedFeeRateMaxAge is the time after which the fee rate of an accepted
+	// transaction is no longer taken into account for fee estimation
+	recentlyAcceptedFeeRateMaxAge = 10 * time.Minute
+)
+
+type acceptedFeeRate struct {
+	feeRate    float64
+	acceptedAt time.Time
+}
+
+// recentlyAcceptedFeeRates is a ring buffer of the fee rates of the
+// latest transactions that were included in a block
+type recentlyAcceptedFeeRates struct {
+	feeRates []acceptedFeeRate
+	next     int
+}
+
+func newRecentlyAcceptedFeeRates() *recentlyAcceptedFeeRates {
+	return &recentlyAcceptedFeeRates{
+		feeRates: make([]acceptedFeeRate, 0, maxRecentlyAcceptedFeeRates),
+	}
+}
+
+func (r *recentlyAcceptedFeeRates) add(feeRate float64, acceptedAt time.Time) {
+	if len(r.feeRates) < maxRecentlyAcceptedFeeRates {
+		r.feeRates = append(r.feeRates, acceptedFeeRate{feeRate: feeRate, acceptedAt: acceptedAt})
+		return
+	}
+	r.feeRates[r.next] = acceptedFeeRate{feeRate: feeRate, acceptedAt: acceptedAt}
+	r.next = (r.next + 1) % maxRecentlyAcceptedFeeRates
+}
+
+// get returns the fee rates that were accepted after the given time
+func (r *recentlyAcceptedFeeRates) get(acceptedAfter time.Time) []float64 {
+	feeRates := make([]float64, 0, len(r.feeRates))
+	for _, feeRate := range r.feeRates {
+		if feeRate.acceptedAt.After(acceptedAfter) {
+			feeRates = append(feeRates, feeRate.feeRate)
+		}
+	}
+	return feeRates
+}
+
+func transactionFeeRate(transaction *externalapi.DomainTransaction) float64 {
+	return float64(transaction.Fee) / float64(transaction.Mass)
+}
+
+// FeeRateStatistics returns a snapshot of the mempool state for the purpose of fee estimation.
+// Pending transactions are returned from the highest fee rate down, until their accumulated mass
+// reaches maxPendingMass.
+func (mp *mempool) FeeRateStatistics(maxPendingMass uint64) *miningmanagermodel.FeeRateStatistics {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	var pendingTransactions []miningmanagermodel.TransactionFeeRate
+	accumulatedMass := uint64(0)
+	orderedTransactions := mp.transactionsPool.transactionsOrderedByFeeRate
+	for i := orderedTransactions.Len() - 1; i >= 0 && accumulatedMass < maxPendingMass; i-- {
+		transaction := orderedTransactions.GetByIndex(i).Transaction()
+		pendingTransactions = append(pendingTransactions, miningmanagermodel.TransactionFeeRate{
+			FeeRate: transactionFeeRate(transaction),
+			Mass:    transaction.Mass,
+		})
+		accumulatedMass += transaction.Mass
+	}
+
+	return &miningmanagermodel.FeeRateStatistics{
+		PendingTransactions:      pendingTransactions,
+		RecentlyAcceptedFeeRates: mp.recentlyAcceptedFeeRates.get(time.Now().Add(-recentlyAcceptedFeeRateMaxAge)),
+		MinimumFeeRate:           float64(mp.config.MinimumRelayTransactionFee) / 1000,
+	}
+}
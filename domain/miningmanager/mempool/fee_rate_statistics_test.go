@@ -0,0 +1,73 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+)
+
+func TestRecentlyAcceptedFeeRates(t *testing.T) {
+	feeRates := newRecentlyAcceptedFeeRates()
+	start := time.Now()
+
+	for i := 0; i < maxRecentlyAcceptedFeeRates+10; i++ {
+		feeRates.add(float64(i), start.Add(time.Duration(i)*time.Second))
+	}
+
+	all := feeRates.get(time.Time{})
+	if len(all) != maxRecentlyAcceptedFeeRates {
+		t.Fatalf("expected %d fee rates but got %d", maxRecentlyAcceptedFeeRates, len(all))
+	}
+	for _, feeRate := range all {
+		if feeRate < 10 {
+			t.Fatalf("fee rate %f should have been overwritten", feeRate)
+		}
+	}
+
+	recent := feeRates.get(start.Add(time.Duration(maxRecentlyAcceptedFeeRates) * time.Second))
+	if len(recent) != 9 {
+		t.Fatalf("expected 9 recent fee rates but got %d", len(recent))
+	}
+}
+
+func TestFeeRateStatistics(t *testing.T) {
+	mp := &mempool{config: &Config{MinimumRelayTransactionFee: 1000}}
+	mp.transactionsPool = newTransactionsPool(mp)
+	mp.recentlyAcceptedFeeRates = newRecentlyAcceptedFeeRates()
+
+	// Fee rates of 1, 2, 3 and 4 leor per gram, each with a mass of 1000 grams
+	for i := uint64(1); i <= 4; i++ {
+		transaction := &externalapi.DomainTransaction{
+			LockTime: i,
+			Fee:      i * 1000,
+			Mass:     1000,
+		}
+		err := mp.transactionsPool.transactionsOrderedByFeeRate.Push(model.NewMempoolTransaction(
+			transaction, model.IDToTransactionMap{}, false, 0))
+		if err != nil {
+			t.Fatalf("Push: %s", err)
+		}
+	}
+	mp.recentlyAcceptedFeeRates.add(5, time.Now())
+
+	statistics := mp.FeeRateStatistics(2500)
+	if statistics.MinimumFeeRate != 1 {
+		t.Fatalf("expected a minimum fee rate of 1 but got %f", statistics.MinimumFeeRate)
+	}
+	expectedFeeRates := []float64{4, 3, 2}
+	if len(statistics.PendingTransactions) != len(expectedFeeRates) {
+		t.Fatalf("expected %d pending transactions but got %d",
+			len(expectedFeeRates), len(statistics.PendingTransactions))
+	}
+	for i, expectedFeeRate := range expectedFeeRates {
+		if statistics.PendingTransactions[i].FeeRate != expectedFeeRate {
+			t.Fatalf("expected pending transaction %d to have fee rate %f but got %f",
+				i, expectedFeeRate, statistics.PendingTransactions[i].FeeRate)
+		}
+	}
+	if len(statistics.RecentlyAcceptedFeeRates) != 1 || statistics.RecentlyAcceptedFeeRates[0] != 5 {
+		t.Fatalf("unexpected recently accepted fee rates %v", statistics.RecentlyAcceptedFeeRates)
+	}
+}
@@ -1,6 +1,8 @@
 package mempool
 
 import (
+	"time"
+
 	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
 	"github.com/kobradag/kobrad/domain/consensus/utils/transactionhelper"
@@ -13,8 +15,14 @@ func (mp *mempool) handleNewBlockTransactions(blockTransactions []*externalapi.D
 	blockTransactions = blockTransactions[transactionhelper.CoinbaseTransactionIndex+1:]
 
 	acceptedOrphans := []*externalapi.DomainTransaction{}
+	now := time.Now()
 	for _, transaction := range blockTransactions {
 		transactionID := consensushashing.TransactionID(transaction)
+		// Only transactions that passed through the mempool have their fee and mass populated
+		if mempoolTransaction, ok := mp.transactionsPool.allTransactions[*transactionID]; ok {
+			mp.recentlyAcceptedFeeRates.add(transactionFeeRate(mempoolTransaction.Transaction()), now)
+		}
+
 		err := mp.removeTransaction(transactionID, false)
 		if err != nil {
 			return nil, err
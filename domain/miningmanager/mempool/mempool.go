@@ -19,9 +19,10 @@ type mempool struct {
 	config             *Config
 	consensusReference consensusreference.ConsensusReference
 
-	mempoolUTXOSet   *mempoolUTXOSet
-	transactionsPool *transactionsPool
-	orphansPool      *orphansPool
+	mempoolUTXOSet           *mempoolUTXOSet
+	transactionsPool         *transactionsPool
+	orphansPool              *orphansPool
+	recentlyAcceptedFeeRates *recentlyAcceptedFeeRates
 }
 
 // New constructs a new mempool
@@ -34,6 +35,7 @@ func New(config *Config, consensusReference consensusreference.ConsensusReferenc
 	mp.mempoolUTXOSet = newMempoolUTXOSet(mp)
 	mp.transactionsPool = newTransactionsPool(mp)
 	mp.orphansPool = newOrphansPool(mp)
+	mp.recentlyAcceptedFeeRates = newRecentlyAcceptedFeeRates()
 
 	return mp
 }
@@ -44,7 +46,20 @@ func (mp *mempool) ValidateAndInsertTransaction(transaction *externalapi.DomainT
 	mp.mtx.Lock()
 	defer mp.mtx.Unlock()
 
-	return mp.validateAndInsertTransaction(transaction, isHighPriority, allowOrphan)
+	allowReplacement := mp.config.ReplaceByFeePolicy == ReplaceByFeeAll
+	acceptedTransactions, _, err = mp.validateAndInsertTransaction(transaction, isHighPriority, allowOrphan, allowReplacement)
+	return acceptedTransactions, err
+}
+
+func (mp *mempool) ValidateAndReplaceTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool,
+	allowOrphan bool) (acceptedTransactions []*externalapi.DomainTransaction,
+	replacedTransactions []*externalapi.DomainTransaction, err error) {
+
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	allowReplacement := mp.config.ReplaceByFeePolicy != ReplaceByFeeDisabled
+	return mp.validateAndInsertTransaction(transaction, isHighPriority, allowOrphan, allowReplacement)
 }
 
 func (mp *mempool) GetTransaction(transactionID *externalapi.DomainTransactionID,
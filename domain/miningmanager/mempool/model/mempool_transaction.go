@@ -57,3 +57,8 @@ func (mt *MempoolTransaction) IsHighPriority() bool {
 func (mt *MempoolTransaction) AddedAtDAAScore() uint64 {
 	return mt.addedAtDAAScore
 }
+
+// SetAddedAtDAAScore sets the virtual DAA score at which this MempoolTransaction was added to the mempool
+func (mt *MempoolTransaction) SetAddedAtDAAScore(addedAtDAAScore uint64) {
+	mt.addedAtDAAScore = addedAtDAAScore
+}
@@ -44,3 +44,8 @@ func (ot *OrphanTransaction) IsHighPriority() bool {
 func (ot *OrphanTransaction) AddedAtDAAScore() uint64 {
 	return ot.addedAtDAAScore
 }
+
+// SetAddedAtDAAScore sets the virtual DAA score at which this OrphanTransaction was added to the mempool
+func (ot *OrphanTransaction) SetAddedAtDAAScore(addedAtDAAScore uint64) {
+	ot.addedAtDAAScore = addedAtDAAScore
+}
@@ -0,0 +1,55 @@
+package mempool
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+// maximumPackageAncestorCount is the maximum number of ancestors in the mempool a transaction
+// may have for its package to be taken into account by BlockCandidatePackageFeeRates
+const maximumPackageAncestorCount = 25
+
+// BlockCandidatePackageFeeRates returns the package fee rate of every transaction that may be included
+// in the next block, i.e. every transaction that has no parents in the mempool.
+//
+// A package is a mempool transaction along with all its ancestors in the mempool, and its fee rate is
+// their total fee divided by their total mass. A block may not contain chained transactions, so every
+// ancestor in a package has to be included in a block before the package's transaction can be. The
+// package fee rate of a block candidate is therefore the highest fee rate of a package it belongs to.
+func (mp *mempool) BlockCandidatePackageFeeRates() map[externalapi.DomainTransactionID]float64 {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	packageFeeRates := make(map[externalapi.DomainTransactionID]float64)
+	raisePackageFeeRate := func(transactionID externalapi.DomainTransactionID, feeRate float64) {
+		if currentFeeRate, ok := packageFeeRates[transactionID]; !ok || feeRate > currentFeeRate {
+			packageFeeRates[transactionID] = feeRate
+		}
+	}
+
+	for transactionID, mempoolTransaction := range mp.transactionsPool.allTransactions {
+		if len(mempoolTransaction.ParentTransactionsInPool()) == 0 {
+			raisePackageFeeRate(transactionID, transactionFeeRate(mempoolTransaction.Transaction()))
+			continue
+		}
+
+		ancestors, ok := mp.transactionsPool.getAncestors(mempoolTransaction, maximumPackageAncestorCount)
+		if !ok {
+			continue
+		}
+		packageFee := mempoolTransaction.Transaction().Fee
+		packageMass := mempoolTransaction.Transaction().Mass
+		for _, ancestor := range ancestors {
+			packageFee += ancestor.Transaction().Fee
+			packageMass += ancestor.Transaction().Mass
+		}
+		packageFeeRate := float64(packageFee) / float64(packageMass)
+
+		for ancestorID, ancestor := range ancestors {
+			if len(ancestor.ParentTransactionsInPool()) == 0 {
+				raisePackageFeeRate(ancestorID, packageFeeRate)
+			}
+		}
+	}
+
+	return packageFeeRates
+}
@@ -0,0 +1,72 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+)
+
+func TestBlockCandidatePackageFeeRates(t *testing.T) {
+	mp := &mempool{config: &Config{}}
+	mp.mempoolUTXOSet = newMempoolUTXOSet(mp)
+	mp.transactionsPool = newTransactionsPool(mp)
+
+	lockTime := uint64(0)
+	addToPool := func(fee uint64, mass uint64, parents ...*model.MempoolTransaction) *model.MempoolTransaction {
+		lockTime++
+		transaction := &externalapi.DomainTransaction{
+			Outputs:  []*externalapi.DomainTransactionOutput{{Value: 1, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+			LockTime: lockTime,
+			Fee:      fee,
+			Mass:     mass,
+		}
+		parentsInPool := model.IDToTransactionMap{}
+		for _, parent := range parents {
+			transaction.Inputs = append(transaction.Inputs, &externalapi.DomainTransactionInput{
+				PreviousOutpoint: externalapi.DomainOutpoint{TransactionID: *parent.TransactionID()},
+			})
+			parentsInPool[*parent.TransactionID()] = parent
+		}
+		mempoolTransaction := model.NewMempoolTransaction(transaction, parentsInPool, false, 0)
+		err := mp.transactionsPool.addMempoolTransaction(mempoolTransaction)
+		if err != nil {
+			t.Fatalf("addMempoolTransaction: %s", err)
+		}
+		return mempoolTransaction
+	}
+
+	// standalone pays 2 leor per gram
+	standalone := addToPool(2000, 1000)
+	// parent pays 1 leor per gram, and its child brings the package fee rate up to 5
+	parent := addToPool(1000, 1000)
+	child := addToPool(9000, 1000, parent)
+	// richParent pays more than any package it belongs to
+	richParent := addToPool(10000, 1000)
+	addToPool(1000, 1000, richParent)
+	// secondParent shares a package with parent: (1000 + 1000 + 9000 + 13000) / 4000 = 6
+	secondParent := addToPool(1000, 1000)
+	addToPool(13000, 1000, child, secondParent)
+
+	packageFeeRates := mp.BlockCandidatePackageFeeRates()
+
+	expectedPackageFeeRates := map[*model.MempoolTransaction]float64{
+		standalone:   2,
+		parent:       6,
+		richParent:   10,
+		secondParent: 6,
+	}
+	if len(packageFeeRates) != len(expectedPackageFeeRates) {
+		t.Fatalf("expected %d package fee rates but got %d", len(expectedPackageFeeRates), len(packageFeeRates))
+	}
+	for transaction, expectedPackageFeeRate := range expectedPackageFeeRates {
+		packageFeeRate, ok := packageFeeRates[*transaction.TransactionID()]
+		if !ok {
+			t.Fatalf("transaction %s is missing a package fee rate", transaction.TransactionID())
+		}
+		if packageFeeRate != expectedPackageFeeRate {
+			t.Fatalf("expected transaction %s to have a package fee rate of %f but got %f",
+				transaction.TransactionID(), expectedPackageFeeRate, packageFeeRate)
+		}
+	}
+}
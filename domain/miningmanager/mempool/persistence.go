@@ -0,0 +1,296 @@
+package mempool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/kobradag/kobrad/domain/consensus/database/serialization"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+	"github.com/pkg/errors"
+)
+
+// mempoolFileVersion is the version of the mempool file format. It should be
+// bumped whenever the format changes, so that old files are rejected rather
+// than misinterpreted.
+const mempoolFileVersion = 1
+
+// maxPersistedTransactionSize is the maximum size of a single serialized transaction
+// in a mempool file. Anything bigger indicates a corrupt file.
+const maxPersistedTransactionSize = 1_000_000
+
+// persistedTransaction is a mempool transaction as it's stored in a mempool file
+type persistedTransaction struct {
+	transaction     *externalapi.DomainTransaction
+	isOrphan        bool
+	isHighPriority  bool
+	addedAtDAAScore uint64
+}
+
+// persistedTransactionHeader precedes every serialized transaction in a mempool file
+type persistedTransactionHeader struct {
+	IsOrphan          bool
+	IsHighPriority    bool
+	AddedAtDAAScore   uint64
+	TransactionLength uint32
+}
+
+// SaveToFile writes all the transactions in the mempool, including orphans, to the file in the given path
+func (mp *mempool) SaveToFile(path string) (savedTransactionCount int, err error) {
+	mp.mtx.RLock()
+	persistedTransactions := mp.persistedTransactions()
+	mp.mtx.RUnlock()
+
+	err = writeMempoolFile(path, persistedTransactions)
+	if err != nil {
+		return 0, err
+	}
+	return len(persistedTransactions), nil
+}
+
+// LoadFromFile reads the transactions in the file in the given path, and inserts them into the mempool
+// the same way as any other transaction. Transactions that have expired since they were saved, or that
+// are no longer valid, are skipped. A missing file is not considered an error.
+func (mp *mempool) LoadFromFile(path string) (acceptedTransactions []*externalapi.DomainTransaction, err error) {
+	persistedTransactions, err := readMempoolFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	virtualDAAScore, err := mp.consensusReference.Consensus().GetVirtualDAAScore()
+	if err != nil {
+		return nil, err
+	}
+
+	acceptedTransactions = []*externalapi.DomainTransaction{}
+	for _, persisted := range persistedTransactions {
+		if mp.isPersistedTransactionExpired(persisted, virtualDAAScore) {
+			log.Debugf("Skipping persisted transaction %s, because it expired",
+				consensushashing.TransactionID(persisted.transaction))
+			continue
+		}
+
+		transactionAcceptedTransactions, _, err := mp.validateAndInsertTransaction(
+			persisted.transaction, persisted.isHighPriority, true, false)
+		if err != nil {
+			if errors.As(err, &RuleError{}) {
+				log.Debugf("Skipping persisted transaction: %s", err)
+				continue
+			}
+			return nil, err
+		}
+		mp.restorePersistedAddedAtDAAScore(persisted, virtualDAAScore)
+		acceptedTransactions = append(acceptedTransactions, transactionAcceptedTransactions...)
+	}
+
+	log.Infof("Loaded %d transactions out of %d persisted transactions into the mempool",
+		len(acceptedTransactions), len(persistedTransactions))
+	return acceptedTransactions, nil
+}
+
+func (mp *mempool) isPersistedTransactionExpired(persisted *persistedTransaction, virtualDAAScore uint64) bool {
+	if persisted.isHighPriority || virtualDAAScore < persisted.addedAtDAAScore {
+		return false
+	}
+	expireInterval := mp.config.TransactionExpireIntervalDAAScore
+	if persisted.isOrphan {
+		expireInterval = mp.config.OrphanExpireIntervalDAAScore
+	}
+	return virtualDAAScore-persisted.addedAtDAAScore > expireInterval
+}
+
+// restorePersistedAddedAtDAAScore sets the DAA score at which the given persisted transaction was originally
+// added to the mempool on the transaction that was loaded from it, so that loading the mempool file doesn't
+// postpone its expiration
+func (mp *mempool) restorePersistedAddedAtDAAScore(persisted *persistedTransaction, virtualDAAScore uint64) {
+	// A DAA score above the virtual's means the DAG was reset since the file was saved
+	if persisted.addedAtDAAScore > virtualDAAScore {
+		return
+	}
+
+	transactionID := consensushashing.TransactionID(persisted.transaction)
+	if mempoolTransaction, ok := mp.transactionsPool.allTransactions[*transactionID]; ok {
+		mempoolTransaction.SetAddedAtDAAScore(persisted.addedAtDAAScore)
+		return
+	}
+	if orphanTransaction, ok := mp.orphansPool.allOrphans[*transactionID]; ok {
+		orphanTransaction.SetAddedAtDAAScore(persisted.addedAtDAAScore)
+	}
+}
+
+// persistedTransactions returns all the transactions in the mempool, ordered such that every
+// transaction appears after its parents in the transaction pool. Orphans appear last.
+// The transactions are cloned, since they are serialized outside of the mempool lock.
+func (mp *mempool) persistedTransactions() []*persistedTransaction {
+	persistedTransactions := make([]*persistedTransaction, 0,
+		len(mp.transactionsPool.allTransactions)+len(mp.orphansPool.allOrphans))
+
+	visited := make(map[externalapi.DomainTransactionID]struct{}, len(mp.transactionsPool.allTransactions))
+	var visit func(mempoolTransaction *model.MempoolTransaction)
+	visit = func(mempoolTransaction *model.MempoolTransaction) {
+		if _, ok := visited[*mempoolTransaction.TransactionID()]; ok {
+			return
+		}
+		visited[*mempoolTransaction.TransactionID()] = struct{}{}
+		for _, parent := range mempoolTransaction.ParentTransactionsInPool() {
+			visit(parent)
+		}
+		persistedTransactions = append(persistedTransactions, &persistedTransaction{
+			transaction:     mempoolTransaction.Transaction().Clone(),
+			isHighPriority:  mempoolTransaction.IsHighPriority(),
+			addedAtDAAScore: mempoolTransaction.AddedAtDAAScore(),
+		})
+	}
+	for _, mempoolTransaction := range mp.transactionsPool.allTransactions {
+		visit(mempoolTransaction)
+	}
+
+	for _, orphanTransaction := range mp.orphansPool.allOrphans {
+		persistedTransactions = append(persistedTransactions, &persistedTransaction{
+			transaction:     orphanTransaction.Transaction().Clone(),
+			isOrphan:        true,
+			isHighPriority:  orphanTransaction.IsHighPriority(),
+			addedAtDAAScore: orphanTransaction.AddedAtDAAScore(),
+		})
+	}
+
+	return persistedTransactions
+}
+
+// writeMempoolFile writes the given transactions to the file in the given path. The file is first
+// written under a temporary name and then renamed, so that a crash never leaves a partial file behind.
+func writeMempoolFile(path string, persistedTransactions []*persistedTransaction) error {
+	temporaryPath := path + ".tmp"
+	file, err := os.Create(temporaryPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = serializePersistedTransactions(file, persistedTransactions)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return errors.WithStack(err)
+	}
+	err = file.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(temporaryPath, path))
+}
+
+func serializePersistedTransactions(w io.Writer, persistedTransactions []*persistedTransaction) error {
+	writer := bufio.NewWriter(w)
+	err := binary.Write(writer, binary.LittleEndian, uint32(mempoolFileVersion))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = binary.Write(writer, binary.LittleEndian, uint64(len(persistedTransactions)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, persisted := range persistedTransactions {
+		serializedTransaction, err := proto.Marshal(serialization.DomainTransactionToDbTransaction(persisted.transaction))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		header := persistedTransactionHeader{
+			IsOrphan:          persisted.isOrphan,
+			IsHighPriority:    persisted.isHighPriority,
+			AddedAtDAAScore:   persisted.addedAtDAAScore,
+			TransactionLength: uint32(len(serializedTransaction)),
+		}
+		err = binary.Write(writer, binary.LittleEndian, &header)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, err = writer.Write(serializedTransaction)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return errors.WithStack(writer.Flush())
+}
+
+// readMempoolFile reads the transactions in the file in the given path
+func readMempoolFile(path string) ([]*persistedTransaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return deserializePersistedTransactions(file)
+}
+
+func deserializePersistedTransactions(r io.Reader) ([]*persistedTransaction, error) {
+	reader := bufio.NewReader(r)
+	var version uint32
+	err := binary.Read(reader, binary.LittleEndian, &version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the mempool file version")
+	}
+	if version != mempoolFileVersion {
+		return nil, errors.Errorf("unsupported mempool file version %d", version)
+	}
+	var count uint64
+	err = binary.Read(reader, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the mempool file transaction count")
+	}
+
+	persistedTransactions := make([]*persistedTransaction, 0)
+	for i := uint64(0); i < count; i++ {
+		var header persistedTransactionHeader
+		err := binary.Read(reader, binary.LittleEndian, &header)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read persisted transaction %d", i)
+		}
+		if header.TransactionLength > maxPersistedTransactionSize {
+			return nil, errors.Errorf("persisted transaction %d has a size of %d bytes, which is more than "+
+				"the maximum of %d", i, header.TransactionLength, maxPersistedTransactionSize)
+		}
+
+		serializedTransaction := make([]byte, header.TransactionLength)
+		_, err = io.ReadFull(reader, serializedTransaction)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read persisted transaction %d", i)
+		}
+		dbTransaction := &serialization.DbTransaction{}
+		err = proto.Unmarshal(serializedTransaction, dbTransaction)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to deserialize persisted transaction %d", i)
+		}
+		transaction, err := serialization.DbTransactionToDomainTransaction(dbTransaction)
+		if err != nil {
+			return nil, err
+		}
+
+		persistedTransactions = append(persistedTransactions, &persistedTransaction{
+			transaction:     transaction,
+			isOrphan:        header.IsOrphan,
+			isHighPriority:  header.IsHighPriority,
+			addedAtDAAScore: header.AddedAtDAAScore,
+		})
+	}
+
+	return persistedTransactions, nil
+}
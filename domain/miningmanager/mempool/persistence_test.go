@@ -0,0 +1,128 @@
+package mempool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/consensus/utils/subnetworks"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+)
+
+func TestPersistedTransactionsRoundTrip(t *testing.T) {
+	mp := &mempool{config: &Config{TransactionExpireIntervalDAAScore: 100, OrphanExpireIntervalDAAScore: 10}}
+	mp.mempoolUTXOSet = newMempoolUTXOSet(mp)
+	mp.transactionsPool = newTransactionsPool(mp)
+	mp.orphansPool = newOrphansPool(mp)
+
+	lockTime := uint64(0)
+	newTransaction := func(parents ...*model.MempoolTransaction) *externalapi.DomainTransaction {
+		lockTime++
+		transaction := &externalapi.DomainTransaction{
+			Outputs:      []*externalapi.DomainTransactionOutput{{Value: 1, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+			LockTime:     lockTime,
+			SubnetworkID: subnetworks.SubnetworkIDNative,
+			Payload:      []byte{},
+			Fee:          1,
+			Mass:         1,
+		}
+		for _, parent := range parents {
+			transaction.Inputs = append(transaction.Inputs, &externalapi.DomainTransactionInput{
+				PreviousOutpoint: externalapi.DomainOutpoint{TransactionID: *parent.TransactionID()},
+				SignatureScript:  []byte{},
+			})
+		}
+		return transaction
+	}
+	addToPool := func(isHighPriority bool, addedAtDAAScore uint64, parents ...*model.MempoolTransaction) *model.MempoolTransaction {
+		parentsInPool := model.IDToTransactionMap{}
+		for _, parent := range parents {
+			parentsInPool[*parent.TransactionID()] = parent
+		}
+		mempoolTransaction := model.NewMempoolTransaction(newTransaction(parents...), parentsInPool,
+			isHighPriority, addedAtDAAScore)
+		err := mp.transactionsPool.addMempoolTransaction(mempoolTransaction)
+		if err != nil {
+			t.Fatalf("addMempoolTransaction: %s", err)
+		}
+		return mempoolTransaction
+	}
+
+	grandparent := addToPool(false, 5)
+	parent := addToPool(true, 6, grandparent)
+	child := addToPool(false, 7, parent, grandparent)
+	orphan := model.NewOrphanTransaction(newTransaction(), false, 8)
+	mp.orphansPool.allOrphans[*orphan.TransactionID()] = orphan
+
+	persistedTransactions := mp.persistedTransactions()
+
+	var buffer bytes.Buffer
+	err := serializePersistedTransactions(&buffer, persistedTransactions)
+	if err != nil {
+		t.Fatalf("serializePersistedTransactions: %s", err)
+	}
+	deserializedTransactions, err := deserializePersistedTransactions(&buffer)
+	if err != nil {
+		t.Fatalf("deserializePersistedTransactions: %s", err)
+	}
+
+	expectedOrder := []*externalapi.DomainTransactionID{
+		grandparent.TransactionID(), parent.TransactionID(), child.TransactionID(), orphan.TransactionID()}
+	if len(deserializedTransactions) != len(expectedOrder) {
+		t.Fatalf("expected %d persisted transactions but got %d", len(expectedOrder), len(deserializedTransactions))
+	}
+	for i, persisted := range deserializedTransactions {
+		if !persisted.transaction.Equal(persistedTransactions[i].transaction) {
+			t.Fatalf("persisted transaction %d changed after deserialization", i)
+		}
+		if *persisted != (persistedTransaction{
+			transaction:     persisted.transaction,
+			isOrphan:        persistedTransactions[i].isOrphan,
+			isHighPriority:  persistedTransactions[i].isHighPriority,
+			addedAtDAAScore: persistedTransactions[i].addedAtDAAScore,
+		}) {
+			t.Fatalf("the metadata of persisted transaction %d changed after deserialization", i)
+		}
+		transactionID := consensushashing.TransactionID(persisted.transaction)
+		if !transactionID.Equal(expectedOrder[i]) {
+			t.Fatalf("expected transaction %s at index %d but got %s", expectedOrder[i], i, transactionID)
+		}
+	}
+	if !deserializedTransactions[1].isHighPriority || deserializedTransactions[0].isHighPriority {
+		t.Fatalf("the high priority flags were not persisted")
+	}
+	if !deserializedTransactions[3].isOrphan || deserializedTransactions[2].isOrphan {
+		t.Fatalf("the orphan flags were not persisted")
+	}
+
+	// At DAA score 107, only the child is recent enough, and the parent never expires.
+	// The orphan expires earlier than the transactions in the pool
+	expectedExpired := []bool{true, false, false, true}
+	for i, persisted := range deserializedTransactions {
+		if mp.isPersistedTransactionExpired(persisted, 107) != expectedExpired[i] {
+			t.Fatalf("expected persisted transaction %d to be expired: %t", i, expectedExpired[i])
+		}
+	}
+
+	// Loaded transactions are added at the current virtual DAA score, and the persisted
+	// DAA score is restored on them afterwards
+	child.SetAddedAtDAAScore(107)
+	orphan.SetAddedAtDAAScore(107)
+	mp.restorePersistedAddedAtDAAScore(deserializedTransactions[2], 107)
+	mp.restorePersistedAddedAtDAAScore(deserializedTransactions[3], 107)
+	if child.AddedAtDAAScore() != 7 || orphan.AddedAtDAAScore() != 8 {
+		t.Fatalf("expected the persisted DAA scores 7 and 8 to be restored, got %d and %d",
+			child.AddedAtDAAScore(), orphan.AddedAtDAAScore())
+	}
+	child.SetAddedAtDAAScore(3)
+	mp.restorePersistedAddedAtDAAScore(deserializedTransactions[2], 3)
+	if child.AddedAtDAAScore() != 3 {
+		t.Fatalf("expected a persisted DAA score above the virtual's not to be restored")
+	}
+
+	_, err = deserializePersistedTransactions(bytes.NewReader([]byte{2, 0, 0, 0}))
+	if err == nil {
+		t.Fatalf("deserializePersistedTransactions unexpectedly accepted an unknown version")
+	}
+}
@@ -0,0 +1,124 @@
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+)
+
+// transactionsToReplace returns the mempool transactions that have to be removed in order to insert
+// the given transaction: the transactions that spend any of its inputs, along with all their redeemers.
+//
+// The given transaction may replace them only if it pays a strictly higher fee than all of them
+// together, and a strictly higher fee rate than each one of them. The fee it adds must also pay for
+// its own mass at the minimum relay fee. Otherwise, an error is returned.
+func (mp *mempool) transactionsToReplace(transaction *externalapi.DomainTransaction,
+	parentsInPool model.IDToTransactionMap) ([]*model.MempoolTransaction, error) {
+
+	transactionID := consensushashing.TransactionID(transaction)
+
+	replaced := make(model.IDToTransactionMap)
+	var transactionsToReplace []*model.MempoolTransaction
+	for _, input := range transaction.Inputs {
+		conflictingTransaction, ok := mp.mempoolUTXOSet.transactionByPreviousOutpoint[input.PreviousOutpoint]
+		if !ok {
+			continue
+		}
+		if _, ok := replaced[*conflictingTransaction.TransactionID()]; ok {
+			continue
+		}
+		conflictAndRedeemers := append([]*model.MempoolTransaction{conflictingTransaction},
+			mp.transactionsPool.getRedeemers(conflictingTransaction)...)
+		for _, transactionToReplace := range conflictAndRedeemers {
+			if _, ok := replaced[*transactionToReplace.TransactionID()]; ok {
+				continue
+			}
+			replaced[*transactionToReplace.TransactionID()] = transactionToReplace
+			transactionsToReplace = append(transactionsToReplace, transactionToReplace)
+		}
+	}
+
+	if len(transactionsToReplace) == 0 {
+		return nil, nil
+	}
+
+	if uint64(len(transactionsToReplace)) > mp.config.MaximumReplacedTransactionCount {
+		str := fmt.Sprintf("transaction %s would replace %d transactions in the memory pool, which is more "+
+			"than the maximum of %d", transactionID, len(transactionsToReplace), mp.config.MaximumReplacedTransactionCount)
+		return nil, transactionRuleError(RejectReplacement, str)
+	}
+
+	for parentID := range parentsInPool {
+		if _, ok := replaced[parentID]; ok {
+			str := fmt.Sprintf("transaction %s spends an output of transaction %s, which it replaces",
+				transactionID, parentID)
+			return nil, transactionRuleError(RejectReplacement, str)
+		}
+	}
+
+	feeRate := transactionFeeRate(transaction)
+	replacedFee := uint64(0)
+	for _, transactionToReplace := range transactionsToReplace {
+		replacedFee += transactionToReplace.Transaction().Fee
+
+		replacedFeeRate := transactionFeeRate(transactionToReplace.Transaction())
+		if feeRate <= replacedFeeRate {
+			str := fmt.Sprintf("transaction %s has a fee rate of %f leor/gram, which is not higher than the "+
+				"fee rate of %f leor/gram of transaction %s which it replaces",
+				transactionID, feeRate, replacedFeeRate, transactionToReplace.TransactionID())
+			return nil, transactionRuleError(RejectInsufficientFee, str)
+		}
+	}
+	if transaction.Fee <= replacedFee {
+		str := fmt.Sprintf("transaction %s has a fee of %d leor, which is not higher than the total fee "+
+			"of %d leor of the transactions it replaces", transactionID, transaction.Fee, replacedFee)
+		return nil, transactionRuleError(RejectInsufficientFee, str)
+	}
+	// The replacement is relayed in addition to the transactions it replaces, so the fee it adds has to pay
+	// for its own mass at the minimum relay fee. Otherwise, replacements could be cycled to relay them for free
+	minimumAdditionalFee := mp.minimumRequiredTransactionRelayFee(transaction.Mass)
+	if transaction.Fee-replacedFee < minimumAdditionalFee {
+		str := fmt.Sprintf("transaction %s adds a fee of %d leor to the transactions it replaces, which is lower "+
+			"than the minimum relay fee of %d leor for its own mass", transactionID, transaction.Fee-replacedFee,
+			minimumAdditionalFee)
+		return nil, transactionRuleError(RejectInsufficientFee, str)
+	}
+
+	return transactionsToReplace, nil
+}
+
+// replaceTransactions removes the given transactions from the mempool, and returns them
+func (mp *mempool) replaceTransactions(transactionsToReplace []*model.MempoolTransaction) (
+	replacedTransactions []*externalapi.DomainTransaction, err error) {
+
+	replacedTransactions = make([]*externalapi.DomainTransaction, 0, len(transactionsToReplace))
+	for _, transactionToReplace := range transactionsToReplace {
+		// Redeemers of a replaced transaction are already part of transactionsToReplace,
+		// and removing a transaction that was already removed is a no-op
+		err := mp.removeTransaction(transactionToReplace.TransactionID(), true)
+		if err != nil {
+			return nil, err
+		}
+		replacedTransactions = append(replacedTransactions, transactionToReplace.Transaction())
+	}
+
+	return replacedTransactions, nil
+}
+
+// restoreReplacedTransactions adds back to the mempool transactions that were removed by
+// replaceTransactions, after the transaction replacing them failed to get into the mempool.
+// Note that orphans that redeemed the replaced transactions are not restored.
+func (mp *mempool) restoreReplacedTransactions(replacedTransactions []*model.MempoolTransaction) error {
+	for _, replacedTransaction := range replacedTransactions {
+		if _, ok := mp.transactionsPool.allTransactions[*replacedTransaction.TransactionID()]; ok {
+			continue
+		}
+		err := mp.transactionsPool.addMempoolTransaction(replacedTransaction)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+)
+
+func TestTransactionsToReplace(t *testing.T) {
+	mp := &mempool{config: &Config{
+		MaximumReplacedTransactionCount: defaultMaximumReplacedTransactionCount,
+		MinimumRelayTransactionFee:      defaultMinimumRelayTransactionFee,
+	}}
+	mp.mempoolUTXOSet = newMempoolUTXOSet(mp)
+	mp.transactionsPool = newTransactionsPool(mp)
+
+	consensusOutpoint := externalapi.DomainOutpoint{
+		TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+	}
+	otherConsensusOutpoint := externalapi.DomainOutpoint{
+		TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{2}),
+	}
+
+	newTransaction := func(fee uint64, mass uint64, outpoints ...externalapi.DomainOutpoint) *externalapi.DomainTransaction {
+		transaction := &externalapi.DomainTransaction{
+			Outputs: []*externalapi.DomainTransactionOutput{{Value: 1, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+			Fee:     fee,
+			Mass:    mass,
+		}
+		for _, outpoint := range outpoints {
+			transaction.Inputs = append(transaction.Inputs, &externalapi.DomainTransactionInput{PreviousOutpoint: outpoint})
+		}
+		return transaction
+	}
+	addToPool := func(transaction *externalapi.DomainTransaction, parentsInPool model.IDToTransactionMap) *model.MempoolTransaction {
+		mempoolTransaction := model.NewMempoolTransaction(transaction, parentsInPool, false, 0)
+		err := mp.transactionsPool.addMempoolTransaction(mempoolTransaction)
+		if err != nil {
+			t.Fatalf("addMempoolTransaction: %s", err)
+		}
+		return mempoolTransaction
+	}
+
+	// conflicting has a fee rate of 1 leor per gram, and its redeemer has a fee rate of 3
+	conflicting := addToPool(newTransaction(1000, 1000, consensusOutpoint), model.IDToTransactionMap{})
+	conflictingOutpoint := externalapi.DomainOutpoint{TransactionID: *conflicting.TransactionID()}
+	redeemer := addToPool(newTransaction(3000, 1000, conflictingOutpoint),
+		model.IDToTransactionMap{*conflicting.TransactionID(): conflicting})
+
+	tests := []struct {
+		name                      string
+		transaction               *externalapi.DomainTransaction
+		parentsInPool             model.IDToTransactionMap
+		maximumReplacedCount      uint64
+		expectedReplacedCount     int
+		expectedRejectCode        RejectCode
+		expectedReplacedIncluding []*model.MempoolTransaction
+	}{
+		{
+			name:                      "higher fee and fee rate",
+			transaction:               newTransaction(5000, 1000, consensusOutpoint),
+			expectedReplacedCount:     2,
+			expectedReplacedIncluding: []*model.MempoolTransaction{conflicting, redeemer},
+		},
+		{
+			name:                  "no conflicts",
+			transaction:           newTransaction(1, 1000, otherConsensusOutpoint),
+			expectedReplacedCount: 0,
+		},
+		{
+			name:               "fee not higher than the total replaced fee",
+			transaction:        newTransaction(4000, 500, consensusOutpoint),
+			expectedRejectCode: RejectInsufficientFee,
+		},
+		{
+			name:               "added fee doesn't pay for the mass of the transaction",
+			transaction:        newTransaction(4500, 1000, consensusOutpoint),
+			expectedRejectCode: RejectInsufficientFee,
+		},
+		{
+			name:               "fee rate not higher than the fee rate of a redeemer",
+			transaction:        newTransaction(12000, 4000, consensusOutpoint),
+			expectedRejectCode: RejectInsufficientFee,
+		},
+		{
+			name:               "spends an output of a replaced transaction",
+			transaction:        newTransaction(50000, 1000, consensusOutpoint, conflictingOutpoint),
+			parentsInPool:      model.IDToTransactionMap{*conflicting.TransactionID(): conflicting},
+			expectedRejectCode: RejectReplacement,
+		},
+		{
+			name:                 "too many replaced transactions",
+			transaction:          newTransaction(5000, 1000, consensusOutpoint),
+			maximumReplacedCount: 1,
+			expectedRejectCode:   RejectReplacement,
+		},
+	}
+
+	for _, test := range tests {
+		mp.config.MaximumReplacedTransactionCount = defaultMaximumReplacedTransactionCount
+		if test.maximumReplacedCount != 0 {
+			mp.config.MaximumReplacedTransactionCount = test.maximumReplacedCount
+		}
+
+		transactionsToReplace, err := mp.transactionsToReplace(test.transaction, test.parentsInPool)
+		if test.expectedRejectCode != 0 {
+			rejectCode, ok := extractRejectCode(err)
+			if !ok || rejectCode != test.expectedRejectCode {
+				t.Fatalf("%s: expected reject code %s but got error: %v", test.name, test.expectedRejectCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: transactionsToReplace: %s", test.name, err)
+		}
+		if len(transactionsToReplace) != test.expectedReplacedCount {
+			t.Fatalf("%s: expected %d transactions to replace but got %d",
+				test.name, test.expectedReplacedCount, len(transactionsToReplace))
+		}
+		for _, expected := range test.expectedReplacedIncluding {
+			found := false
+			for _, transactionToReplace := range transactionsToReplace {
+				if transactionToReplace == expected {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("%s: transaction %s is missing from the transactions to replace",
+					test.name, consensushashing.TransactionID(expected.Transaction()))
+			}
+		}
+	}
+}
+
+func TestRestoreReplacedTransactions(t *testing.T) {
+	mp := &mempool{config: &Config{MaximumReplacedTransactionCount: defaultMaximumReplacedTransactionCount}}
+	mp.mempoolUTXOSet = newMempoolUTXOSet(mp)
+	mp.transactionsPool = newTransactionsPool(mp)
+	mp.orphansPool = newOrphansPool(mp)
+
+	consensusOutpoint := externalapi.DomainOutpoint{
+		TransactionID: *externalapi.NewDomainTransactionIDFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+	}
+	conflicting := model.NewMempoolTransaction(&externalapi.DomainTransaction{
+		Inputs:  []*externalapi.DomainTransactionInput{{PreviousOutpoint: consensusOutpoint}},
+		Outputs: []*externalapi.DomainTransactionOutput{{Value: 1, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+		Fee:     1000,
+		Mass:    1000,
+	}, model.IDToTransactionMap{}, false, 0)
+	err := mp.transactionsPool.addMempoolTransaction(conflicting)
+	if err != nil {
+		t.Fatalf("addMempoolTransaction: %s", err)
+	}
+	conflictingOutpoint := externalapi.DomainOutpoint{TransactionID: *conflicting.TransactionID()}
+	redeemer := model.NewMempoolTransaction(&externalapi.DomainTransaction{
+		Inputs:  []*externalapi.DomainTransactionInput{{PreviousOutpoint: conflictingOutpoint}},
+		Outputs: []*externalapi.DomainTransactionOutput{{Value: 1, ScriptPublicKey: &externalapi.ScriptPublicKey{}}},
+		Fee:     1000,
+		Mass:    1000,
+	}, model.IDToTransactionMap{*conflicting.TransactionID(): conflicting}, false, 0)
+	err = mp.transactionsPool.addMempoolTransaction(redeemer)
+	if err != nil {
+		t.Fatalf("addMempoolTransaction: %s", err)
+	}
+
+	transactionsToReplace := []*model.MempoolTransaction{conflicting, redeemer}
+	replacedTransactions, err := mp.replaceTransactions(transactionsToReplace)
+	if err != nil {
+		t.Fatalf("replaceTransactions: %s", err)
+	}
+	if len(replacedTransactions) != 2 || mp.transactionsPool.transactionCount() != 0 {
+		t.Fatalf("expected both transactions to be replaced, got %d replaced and %d left in the pool",
+			len(replacedTransactions), mp.transactionsPool.transactionCount())
+	}
+	if _, ok := mp.mempoolUTXOSet.transactionByPreviousOutpoint[consensusOutpoint]; ok {
+		t.Fatalf("expected the outpoint spent by the replaced transaction to be free")
+	}
+
+	err = mp.restoreReplacedTransactions(transactionsToReplace)
+	if err != nil {
+		t.Fatalf("restoreReplacedTransactions: %s", err)
+	}
+	if mp.transactionsPool.transactionCount() != 2 {
+		t.Fatalf("expected both transactions to be restored, got %d", mp.transactionsPool.transactionCount())
+	}
+	if mp.mempoolUTXOSet.transactionByPreviousOutpoint[consensusOutpoint] != conflicting {
+		t.Fatalf("expected the outpoint to be spent by the restored transaction")
+	}
+	redeemers := mp.transactionsPool.getRedeemers(conflicting)
+	if len(redeemers) != 1 || redeemers[0] != redeemer {
+		t.Fatalf("expected the restored transaction to be redeemed by the restored redeemer")
+	}
+}
+
+func TestParseReplaceByFeePolicy(t *testing.T) {
+	for _, policy := range []ReplaceByFeePolicy{ReplaceByFeeDisabled, ReplaceByFeeOptIn, ReplaceByFeeAll} {
+		parsed, err := ParseReplaceByFeePolicy(policy.String())
+		if err != nil {
+			t.Fatalf("ParseReplaceByFeePolicy(%s): %s", policy, err)
+		}
+		if parsed != policy {
+			t.Fatalf("expected %s but got %s", policy, parsed)
+		}
+	}
+
+	_, err := ParseReplaceByFeePolicy("sometimes")
+	if err == nil {
+		t.Fatalf("ParseReplaceByFeePolicy unexpectedly succeeded for an unknown policy")
+	}
+}
@@ -0,0 +1,53 @@
+package mempool
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+// TransactionsByShortIDs returns the transactions in the transaction pool and in the orphan
+// pool whose short IDs, as computed by the given shortID function, are the given short IDs.
+// The returned slice is aligned with shortIDs, and holds nil for short IDs that match no
+// transaction, or that are ambiguous because they match more than one transaction.
+// Only the matched transactions are cloned, so this is much cheaper than AllTransactions
+// for relaying compact blocks.
+func (mp *mempool) TransactionsByShortIDs(shortIDs []uint64,
+	shortID func(transactionID *externalapi.DomainTransactionID) uint64) []*externalapi.DomainTransaction {
+
+	requestedShortIDs := make(map[uint64]struct{}, len(shortIDs))
+	for _, id := range shortIDs {
+		requestedShortIDs[id] = struct{}{}
+	}
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	// A nil value marks a short ID that matches more than one transaction
+	transactionsByShortID := make(map[uint64]*externalapi.DomainTransaction, len(requestedShortIDs))
+	addTransaction := func(transactionID *externalapi.DomainTransactionID, transaction *externalapi.DomainTransaction) {
+		id := shortID(transactionID)
+		if _, ok := requestedShortIDs[id]; !ok {
+			return
+		}
+		if _, ok := transactionsByShortID[id]; ok {
+			transactionsByShortID[id] = nil
+			return
+		}
+		transactionsByShortID[id] = transaction
+	}
+	for transactionID, mempoolTransaction := range mp.transactionsPool.allTransactions {
+		transactionID := transactionID
+		addTransaction(&transactionID, mempoolTransaction.Transaction())
+	}
+	for transactionID, orphanTransaction := range mp.orphansPool.allOrphans {
+		transactionID := transactionID
+		addTransaction(&transactionID, orphanTransaction.Transaction())
+	}
+
+	transactions := make([]*externalapi.DomainTransaction, len(shortIDs))
+	for i, id := range shortIDs {
+		if transaction := transactionsByShortID[id]; transaction != nil {
+			transactions[i] = transaction.Clone() //this pointer leaves the mempool, hence we clone.
+		}
+	}
+	return transactions
+}
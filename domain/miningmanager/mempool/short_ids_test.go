@@ -0,0 +1,57 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
+)
+
+func TestTransactionsByShortIDs(t *testing.T) {
+	mp := &mempool{config: &Config{}}
+	mp.transactionsPool = newTransactionsPool(mp)
+	mp.orphansPool = newOrphansPool(mp)
+
+	var transactions []*externalapi.DomainTransaction
+	for i := uint64(0); i < 4; i++ {
+		transactions = append(transactions, &externalapi.DomainTransaction{LockTime: i})
+	}
+	for _, transaction := range transactions[:3] {
+		mp.transactionsPool.allTransactions[*consensushashing.TransactionID(transaction)] =
+			model.NewMempoolTransaction(transaction, model.IDToTransactionMap{}, false, 0)
+	}
+	mp.orphansPool.allOrphans[*consensushashing.TransactionID(transactions[3])] =
+		model.NewOrphanTransaction(transactions[3], false, 0)
+
+	// Transactions 0 and 1 share a short ID, so they're ambiguous
+	shortIDs := map[externalapi.DomainTransactionID]uint64{
+		*consensushashing.TransactionID(transactions[0]): 1,
+		*consensushashing.TransactionID(transactions[1]): 1,
+		*consensushashing.TransactionID(transactions[2]): 2,
+		*consensushashing.TransactionID(transactions[3]): 3,
+	}
+	shortID := func(transactionID *externalapi.DomainTransactionID) uint64 {
+		return shortIDs[*transactionID]
+	}
+
+	matched := mp.TransactionsByShortIDs([]uint64{3, 1, 4, 2}, shortID)
+	expected := []*externalapi.DomainTransaction{transactions[3], nil, nil, transactions[2]}
+	if len(matched) != len(expected) {
+		t.Fatalf("expected %d transactions but got %d", len(expected), len(matched))
+	}
+	for i, transaction := range matched {
+		if expected[i] == nil {
+			if transaction != nil {
+				t.Fatalf("expected no transaction for short ID %d but got %v", i, transaction)
+			}
+			continue
+		}
+		if !transaction.Equal(expected[i]) {
+			t.Fatalf("expected transaction %v for short ID %d but got %v", expected[i], i, transaction)
+		}
+		if transaction == expected[i] {
+			t.Fatalf("the transaction for short ID %d wasn't cloned", i)
+		}
+	}
+}
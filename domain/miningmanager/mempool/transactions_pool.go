@@ -173,6 +173,32 @@ func (tp *transactionsPool) getRedeemers(transaction *model.MempoolTransaction)
 	return redeemers
 }
 
+// getAncestors returns all the ancestors of the given transaction that are in the transaction pool.
+// If the transaction has more than maxAncestors such ancestors, ok is false
+func (tp *transactionsPool) getAncestors(transaction *model.MempoolTransaction, maxAncestors int) (
+	ancestors model.IDToTransactionMap, ok bool) {
+
+	stack := []*model.MempoolTransaction{transaction}
+	ancestors = model.IDToTransactionMap{}
+	for len(stack) > 0 {
+		var current *model.MempoolTransaction
+		last := len(stack) - 1
+		current, stack = stack[last], stack[:last]
+
+		for parentID, parent := range current.ParentTransactionsInPool() {
+			if _, ok := ancestors[parentID]; ok {
+				continue
+			}
+			if len(ancestors) == maxAncestors {
+				return nil, false
+			}
+			ancestors[parentID] = parent
+			stack = append(stack, parent)
+		}
+	}
+	return ancestors, true
+}
+
 func (tp *transactionsPool) limitTransactionCount() error {
 	currentIndex := 0
 
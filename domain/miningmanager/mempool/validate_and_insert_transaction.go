@@ -7,10 +7,12 @@ import (
 
 	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/miningmanager/mempool/model"
 )
 
 func (mp *mempool) validateAndInsertTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool,
-	allowOrphan bool) (acceptedTransactions []*externalapi.DomainTransaction, err error) {
+	allowOrphan bool, allowReplacement bool) (acceptedTransactions []*externalapi.DomainTransaction,
+	replacedTransactions []*externalapi.DomainTransaction, err error) {
 
 	onEnd := logger.LogAndMeasureExecutionTime(log,
 		fmt.Sprintf("validateAndInsertTransaction %s", consensushashing.TransactionID(transaction)))
@@ -19,47 +21,85 @@ func (mp *mempool) validateAndInsertTransaction(transaction *externalapi.DomainT
 	// Populate mass in the beginning, it will be used in multiple places throughout the validation and insertion.
 	mp.consensusReference.Consensus().PopulateMass(transaction)
 
-	err = mp.validateTransactionPreUTXOEntry(transaction)
+	err = mp.validateTransactionPreUTXOEntry(transaction, allowReplacement)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	parentsInPool, missingOutpoints, err := mp.fillInputsAndGetMissingParents(transaction)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(missingOutpoints) > 0 {
 		if !allowOrphan {
 			str := fmt.Sprintf("Transaction %s is an orphan, where allowOrphan = false",
 				consensushashing.TransactionID(transaction))
-			return nil, transactionRuleError(RejectBadOrphan, str)
+			return nil, nil, transactionRuleError(RejectBadOrphan, str)
 		}
 
-		return nil, mp.orphansPool.maybeAddOrphan(transaction, isHighPriority)
+		// The fee of an orphan is unknown, so it can't replace anything
+		if allowReplacement {
+			err := mp.mempoolUTXOSet.checkDoubleSpends(transaction)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return nil, nil, mp.orphansPool.maybeAddOrphan(transaction, isHighPriority)
 	}
 
 	err = mp.validateTransactionInContext(transaction)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var transactionsToReplace []*model.MempoolTransaction
+	if allowReplacement {
+		transactionsToReplace, err = mp.transactionsToReplace(transaction, parentsInPool)
+		if err != nil {
+			return nil, nil, err
+		}
+		replacedTransactions, err = mp.replaceTransactions(transactionsToReplace)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	mempoolTransaction, err := mp.transactionsPool.addTransaction(transaction, parentsInPool, isHighPriority)
 	if err != nil {
-		return nil, err
+		restoreErr := mp.restoreReplacedTransactions(transactionsToReplace)
+		if restoreErr != nil {
+			return nil, nil, restoreErr
+		}
+		return nil, nil, err
 	}
 
 	acceptedOrphans, err := mp.orphansPool.processOrphansAfterAcceptedTransaction(mempoolTransaction.Transaction())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	acceptedTransactions = append([]*externalapi.DomainTransaction{transaction.Clone()}, acceptedOrphans...) //these pointer leave the mempool, hence we clone.
 
 	err = mp.transactionsPool.limitTransactionCount()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// A replacing transaction that was evicted right away because the mempool is full must not
+	// leave the mempool without the transactions it replaced
+	if _, ok := mp.transactionsPool.allTransactions[*mempoolTransaction.TransactionID()]; !ok &&
+		len(transactionsToReplace) > 0 {
+
+		err := mp.restoreReplacedTransactions(transactionsToReplace)
+		if err != nil {
+			return nil, nil, err
+		}
+		str := fmt.Sprintf("transaction %s was evicted from the full memory pool, so the transactions "+
+			"it replaces were kept", mempoolTransaction.TransactionID())
+		return nil, nil, transactionRuleError(RejectInsufficientFee, str)
 	}
 
-	return acceptedTransactions, nil
+	return acceptedTransactions, replacedTransactions, nil
 }
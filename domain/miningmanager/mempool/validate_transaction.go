@@ -8,12 +8,16 @@ import (
 	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
 )
 
-func (mp *mempool) validateTransactionPreUTXOEntry(transaction *externalapi.DomainTransaction) error {
+func (mp *mempool) validateTransactionPreUTXOEntry(transaction *externalapi.DomainTransaction, allowReplacement bool) error {
 	err := mp.validateTransactionInIsolation(transaction)
 	if err != nil {
 		return err
 	}
 
+	// Double spends of replaceable transactions are checked once the transaction's fee is known
+	if allowReplacement {
+		return nil
+	}
 	if err := mp.mempoolUTXOSet.checkDoubleSpends(transaction); err != nil {
 		return err
 	}
@@ -28,11 +28,19 @@ type MiningManager interface {
 	AllTransactions(includeTransactionPool bool, includeOrphanPool bool) (
 		transactionPoolTransactions []*externalapi.DomainTransaction,
 		orphanPoolTransactions []*externalapi.DomainTransaction)
+	TransactionsByShortIDs(shortIDs []uint64,
+		shortID func(transactionID *externalapi.DomainTransactionID) uint64) []*externalapi.DomainTransaction
 	TransactionCount(includeTransactionPool bool, includeOrphanPool bool) int
 	HandleNewBlockTransactions(txs []*externalapi.DomainTransaction) ([]*externalapi.DomainTransaction, error)
 	ValidateAndInsertTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool, allowOrphan bool) (
 		acceptedTransactions []*externalapi.DomainTransaction, err error)
+	ValidateAndReplaceTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool, allowOrphan bool) (
+		acceptedTransactions []*externalapi.DomainTransaction, replacedTransactions []*externalapi.DomainTransaction,
+		err error)
 	RevalidateHighPriorityTransactions() (validTransactions []*externalapi.DomainTransaction, err error)
+	GetFeeEstimate() (*miningmanagermodel.FeeEstimate, error)
+	SaveMempool(path string) (savedTransactionCount int, err error)
+	LoadMempool(path string) (acceptedTransactions []*externalapi.DomainTransaction, err error)
 }
 
 type miningManager struct {
@@ -42,6 +50,7 @@ type miningManager struct {
 	cachedBlockTemplate  *externalapi.DomainBlockTemplate
 	cachingTime          time.Time
 	cacheLock            *sync.Mutex
+	targetTimePerBlock   time.Duration
 }
 
 // GetBlockTemplate obtains a block template for a miner to consume
@@ -117,6 +126,29 @@ func (mm *miningManager) ValidateAndInsertTransaction(transaction *externalapi.D
 	return mm.mempool.ValidateAndInsertTransaction(transaction, isHighPriority, allowOrphan)
 }
 
+// ValidateAndReplaceTransaction is like ValidateAndInsertTransaction, except that
+// unless replace-by-fee is disabled, the given transaction may replace the mempool
+// transactions it conflicts with by paying a higher fee. The replaced transactions
+// are returned
+func (mm *miningManager) ValidateAndReplaceTransaction(transaction *externalapi.DomainTransaction,
+	isHighPriority bool, allowOrphan bool) (acceptedTransactions []*externalapi.DomainTransaction,
+	replacedTransactions []*externalapi.DomainTransaction, err error) {
+
+	return mm.mempool.ValidateAndReplaceTransaction(transaction, isHighPriority, allowOrphan)
+}
+
+// SaveMempool writes all the transactions in the mempool, including orphans, to the file in the given path
+func (mm *miningManager) SaveMempool(path string) (savedTransactionCount int, err error) {
+	return mm.mempool.SaveToFile(path)
+}
+
+// LoadMempool inserts the transactions saved by SaveMempool into the mempool,
+// skipping the ones that have since expired or became invalid. The accepted
+// transactions are returned
+func (mm *miningManager) LoadMempool(path string) (acceptedTransactions []*externalapi.DomainTransaction, err error) {
+	return mm.mempool.LoadFromFile(path)
+}
+
 func (mm *miningManager) GetTransaction(
 	transactionID *externalapi.DomainTransactionID,
 	includeTransactionPool bool,
@@ -135,6 +167,14 @@ func (mm *miningManager) AllTransactions(includeTransactionPool bool, includeOrp
 	return mm.mempool.AllTransactions(includeTransactionPool, includeOrphanPool)
 }
 
+// TransactionsByShortIDs returns the mempool transactions that match the given short IDs,
+// without cloning the whole mempool. Unmatched and ambiguous short IDs get a nil transaction
+func (mm *miningManager) TransactionsByShortIDs(shortIDs []uint64,
+	shortID func(transactionID *externalapi.DomainTransactionID) uint64) []*externalapi.DomainTransaction {
+
+	return mm.mempool.TransactionsByShortIDs(shortIDs, shortID)
+}
+
 func (mm *miningManager) GetTransactionsByAddresses(includeTransactionPool bool, includeOrphanPool bool) (
 	sendingInTransactionPool map[string]*externalapi.DomainTransaction,
 	receivingInTransactionPool map[string]*externalapi.DomainTransaction,
@@ -0,0 +1,35 @@
+package model
+
+// TransactionFeeRate is the fee rate (in leor per gram) and mass of a single mempool transaction
+type TransactionFeeRate struct {
+	FeeRate float64
+	Mass    uint64
+}
+
+// FeeRateStatistics is a snapshot of the mempool state that is relevant for fee estimation
+type FeeRateStatistics struct {
+	// PendingTransactions are the transactions waiting in the transaction pool,
+	// ordered from the highest fee rate to the lowest
+	PendingTransactions []TransactionFeeRate
+
+	// RecentlyAcceptedFeeRates are the fee rates of transactions that recently
+	// left the mempool because they were included in a block
+	RecentlyAcceptedFeeRates []float64
+
+	// MinimumFeeRate is the lowest fee rate the mempool accepts
+	MinimumFeeRate float64
+}
+
+// FeeRateBucket is a fee rate along with the estimated time it would take
+// a transaction paying this fee rate to be included in a block
+type FeeRateBucket struct {
+	FeeRate          float64
+	EstimatedSeconds float64
+}
+
+// FeeEstimate holds the estimated fee rates for the different inclusion priorities
+type FeeEstimate struct {
+	PriorityBucket FeeRateBucket
+	NormalBucket   FeeRateBucket
+	LowBucket      FeeRateBucket
+}
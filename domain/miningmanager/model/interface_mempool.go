@@ -10,8 +10,12 @@ import (
 type Mempool interface {
 	HandleNewBlockTransactions(txs []*externalapi.DomainTransaction) ([]*externalapi.DomainTransaction, error)
 	BlockCandidateTransactions() []*externalapi.DomainTransaction
+	BlockCandidatePackageFeeRates() map[externalapi.DomainTransactionID]float64
 	ValidateAndInsertTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool, allowOrphan bool) (
 		acceptedTransactions []*externalapi.DomainTransaction, err error)
+	ValidateAndReplaceTransaction(transaction *externalapi.DomainTransaction, isHighPriority bool, allowOrphan bool) (
+		acceptedTransactions []*externalapi.DomainTransaction, replacedTransactions []*externalapi.DomainTransaction,
+		err error)
 	RemoveInvalidTransactions(err *ruleerrors.ErrInvalidTransactionsInNewBlock) error
 	GetTransaction(
 		transactionID *externalapi.DomainTransactionID,
@@ -35,9 +39,14 @@ type Mempool interface {
 	) (
 		transactionPoolTransactions []*externalapi.DomainTransaction,
 		orphanPoolTransactions []*externalapi.DomainTransaction)
+	TransactionsByShortIDs(shortIDs []uint64,
+		shortID func(transactionID *externalapi.DomainTransactionID) uint64) []*externalapi.DomainTransaction
 	TransactionCount(
 		includeTransactionPool bool,
 		includeOrphanPool bool) int
 	RevalidateHighPriorityTransactions() (validTransactions []*externalapi.DomainTransaction, err error)
 	IsTransactionOutputDust(output *externalapi.DomainTransactionOutput) bool
+	FeeRateStatistics(maxPendingMass uint64) *FeeRateStatistics
+	SaveToFile(path string) (savedTransactionCount int, err error)
+	LoadFromFile(path string) (acceptedTransactions []*externalapi.DomainTransaction, err error)
 }
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// TransactionSelectionMode determines how transactions are selected for block templates
+type TransactionSelectionMode uint8
+
+const (
+	// TransactionSelectionProbabilistic selects transactions at random, where a transaction's
+	// chance to be selected grows with its own fee rate
+	TransactionSelectionProbabilistic TransactionSelectionMode = iota
+
+	// TransactionSelectionPackage greedily selects the transactions that belong to the transaction
+	// packages (a transaction along with its ancestors in the mempool) with the highest fee rates,
+	// so that a high-fee child pays for its low-fee parents
+	TransactionSelectionPackage
+)
+
+var transactionSelectionModeStrings = map[TransactionSelectionMode]string{
+	TransactionSelectionProbabilistic: "probabilistic",
+	TransactionSelectionPackage:       "package",
+}
+
+// String returns the TransactionSelectionMode in human-readable form.
+func (mode TransactionSelectionMode) String() string {
+	if s, ok := transactionSelectionModeStrings[mode]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown TransactionSelectionMode (%d)", uint8(mode))
+}
+
+// ParseTransactionSelectionMode returns the TransactionSelectionMode represented by the given string
+func ParseTransactionSelectionMode(modeString string) (TransactionSelectionMode, error) {
+	for mode, s := range transactionSelectionModeStrings {
+		if s == modeString {
+			return mode, nil
+		}
+	}
+	return 0, errors.Errorf("unknown transaction selection mode %s", modeString)
+}
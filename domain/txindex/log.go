@@ -0,0 +1,7 @@
+package txindex
+
+import (
+	"github.com/kobradag/kobrad/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("TXIN")
@@ -0,0 +1,25 @@
+package txindex
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+)
+
+// TransactionLocation describes where a transaction was found in the DAG
+type TransactionLocation struct {
+	// IncludingBlockHashes are the hashes of all the merged blocks
+	// that contain the transaction
+	IncludingBlockHashes []*externalapi.DomainHash
+
+	// AcceptingBlockHash is the hash of the selected parent chain block
+	// that accepted the transaction. It is nil if the transaction was
+	// included in the DAG but was not accepted by any chain block.
+	AcceptingBlockHash *externalapi.DomainHash
+
+	// AcceptingBlockBlueScore is the blue score of the accepting block
+	AcceptingBlockBlueScore uint64
+}
+
+// IsAccepted returns whether the transaction was accepted by a chain block
+func (tl *TransactionLocation) IsAccepted() bool {
+	return tl.AcceptingBlockHash != nil
+}
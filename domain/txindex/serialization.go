@@ -0,0 +1,41 @@
+package txindex
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+)
+
+// acceptingBlock is the value stored for every accepted transaction
+type acceptingBlock struct {
+	hash      *externalapi.DomainHash
+	blueScore uint64
+}
+
+const blueScoreSize = 8
+
+func serializeAcceptingBlock(acceptingBlock *acceptingBlock) []byte {
+	serializedAcceptingBlock := make([]byte, externalapi.DomainHashSize+blueScoreSize)
+	copy(serializedAcceptingBlock[:externalapi.DomainHashSize], acceptingBlock.hash.ByteSlice())
+	binary.LittleEndian.PutUint64(serializedAcceptingBlock[externalapi.DomainHashSize:], acceptingBlock.blueScore)
+	return serializedAcceptingBlock
+}
+
+func deserializeAcceptingBlock(serializedAcceptingBlock []byte) (*acceptingBlock, error) {
+	if len(serializedAcceptingBlock) != externalapi.DomainHashSize+blueScoreSize {
+		return nil, errors.Wrapf(io.ErrUnexpectedEOF, "unexpected length %d while deserializing an accepting block",
+			len(serializedAcceptingBlock))
+	}
+
+	hash, err := externalapi.NewDomainHashFromByteSlice(serializedAcceptingBlock[:externalapi.DomainHashSize])
+	if err != nil {
+		return nil, err
+	}
+
+	return &acceptingBlock{
+		hash:      hash,
+		blueScore: binary.LittleEndian.Uint64(serializedAcceptingBlock[externalapi.DomainHashSize:]),
+	}, nil
+}
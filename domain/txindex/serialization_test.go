@@ -0,0 +1,41 @@
+package txindex
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/pkg/errors"
+)
+
+func Test_serializeAcceptingBlock(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+
+	for i := 0; i < 32; i++ {
+		var hashBytes [externalapi.DomainHashSize]byte
+		r.Read(hashBytes[:])
+		expected := &acceptingBlock{
+			hash:      externalapi.NewDomainHashFromByteArray(&hashBytes),
+			blueScore: r.Uint64(),
+		}
+		result, err := deserializeAcceptingBlock(serializeAcceptingBlock(expected))
+		if err != nil {
+			t.Fatalf("Failed deserializing accepting block: %v", err)
+		}
+		if !result.hash.Equal(expected.hash) || result.blueScore != expected.blueScore {
+			t.Fatalf("Expected \n %+v \n==\n %+v\n", expected, result)
+		}
+	}
+}
+
+func Test_deserializeAcceptingBlockFailure(t *testing.T) {
+	serialized := serializeAcceptingBlock(&acceptingBlock{
+		hash:      externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+		blueScore: 1,
+	})
+	_, err := deserializeAcceptingBlock(serialized[:len(serialized)-1])
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected error to be EOF, instead got: %v", err)
+	}
+}
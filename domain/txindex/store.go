@@ -0,0 +1,210 @@
+package txindex
+
+import (
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/kobradag/kobrad/infrastructure/logger"
+	"github.com/pkg/errors"
+)
+
+var acceptingBlockBucket = database.MakeBucket([]byte("tx-index-accepting-block"))
+var includingBlocksBucket = database.MakeBucket([]byte("tx-index-including-blocks"))
+var selectedTipKey = database.MakeBucket([]byte("")).Key([]byte("tx-index-selected-tip"))
+
+type txIndexStore struct {
+	database   database.Database
+	toAccept   map[externalapi.DomainTransactionID]*acceptingBlock
+	toUnaccept map[externalapi.DomainTransactionID]*externalapi.DomainHash
+	toInclude  map[externalapi.DomainTransactionID]map[externalapi.DomainHash]struct{}
+
+	selectedTip *externalapi.DomainHash
+}
+
+func newTXIndexStore(database database.Database) *txIndexStore {
+	return &txIndexStore{
+		database:   database,
+		toAccept:   make(map[externalapi.DomainTransactionID]*acceptingBlock),
+		toUnaccept: make(map[externalapi.DomainTransactionID]*externalapi.DomainHash),
+		toInclude:  make(map[externalapi.DomainTransactionID]map[externalapi.DomainHash]struct{}),
+	}
+}
+
+func (tis *txIndexStore) accept(transactionID *externalapi.DomainTransactionID,
+	acceptingBlockHash *externalapi.DomainHash, acceptingBlockBlueScore uint64) {
+
+	log.Tracef("Marking transaction %s as accepted by block %s", transactionID, acceptingBlockHash)
+
+	delete(tis.toUnaccept, *transactionID)
+	tis.toAccept[*transactionID] = &acceptingBlock{hash: acceptingBlockHash, blueScore: acceptingBlockBlueScore}
+}
+
+func (tis *txIndexStore) unaccept(transactionID *externalapi.DomainTransactionID, acceptingBlockHash *externalapi.DomainHash) {
+	log.Tracef("Unmarking transaction %s as accepted by block %s", transactionID, acceptingBlockHash)
+
+	// If the acceptance was staged by this very block simply remove it from there and return
+	if stagedAcceptingBlock, ok := tis.toAccept[*transactionID]; ok {
+		if stagedAcceptingBlock.hash.Equal(acceptingBlockHash) {
+			delete(tis.toAccept, *transactionID)
+		}
+		return
+	}
+
+	tis.toUnaccept[*transactionID] = acceptingBlockHash
+}
+
+func (tis *txIndexStore) include(transactionID *externalapi.DomainTransactionID, includingBlockHash *externalapi.DomainHash) {
+	if _, ok := tis.toInclude[*transactionID]; !ok {
+		tis.toInclude[*transactionID] = make(map[externalapi.DomainHash]struct{})
+	}
+	tis.toInclude[*transactionID][*includingBlockHash] = struct{}{}
+}
+
+func (tis *txIndexStore) updateSelectedTip(selectedTip *externalapi.DomainHash) {
+	tis.selectedTip = selectedTip
+}
+
+func (tis *txIndexStore) discard() {
+	tis.toAccept = make(map[externalapi.DomainTransactionID]*acceptingBlock)
+	tis.toUnaccept = make(map[externalapi.DomainTransactionID]*externalapi.DomainHash)
+	tis.toInclude = make(map[externalapi.DomainTransactionID]map[externalapi.DomainHash]struct{})
+	tis.selectedTip = nil
+}
+
+func (tis *txIndexStore) commit() error {
+	onEnd := logger.LogAndMeasureExecutionTime(log, "txIndexStore.commit")
+	defer onEnd()
+
+	dbTransaction, err := tis.database.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTransaction.RollbackUnlessClosed()
+
+	for transactionID, acceptingBlockHash := range tis.toUnaccept {
+		key := tis.acceptingBlockKey(&transactionID)
+		serializedAcceptingBlock, err := dbTransaction.Get(key)
+		if err != nil {
+			if database.IsNotFoundError(err) {
+				continue
+			}
+			return err
+		}
+
+		// The transaction might have been re-accepted by a different block
+		// in an earlier update, in which case we must not touch it.
+		storedAcceptingBlock, err := deserializeAcceptingBlock(serializedAcceptingBlock)
+		if err != nil {
+			return err
+		}
+		if !storedAcceptingBlock.hash.Equal(acceptingBlockHash) {
+			continue
+		}
+
+		err = dbTransaction.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	for transactionID, acceptingBlock := range tis.toAccept {
+		err := dbTransaction.Put(tis.acceptingBlockKey(&transactionID), serializeAcceptingBlock(acceptingBlock))
+		if err != nil {
+			return err
+		}
+	}
+
+	for transactionID, includingBlockHashes := range tis.toInclude {
+		bucket := tis.includingBlocksBucket(&transactionID)
+		for includingBlockHash := range includingBlockHashes {
+			err := dbTransaction.Put(bucket.Key(includingBlockHash.ByteSlice()), []byte{})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if tis.selectedTip != nil {
+		err = dbTransaction.Put(selectedTipKey, tis.selectedTip.ByteSlice())
+		if err != nil {
+			return err
+		}
+	}
+
+	err = dbTransaction.Commit()
+	if err != nil {
+		return err
+	}
+
+	tis.discard()
+	return nil
+}
+
+func (tis *txIndexStore) isAnythingStaged() bool {
+	return len(tis.toAccept) > 0 || len(tis.toUnaccept) > 0 || len(tis.toInclude) > 0
+}
+
+func (tis *txIndexStore) acceptingBlockKey(transactionID *externalapi.DomainTransactionID) *database.Key {
+	return acceptingBlockBucket.Key(transactionID.ByteSlice())
+}
+
+func (tis *txIndexStore) includingBlocksBucket(transactionID *externalapi.DomainTransactionID) *database.Bucket {
+	return includingBlocksBucket.Bucket(transactionID.ByteSlice())
+}
+
+func (tis *txIndexStore) getTransactionLocation(transactionID *externalapi.DomainTransactionID) (
+	location *TransactionLocation, found bool, err error) {
+
+	if tis.isAnythingStaged() {
+		return nil, false, errors.Errorf("cannot get transaction location while staging isn't empty")
+	}
+
+	location = &TransactionLocation{}
+
+	serializedAcceptingBlock, err := tis.database.Get(tis.acceptingBlockKey(transactionID))
+	if err != nil && !database.IsNotFoundError(err) {
+		return nil, false, err
+	}
+	if err == nil {
+		acceptingBlock, err := deserializeAcceptingBlock(serializedAcceptingBlock)
+		if err != nil {
+			return nil, false, err
+		}
+		location.AcceptingBlockHash = acceptingBlock.hash
+		location.AcceptingBlockBlueScore = acceptingBlock.blueScore
+	}
+
+	cursor, err := tis.database.Cursor(tis.includingBlocksBucket(transactionID))
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return nil, false, err
+		}
+		includingBlockHash, err := externalapi.NewDomainHashFromByteSlice(key.Suffix())
+		if err != nil {
+			return nil, false, err
+		}
+		location.IncludingBlockHashes = append(location.IncludingBlockHashes, includingBlockHash)
+	}
+
+	if location.AcceptingBlockHash == nil && len(location.IncludingBlockHashes) == 0 {
+		return nil, false, nil
+	}
+	return location, true, nil
+}
+
+func (tis *txIndexStore) getSelectedTip() (*externalapi.DomainHash, error) {
+	if tis.isAnythingStaged() {
+		return nil, errors.Errorf("cannot get the selected tip while staging isn't empty")
+	}
+
+	serializedSelectedTip, err := tis.database.Get(selectedTipKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return externalapi.NewDomainHashFromByteSlice(serializedSelectedTip)
+}
@@ -0,0 +1,260 @@
+package txindex
+
+import (
+	"sync"
+
+	"github.com/kobradag/kobrad/domain"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/kobradag/kobrad/infrastructure/logger"
+)
+
+// syncStep is the amount of chain blocks whose acceptance data
+// is fetched and committed at once while syncing the TX index
+const syncStep = 100
+
+// TXIndex maintains an index between transaction IDs and the blocks
+// that include and accept them
+type TXIndex struct {
+	domain domain.Domain
+	store  *txIndexStore
+
+	mutex sync.Mutex
+}
+
+// New creates a new TX index.
+//
+// NOTE: While this is called no new blocks can be added to the consensus.
+func New(domain domain.Domain, database database.Database) (*TXIndex, error) {
+	txIndex := &TXIndex{
+		domain: domain,
+		store:  newTXIndexStore(database),
+	}
+
+	err := txIndex.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	return txIndex, nil
+}
+
+// Sync brings the TX index up to date with the virtual's selected parent chain.
+// If the last indexed chain block is still known to consensus, only the chain
+// changes since that block are applied. Otherwise (e.g. after the pruning point
+// was overridden during IBD) the chain above the current pruning point is indexed
+// on top of the existing data, so entries of pruned blocks are kept.
+func (ti *TXIndex) Sync() error {
+	ti.mutex.Lock()
+	defer ti.mutex.Unlock()
+
+	selectedTip, err := ti.store.getSelectedTip()
+	if err != nil {
+		if !database.IsNotFoundError(err) {
+			return err
+		}
+		log.Infof("TX index is empty. Indexing the selected parent chain from the pruning point")
+		return ti.syncFromPruningPoint()
+	}
+
+	blockInfo, err := ti.domain.Consensus().GetBlockInfo(selectedTip)
+	if err != nil {
+		return err
+	}
+	if !blockInfo.Exists || blockInfo.BlockStatus != externalapi.StatusUTXOValid {
+		log.Infof("The last block indexed by the TX index (%s) is no longer available. "+
+			"Indexing the selected parent chain from the pruning point", selectedTip)
+		return ti.syncFromPruningPoint()
+	}
+
+	chainChanges, err := ti.domain.Consensus().GetVirtualSelectedParentChainFromBlock(selectedTip)
+	if err != nil {
+		return err
+	}
+	log.Infof("Syncing the TX index: %d chain blocks were removed and %d were added since the last sync",
+		len(chainChanges.Removed), len(chainChanges.Added))
+	return ti.applyChainChanges(chainChanges)
+}
+
+func (ti *TXIndex) syncFromPruningPoint() error {
+	pruningPoint, err := ti.domain.Consensus().PruningPoint()
+	if err != nil {
+		return err
+	}
+
+	chainChanges, err := ti.domain.Consensus().GetVirtualSelectedParentChainFromBlock(pruningPoint)
+	if err != nil {
+		return err
+	}
+
+	// The pruning point itself is not indexed, since its acceptance data
+	// isn't available when it was imported from a peer.
+	return ti.applyChainChanges(&externalapi.SelectedChainPath{Added: chainChanges.Added})
+}
+
+// applyChainChanges stages and commits the given chain changes in steps of syncStep
+// blocks, so that an interrupted sync continues from the last committed block.
+func (ti *TXIndex) applyChainChanges(chainChanges *externalapi.SelectedChainPath) error {
+	for start := 0; start < len(chainChanges.Removed); start += syncStep {
+		end := start + syncStep
+		if end > len(chainChanges.Removed) {
+			end = len(chainChanges.Removed)
+		}
+		err := ti.removeChainBlocks(chainChanges.Removed[start:end])
+		if err != nil {
+			return err
+		}
+
+		// The selected parent of the last removed block is still in the selected
+		// parent chain, so it's safe to resume from it.
+		selectedTip, err := ti.selectedParent(chainChanges.Removed[end-1])
+		if err != nil {
+			return err
+		}
+		ti.store.updateSelectedTip(selectedTip)
+		err = ti.store.commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(chainChanges.Added); start += syncStep {
+		end := start + syncStep
+		if end > len(chainChanges.Added) {
+			end = len(chainChanges.Added)
+		}
+		err := ti.addChainBlocks(chainChanges.Added[start:end])
+		if err != nil {
+			return err
+		}
+
+		ti.store.updateSelectedTip(chainChanges.Added[end-1])
+		err = ti.store.commit()
+		if err != nil {
+			return err
+		}
+		log.Debugf("TX index indexed %d out of %d added chain blocks", end, len(chainChanges.Added))
+	}
+
+	return nil
+}
+
+// Update updates the TX index with the given DAG selected parent chain changes
+func (ti *TXIndex) Update(virtualChangeSet *externalapi.VirtualChangeSet) error {
+	onEnd := logger.LogAndMeasureExecutionTime(log, "TXIndex.Update")
+	defer onEnd()
+
+	ti.mutex.Lock()
+	defer ti.mutex.Unlock()
+
+	chainChanges := virtualChangeSet.VirtualSelectedParentChainChanges
+	if chainChanges == nil || (len(chainChanges.Added) == 0 && len(chainChanges.Removed) == 0) {
+		return nil
+	}
+
+	log.Tracef("Updating TX index with chain changes: %d removed, %d added",
+		len(chainChanges.Removed), len(chainChanges.Added))
+
+	err := ti.removeChainBlocks(chainChanges.Removed)
+	if err != nil {
+		return err
+	}
+
+	err = ti.addChainBlocks(chainChanges.Added)
+	if err != nil {
+		return err
+	}
+
+	if len(chainChanges.Added) > 0 {
+		ti.store.updateSelectedTip(chainChanges.Added[len(chainChanges.Added)-1])
+	} else {
+		selectedTip, err := ti.selectedParent(chainChanges.Removed[len(chainChanges.Removed)-1])
+		if err != nil {
+			return err
+		}
+		ti.store.updateSelectedTip(selectedTip)
+	}
+
+	return ti.store.commit()
+}
+
+func (ti *TXIndex) selectedParent(blockHash *externalapi.DomainHash) (*externalapi.DomainHash, error) {
+	blockInfo, err := ti.domain.Consensus().GetBlockInfo(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return blockInfo.SelectedParent, nil
+}
+
+func (ti *TXIndex) addChainBlocks(chainBlockHashes []*externalapi.DomainHash) error {
+	if len(chainBlockHashes) == 0 {
+		return nil
+	}
+
+	blocksAcceptanceData, err := ti.domain.Consensus().GetBlocksAcceptanceData(chainBlockHashes)
+	if err != nil {
+		return err
+	}
+
+	for i, acceptanceData := range blocksAcceptanceData {
+		acceptingBlockHash := chainBlockHashes[i]
+		acceptingBlockInfo, err := ti.domain.Consensus().GetBlockInfo(acceptingBlockHash)
+		if err != nil {
+			return err
+		}
+		for _, blockAcceptanceData := range acceptanceData {
+			for _, transactionAcceptanceData := range blockAcceptanceData.TransactionAcceptanceData {
+				transactionID := consensushashing.TransactionID(transactionAcceptanceData.Transaction)
+				ti.store.include(transactionID, blockAcceptanceData.BlockHash)
+				if transactionAcceptanceData.IsAccepted {
+					ti.store.accept(transactionID, acceptingBlockHash, acceptingBlockInfo.BlueScore)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ti *TXIndex) removeChainBlocks(chainBlockHashes []*externalapi.DomainHash) error {
+	if len(chainBlockHashes) == 0 {
+		return nil
+	}
+
+	blocksAcceptanceData, err := ti.domain.Consensus().GetBlocksAcceptanceData(chainBlockHashes)
+	if err != nil {
+		return err
+	}
+
+	// Note that blocks that were merged by a removed chain block still include
+	// their transactions, so only the acceptance is reverted here.
+	for i, acceptanceData := range blocksAcceptanceData {
+		acceptingBlockHash := chainBlockHashes[i]
+		for _, blockAcceptanceData := range acceptanceData {
+			for _, transactionAcceptanceData := range blockAcceptanceData.TransactionAcceptanceData {
+				if !transactionAcceptanceData.IsAccepted {
+					continue
+				}
+				transactionID := consensushashing.TransactionID(transactionAcceptanceData.Transaction)
+				ti.store.unaccept(transactionID, acceptingBlockHash)
+			}
+		}
+	}
+
+	return nil
+}
+
+// TransactionLocation returns the blocks that include and accept the given transaction.
+// found is false if the transaction is unknown to the TX index.
+func (ti *TXIndex) TransactionLocation(transactionID *externalapi.DomainTransactionID) (
+	location *TransactionLocation, found bool, err error) {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "TXIndex.TransactionLocation")
+	defer onEnd()
+
+	ti.mutex.Lock()
+	defer ti.mutex.Unlock()
+
+	return ti.store.getTransactionLocation(transactionID)
+}
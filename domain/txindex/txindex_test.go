@@ -0,0 +1,140 @@
+package txindex
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/domain"
+	"github.com/kobradag/kobrad/domain/consensus"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
+	"github.com/kobradag/kobrad/domain/consensus/model/testapi"
+	"github.com/kobradag/kobrad/domain/consensus/utils/consensushashing"
+	"github.com/kobradag/kobrad/domain/consensus/utils/constants"
+	"github.com/kobradag/kobrad/domain/consensus/utils/txscript"
+	"github.com/kobradag/kobrad/domain/dagconfig"
+)
+
+// testDomain is a domain whose consensus is a test consensus, so that the test
+// can build blocks on any parents
+type testDomain struct {
+	domain.Domain
+	consensus testapi.TestConsensus
+}
+
+func (d *testDomain) Consensus() externalapi.Consensus {
+	return d.consensus
+}
+
+func TestTXIndex(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.MainnetParams}
+	consensusConfig.SkipProofOfWork = true
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestTXIndex")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	txIndex, err := New(&testDomain{consensus: tc}, tc.Database())
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+
+	// addChain adds a chain of blocks on top of the given parent, and updates the
+	// TX index with the resulting virtual changes if shouldUpdate is set. The coinbase
+	// transactions of different chains differ by their extra data, since they'd have
+	// the same IDs in blocks of the same blue score otherwise
+	addChain := func(name string, parent *externalapi.DomainHash, length int,
+		shouldUpdate bool) []*externalapi.DomainHash {
+
+		scriptPublicKeyScript, err := txscript.PayToScriptHashScript([]byte{txscript.OpTrue})
+		if err != nil {
+			t.Fatalf("PayToScriptHashScript: %+v", err)
+		}
+		coinbaseData := &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{
+				Script:  scriptPublicKeyScript,
+				Version: constants.MaxScriptPublicKeyVersion,
+			},
+			ExtraData: []byte(name),
+		}
+
+		chain := make([]*externalapi.DomainHash, length)
+		for i := range chain {
+			blockHash, virtualChangeSet, err := tc.AddBlock([]*externalapi.DomainHash{parent}, coinbaseData, nil)
+			if err != nil {
+				t.Fatalf("AddBlock: %+v", err)
+			}
+			if shouldUpdate {
+				err = txIndex.Update(virtualChangeSet)
+				if err != nil {
+					t.Fatalf("Update: %+v", err)
+				}
+			}
+			chain[i] = blockHash
+			parent = blockHash
+		}
+		return chain
+	}
+
+	coinbaseTransactionID := func(blockHash *externalapi.DomainHash) *externalapi.DomainTransactionID {
+		block, _, err := tc.GetBlock(blockHash)
+		if err != nil {
+			t.Fatalf("GetBlock: %+v", err)
+		}
+		return consensushashing.TransactionID(block.Transactions[0])
+	}
+
+	// checkCoinbase checks that the coinbase transaction of the given block is included in it,
+	// and is accepted by expectedAcceptingBlock, or isn't accepted at all if it's nil
+	checkCoinbase := func(blockHash *externalapi.DomainHash, expectedAcceptingBlock *externalapi.DomainHash) {
+		location, found, err := txIndex.TransactionLocation(coinbaseTransactionID(blockHash))
+		if err != nil {
+			t.Fatalf("TransactionLocation: %+v", err)
+		}
+		if !found {
+			t.Fatalf("the coinbase transaction of block %s isn't in the TX index", blockHash)
+		}
+		if len(location.IncludingBlockHashes) != 1 || !location.IncludingBlockHashes[0].Equal(blockHash) {
+			t.Fatalf("expected the coinbase transaction of block %s to be included in it, got %v",
+				blockHash, location.IncludingBlockHashes)
+		}
+		if expectedAcceptingBlock == nil {
+			if location.IsAccepted() {
+				t.Fatalf("expected the coinbase transaction of block %s not to be accepted, but it's accepted by %s",
+					blockHash, location.AcceptingBlockHash)
+			}
+			return
+		}
+		if !location.IsAccepted() || !location.AcceptingBlockHash.Equal(expectedAcceptingBlock) {
+			t.Fatalf("expected the coinbase transaction of block %s to be accepted by %s, got %v",
+				blockHash, expectedAcceptingBlock, location.AcceptingBlockHash)
+		}
+	}
+
+	// Every chain block accepts the coinbase transaction of its selected parent
+	chainA := addChain("a", consensusConfig.GenesisHash, 2, true)
+	checkCoinbase(chainA[0], chainA[1])
+
+	// A longer chain that doesn't merge chainA becomes the selected chain, and the transactions
+	// chainA accepted are no longer accepted
+	chainB := addChain("b", consensusConfig.GenesisHash, 3, true)
+	checkCoinbase(chainA[0], nil)
+	checkCoinbase(chainB[0], chainB[1])
+	checkCoinbase(chainB[1], chainB[2])
+
+	// Blocks that are added while the TX index isn't updated are indexed by Sync, including
+	// a reorg back to chainA
+	chainB = append(chainB, addChain("b", chainB[len(chainB)-1], 1, false)...)
+	chainA = append(chainA, addChain("a", chainA[len(chainA)-1], 4, false)...)
+	err = txIndex.Sync()
+	if err != nil {
+		t.Fatalf("Sync: %+v", err)
+	}
+	for i := 0; i < len(chainA)-1; i++ {
+		checkCoinbase(chainA[i], chainA[i+1])
+	}
+	// Transactions are indexed through the acceptance data of chain blocks, so only the
+	// blocks of chainB that were accepted before the reorg are known to the TX index
+	for i := 0; i < len(chainB)-2; i++ {
+		checkCoinbase(chainB[i], nil)
+	}
+}
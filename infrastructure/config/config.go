@@ -5,6 +5,7 @@
 package config
 
 import (
+	"crypto/ed25519"
 	// _ "embed" is necessary for the go:embed feature.
 	_ "embed"
 	"fmt"
@@ -34,6 +35,7 @@ const (
 	defaultLogFilename         = "kobra.log"
 	defaultErrLogFilename      = "kobra_err.log"
 	defaultTargetOutboundPeers = 8
+	defaultBlockRelayOnlyPeers = 2
 	defaultMaxInboundPeers     = 117
 	defaultBanDuration         = time.Hour * 24
 	defaultBanThreshold        = 100
@@ -48,14 +50,40 @@ const (
 	blockMaxMassMax              = 10_000_000
 	defaultMinRelayTxFee         = 1e-5 // 1 leor per byte
 	defaultMaxOrphanTransactions = 100
+	defaultRBFPolicy             = "optin"
+	defaultTxSelection           = "probabilistic"
 	//DefaultMaxOrphanTxSize is the default maximum size for an orphan transaction
 	DefaultMaxOrphanTxSize  = 100_000
 	defaultSigCacheMaxSize  = 100_000
 	sampleConfigFilename    = "sample-kobra.conf"
 	defaultMaxUTXOCacheSize = 5_000_000_000
-	defaultProtocolVersion  = 5
+	defaultProtocolVersion  = 6
 )
 
+const (
+	// DbTypeLevelDB is the --dbtype value of the leveldb database backend
+	DbTypeLevelDB = "leveldb"
+	// DbTypePebble is the --dbtype value of the pebble database backend
+	DbTypePebble = "pebble"
+
+	defaultDbType = DbTypeLevelDB
+)
+
+const (
+	// OnlyNetIPv4 is the --onlynet value of the IPv4 network
+	OnlyNetIPv4 = "ipv4"
+	// OnlyNetIPv6 is the --onlynet value of the IPv6 network
+	OnlyNetIPv6 = "ipv6"
+	// OnlyNetOnion is the --onlynet value of the Tor onion network
+	OnlyNetOnion = "onion"
+)
+
+var validOnlyNets = map[string]bool{
+	OnlyNetIPv4:  true,
+	OnlyNetIPv6:  true,
+	OnlyNetOnion: true,
+}
+
 var (
 	// DefaultAppDir is the default home directory for kobrad.
 	DefaultAppDir = util.AppDir("kobradag", false)
@@ -81,20 +109,26 @@ type Flags struct {
 	ConfigFile                      string        `short:"C" long:"configfile" description:"Path to configuration file"`
 	AppDir                          string        `short:"b" long:"appdir" description:"Directory to store data"`
 	LogDir                          string        `long:"logdir" description:"Directory to log output."`
-	AddPeers                        []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
-	ConnectPeers                    []string      `long:"connect" description:"Connect only to the specified peers at startup"`
+	AddPeers                        []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup -- Use <identity key>@<address> to only accept the peer over an encrypted connection authenticated with the given identity key"`
+	ConnectPeers                    []string      `long:"connect" description:"Connect only to the specified peers at startup -- Use <identity key>@<address> to only accept the peer over an encrypted connection authenticated with the given identity key"`
 	DisableListen                   bool          `long:"nolisten" description:"Disable listening for incoming connections -- NOTE: Listening is automatically disabled if the --connect or --proxy options are used without also specifying listen interfaces via --listen"`
 	Listeners                       []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 25111, testnet: 16211)"`
 	TargetOutboundPeers             int           `long:"outpeers" description:"Target number of outbound peers"`
+	BlockRelayOnlyPeers             int           `long:"blockrelayonlypeers" description:"Target number of additional outbound peers over which only blocks are relayed (no transactions or addresses)"`
 	MaxInboundPeers                 int           `long:"maxinpeers" description:"Max number of inbound peers"`
 	EnableBanning                   bool          `long:"enablebanning" description:"Enable banning of misbehaving peers"`
 	BanDuration                     time.Duration `long:"banduration" description:"How long to ban misbehaving peers. Valid time units are {s, m, h}. Minimum 1 second"`
 	BanThreshold                    uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
+	ASNMapFile                      string        `long:"asmap" description:"Path to a file that maps IP prefixes to autonomous system numbers. If given, outbound peers are diversified by ASN rather than by /16 (IPv4) or /32 (IPv6) network"`
 	Whitelists                      []string      `long:"whitelist" description:"Add an IP network or IP that will not be banned. (eg. 192.168.1.0/24 or ::1)"`
+	MaxUploadRate                   uint64        `long:"maxuploadrate" description:"Max rate in KiB/s at which IBD data (blocks, headers, UTXO set chunks and proofs) is served to all peers together -- relayed blocks are not throttled (0 for unlimited)"`
+	MaxPeerUploadRate               uint64        `long:"maxpeeruploadrate" description:"Max rate in KiB/s at which IBD data is served to any single peer (0 for unlimited)"`
 	RPCListeners                    []string      `long:"rpclisten" description:"Add an interface/port to listen for RPC connections (default port: 25110, testnet: 16210)"`
 	RPCCert                         string        `long:"rpccert" description:"File containing the certificate file"`
 	RPCKey                          string        `long:"rpckey" description:"File containing the certificate key"`
 	RPCMaxClients                   int           `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
+	RPCJSONListeners                []string      `long:"rpcjsonlisten" description:"Add an interface/port to listen for JSON-RPC connections over WebSocket and HTTP POST (e.g. 127.0.0.1:25120). JSON-RPC is disabled if none are specified"`
+	RPCJSONAllowedOrigins           []string      `long:"rpcjsonorigin" description:"Add a browser origin that is allowed to use the JSON-RPC server (e.g. http://localhost:3000, or * to allow any origin). Requests that carry no Origin header are always allowed"`
 	RPCMaxWebsockets                int           `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
 	RPCMaxConcurrentReqs            int           `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
 	DisableRPC                      bool          `long:"norpc" description:"Disable built-in RPC server"`
@@ -106,13 +140,21 @@ type Flags struct {
 	Proxy                           string        `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
 	ProxyUser                       string        `long:"proxyuser" description:"Username for proxy server"`
 	ProxyPass                       string        `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
-	DbType                          string        `long:"dbtype" description:"Database backend to use for the Block DAG"`
+	DisableP2PEncryption            bool          `long:"nop2pencryption" description:"Disable opportunistic encryption of P2P connections"`
+	OnlyNets                        []string      `long:"onlynet" description:"Only make automatic outbound connections to peers in the given network {ipv4, ipv6, onion} -- May be specified multiple times. Connecting to onion peers requires --proxy. Onion-only nodes skip DNS seeding and bootstrap from the onion peers given with --addpeer or --connect"`
+	TorControl                      string        `long:"torcontrol" description:"Tor control port to use to create an onion service for incoming connections over Tor (eg. 127.0.0.1:9051)"`
+	TorPassword                     string        `long:"torpassword" default-mask:"-" description:"Password for the Tor control port, if it's protected by HashedControlPassword"`
+	DbType                          string        `long:"dbtype" description:"Database backend to use for the Block DAG {leveldb, pebble}"`
+	BootstrapFile                   string        `long:"bootstrap-file" description:"Bootstrap the node from a pruning point snapshot file that was exported with the ExportPruningPointSnapshot RPC command, instead of downloading the pruning point UTXO set from a peer"`
 	Profile                         string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
 	LogLevel                        string        `short:"d" long:"loglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 	Upnp                            bool          `long:"upnp" description:"Use UPnP to map our listening port outside of NAT"`
 	MinRelayTxFee                   float64       `long:"minrelaytxfee" description:"The minimum transaction fee in KODA/kB to be considered a non-zero fee."`
 	MaxOrphanTxs                    uint64        `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	RBFPolicy                       string        `long:"rbfpolicy" description:"Replace-by-fee policy {disabled, optin, all} -- optin only lets transactions submitted over RPC with allowReplacement replace conflicting mempool transactions, all also applies to transactions relayed by peers"`
+	NoMempoolPersistence            bool          `long:"nomempoolpersist" description:"Do not save the mempool on shutdown and load it on startup"`
 	BlockMaxMass                    uint64        `long:"blockmaxmass" description:"Maximum transaction mass to be used when creating a block"`
+	TxSelection                     string        `long:"txselection" description:"How transactions are selected when creating a block {probabilistic, package} -- package prefers transactions whose mempool descendants pay high fees"`
 	UserAgentComments               []string      `long:"uacomment" description:"Comment to add to the user agent -- See BIP 14 for more information."`
 	NoPeerBloomFilters              bool          `long:"nopeerbloomfilters" description:"Disable bloom filtering support"`
 	SigCacheMaxSize                 uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
@@ -122,6 +164,7 @@ type Flags struct {
 	ResetDatabase                   bool          `long:"reset-db" description:"Reset database before starting node. It's needed when switching between subnetworks."`
 	MaxUTXOCacheSize                uint64        `long:"maxutxocachesize" description:"Max size of loaded UTXO into ram from the disk in bytes"`
 	UTXOIndex                       bool          `long:"utxoindex" description:"Enable the UTXO index"`
+	TXIndex                         bool          `long:"txindex" description:"Enable the transaction index"`
 	IsArchivalNode                  bool          `long:"archival" description:"Run as an archival node: don't delete old block data when moving the pruning point (Warning: heavy disk usage)'"`
 	AllowSubmitBlockWhenNotSynced   bool          `long:"allow-submit-block-when-not-synced" hidden:"true" description:"Allow the node to accept blocks from RPC while not synced (this flag is mainly used for testing)"`
 	EnableSanityCheckPruningUTXOSet bool          `long:"enable-sanity-check-pruning-utxo" hidden:"true" description:"When moving the pruning point - check that the utxo set matches the utxo commitment"`
@@ -141,6 +184,13 @@ type Config struct {
 	MinRelayTxFee util.Amount
 	Whitelists    []*net.IPNet
 	SubnetworkID  *externalapi.DomainSubnetworkID // nil in full nodes
+
+	// P2PIdentityKey authenticates the encrypted P2P connections of the node. It's nil if
+	// P2P encryption is disabled.
+	P2PIdentityKey ed25519.PrivateKey
+	// PinnedPeerKeys maps the addresses of peers given with --connect or --addpeer in the form
+	// <identity key>@<address> to the identity keys they must have
+	PinnedPeerKeys map[string]ed25519.PublicKey
 }
 
 // ServiceOptions defines the configuration options for the daemon as a service on
@@ -177,6 +227,7 @@ func defaultFlags() *Flags {
 		ConfigFile:           defaultConfigFile,
 		LogLevel:             defaultLogLevel,
 		TargetOutboundPeers:  defaultTargetOutboundPeers,
+		BlockRelayOnlyPeers:  defaultBlockRelayOnlyPeers,
 		MaxInboundPeers:      defaultMaxInboundPeers,
 		BanDuration:          defaultBanDuration,
 		BanThreshold:         defaultBanThreshold,
@@ -184,10 +235,13 @@ func defaultFlags() *Flags {
 		RPCMaxWebsockets:     defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs: defaultMaxRPCConcurrentReqs,
 		AppDir:               defaultDataDir,
+		DbType:               defaultDbType,
 		RPCKey:               defaultRPCKeyFile,
 		RPCCert:              defaultRPCCertFile,
 		BlockMaxMass:         defaultBlockMaxMass,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
+		RBFPolicy:            defaultRBFPolicy,
+		TxSelection:          defaultTxSelection,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
 		MinRelayTxFee:        defaultMinRelayTxFee,
 		MaxUTXOCacheSize:     defaultMaxUTXOCacheSize,
@@ -334,6 +388,22 @@ func LoadConfig() (*Config, error) {
 	}
 	cfg.LogDir = cleanAndExpandPath(cfg.LogDir)
 
+	if cfg.ASNMapFile != "" {
+		cfg.ASNMapFile = cleanAndExpandPath(cfg.ASNMapFile)
+	}
+
+	// The bootstrap file is imported by its path as given, so it's made absolute
+	// while the working directory is still meaningful
+	if cfg.BootstrapFile != "" {
+		cfg.BootstrapFile, err = filepath.Abs(cleanAndExpandPath(cfg.BootstrapFile))
+		if err != nil {
+			str := "%s: Failed to resolve the bootstrap file path: %s"
+			err := errors.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, err
+		}
+	}
+
 	// Special show command to list supported subsystems and exit.
 	if cfg.LogLevel == "show" {
 		fmt.Println("Supported subsystems", logger.SupportedSubsystems())
@@ -425,6 +495,7 @@ func LoadConfig() (*Config, error) {
 	if len(cfg.ConnectPeers) > 0 {
 		cfg.DisableDNSSeed = true
 		cfg.TargetOutboundPeers = 0
+		cfg.BlockRelayOnlyPeers = 0
 	}
 
 	// Add the default listener if none were specified. The default
@@ -477,6 +548,39 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	// Validate the dbtype.
+	switch cfg.DbType {
+	case DbTypeLevelDB, DbTypePebble:
+	default:
+		str := "%s: The dbtype option must be one of %s or %s -- parsed [%s]"
+		err := errors.Errorf(str, funcName, DbTypeLevelDB, DbTypePebble, cfg.DbType)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, err
+	}
+
+	// Validate the rbfpolicy.
+	switch cfg.RBFPolicy {
+	case "disabled", "optin", "all":
+	default:
+		str := "%s: The rbfpolicy option must be one of disabled, optin or all -- parsed [%s]"
+		err := errors.Errorf(str, funcName, cfg.RBFPolicy)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, err
+	}
+
+	// Validate the txselection.
+	switch cfg.TxSelection {
+	case "probabilistic", "package":
+	default:
+		str := "%s: The txselection option must be one of probabilistic or package -- parsed [%s]"
+		err := errors.Errorf(str, funcName, cfg.TxSelection)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, err
+	}
+
 	// Limit the max block mass to a sane value.
 	if cfg.BlockMaxMass < blockMaxMassMin || cfg.BlockMaxMass >
 		blockMaxMassMax {
@@ -518,6 +622,21 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	// JSON-RPC listeners have no default port, so one must always be specified.
+	if cfg.DisableRPC {
+		cfg.RPCJSONListeners = nil
+	}
+	for _, listener := range cfg.RPCJSONListeners {
+		_, _, err := net.SplitHostPort(listener)
+		if err != nil {
+			str := "%s: The rpcjsonlisten option requires an interface/port pair -- parsed [%s]"
+			err := errors.Errorf(str, funcName, listener)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+	}
+
 	// Disallow --addpeer and --connect used together
 	if len(cfg.AddPeers) > 0 && len(cfg.ConnectPeers) > 0 {
 		str := "%s: --addpeer and --connect can not be used together"
@@ -527,6 +646,33 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	// Take the pinned identity keys out of the peer addresses before they're normalized
+	cfg.PinnedPeerKeys = make(map[string]ed25519.PublicKey)
+	cfg.AddPeers, err = extractPinnedPeerKeys(cfg.AddPeers, cfg.NetParams().DefaultPort, cfg.PinnedPeerKeys)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ConnectPeers, err = extractPinnedPeerKeys(cfg.ConnectPeers, cfg.NetParams().DefaultPort, cfg.PinnedPeerKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.DisableP2PEncryption {
+		cfg.P2PIdentityKey, err = loadOrCreateP2PIdentityKey(cfg.AppDir)
+		if err != nil {
+			str := "%s: Failed to load the P2P identity key: %s"
+			err := errors.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, err
+		}
+	} else if len(cfg.PinnedPeerKeys) > 0 {
+		str := "%s: Peer identity keys can not be pinned when --nop2pencryption is used"
+		err := errors.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, err
+	}
+
 	// Add default port to all added peer addresses if needed and remove
 	// duplicate addresses.
 	cfg.AddPeers, err = network.NormalizeAddresses(cfg.AddPeers,
@@ -541,6 +687,36 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	for i, network := range cfg.OnlyNets {
+		cfg.OnlyNets[i] = strings.ToLower(network)
+		if !validOnlyNets[cfg.OnlyNets[i]] {
+			str := "%s: The onlynet option must be one of ipv4, ipv6 or onion -- parsed [%s]"
+			err := errors.Errorf(str, funcName, network)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+		// Onion addresses can only be reached through Tor
+		if cfg.OnlyNets[i] == OnlyNetOnion && cfg.Proxy == "" {
+			str := "%s: --onlynet=onion requires a Tor SOCKS5 proxy to be set with --proxy"
+			err := errors.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+	}
+
+	if cfg.TorControl != "" {
+		_, _, err := net.SplitHostPort(cfg.TorControl)
+		if err != nil {
+			str := "%s: Tor control address '%s' is invalid: %s"
+			err := errors.Errorf(str, funcName, cfg.TorControl, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, err
+		}
+	}
+
 	// Setup dial and DNS resolution (lookup) functions depending on the
 	// specified options. The default is to use the standard
 	// net.DialTimeout function as well as the system DNS resolver. When a
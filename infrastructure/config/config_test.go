@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -70,3 +72,23 @@ func TestConstants(t *testing.T) {
 		t.Errorf("subnetworks.SubnetworkIDRegistry value was changed from 2, therefore you probably need to update the help text for SubnetworkID")
 	}
 }
+
+func TestExtractPinnedPeerKeys(t *testing.T) {
+	identityKey := "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c"
+	pinnedKeys := make(map[string]ed25519.PublicKey)
+	addresses, err := extractPinnedPeerKeys([]string{identityKey + "@192.168.1.1", "10.0.0.2:16111"}, "16111", pinnedKeys)
+	if err != nil {
+		t.Fatalf("extractPinnedPeerKeys: %s", err)
+	}
+	if addresses[0] != "192.168.1.1:16111" || addresses[1] != "10.0.0.2:16111" {
+		t.Errorf("extractPinnedPeerKeys: unexpected addresses %v", addresses)
+	}
+	if len(pinnedKeys) != 1 || hex.EncodeToString(pinnedKeys["192.168.1.1:16111"]) != identityKey {
+		t.Errorf("extractPinnedPeerKeys: unexpected pinned keys %v", pinnedKeys)
+	}
+
+	_, err = extractPinnedPeerKeys([]string{"abcd@192.168.1.1"}, "16111", pinnedKeys)
+	if err == nil {
+		t.Errorf("extractPinnedPeerKeys: expected an error for a malformed identity key")
+	}
+}
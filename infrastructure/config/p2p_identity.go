@@ -0,0 +1,73 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kobradag/kobrad/util/network"
+	"github.com/pkg/errors"
+)
+
+// p2pIdentityKeyFilename is the name of the file in the app directory that keeps the
+// identity key of the node, with which its encrypted P2P connections are authenticated
+const p2pIdentityKeyFilename = "p2p_identity.key"
+
+// loadOrCreateP2PIdentityKey loads the identity key of the node from the app directory,
+// or creates one if there is none
+func loadOrCreateP2PIdentityKey(appDir string) (ed25519.PrivateKey, error) {
+	path := filepath.Join(appDir, p2pIdentityKeyFilename)
+	serializedSeed, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := hex.DecodeString(strings.TrimSpace(string(serializedSeed)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.Errorf("the P2P identity key in %s is malformed", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, identityKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(appDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(path, []byte(hex.EncodeToString(identityKey.Seed())), 0600)
+	if err != nil {
+		return nil, err
+	}
+	return identityKey, nil
+}
+
+// extractPinnedPeerKeys removes the identity keys from peer addresses in the form
+// <hex identity key>@<host>[:<port>], and adds them to pinnedKeys under the normalized
+// address. The default port is added to addresses without a port.
+func extractPinnedPeerKeys(addresses []string, defaultPort string,
+	pinnedKeys map[string]ed25519.PublicKey) ([]string, error) {
+
+	for i, address := range addresses {
+		separatorIndex := strings.Index(address, "@")
+		if separatorIndex == -1 {
+			continue
+		}
+		identityKey, err := hex.DecodeString(address[:separatorIndex])
+		if err != nil || len(identityKey) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("peer %s has a malformed identity key", address)
+		}
+		normalizedAddress, err := network.NormalizeAddress(address[separatorIndex+1:], defaultPort)
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = normalizedAddress
+		pinnedKeys[normalizedAddress] = identityKey
+	}
+	return addresses, nil
+}
@@ -48,6 +48,24 @@ func NewLevelDB(path string, cacheSizeMiB int) (*LevelDB, error) {
 	return db, nil
 }
 
+// NewReadOnlyLevelDB opens an existing leveldb instance defined by the given
+// path for reading only. Any attempt to write to it returns an error.
+func NewReadOnlyLevelDB(path string, cacheSizeMiB int) (*LevelDB, error) {
+	options := Options()
+	options.BlockCacheCapacity = cacheSizeMiB * opt.MiB
+	options.ReadOnly = true
+	options.ErrorIfMissing = true
+	ldb, err := leveldb.OpenFile(path, &options)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	db := &LevelDB{
+		ldb: ldb,
+	}
+	return db, nil
+}
+
 // Compact compacts the leveldb instance.
 func (db *LevelDB) Compact() error {
 	err := db.ldb.CompactRange(util.Range{Start: nil, Limit: nil})
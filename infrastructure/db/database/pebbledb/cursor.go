@@ -0,0 +1,163 @@
+package pebbledb
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+// PebbleDBCursor is a thin wrapper around native pebble iterators.
+type PebbleDBCursor struct {
+	db             *PebbleDB
+	pebbleIterator *pebble.Iterator
+	bucket         *database.Bucket
+
+	// isPositioned is false until the iterator is first moved. Unlike
+	// leveldb iterators, pebble iterators must be positioned before
+	// Next is called, so the first call to Next moves to the first pair.
+	isPositioned bool
+	isClosed     bool
+}
+
+// Cursor begins a new cursor over the given prefix.
+func (db *PebbleDB) Cursor(bucket *database.Bucket) (database.Cursor, error) {
+	pebbleIterator, err := db.db.NewIter(prefixIterOptions(bucket.Path()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	cursor := &PebbleDBCursor{
+		db:             db,
+		pebbleIterator: pebbleIterator,
+		bucket:         bucket,
+		isPositioned:   false,
+		isClosed:       false,
+	}
+
+	db.openCursorsLock.Lock()
+	defer db.openCursorsLock.Unlock()
+	db.openCursors[cursor] = struct{}{}
+
+	return cursor, nil
+}
+
+// prefixIterOptions returns iterator options that limit the iteration
+// to the keys that start with the given prefix
+func prefixIterOptions(prefix []byte) *pebble.IterOptions {
+	if len(prefix) == 0 {
+		return nil
+	}
+
+	// The upper bound is exclusive, so it's the smallest key that is greater
+	// than all the keys with the given prefix. If there's no such key, the
+	// iteration is only bounded from below.
+	var upperBound []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			upperBound = make([]byte, i+1)
+			copy(upperBound, prefix)
+			upperBound[i]++
+			break
+		}
+	}
+
+	return &pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: upperBound,
+	}
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted. Panics if the cursor is closed.
+func (c *PebbleDBCursor) Next() bool {
+	if c.isClosed {
+		panic("cannot call next on a closed cursor")
+	}
+	if !c.isPositioned {
+		c.isPositioned = true
+		return c.pebbleIterator.First()
+	}
+	return c.pebbleIterator.Next()
+}
+
+// First moves the iterator to the first key/value pair. It returns false if
+// such a pair does not exist. Panics if the cursor is closed.
+func (c *PebbleDBCursor) First() bool {
+	if c.isClosed {
+		panic("cannot call first on a closed cursor")
+	}
+	c.isPositioned = true
+	return c.pebbleIterator.First()
+}
+
+// Seek moves the iterator to the first key/value pair whose key is greater
+// than or equal to the given key. It returns ErrNotFound if such pair does not
+// exist.
+func (c *PebbleDBCursor) Seek(key *database.Key) error {
+	if c.isClosed {
+		return errors.New("cannot seek a closed cursor")
+	}
+
+	c.isPositioned = true
+	found := c.pebbleIterator.SeekGE(key.Bytes())
+	if !found {
+		return errors.Wrapf(database.ErrNotFound, "key %s not found", key)
+	}
+
+	// Use c.pebbleIterator.Key because c.Key removes the prefix from the key
+	currentKey := c.pebbleIterator.Key()
+	if !bytes.Equal(currentKey, key.Bytes()) {
+		return errors.Wrapf(database.ErrNotFound, "key %s not found", key)
+	}
+
+	return nil
+}
+
+// Key returns the key of the current key/value pair, or ErrNotFound if done.
+// Note that the key is trimmed to not include the prefix the cursor was opened
+// with. The caller should not modify the contents of the returned slice, and
+// its contents may change on the next call to Next.
+func (c *PebbleDBCursor) Key() (*database.Key, error) {
+	if c.isClosed {
+		return nil, errors.New("cannot get the key of a closed cursor")
+	}
+	if !c.isPositioned || !c.pebbleIterator.Valid() {
+		return nil, errors.Wrapf(database.ErrNotFound, "cannot get the "+
+			"key of an exhausted cursor")
+	}
+	suffix := bytes.TrimPrefix(c.pebbleIterator.Key(), c.bucket.Path())
+	return c.bucket.Key(suffix), nil
+}
+
+// Value returns the value of the current key/value pair, or ErrNotFound if done.
+// The caller should not modify the contents of the returned slice, and its
+// contents may change on the next call to Next.
+func (c *PebbleDBCursor) Value() ([]byte, error) {
+	if c.isClosed {
+		return nil, errors.New("cannot get the value of a closed cursor")
+	}
+	if !c.isPositioned || !c.pebbleIterator.Valid() {
+		return nil, errors.Wrapf(database.ErrNotFound, "cannot get the "+
+			"value of an exhausted cursor")
+	}
+	return c.pebbleIterator.Value(), nil
+}
+
+// Close releases associated resources.
+func (c *PebbleDBCursor) Close() error {
+	c.db.openCursorsLock.Lock()
+	defer c.db.openCursorsLock.Unlock()
+
+	if c.isClosed {
+		return errors.New("cannot close an already closed cursor")
+	}
+	c.isClosed = true
+	delete(c.db.openCursors, c)
+
+	err := c.pebbleIterator.Close()
+	c.pebbleIterator = nil
+	c.bucket = nil
+	return errors.WithStack(err)
+}
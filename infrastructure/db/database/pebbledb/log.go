@@ -0,0 +1,24 @@
+package pebbledb
+
+import (
+	"fmt"
+
+	"github.com/kobradag/kobrad/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("KSDB")
+
+// pebbleLogger routes pebble's internal logs into the KSDB subsystem
+type pebbleLogger struct{}
+
+// Infof logs pebble's informational messages, which are mostly
+// about flushes and compactions, in debug level
+func (pebbleLogger) Infof(format string, args ...interface{}) {
+	log.Debugf(format, args...)
+}
+
+// Fatalf logs an unrecoverable pebble error and panics
+func (pebbleLogger) Fatalf(format string, args ...interface{}) {
+	log.Criticalf(format, args...)
+	panic(fmt.Sprintf(format, args...))
+}
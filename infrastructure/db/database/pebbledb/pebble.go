@@ -0,0 +1,148 @@
+package pebbledb
+
+import (
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+const mib = 1024 * 1024
+
+// PebbleDB defines a thin wrapper around pebble.
+type PebbleDB struct {
+	db *pebble.DB
+
+	// openCursors are released when the database is closed, since unlike
+	// leveldb, pebble refuses to close while iterators are still open
+	openCursors     map[*PebbleDBCursor]struct{}
+	openCursorsLock sync.Mutex
+}
+
+// NewPebbleDB opens a pebble instance defined by the given path.
+func NewPebbleDB(path string, cacheSizeMiB int) (*PebbleDB, error) {
+	// Open pebble. If it doesn't exist, create it.
+	options := Options()
+	cache := pebble.NewCache(int64(cacheSizeMiB * mib))
+	defer cache.Unref()
+	options.Cache = cache
+	options.MemTableSize = uint64(cacheSizeMiB*mib) / 2
+
+	db, err := pebble.Open(path, options)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &PebbleDB{
+		db:          db,
+		openCursors: make(map[*PebbleDBCursor]struct{}),
+	}, nil
+}
+
+// NewReadOnlyPebbleDB opens an existing pebble instance defined by the given
+// path for reading only. Any attempt to write to it returns an error.
+func NewReadOnlyPebbleDB(path string, cacheSizeMiB int) (*PebbleDB, error) {
+	options := Options()
+	cache := pebble.NewCache(int64(cacheSizeMiB * mib))
+	defer cache.Unref()
+	options.Cache = cache
+	options.ReadOnly = true
+	options.ErrorIfNotExists = true
+
+	db, err := pebble.Open(path, options)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &PebbleDB{
+		db:          db,
+		openCursors: make(map[*PebbleDBCursor]struct{}),
+	}, nil
+}
+
+// Compact compacts the pebble instance.
+func (db *PebbleDB) Compact() error {
+	iterator, err := db.db.NewIter(nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !iterator.First() {
+		return errors.WithStack(iterator.Close())
+	}
+	start := append([]byte{}, iterator.Key()...)
+	iterator.Last()
+	// The end of the range is exclusive, so it's extended past the last key
+	end := append(append([]byte{}, iterator.Key()...), 0)
+	err = iterator.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(db.db.Compact(start, end, true))
+}
+
+// Close closes the pebble instance.
+func (db *PebbleDB) Close() error {
+	db.openCursorsLock.Lock()
+	for cursor := range db.openCursors {
+		err := cursor.pebbleIterator.Close()
+		if err != nil {
+			log.Warnf("Error closing a cursor while closing the database: %s", err)
+		}
+		cursor.isClosed = true
+	}
+	db.openCursors = make(map[*PebbleDBCursor]struct{})
+	db.openCursorsLock.Unlock()
+
+	err := db.db.Close()
+	return errors.WithStack(err)
+}
+
+// Put sets the value for the given key. It overwrites
+// any previous value for that key.
+func (db *PebbleDB) Put(key *database.Key, value []byte) error {
+	err := db.db.Set(key.Bytes(), value, pebble.NoSync)
+	return errors.WithStack(err)
+}
+
+// Get gets the value for the given key. It returns
+// ErrNotFound if the given key does not exist.
+func (db *PebbleDB) Get(key *database.Key) ([]byte, error) {
+	data, closer, err := db.db.Get(key.Bytes())
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil, errors.Wrapf(database.ErrNotFound,
+				"key %s not found", key)
+		}
+		return nil, errors.WithStack(err)
+	}
+	// The returned slice is only valid until the closer is closed
+	value := make([]byte, len(data))
+	copy(value, data)
+	err = closer.Close()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return value, nil
+}
+
+// Has returns true if the database does contains the
+// given key.
+func (db *PebbleDB) Has(key *database.Key) (bool, error) {
+	_, closer, err := db.db.Get(key.Bytes())
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return false, nil
+		}
+		return false, errors.WithStack(err)
+	}
+	return true, errors.WithStack(closer.Close())
+}
+
+// Delete deletes the value for the given key. Will not
+// return an error if the key doesn't exist.
+func (db *PebbleDB) Delete(key *database.Key) error {
+	err := db.db.Delete(key.Bytes(), pebble.NoSync)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,111 @@
+package pebbledb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+)
+
+func prepareDatabaseForTest(t *testing.T, testName string) (db *PebbleDB, teardownFunc func()) {
+	// Create a temp db to run tests against
+	path, err := ioutil.TempDir("", testName)
+	if err != nil {
+		t.Fatalf("%s: TempDir unexpectedly "+
+			"failed: %s", testName, err)
+	}
+	db, err = NewPebbleDB(path, 8)
+	if err != nil {
+		t.Fatalf("%s: NewPebbleDB unexpectedly "+
+			"failed: %s", testName, err)
+	}
+	teardownFunc = func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatalf("%s: Close unexpectedly "+
+				"failed: %s", testName, err)
+		}
+	}
+	return db, teardownFunc
+}
+
+func TestPebbleDBCursorPrefixBounds(t *testing.T) {
+	db, teardownFunc := prepareDatabaseForTest(t, "TestPebbleDBCursorPrefixBounds")
+	defer teardownFunc()
+
+	// Buckets whose paths end with 0xff bytes have no simple upper bound
+	bucket := database.MakeBucket([]byte{0xff})
+	nextBucket := database.MakeBucket([]byte{0xff, 0xff})
+	for i, key := range []*database.Key{
+		bucket.Key([]byte{0x00}),
+		bucket.Key([]byte{0xff}),
+		nextBucket.Key([]byte{0x01}),
+		database.MakeBucket([]byte{0xfe}).Key([]byte{0xff}),
+	} {
+		err := db.Put(key, []byte{byte(i)})
+		if err != nil {
+			t.Fatalf("TestPebbleDBCursorPrefixBounds: Put "+
+				"unexpectedly failed: %s", err)
+		}
+	}
+
+	cursor, err := db.Cursor(nextBucket)
+	if err != nil {
+		t.Fatalf("TestPebbleDBCursorPrefixBounds: Cursor "+
+			"unexpectedly failed: %s", err)
+	}
+	defer cursor.Close()
+
+	var values []byte
+	for cursor.Next() {
+		value, err := cursor.Value()
+		if err != nil {
+			t.Fatalf("TestPebbleDBCursorPrefixBounds: Value "+
+				"unexpectedly failed: %s", err)
+		}
+		values = append(values, value...)
+	}
+	if !bytes.Equal(values, []byte{2}) {
+		t.Fatalf("TestPebbleDBCursorPrefixBounds: unexpected "+
+			"values %v", values)
+	}
+}
+
+func TestPebbleDBCloseWithOpenCursors(t *testing.T) {
+	db, _ := prepareDatabaseForTest(t, "TestPebbleDBCloseWithOpenCursors")
+
+	err := db.Put(database.MakeBucket(nil).Key([]byte("key")), []byte("value"))
+	if err != nil {
+		t.Fatalf("TestPebbleDBCloseWithOpenCursors: Put "+
+			"unexpectedly failed: %s", err)
+	}
+	err = db.Compact()
+	if err != nil {
+		t.Fatalf("TestPebbleDBCloseWithOpenCursors: Compact "+
+			"unexpectedly failed: %s", err)
+	}
+
+	closedCursor, err := db.Cursor(database.MakeBucket(nil))
+	if err != nil {
+		t.Fatalf("TestPebbleDBCloseWithOpenCursors: Cursor "+
+			"unexpectedly failed: %s", err)
+	}
+	err = closedCursor.Close()
+	if err != nil {
+		t.Fatalf("TestPebbleDBCloseWithOpenCursors: Close "+
+			"unexpectedly failed: %s", err)
+	}
+	_, err = db.Cursor(database.MakeBucket(nil))
+	if err != nil {
+		t.Fatalf("TestPebbleDBCloseWithOpenCursors: Cursor "+
+			"unexpectedly failed: %s", err)
+	}
+
+	// Closing the database must succeed even though a cursor was left open
+	err = db.Close()
+	if err != nil {
+		t.Fatalf("TestPebbleDBCloseWithOpenCursors: Close "+
+			"unexpectedly failed: %s", err)
+	}
+}
@@ -0,0 +1,177 @@
+package addressmanager
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/db/database"
+)
+
+// Addresses are kept in two tables, similarly to Bitcoin's addrman: the new table holds
+// addresses we heard of but never connected to, and the tried table holds addresses we
+// successfully connected to. Each table is divided into buckets of limited size, and the
+// bucket of an address is chosen by a keyed hash of its network group and of the network
+// group of the peer that told us about it. This limits the part of the tables an attacker
+// can fill from a few network groups, or by telling us about addresses in a few network
+// groups, no matter how many addresses it has.
+const (
+	newBucketCount   = 64
+	triedBucketCount = 16
+	bucketSize       = 64
+
+	// newBucketsPerSourceGroup is the number of new buckets the addresses from a single
+	// source group can occupy
+	newBucketsPerSourceGroup = 8
+
+	// triedBucketsPerGroup is the number of tried buckets the addresses in a single
+	// network group can occupy
+	triedBucketsPerGroup = 4
+)
+
+var bucketingKeyDatabaseKey = database.MakeBucket([]byte("address-manager")).Key([]byte("bucketing-key"))
+
+// bucketTable is either the new or the tried table
+type bucketTable []map[addressKey]*address
+
+func newBucketTable(bucketCount int) bucketTable {
+	table := make(bucketTable, bucketCount)
+	for i := range table {
+		table[i] = make(map[addressKey]*address)
+	}
+	return table
+}
+
+// loadOrCreateBucketingKey loads the secret key that's used to choose the buckets of
+// addresses, or creates one if there is none. The key is kept secret so that attackers
+// can't choose addresses that fall into the same buckets.
+func (as *addressStore) loadOrCreateBucketingKey() error {
+	bucketingKey, err := as.database.Get(bucketingKeyDatabaseKey)
+	if err == nil {
+		as.bucketingKey = bucketingKey
+		return nil
+	}
+	if !database.IsNotFoundError(err) {
+		return err
+	}
+
+	bucketingKey = make([]byte, sha256.Size)
+	_, err = rand.Read(bucketingKey)
+	if err != nil {
+		return err
+	}
+	as.bucketingKey = bucketingKey
+	return as.database.Put(bucketingKeyDatabaseKey, bucketingKey)
+}
+
+func (as *addressStore) bucketHash(parts ...string) uint64 {
+	hasher := sha256.New()
+	hasher.Write(as.bucketingKey)
+	for _, part := range parts {
+		// Length-prefix every part so that different parts can't be concatenated to the same input
+		var length [8]byte
+		binary.LittleEndian.PutUint64(length[:], uint64(len(part)))
+		hasher.Write(length[:])
+		hasher.Write([]byte(part))
+	}
+	return binary.LittleEndian.Uint64(hasher.Sum(nil))
+}
+
+// sourceGroup returns the network group of the peer that told us about the address.
+// Addresses whose source is unknown are considered to be their own source.
+func (as *addressStore) sourceGroup(address *address) string {
+	if address.source == nil {
+		return as.groupKey(address.netAddress)
+	}
+	return as.groupKey(&appmessage.NetAddress{IP: address.source})
+}
+
+func (as *addressStore) newBucketIndex(address *address) int {
+	group := as.groupKey(address.netAddress)
+	sourceGroup := as.sourceGroup(address)
+	bucketInSourceGroup := as.bucketHash("new", group, sourceGroup) % newBucketsPerSourceGroup
+	return int(as.bucketHash("new", sourceGroup, strconv.FormatUint(bucketInSourceGroup, 10)) % newBucketCount)
+}
+
+func (as *addressStore) triedBucketIndex(key addressKey, address *address) int {
+	group := as.groupKey(address.netAddress)
+	bucketInGroup := as.bucketHash("tried", string(as.serializeAddressKey(key))) % triedBucketsPerGroup
+	return int(as.bucketHash("tried", group, strconv.FormatUint(bucketInGroup, 10)) % triedBucketCount)
+}
+
+func (as *addressStore) bucketOf(key addressKey, address *address) map[addressKey]*address {
+	if address.tried {
+		return as.triedTable[as.triedBucketIndex(key, address)]
+	}
+	return as.newTable[as.newBucketIndex(address)]
+}
+
+// worstInBucket returns the address in the bucket that's the least worth keeping: the
+// one with the most connection failures, and out of those the one that was heard of
+// the longest time ago
+func worstInBucket(bucket map[addressKey]*address) (addressKey, *address) {
+	var worstKey addressKey
+	var worst *address
+	for key, address := range bucket {
+		if worst == nil || address.connectionFailedCount > worst.connectionFailedCount ||
+			(address.connectionFailedCount == worst.connectionFailedCount &&
+				address.netAddress.Timestamp.Before(worst.netAddress.Timestamp)) {
+
+			worstKey, worst = key, address
+		}
+	}
+	return worstKey, worst
+}
+
+// addToBucket adds the address to its bucket, in the new or the tried table according
+// to address.tried. If the bucket is full, the worst address in it is evicted first. An
+// address that's evicted from the tried table is moved back to the new table.
+func (as *addressStore) addToBucket(key addressKey, address *address) error {
+	bucket := as.bucketOf(key, address)
+	if len(bucket) >= bucketSize {
+		evictedKey, evicted := worstInBucket(bucket)
+		delete(bucket, evictedKey)
+
+		if evicted.tried {
+			log.Debugf("Moving address %s from the tried table back to the new table",
+				evicted.netAddress.TCPAddress())
+			evicted.tried = false
+			err := as.addToBucket(evictedKey, evicted)
+			if err != nil {
+				return err
+			}
+			err = as.database.Put(as.notBannedDatabaseKey(evictedKey), as.serializeAddress(evicted))
+			if err != nil {
+				return err
+			}
+		} else {
+			log.Debugf("Evicting address %s from the new table", evicted.netAddress.TCPAddress())
+			delete(as.notBannedAddresses, evictedKey)
+			err := as.database.Delete(as.notBannedDatabaseKey(evictedKey))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	bucket[key] = address
+	return nil
+}
+
+func (as *addressStore) removeFromBucket(key addressKey, address *address) {
+	delete(as.bucketOf(key, address), key)
+}
+
+// markTried moves the address to the tried table
+func (as *addressStore) markTried(key addressKey) error {
+	address, ok := as.notBannedAddresses[key]
+	if !ok || address.tried {
+		return nil
+	}
+
+	as.removeFromBucket(key, address)
+	address.tried = true
+	return as.addToBucket(key, address)
+}
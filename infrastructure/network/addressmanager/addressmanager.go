@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kobradag/kobrad/infrastructure/config"
 	"github.com/kobradag/kobrad/infrastructure/db/database"
 	"github.com/kobradag/kobrad/util/mstime"
 
@@ -17,13 +18,17 @@ import (
 )
 
 const (
-	maxAddresses                   = 4096
 	connectionFailedCountForRemove = 4
+
+	// MaxAnchors is the maximum amount of anchors that are kept by the address manager
+	MaxAnchors = 4
 )
 
 // addressRandomizer is the interface for the randomizer needed for the AddressManager.
 type addressRandomizer interface {
 	RandomAddresses(addresses []*address, count int) []*appmessage.NetAddress
+	RandomAddressesFromDistinctGroups(addresses []*address, count int,
+		groupKey func(*appmessage.NetAddress) string) []*appmessage.NetAddress
 }
 
 // addressKey represents a pair of IP and port, the IP is always in V6 representation
@@ -35,6 +40,9 @@ type addressKey struct {
 type address struct {
 	netAddress            *appmessage.NetAddress
 	connectionFailedCount uint64
+	reputation            float64
+	tried                 bool   // Whether the address is in the tried table rather than in the new table
+	source                net.IP // The IP of the peer that told us about the address, or nil if unknown
 }
 
 type ipv6 [net.IPv6len]byte
@@ -43,6 +51,15 @@ func (i ipv6) equal(other ipv6) bool {
 	return i == other
 }
 
+// Anchor is the address of a long-lived and well-behaved outbound peer that was
+// connected when the node shut down, which is reconnected to first on startup.
+// Reconnecting to the same trusted peers after a restart makes it harder for an
+// attacker to surround the node with its own peers.
+type Anchor struct {
+	NetAddress       *appmessage.NetAddress
+	IsBlockRelayOnly bool
+}
+
 // ErrAddressNotFound is an error returned from some functions when a
 // given address is not found in the address manager
 var ErrAddressNotFound = errors.New("address not found")
@@ -63,28 +80,39 @@ type AddressManager struct {
 	mutex          sync.Mutex
 	cfg            *Config
 	random         addressRandomizer
+	asnMap         *asnMap
 }
 
 // New returns a new Pyrin address manager.
 func New(cfg *Config, database database.Database) (*AddressManager, error) {
-	addressStore, err := newAddressStore(database)
-	if err != nil {
-		return nil, err
-	}
 	localAddresses, err := newLocalAddressManager(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &AddressManager{
-		store:          addressStore,
+	addressManager := &AddressManager{
 		localAddresses: localAddresses,
 		random:         NewAddressRandomize(connectionFailedCountForRemove),
 		cfg:            cfg,
-	}, nil
+	}
+
+	if cfg.ASNMapFile != "" {
+		addressManager.asnMap, err = loadASNMap(cfg.ASNMapFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Loaded %d prefixes from the ASN map %s", len(addressManager.asnMap.prefixes), cfg.ASNMapFile)
+	}
+
+	addressManager.store, err = newAddressStore(database, addressManager.GroupKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return addressManager, nil
 }
 
-func (am *AddressManager) addAddressNoLock(netAddress *appmessage.NetAddress) error {
+func (am *AddressManager) addAddressNoLock(netAddress *appmessage.NetAddress, source *appmessage.NetAddress) error {
 	if !IsRoutable(netAddress, am.cfg.AcceptUnroutable) {
 		return nil
 	}
@@ -92,29 +120,10 @@ func (am *AddressManager) addAddressNoLock(netAddress *appmessage.NetAddress) er
 	key := netAddressKey(netAddress)
 	// We mark `connectionFailedCount` as 0 only after first success
 	address := &address{netAddress: netAddress, connectionFailedCount: 1}
-	err := am.store.add(key, address)
-	if err != nil {
-		return err
+	if source != nil {
+		address.source = source.IP
 	}
-
-	if am.store.notBannedCount() > maxAddresses {
-		allAddresses := am.store.getAllNotBanned()
-
-		maxConnectionFailedCount := uint64(0)
-		toRemove := allAddresses[0]
-		for _, address := range allAddresses[1:] {
-			if address.connectionFailedCount > maxConnectionFailedCount {
-				maxConnectionFailedCount = address.connectionFailedCount
-				toRemove = address
-			}
-		}
-
-		err := am.removeAddressNoLock(toRemove.netAddress)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return am.store.add(key, address)
 }
 
 func (am *AddressManager) removeAddressNoLock(address *appmessage.NetAddress) error {
@@ -127,16 +136,23 @@ func (am *AddressManager) AddAddress(address *appmessage.NetAddress) error {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
-	return am.addAddressNoLock(address)
+	return am.addAddressNoLock(address, nil)
 }
 
 // AddAddresses adds addresses to the address manager
 func (am *AddressManager) AddAddresses(addresses ...*appmessage.NetAddress) error {
+	return am.AddAddressesFromSource(nil, addresses...)
+}
+
+// AddAddressesFromSource adds addresses that the peer at the given source address told
+// us about to the address manager. The network group of the source limits the buckets
+// the addresses can be put in. A nil source means the source is unknown.
+func (am *AddressManager) AddAddressesFromSource(source *appmessage.NetAddress, addresses ...*appmessage.NetAddress) error {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 
 	for _, address := range addresses {
-		err := am.addAddressNoLock(address)
+		err := am.addAddressNoLock(address, source)
 		if err != nil {
 			return err
 		}
@@ -174,7 +190,7 @@ func (am *AddressManager) MarkConnectionFailure(address *appmessage.NetAddress)
 }
 
 // MarkConnectionSuccess notifies the address manager that the given address
-// has successfully connected
+// has successfully connected, and moves it to the tried table
 func (am *AddressManager) MarkConnectionSuccess(address *appmessage.NetAddress) error {
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
@@ -185,9 +201,50 @@ func (am *AddressManager) MarkConnectionSuccess(address *appmessage.NetAddress)
 		return errors.Errorf("address %s is not registered with the address manager", address.TCPAddress())
 	}
 	entry.connectionFailedCount = 0
+	err := am.store.markTried(key)
+	if err != nil {
+		return err
+	}
 	return am.store.updateNotBanned(key, entry)
 }
 
+// SetReputation records the reputation the peer at the given address had when we
+// last disconnected from it. Addresses with a higher reputation are more likely to
+// be returned by RandomAddresses.
+func (am *AddressManager) SetReputation(address *appmessage.NetAddress, reputation float64) error {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	key := netAddressKey(address)
+	entry, ok := am.store.getNotBanned(key)
+	if !ok {
+		return errors.Wrapf(ErrAddressNotFound, "address %s is not registered with the address manager",
+			address.TCPAddress())
+	}
+	entry.reputation = reputation
+	return am.store.updateNotBanned(key, entry)
+}
+
+// SetAnchors replaces the stored anchors with the given ones. Anchors should be
+// given in order of preference, since only the first MaxAnchors of them are kept.
+func (am *AddressManager) SetAnchors(anchors []*Anchor) error {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	if len(anchors) > MaxAnchors {
+		anchors = anchors[:MaxAnchors]
+	}
+	return am.store.setAnchors(anchors)
+}
+
+// Anchors returns the stored anchors
+func (am *AddressManager) Anchors() []*Anchor {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	return am.store.getAnchors()
+}
+
 // Addresses returns all addresses
 func (am *AddressManager) Addresses() []*appmessage.NetAddress {
 	am.mutex.Lock()
@@ -218,6 +275,66 @@ func (am *AddressManager) RandomAddresses(count int, exceptions []*appmessage.Ne
 	return am.random.RandomAddresses(validAddresses, count)
 }
 
+// RandomOutboundAddresses returns count reachable addresses at random that aren't banned and
+// aren't in connectedAddresses, to make outbound connections to. No two of the returned addresses
+// are in the same network group, and none of them is in the network group of any of
+// outboundAddresses, so that the outbound peers are spread over as many network groups
+// as possible.
+func (am *AddressManager) RandomOutboundAddresses(count int, connectedAddresses []*appmessage.NetAddress,
+	outboundAddresses []*appmessage.NetAddress) []*appmessage.NetAddress {
+
+	excludedGroups := make(map[string]struct{}, len(outboundAddresses))
+	for _, outboundAddress := range outboundAddresses {
+		excludedGroups[am.outboundGroupKey(outboundAddress)] = struct{}{}
+	}
+
+	validAddresses := am.notBannedAddressesWithException(connectedAddresses)
+	addressesInNewGroups := make([]*address, 0, len(validAddresses))
+	for _, validAddress := range validAddresses {
+		if !am.IsReachable(validAddress.netAddress) {
+			continue
+		}
+		if _, ok := excludedGroups[am.outboundGroupKey(validAddress.netAddress)]; !ok {
+			addressesInNewGroups = append(addressesInNewGroups, validAddress)
+		}
+	}
+	return am.random.RandomAddressesFromDistinctGroups(addressesInNewGroups, count, am.outboundGroupKey)
+}
+
+// outboundGroupKey returns the network group that limits the outbound peers to one per
+// group. Unroutable addresses, which are only accepted in test networks, are never limited.
+func (am *AddressManager) outboundGroupKey(netAddress *appmessage.NetAddress) string {
+	if !IsRoutable(netAddress, false) {
+		return netAddress.TCPAddress().String()
+	}
+	return am.GroupKey(netAddress)
+}
+
+// IsReachable returns whether automatic outbound connections may be made to the given
+// address: onion addresses can only be reached through a proxy, and if --onlynet was
+// given, only addresses in the given networks are connected to.
+func (am *AddressManager) IsReachable(netAddress *appmessage.NetAddress) bool {
+	network := NetworkOf(netAddress)
+	if network == config.OnlyNetOnion && !am.cfg.CanReachOnion {
+		return false
+	}
+	if len(am.cfg.OnlyNetworks) == 0 {
+		return true
+	}
+	for _, onlyNetwork := range am.cfg.OnlyNetworks {
+		if network == onlyNetwork {
+			return true
+		}
+	}
+	return false
+}
+
+// AddLocalAddress adds netAddress to the local addresses that are advertised to peers,
+// with the given priority
+func (am *AddressManager) AddLocalAddress(netAddress *appmessage.NetAddress, priority AddressPriority) error {
+	return am.localAddresses.addLocalNetAddress(netAddress, priority)
+}
+
 // BestLocalAddress returns the most appropriate local address to use
 // for the given remote address.
 func (am *AddressManager) BestLocalAddress(remoteAddress *appmessage.NetAddress) *appmessage.NetAddress {
@@ -309,6 +309,9 @@ func TestOverfillAddressManager(t *testing.T) {
 	addressManager, teardown := newAddressManagerForTest(t, "TestAddressManager")
 	defer teardown()
 
+	// All the addresses in a single /16 that were received from a single source
+	// are put in the same new bucket
+	source := &appmessage.NetAddress{IP: net.IP{9, 9, 9, 9}}
 	generateTestAddresses := func(amount int) []*appmessage.NetAddress {
 		testAddresses := make([]*appmessage.NetAddress, 0, amount)
 		for i := byte(0); i < 128; i++ {
@@ -327,23 +330,23 @@ func TestOverfillAddressManager(t *testing.T) {
 	}
 
 	// Add a single test address to the address manager
-	testAddress := &appmessage.NetAddress{IP: net.IP{5, 6, 0, 0}, Timestamp: mstime.Now()}
-	err := addressManager.AddAddress(testAddress)
+	testAddress := &appmessage.NetAddress{IP: net.IP{1, 2, 255, 255}, Timestamp: mstime.Now()}
+	err := addressManager.AddAddressesFromSource(source, testAddress)
 	if err != nil {
-		t.Fatalf("AddAddress: %s", err)
+		t.Fatalf("AddAddressesFromSource: %s", err)
 	}
 
-	// Add `maxAddresses-1` addresses to the address manager
-	addresses := generateTestAddresses(maxAddresses - 1)
-	err = addressManager.AddAddresses(addresses...)
+	// Add `bucketSize-1` addresses to the same bucket
+	addresses := generateTestAddresses(bucketSize - 1)
+	err = addressManager.AddAddressesFromSource(source, addresses...)
 	if err != nil {
-		t.Fatalf("AddAddresses: %s", err)
+		t.Fatalf("AddAddressesFromSource: %s", err)
 	}
 
-	// Make sure that it now contains exactly `maxAddresses` entries
+	// Make sure that it now contains exactly `bucketSize` entries
 	returnedAddresses := addressManager.Addresses()
-	if len(returnedAddresses) != maxAddresses {
-		t.Fatalf("Unexpected address amount. Want: %d, got: %d", maxAddresses, len(returnedAddresses))
+	if len(returnedAddresses) != bucketSize {
+		t.Fatalf("Unexpected address amount. Want: %d, got: %d", bucketSize, len(returnedAddresses))
 	}
 
 	// Mark the first test address as a connection failure
@@ -352,16 +355,17 @@ func TestOverfillAddressManager(t *testing.T) {
 		t.Fatalf("MarkConnectionFailure: %s", err)
 	}
 
-	// Add one more address to the address manager
-	err = addressManager.AddAddress(&appmessage.NetAddress{IP: net.IP{7, 8, 0, 0}, Timestamp: mstime.Now()})
+	// Add one more address to the same bucket
+	err = addressManager.AddAddressesFromSource(source,
+		&appmessage.NetAddress{IP: net.IP{1, 2, 200, 0}, Timestamp: mstime.Now()})
 	if err != nil {
-		t.Fatalf("AddAddress: %s", err)
+		t.Fatalf("AddAddressesFromSource: %s", err)
 	}
 
-	// Make sure that it now still contains exactly `maxAddresses` entries
+	// Make sure that it now still contains exactly `bucketSize` entries
 	returnedAddresses = addressManager.Addresses()
-	if len(returnedAddresses) != maxAddresses {
-		t.Fatalf("Unexpected address amount. Want: %d, got: %d", maxAddresses, len(returnedAddresses))
+	if len(returnedAddresses) != bucketSize {
+		t.Fatalf("Unexpected address amount. Want: %d, got: %d", bucketSize, len(returnedAddresses))
 	}
 
 	// Make sure that the first address is no longer in the
@@ -371,4 +375,94 @@ func TestOverfillAddressManager(t *testing.T) {
 			t.Fatalf("Unexpectedly found testAddress returned addresses")
 		}
 	}
+
+	// Addresses in other network groups aren't affected by the full bucket
+	err = addressManager.AddAddressesFromSource(source,
+		&appmessage.NetAddress{IP: net.IP{3, 4, 0, 0}, Timestamp: mstime.Now()})
+	if err != nil {
+		t.Fatalf("AddAddressesFromSource: %s", err)
+	}
+	if len(addressManager.Addresses()) <= bucketSize-1 {
+		t.Fatalf("Unexpected address amount. Want more than %d, got: %d", bucketSize-1, len(addressManager.Addresses()))
+	}
+}
+
+func TestMarkConnectionSuccessMovesToTried(t *testing.T) {
+	addressManager, teardown := newAddressManagerForTest(t, "TestMarkConnectionSuccessMovesToTried")
+	defer teardown()
+
+	testAddress := &appmessage.NetAddress{IP: net.ParseIP("1.2.3.4"), Timestamp: mstime.Now()}
+	err := addressManager.AddAddress(testAddress)
+	if err != nil {
+		t.Fatalf("AddAddress: %s", err)
+	}
+	key := netAddressKey(testAddress)
+	entry, _ := addressManager.store.getNotBanned(key)
+	if entry.tried {
+		t.Fatalf("A new address is unexpectedly in the tried table")
+	}
+
+	err = addressManager.MarkConnectionSuccess(testAddress)
+	if err != nil {
+		t.Fatalf("MarkConnectionSuccess: %s", err)
+	}
+	entry, _ = addressManager.store.getNotBanned(key)
+	if !entry.tried {
+		t.Fatalf("A successfully connected address is unexpectedly not in the tried table")
+	}
+	if _, ok := addressManager.store.bucketOf(key, entry)[key]; !ok {
+		t.Fatalf("A successfully connected address is missing from its tried bucket")
+	}
+
+	// The table of the address should be restored from the database
+	restoredStore, err := newAddressStore(addressManager.store.database, addressManager.GroupKey)
+	if err != nil {
+		t.Fatalf("newAddressStore: %s", err)
+	}
+	restoredEntry, ok := restoredStore.getNotBanned(key)
+	if !ok || !restoredEntry.tried {
+		t.Fatalf("The tried address was not restored to the tried table")
+	}
+}
+
+func TestRandomOutboundAddresses(t *testing.T) {
+	addressManager, teardown := newAddressManagerForTest(t, "TestRandomOutboundAddresses")
+	defer teardown()
+
+	addresses := []*appmessage.NetAddress{
+		{IP: net.ParseIP("1.2.3.4"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("1.2.5.6"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("1.2.7.8"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("5.6.7.8"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("5.6.9.10"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("9.10.11.12"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("2602:100:abcd::1"), Timestamp: mstime.Now()},
+		{IP: net.ParseIP("2602:100:1234::1"), Timestamp: mstime.Now()},
+	}
+	err := addressManager.AddAddresses(addresses...)
+	if err != nil {
+		t.Fatalf("AddAddresses: %s", err)
+	}
+
+	// We already have an outbound peer in 9.10.0.0/16
+	outboundAddresses := []*appmessage.NetAddress{{IP: net.ParseIP("9.10.1.1")}}
+	for i := 0; i < 100; i++ {
+		randomAddresses := addressManager.RandomOutboundAddresses(len(addresses), outboundAddresses, outboundAddresses)
+		if len(randomAddresses) != 3 {
+			t.Fatalf("expected an address from each of 1.2.0.0/16, 5.6.0.0/16 and 2602:100::/32, got %v",
+				randomAddresses)
+		}
+
+		groups := make(map[string]struct{})
+		for _, randomAddress := range randomAddresses {
+			group := addressManager.GroupKey(randomAddress)
+			if group == "9.10.0.0" {
+				t.Fatalf("got an address in the network group of an outbound peer: %s", randomAddress.IP)
+			}
+			if _, ok := groups[group]; ok {
+				t.Fatalf("got more than one address in %s: %v", group, randomAddresses)
+			}
+			groups[group] = struct{}{}
+		}
+	}
 }
@@ -22,6 +22,27 @@ func NewAddressRandomize(maxFailedCount uint64) *AddressRandomize {
 	}
 }
 
+// maxReputationWeightExponent bounds how much more (or less) likely an address is to be
+// picked because of its reputation, to 2^maxReputationWeightExponent times
+const maxReputationWeightExponent = 4
+
+// reputationPointsPerWeightDoubling is the amount of reputation points that doubles the
+// chance of an address to be picked
+const reputationPointsPerWeightDoubling = 10
+
+// reputationWeightFactor returns the factor by which the reputation of an address
+// multiplies its weight
+func reputationWeightFactor(reputation float64) float64 {
+	exponent := reputation / reputationPointsPerWeightDoubling
+	if exponent > maxReputationWeightExponent {
+		exponent = maxReputationWeightExponent
+	}
+	if exponent < -maxReputationWeightExponent {
+		exponent = -maxReputationWeightExponent
+	}
+	return math.Exp2(exponent)
+}
+
 // weightedRand is a help function which returns a random index in the
 // range [0, len(weights)-1] with probability weighted by `weights`
 func weightedRand(weights []float32) int {
@@ -41,15 +62,22 @@ func weightedRand(weights []float32) int {
 	return len(weights) - 1
 }
 
+func (amc *AddressRandomize) weights(addresses []*address) []float32 {
+	weights := make([]float32, 0, len(addresses))
+	for _, addr := range addresses {
+		weight := math.Pow(64, float64(amc.maxFailedCount-addr.connectionFailedCount)) *
+			reputationWeightFactor(addr.reputation)
+		weights = append(weights, float32(weight))
+	}
+	return weights
+}
+
 // RandomAddresses returns count addresses at random from input list
 func (amc *AddressRandomize) RandomAddresses(addresses []*address, count int) []*appmessage.NetAddress {
 	if len(addresses) < count {
 		count = len(addresses)
 	}
-	weights := make([]float32, 0, len(addresses))
-	for _, addr := range addresses {
-		weights = append(weights, float32(math.Pow(64, float64(amc.maxFailedCount-addr.connectionFailedCount))))
-	}
+	weights := amc.weights(addresses)
 	result := make([]*appmessage.NetAddress, 0, count)
 	for count > 0 {
 		i := weightedRand(weights)
@@ -61,3 +89,30 @@ func (amc *AddressRandomize) RandomAddresses(addresses []*address, count int) []
 	}
 	return result
 }
+
+// RandomAddressesFromDistinctGroups returns up to count addresses at random from input
+// list, no two of which have the same group key
+func (amc *AddressRandomize) RandomAddressesFromDistinctGroups(addresses []*address, count int,
+	groupKey func(*appmessage.NetAddress) string) []*appmessage.NetAddress {
+
+	groups := make([]string, len(addresses))
+	for i, addr := range addresses {
+		groups[i] = groupKey(addr.netAddress)
+	}
+	remaining := len(addresses)
+
+	weights := amc.weights(addresses)
+	result := make([]*appmessage.NetAddress, 0, count)
+	for len(result) < count && remaining > 0 {
+		i := weightedRand(weights)
+		result = append(result, addresses[i].netAddress)
+		// Zero all the entries of the same group to avoid selecting it again
+		for j := range addresses {
+			if weights[j] != 0 && groups[j] == groups[i] {
+				weights[j] = 0
+				remaining--
+			}
+		}
+	}
+	return result
+}
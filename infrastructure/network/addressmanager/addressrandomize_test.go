@@ -0,0 +1,56 @@
+package addressmanager
+
+import (
+	"net"
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+)
+
+func TestRandomAddressesPrefersReputableAddresses(t *testing.T) {
+	reputable := &address{
+		netAddress: &appmessage.NetAddress{IP: net.ParseIP("1.2.3.4"), Port: 1234},
+		reputation: 100,
+	}
+	disreputable := &address{
+		netAddress: &appmessage.NetAddress{IP: net.ParseIP("5.6.7.8"), Port: 5678},
+		reputation: -100,
+	}
+
+	randomizer := NewAddressRandomize(connectionFailedCountForRemove)
+	const trials = 1000
+	reputableCount := 0
+	for i := 0; i < trials; i++ {
+		picked := randomizer.RandomAddresses([]*address{disreputable, reputable}, 1)
+		if picked[0] == reputable.netAddress {
+			reputableCount++
+		}
+	}
+
+	// With the maximal reputation factor of 2^4 on each side, the reputable address
+	// is expected to be picked 256 times more often than the disreputable one
+	if reputableCount < trials*9/10 {
+		t.Fatalf("expected the reputable address to be picked in most trials, got %d out of %d",
+			reputableCount, trials)
+	}
+}
+
+func TestReputationWeightFactor(t *testing.T) {
+	tests := []struct {
+		reputation     float64
+		expectedFactor float64
+	}{
+		{reputation: 0, expectedFactor: 1},
+		{reputation: 10, expectedFactor: 2},
+		{reputation: -20, expectedFactor: 0.25},
+		{reputation: 1000, expectedFactor: 16},
+		{reputation: -1000, expectedFactor: 1.0 / 16},
+	}
+	for _, test := range tests {
+		factor := reputationWeightFactor(test.reputation)
+		if factor != test.expectedFactor {
+			t.Errorf("expected the factor of reputation %f to be %f, got %f",
+				test.reputation, test.expectedFactor, factor)
+		}
+	}
+}
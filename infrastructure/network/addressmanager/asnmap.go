@@ -0,0 +1,120 @@
+package addressmanager
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// asnMapPrefix is an IP prefix in an ASN map. IPv4 prefixes are represented as
+// IPv4-mapped IPv6 prefixes.
+type asnMapPrefix struct {
+	ip   ipv6
+	ones int
+}
+
+// asnMap maps IP prefixes to the numbers of the autonomous systems that announce them.
+// Grouping addresses by ASN rather than by /16 or /32 makes it harder for an attacker
+// with addresses in many prefixes of a single hosting provider to occupy all the
+// outbound connections.
+type asnMap struct {
+	prefixes map[asnMapPrefix]uint32
+
+	// The distinct prefix lengths in the map, from the longest to the shortest
+	prefixLengths []int
+}
+
+// loadASNMap loads an ASN map from the file at the given path. See parseASNMap
+// for the file format.
+func loadASNMap(path string) (*asnMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open ASN map file %s", path)
+	}
+	defer file.Close()
+
+	asnMap, err := parseASNMap(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse ASN map file %s", path)
+	}
+	return asnMap, nil
+}
+
+// parseASNMap parses an ASN map where every line is an IP prefix in CIDR notation
+// followed by the number of the autonomous system that announces it, optionally
+// prefixed by "AS". For example:
+//
+//	# Comments and empty lines are ignored
+//	1.2.0.0/16 AS13335
+//	2001:db8::/32 64496
+func parseASNMap(reader io.Reader) (*asnMap, error) {
+	asnMap := &asnMap{prefixes: make(map[asnMapPrefix]uint32)}
+	prefixLengths := make(map[int]struct{})
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("line %d: expected a prefix and an ASN, got %q", lineNumber, line)
+		}
+		_, ipNet, err := net.ParseCIDR(fields[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: invalid prefix", lineNumber)
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(fields[1]), "AS"), 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: invalid ASN", lineNumber)
+		}
+
+		ones, bits := ipNet.Mask.Size()
+		if bits == net.IPv4len*8 {
+			ones += (net.IPv6len - net.IPv4len) * 8
+		}
+		prefix := asnMapPrefix{ones: ones}
+		copy(prefix.ip[:], ipNet.IP.To16().Mask(net.CIDRMask(ones, net.IPv6len*8)))
+
+		asnMap.prefixes[prefix] = uint32(asn)
+		prefixLengths[ones] = struct{}{}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	for prefixLength := range prefixLengths {
+		asnMap.prefixLengths = append(asnMap.prefixLengths, prefixLength)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(asnMap.prefixLengths)))
+
+	return asnMap, nil
+}
+
+// lookup returns the ASN of the longest prefix that contains the given IP
+func (m *asnMap) lookup(ip net.IP) (asn uint32, ok bool) {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return 0, false
+	}
+	for _, prefixLength := range m.prefixLengths {
+		prefix := asnMapPrefix{ones: prefixLength}
+		copy(prefix.ip[:], ip16.Mask(net.CIDRMask(prefixLength, net.IPv6len*8)))
+		asn, ok := m.prefixes[prefix]
+		if ok {
+			return asn, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package addressmanager
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/config"
+	"github.com/kobradag/kobrad/infrastructure/db/database/ldb"
+)
+
+const testASNMap = `
+# A comment
+1.2.0.0/16 AS100
+1.2.3.0/24 AS200
+5.6.0.0/15 300
+2602:100::/32 AS400
+`
+
+func TestParseASNMap(t *testing.T) {
+	asnMap, err := parseASNMap(strings.NewReader(testASNMap))
+	if err != nil {
+		t.Fatalf("parseASNMap: %s", err)
+	}
+
+	tests := []struct {
+		ip          string
+		expectedASN uint32
+		expectedOK  bool
+	}{
+		{ip: "1.2.4.5", expectedASN: 100, expectedOK: true},
+		{ip: "1.2.3.4", expectedASN: 200, expectedOK: true},
+		{ip: "5.7.1.2", expectedASN: 300, expectedOK: true},
+		{ip: "2602:100:abcd::1", expectedASN: 400, expectedOK: true},
+		{ip: "9.9.9.9", expectedOK: false},
+		{ip: "2602:101::1", expectedOK: false},
+	}
+	for _, test := range tests {
+		asn, ok := asnMap.lookup(net.ParseIP(test.ip))
+		if ok != test.expectedOK || asn != test.expectedASN {
+			t.Errorf("lookup(%s): expected (%d, %t), got (%d, %t)",
+				test.ip, test.expectedASN, test.expectedOK, asn, ok)
+		}
+	}
+
+	invalidASNMaps := []string{
+		"1.2.0.0/16",
+		"1.2.0.0 AS100",
+		"1.2.0.0/16 ASX",
+		"1.2.0.0/16 AS100 extra",
+	}
+	for _, invalidASNMap := range invalidASNMaps {
+		_, err := parseASNMap(strings.NewReader(invalidASNMap))
+		if err == nil {
+			t.Errorf("parseASNMap(%q): expected an error", invalidASNMap)
+		}
+	}
+}
+
+func TestGroupKeyWithASNMap(t *testing.T) {
+	asnMapPath := filepath.Join(t.TempDir(), "asmap.txt")
+	err := os.WriteFile(asnMapPath, []byte(testASNMap), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	database, err := ldb.NewLevelDB(t.TempDir(), 8)
+	if err != nil {
+		t.Fatalf("could not create a database: %s", err)
+	}
+	defer database.Close()
+
+	cfg := NewConfig(config.DefaultConfig())
+	cfg.ASNMapFile = asnMapPath
+	addressManager, err := New(cfg, database)
+	if err != nil {
+		t.Fatalf("error creating address manager: %s", err)
+	}
+
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{ip: "1.2.4.5", expected: "AS100"},
+		{ip: "1.2.3.4", expected: "AS200"},
+		{ip: "5.6.1.2", expected: "AS300"},
+		{ip: "5.7.1.2", expected: "AS300"},
+		// Addresses that aren't in the map fall back to their /16
+		{ip: "9.9.9.9", expected: "9.9.0.0"},
+		{ip: "127.0.0.1", expected: "local"},
+	}
+	for _, test := range tests {
+		key := addressManager.GroupKey(&appmessage.NetAddress{IP: net.ParseIP(test.ip)})
+		if key != test.expected {
+			t.Errorf("GroupKey(%s): expected %s, got %s", test.ip, test.expected, key)
+		}
+	}
+}
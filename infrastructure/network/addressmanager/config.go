@@ -13,6 +13,9 @@ type Config struct {
 	ExternalIPs      []string
 	Listeners        []string
 	Lookup           func(string) ([]net.IP, error)
+	ASNMapFile       string
+	OnlyNetworks     []string
+	CanReachOnion    bool
 }
 
 // NewConfig returns a new address manager Config.
@@ -23,5 +26,8 @@ func NewConfig(cfg *config.Config) *Config {
 		ExternalIPs:      cfg.ExternalIPs,
 		Listeners:        cfg.Listeners,
 		Lookup:           cfg.Lookup,
+		ASNMapFile:       cfg.ASNMapFile,
+		OnlyNetworks:     cfg.OnlyNets,
+		CanReachOnion:    cfg.Proxy != "",
 	}
 }
@@ -30,6 +30,10 @@ const (
 
 	// ManualPrio signifies the address was provided by --externalip.
 	ManualPrio
+
+	// OnionPrio signifies the address is of an onion service that was created
+	// through the Tor control port.
+	OnionPrio
 )
 
 type localAddress struct {
@@ -322,6 +326,15 @@ func reachabilityFrom(localAddress, remoteAddress *appmessage.NetAddress, accept
 			IsLocal(na) || (IsRFC4193(na)))
 	}
 
+	// Onion addresses are only advertised to onion peers, and onion peers are only told
+	// about onion addresses, so that the onion address of the node can't be linked to its IP
+	if IsOnionV3(remoteAddress) || IsOnionV3(localAddress) {
+		if IsOnionV3(remoteAddress) && IsOnionV3(localAddress) {
+			return Private
+		}
+		return Unreachable
+	}
+
 	if !IsRoutable(remoteAddress) {
 		return Unreachable
 	}
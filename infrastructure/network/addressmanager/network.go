@@ -6,8 +6,10 @@ package addressmanager
 
 import (
 	"net"
+	"strconv"
 
 	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/config"
 )
 
 var (
@@ -97,6 +99,22 @@ func IsIPv4(na *appmessage.NetAddress) bool {
 	return na.IP.To4() != nil
 }
 
+// IsOnionV3 returns whether or not the passed address is a Tor v3 onion address
+func IsOnionV3(na *appmessage.NetAddress) bool {
+	return na.IsOnionV3()
+}
+
+// NetworkOf returns the network of the passed address, as named by the --onlynet option
+func NetworkOf(na *appmessage.NetAddress) string {
+	if IsOnionV3(na) {
+		return config.OnlyNetOnion
+	}
+	if IsIPv4(na) {
+		return config.OnlyNetIPv4
+	}
+	return config.OnlyNetIPv6
+}
+
 // IsLocal returns whether or not the given address is a local address.
 func IsLocal(na *appmessage.NetAddress) bool {
 	return na.IP.IsLoopback() || zero4Net.Contains(na.IP)
@@ -208,6 +226,11 @@ func IsValid(na *appmessage.NetAddress) bool {
 // the public internet. This is true as long as the address is valid and is not
 // in any reserved ranges.
 func IsRoutable(na *appmessage.NetAddress, acceptUnroutable bool) bool {
+	// The OnionCat IPs of onion addresses are in the RFC4193 range, but onion
+	// addresses are reachable through Tor
+	if IsOnionV3(na) {
+		return true
+	}
 	if acceptUnroutable {
 		return !IsLocal(na)
 	}
@@ -221,14 +244,25 @@ func IsRoutable(na *appmessage.NetAddress, acceptUnroutable bool) bool {
 // GroupKey returns a string representing the network group an address is part
 // of. This is the /16 for IPv4, the /32 (/36 for he.net) for IPv6, the string
 // "local" for a local address, and the string "unroutable" for an unroutable
-// address.
+// address. If an ASN map was loaded, routable addresses in it are grouped by
+// their ASN instead, in the form "AS<number>". Onion addresses are grouped by the
+// first 4 bits of their public key, in the form "onion:<hex digit>", since
+// anyone can create as many of them as they want.
 func (am *AddressManager) GroupKey(na *appmessage.NetAddress) string {
+	if IsOnionV3(na) {
+		return "onion:" + strconv.FormatUint(uint64(na.OnionV3PublicKey[0]>>4), 16)
+	}
 	if IsLocal(na) {
 		return "local"
 	}
 	if !IsRoutable(na, am.cfg.AcceptUnroutable) {
 		return "unroutable"
 	}
+	if am.asnMap != nil {
+		if asn, ok := am.asnMap.lookup(na.IP); ok {
+			return "AS" + strconv.FormatUint(uint64(asn), 10)
+		}
+	}
 	if IsIPv4(na) {
 		return na.IP.Mask(net.CIDRMask(16, 32)).String()
 	}
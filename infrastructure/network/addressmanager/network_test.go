@@ -204,3 +204,59 @@ func TestGroupKey(t *testing.T) {
 		}
 	}
 }
+
+func TestOnionV3Addresses(t *testing.T) {
+	amgr, teardown := newAddressManagerForTest(t, "TestOnionV3Addresses")
+	defer teardown()
+
+	onionAddress, err := appmessage.ParseNetAddress("duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion:8333")
+	if err != nil {
+		t.Fatalf("ParseNetAddress: %s", err)
+	}
+	ipv4Address := appmessage.NewNetAddressIPPort(net.ParseIP("12.1.2.3"), 8333)
+	ipv6Address := appmessage.NewNetAddressIPPort(net.ParseIP("2602:100::1"), 8333)
+
+	if !IsRoutable(onionAddress, false) {
+		t.Errorf("IsRoutable: onion addresses should be routable")
+	}
+	if key := amgr.GroupKey(onionAddress); key != "onion:1" {
+		t.Errorf("GroupKey: got '%s', want 'onion:1'", key)
+	}
+
+	tests := []struct {
+		name          string
+		onlyNetworks  []string
+		canReachOnion bool
+		expected      map[*appmessage.NetAddress]bool
+	}{
+		{
+			name:     "no proxy",
+			expected: map[*appmessage.NetAddress]bool{onionAddress: false, ipv4Address: true, ipv6Address: true},
+		},
+		{
+			name:          "proxy",
+			canReachOnion: true,
+			expected:      map[*appmessage.NetAddress]bool{onionAddress: true, ipv4Address: true, ipv6Address: true},
+		},
+		{
+			name:          "onlynet onion",
+			onlyNetworks:  []string{"onion"},
+			canReachOnion: true,
+			expected:      map[*appmessage.NetAddress]bool{onionAddress: true, ipv4Address: false, ipv6Address: false},
+		},
+		{
+			name:         "onlynet ipv4",
+			onlyNetworks: []string{"ipv4"},
+			expected:     map[*appmessage.NetAddress]bool{onionAddress: false, ipv4Address: true, ipv6Address: false},
+		},
+	}
+	for _, test := range tests {
+		amgr.cfg.OnlyNetworks = test.onlyNetworks
+		amgr.cfg.CanReachOnion = test.canReachOnion
+		for netAddress, expected := range test.expected {
+			if isReachable := amgr.IsReachable(netAddress); isReachable != expected {
+				t.Errorf("%s: IsReachable(%s): got %t, want %t", test.name, netAddress, isReachable, expected)
+			}
+		}
+	}
+}
@@ -1,30 +1,46 @@
 package addressmanager
 
 import (
+	"bytes"
 	"encoding/binary"
 	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/infrastructure/db/database"
 	"github.com/kobradag/kobrad/util/mstime"
 	"github.com/pkg/errors"
+	"math"
 	"net"
 )
 
 var notBannedAddressBucket = database.MakeBucket([]byte("not-banned-addresses"))
 var bannedAddressBucket = database.MakeBucket([]byte("banned-addresses"))
+var anchorBucket = database.MakeBucket([]byte("anchors"))
 
 type addressStore struct {
 	database           database.Database
 	notBannedAddresses map[addressKey]*address
 	bannedAddresses    map[ipv6]*address
+	anchors            []*Anchor
+
+	groupKey     func(*appmessage.NetAddress) string
+	bucketingKey []byte
+	newTable     bucketTable
+	triedTable   bucketTable
 }
 
-func newAddressStore(database database.Database) (*addressStore, error) {
+func newAddressStore(database database.Database, groupKey func(*appmessage.NetAddress) string) (*addressStore, error) {
 	addressStore := &addressStore{
 		database:           database,
 		notBannedAddresses: map[addressKey]*address{},
 		bannedAddresses:    map[ipv6]*address{},
+		groupKey:           groupKey,
+		newTable:           newBucketTable(newBucketCount),
+		triedTable:         newBucketTable(triedBucketCount),
+	}
+	err := addressStore.loadOrCreateBucketingKey()
+	if err != nil {
+		return nil, err
 	}
-	err := addressStore.restoreNotBannedAddresses()
+	err = addressStore.restoreNotBannedAddresses()
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +48,10 @@ func newAddressStore(database database.Database) (*addressStore, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = addressStore.restoreAnchors()
+	if err != nil {
+		return nil, err
+	}
 
 	log.Infof("Loaded %d addresses and %d banned addresses",
 		len(addressStore.notBannedAddresses), len(addressStore.bannedAddresses))
@@ -59,6 +79,10 @@ func (as *addressStore) restoreNotBannedAddresses() error {
 		}
 		netAddress := as.deserializeAddress(serializedNetAddress)
 		as.notBannedAddresses[key] = netAddress
+		err = as.addToBucket(key, netAddress)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -87,6 +111,22 @@ func (as *addressStore) restoreBannedAddresses() error {
 	return nil
 }
 
+func (as *addressStore) restoreAnchors() error {
+	cursor, err := as.database.Cursor(anchorBucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		serializedAnchor, err := cursor.Value()
+		if err != nil {
+			return err
+		}
+		as.anchors = append(as.anchors, as.deserializeAnchor(serializedAnchor))
+	}
+	return nil
+}
+
 func (as *addressStore) notBannedCount() int {
 	return len(as.notBannedAddresses)
 }
@@ -96,6 +136,10 @@ func (as *addressStore) add(key addressKey, address *address) error {
 		return nil
 	}
 
+	err := as.addToBucket(key, address)
+	if err != nil {
+		return err
+	}
 	as.notBannedAddresses[key] = address
 
 	databaseKey := as.notBannedDatabaseKey(key)
@@ -110,6 +154,7 @@ func (as *addressStore) updateNotBanned(key addressKey, address *address) error
 	}
 
 	as.notBannedAddresses[key] = address
+	as.bucketOf(key, address)[key] = address
 
 	databaseKey := as.notBannedDatabaseKey(key)
 	serializedAddress := as.serializeAddress(address)
@@ -122,6 +167,9 @@ func (as *addressStore) getNotBanned(key addressKey) (*address, bool) {
 }
 
 func (as *addressStore) remove(key addressKey) error {
+	if address, ok := as.notBannedAddresses[key]; ok {
+		as.removeFromBucket(key, address)
+	}
 	delete(as.notBannedAddresses, key)
 
 	databaseKey := as.notBannedDatabaseKey(key)
@@ -198,6 +246,32 @@ func (as *addressStore) getBanned(key addressKey) (*address, bool) {
 	return bannedAddress, ok
 }
 
+func (as *addressStore) getAnchors() []*Anchor {
+	anchors := make([]*Anchor, len(as.anchors))
+	copy(anchors, as.anchors)
+	return anchors
+}
+
+// setAnchors replaces the stored anchors with the given ones
+func (as *addressStore) setAnchors(anchors []*Anchor) error {
+	for i := range as.anchors {
+		err := as.database.Delete(as.anchorDatabaseKey(i))
+		if err != nil {
+			return err
+		}
+	}
+	as.anchors = nil
+
+	for i, anchor := range anchors {
+		err := as.database.Put(as.anchorDatabaseKey(i), as.serializeAnchor(anchor))
+		if err != nil {
+			return err
+		}
+		as.anchors = append(as.anchors, anchor)
+	}
+	return nil
+}
+
 // netAddressKeys returns a key of the ip address to use it in maps.
 func netAddressesKeys(netAddresses []*appmessage.NetAddress) map[addressKey]bool {
 	result := make(map[addressKey]bool, len(netAddresses))
@@ -217,6 +291,12 @@ func (as *addressStore) bannedDatabaseKey(key addressKey) *database.Key {
 	return bannedAddressBucket.Key(key.address[:])
 }
 
+func (as *addressStore) anchorDatabaseKey(index int) *database.Key {
+	serializedIndex := make([]byte, 2)
+	binary.BigEndian.PutUint16(serializedIndex, uint16(index))
+	return anchorBucket.Key(serializedIndex)
+}
+
 func (as *addressStore) serializeAddressKey(key addressKey) []byte {
 	serializedSize := 16 + 2 // ipv6 + port
 	serializedKey := make([]byte, serializedSize)
@@ -240,13 +320,24 @@ func (as *addressStore) deserializeAddressKey(serializedKey []byte) addressKey {
 }
 
 func (as *addressStore) serializeAddress(address *address) []byte {
-	serializedSize := 16 + 2 + 8 + 8 // ipv6 + port + timestamp + connectionFailedCount
+	// ipv6 + port + timestamp + connectionFailedCount + reputation + tried + source ipv6 + onion v3 public key
+	serializedSize := 16 + 2 + 8 + 8 + 8 + 1 + 16 + appmessage.OnionV3PublicKeySize
 	serializedNetAddress := make([]byte, serializedSize)
 
 	copy(serializedNetAddress[:], address.netAddress.IP.To16()[:])
 	binary.LittleEndian.PutUint16(serializedNetAddress[16:], address.netAddress.Port)
 	binary.LittleEndian.PutUint64(serializedNetAddress[18:], uint64(address.netAddress.Timestamp.UnixMilliseconds()))
 	binary.LittleEndian.PutUint64(serializedNetAddress[26:], uint64(address.connectionFailedCount))
+	binary.LittleEndian.PutUint64(serializedNetAddress[34:], math.Float64bits(address.reputation))
+	if address.tried {
+		serializedNetAddress[42] = 1
+	}
+	if address.source != nil {
+		copy(serializedNetAddress[43:], address.source.To16())
+	}
+	if address.netAddress.IsOnionV3() {
+		copy(serializedNetAddress[59:], address.netAddress.OnionV3PublicKey)
+	}
 
 	return serializedNetAddress
 }
@@ -259,12 +350,61 @@ func (as *addressStore) deserializeAddress(serializedAddress []byte) *address {
 	timestamp := mstime.UnixMilliseconds(int64(binary.LittleEndian.Uint64(serializedAddress[18:])))
 	connectionFailedCount := binary.LittleEndian.Uint64(serializedAddress[26:])
 
+	// Addresses that were stored before reputations were introduced don't have one
+	reputation := float64(0)
+	if len(serializedAddress) >= 42 {
+		reputation = math.Float64frombits(binary.LittleEndian.Uint64(serializedAddress[34:]))
+	}
+
+	// Addresses that were stored before the address tables were introduced are put in the
+	// new table, and are considered to be their own source
+	tried := false
+	var source net.IP
+	if len(serializedAddress) >= 59 {
+		tried = serializedAddress[42] == 1
+		if !net.IP(serializedAddress[43:59]).IsUnspecified() {
+			source = make(net.IP, 16)
+			copy(source, serializedAddress[43:59])
+		}
+	}
+
+	// Addresses that were stored before onion addresses were supported are never onion addresses
+	var onionV3PublicKey []byte
+	if len(serializedAddress) >= 59+appmessage.OnionV3PublicKeySize {
+		serializedOnionV3PublicKey := serializedAddress[59 : 59+appmessage.OnionV3PublicKeySize]
+		if !bytes.Equal(serializedOnionV3PublicKey, make([]byte, appmessage.OnionV3PublicKeySize)) {
+			onionV3PublicKey = make([]byte, appmessage.OnionV3PublicKeySize)
+			copy(onionV3PublicKey, serializedOnionV3PublicKey)
+		}
+	}
+
 	return &address{
 		netAddress: &appmessage.NetAddress{
-			IP:        ip,
-			Port:      port,
-			Timestamp: timestamp,
+			IP:               ip,
+			Port:             port,
+			Timestamp:        timestamp,
+			OnionV3PublicKey: onionV3PublicKey,
 		},
 		connectionFailedCount: connectionFailedCount,
+		reputation:            reputation,
+		tried:                 tried,
+		source:                source,
+	}
+}
+
+func (as *addressStore) serializeAnchor(anchor *Anchor) []byte {
+	serializedAnchor := as.serializeAddress(&address{netAddress: anchor.NetAddress})
+	isBlockRelayOnly := byte(0)
+	if anchor.IsBlockRelayOnly {
+		isBlockRelayOnly = 1
+	}
+	return append(serializedAnchor, isBlockRelayOnly)
+}
+
+func (as *addressStore) deserializeAnchor(serializedAnchor []byte) *Anchor {
+	address := as.deserializeAddress(serializedAnchor)
+	return &Anchor{
+		NetAddress:       address.netAddress,
+		IsBlockRelayOnly: serializedAnchor[len(serializedAnchor)-1] == 1,
 	}
 }
@@ -36,6 +36,9 @@ func TestAddressSerialization(t *testing.T) {
 			Timestamp: mstime.Now(),
 		},
 		connectionFailedCount: 98465,
+		reputation:            -12.5,
+		tried:                 true,
+		source:                net.ParseIP("1.2.3.4"),
 	}
 
 	serializedTestAddress := addressStore.serializeAddress(testAddress)
@@ -45,3 +48,100 @@ func TestAddressSerialization(t *testing.T) {
 			"testAddress:%+v\ndeserializedTestAddress:%+v", testAddress, deserializedTestAddress)
 	}
 }
+
+func TestOnionAddressSerialization(t *testing.T) {
+	addressManager, teardown := newAddressManagerForTest(t, "TestOnionAddressSerialization")
+	defer teardown()
+	addressStore := addressManager.store
+
+	netAddress, err := appmessage.ParseNetAddress("duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion:12345")
+	if err != nil {
+		t.Fatalf("ParseNetAddress: %s", err)
+	}
+	netAddress.Timestamp = mstime.Now()
+	testAddress := &address{netAddress: netAddress}
+
+	serializedTestAddress := addressStore.serializeAddress(testAddress)
+	deserializedTestAddress := addressStore.deserializeAddress(serializedTestAddress)
+	if !reflect.DeepEqual(testAddress, deserializedTestAddress) {
+		t.Fatalf("testAddress and deserializedTestAddress are not equal\n"+
+			"testAddress:%+v\ndeserializedTestAddress:%+v", testAddress, deserializedTestAddress)
+	}
+}
+
+func TestAddressWithoutReputationDeserialization(t *testing.T) {
+	addressManager, teardown := newAddressManagerForTest(t, "TestAddressWithoutReputationDeserialization")
+	defer teardown()
+	addressStore := addressManager.store
+
+	testAddress := &address{
+		netAddress: &appmessage.NetAddress{
+			IP:        net.ParseIP("2602:100:abcd::102"),
+			Port:      12345,
+			Timestamp: mstime.Now(),
+		},
+		connectionFailedCount: 3,
+		reputation:            7,
+	}
+
+	// Addresses that were stored before reputations were introduced lack the last 8 bytes
+	serializedTestAddress := addressStore.serializeAddress(testAddress)
+	deserializedTestAddress := addressStore.deserializeAddress(serializedTestAddress[:34])
+	if deserializedTestAddress.reputation != 0 {
+		t.Fatalf("expected an address without a reputation to have a reputation of 0, got %f",
+			deserializedTestAddress.reputation)
+	}
+	if deserializedTestAddress.connectionFailedCount != testAddress.connectionFailedCount {
+		t.Fatalf("expected a connectionFailedCount of %d, got %d",
+			testAddress.connectionFailedCount, deserializedTestAddress.connectionFailedCount)
+	}
+}
+
+func TestAnchorsPersistence(t *testing.T) {
+	addressManager, teardown := newAddressManagerForTest(t, "TestAnchorsPersistence")
+	defer teardown()
+
+	anchors := make([]*Anchor, MaxAnchors+1)
+	for i := range anchors {
+		anchors[i] = &Anchor{
+			NetAddress: &appmessage.NetAddress{
+				IP:        net.ParseIP("2602:100:abcd::102"),
+				Port:      uint16(12345 + i),
+				Timestamp: mstime.Now(),
+			},
+			IsBlockRelayOnly: i%2 == 0,
+		}
+	}
+
+	err := addressManager.SetAnchors(anchors)
+	if err != nil {
+		t.Fatalf("SetAnchors: %s", err)
+	}
+	if !reflect.DeepEqual(addressManager.Anchors(), anchors[:MaxAnchors]) {
+		t.Fatalf("expected the anchors to be the first %d given anchors, got %+v", MaxAnchors, addressManager.Anchors())
+	}
+
+	// The anchors should be restored from the database
+	restoredStore, err := newAddressStore(addressManager.store.database, addressManager.GroupKey)
+	if err != nil {
+		t.Fatalf("newAddressStore: %s", err)
+	}
+	if !reflect.DeepEqual(restoredStore.getAnchors(), anchors[:MaxAnchors]) {
+		t.Fatalf("restored anchors are not equal to the stored ones\n"+
+			"anchors:%+v\nrestored anchors:%+v", anchors[:MaxAnchors], restoredStore.getAnchors())
+	}
+
+	// Setting fewer anchors should remove the previous ones from the database
+	err = addressManager.SetAnchors(anchors[:1])
+	if err != nil {
+		t.Fatalf("SetAnchors: %s", err)
+	}
+	restoredStore, err = newAddressStore(addressManager.store.database, addressManager.GroupKey)
+	if err != nil {
+		t.Fatalf("newAddressStore: %s", err)
+	}
+	if !reflect.DeepEqual(restoredStore.getAnchors(), anchors[:1]) {
+		t.Fatalf("restored anchors are not equal to the stored ones\n"+
+			"anchors:%+v\nrestored anchors:%+v", anchors[:1], restoredStore.getAnchors())
+	}
+}
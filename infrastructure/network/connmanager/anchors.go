@@ -0,0 +1,55 @@
+package connmanager
+
+// loadAnchors takes the anchors that were saved on the last shutdown out of the address
+// manager. They're removed from it right away, so that if the node doesn't shut down
+// cleanly this time it doesn't keep reconnecting to the same peers forever.
+func (c *ConnectionManager) loadAnchors() error {
+	c.anchors = c.addressManager.Anchors()
+	if len(c.anchors) == 0 {
+		return nil
+	}
+	log.Infof("Loaded %d anchor peers", len(c.anchors))
+	return c.addressManager.SetAnchors(nil)
+}
+
+// connectToAnchors connects to the anchors before any other outgoing connection is made,
+// and before addresses are seeded from DNS. Anchors count towards the regular or the
+// block-relay-only outgoing connections, according to how they were connected before the
+// restart. Anchors that can't be connected to are skipped, and are replaced by random
+// addresses in the regular outgoing connection checks.
+func (c *ConnectionManager) connectToAnchors() {
+	for _, anchor := range c.anchors {
+		addressString := anchor.NetAddress.String()
+		// The anchor may be in a network that's no longer reachable, if --onlynet or --proxy changed
+		if c.isRequested(addressString) || !c.addressManager.IsReachable(anchor.NetAddress) {
+			continue
+		}
+
+		activeOutgoing, targetOutgoing, initiateConnection := c.activeOutgoing, c.targetOutgoing, c.initiateConnection
+		if anchor.IsBlockRelayOnly {
+			activeOutgoing, targetOutgoing, initiateConnection =
+				c.activeBlockRelayOnlyOutgoing, c.targetBlockRelayOnlyOutgoing, c.initiateBlockRelayOnlyConnection
+		}
+		if len(activeOutgoing) >= targetOutgoing {
+			continue
+		}
+
+		log.Debugf("Connecting to anchor %s", addressString)
+		err := initiateConnection(addressString)
+		if err != nil {
+			log.Infof("Couldn't connect to anchor %s: %s", addressString, err)
+			continue
+		}
+		activeOutgoing[addressString] = struct{}{}
+	}
+	c.anchors = nil
+}
+
+func (c *ConnectionManager) isRequested(address string) bool {
+	c.connectionRequestsLock.RLock()
+	defer c.connectionRequestsLock.RUnlock()
+
+	_, isActive := c.activeRequested[address]
+	_, isPending := c.pendingRequested[address]
+	return isActive || isPending
+}
@@ -39,6 +39,15 @@ type ConnectionManager struct {
 	activeIncoming   map[string]struct{}
 	maxIncoming      int
 
+	activeBlockRelayOnlyOutgoing map[string]struct{}
+	targetBlockRelayOnlyOutgoing int
+
+	// The anchors that were saved on the last shutdown, until they're connected to
+	anchors []*addressmanager.Anchor
+
+	peerScores     map[string]*PeerScore
+	peerScoresLock sync.Mutex
+
 	stop                   uint32
 	connectionRequestsLock sync.RWMutex
 
@@ -58,6 +67,9 @@ func New(cfg *config.Config, netAdapter *netadapter.NetAdapter, addressManager *
 		activeIncoming:   map[string]struct{}{},
 		resetLoopChan:    make(chan struct{}),
 		loopTicker:       time.NewTicker(connectionsLoopInterval),
+
+		activeBlockRelayOnlyOutgoing: map[string]struct{}{},
+		peerScores:                   map[string]*PeerScore{},
 	}
 
 	connectPeers := cfg.AddPeers
@@ -67,6 +79,7 @@ func New(cfg *config.Config, netAdapter *netadapter.NetAdapter, addressManager *
 
 	c.maxIncoming = cfg.MaxInboundPeers
 	c.targetOutgoing = cfg.TargetOutboundPeers
+	c.targetBlockRelayOnlyOutgoing = cfg.BlockRelayOnlyPeers
 
 	for _, connectPeer := range connectPeers {
 		c.pendingRequested[connectPeer] = &connectionRequest{
@@ -75,6 +88,11 @@ func New(cfg *config.Config, netAdapter *netadapter.NetAdapter, addressManager *
 		}
 	}
 
+	err := c.loadAnchors()
+	if err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
@@ -105,15 +123,22 @@ func (c *ConnectionManager) initiateConnection(address string) error {
 	return c.netAdapter.P2PConnect(address)
 }
 
+func (c *ConnectionManager) initiateBlockRelayOnlyConnection(address string) error {
+	log.Infof("Connecting to %s (block relay only)", address)
+	return c.netAdapter.P2PConnectBlockRelayOnly(address)
+}
+
 const connectionsLoopInterval = 30 * time.Second
 
 func (c *ConnectionManager) connectionsLoop() {
+	c.connectToAnchors()
 
 	for atomic.LoadUint32(&c.stop) == 0 {
 		connections := c.netAdapter.P2PConnections()
 
 		// We convert the connections list to a set, so that connections can be found quickly
-		// Then we go over the set, classifying connection by category: requested, outgoing or incoming.
+		// Then we go over the set, classifying connection by category: requested, outgoing,
+		// block-relay-only outgoing or incoming.
 		// Every step removes all matching connections so that once we get to checkIncomingConnections -
 		// the only connections left are the incoming ones
 		connSet := convertToSet(connections)
@@ -122,6 +147,8 @@ func (c *ConnectionManager) connectionsLoop() {
 
 		c.checkOutgoingConnections(connSet)
 
+		c.checkBlockRelayOnlyOutgoingConnections(connSet)
+
 		c.checkIncomingConnections(connSet)
 
 		c.waitTillNextIteration()
@@ -142,9 +169,21 @@ func (c *ConnectionManager) Ban(netConnection *netadapter.NetConnection) error {
 		return errors.Wrapf(ErrCannotBanPermanent, "Cannot ban %s because it's a permanent connection", netConnection.Address())
 	}
 
+	// Banning the address of an onion peer would ban every onion peer, so such peers are only disconnected
+	if c.isInboundOnionConnection(netConnection) {
+		log.Debugf("Not banning %s since it may be an onion peer", netConnection)
+		return nil
+	}
+
 	return c.addressManager.Ban(netConnection.NetAddress())
 }
 
+// isInboundOnionConnection returns whether the given connection may be an inbound connection over
+// an onion service. Such connections all come from the local Tor daemon.
+func (c *ConnectionManager) isInboundOnionConnection(netConnection *netadapter.NetConnection) bool {
+	return c.cfg.TorControl != "" && !netConnection.IsOutbound() && addressmanager.IsLocal(netConnection.NetAddress())
+}
+
 // BanByIP bans the given IP and disconnects from all the connection with that IP.
 func (c *ConnectionManager) BanByIP(ip net.IP) error {
 	ipHasPermanentConnection, err := c.ipHasPermanentConnection(ip)
@@ -253,9 +292,13 @@ func (c *ConnectionManager) extractAddressIPs(address string) ([]net.IP, error)
 	return []net.IP{ip}, nil
 }
 
+// seedFromDNS asks the DNS and gRPC seeders for addresses of peers to connect to. The seeders
+// only return IPv4 and IPv6 addresses, so nodes restricted to onion peers by --onlynet skip
+// seeding, rather than leaking their clearnet IP for addresses they can't use, and bootstrap
+// from the onion peers given with --addpeer or --connect instead.
 func (c *ConnectionManager) seedFromDNS() {
 	cfg := c.cfg
-	if len(c.activeOutgoing) == 0 && !cfg.DisableDNSSeed {
+	if len(c.activeOutgoing) == 0 && !cfg.DisableDNSSeed && c.canReachSeededAddresses() {
 		dnsseed.SeedFromDNS(cfg.NetParams(), cfg.DNSSeed, false, nil,
 			cfg.Lookup, func(addresses []*appmessage.NetAddress) {
 				// kobrad uses a lookup of the dns seeder here. Since seeder returns
@@ -270,3 +313,17 @@ func (c *ConnectionManager) seedFromDNS() {
 			})
 	}
 }
+
+// canReachSeededAddresses returns whether --onlynet allows connecting to the IPv4
+// and IPv6 addresses returned by the seeders
+func (c *ConnectionManager) canReachSeededAddresses() bool {
+	if len(c.cfg.OnlyNets) == 0 {
+		return true
+	}
+	for _, network := range c.cfg.OnlyNets {
+		if network == config.OnlyNetIPv4 || network == config.OnlyNetIPv6 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package connmanager
+
+import (
+	"testing"
+
+	"github.com/kobradag/kobrad/infrastructure/config"
+)
+
+func TestCanReachSeededAddresses(t *testing.T) {
+	tests := []struct {
+		onlyNets []string
+		expected bool
+	}{
+		{onlyNets: nil, expected: true},
+		{onlyNets: []string{config.OnlyNetIPv4}, expected: true},
+		{onlyNets: []string{config.OnlyNetOnion, config.OnlyNetIPv6}, expected: true},
+		{onlyNets: []string{config.OnlyNetOnion}, expected: false},
+	}
+	for _, test := range tests {
+		c := &ConnectionManager{cfg: &config.Config{Flags: &config.Flags{OnlyNets: test.onlyNets}}}
+		if reachable := c.canReachSeededAddresses(); reachable != test.expected {
+			t.Errorf("expected canReachSeededAddresses with --onlynet %v to be %t, got %t",
+				test.onlyNets, test.expected, reachable)
+		}
+	}
+}
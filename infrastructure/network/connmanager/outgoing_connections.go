@@ -5,7 +5,26 @@ import "github.com/kobradag/kobrad/app/appmessage"
 // checkOutgoingConnections goes over all activeOutgoing and makes sure they are still active.
 // Then it opens connections so that we have targetOutgoing active connections
 func (c *ConnectionManager) checkOutgoingConnections(connSet connectionSet) {
-	for address := range c.activeOutgoing {
+	c.checkOutgoingConnectionsOfKind(connSet, c.activeOutgoing, c.targetOutgoing, "outgoing",
+		c.initiateConnection)
+}
+
+// checkBlockRelayOnlyOutgoingConnections goes over all activeBlockRelayOnlyOutgoing and makes sure
+// they are still active. Then it opens block-relay-only connections so that we have
+// targetBlockRelayOnlyOutgoing active block-relay-only connections.
+// These connections are in addition to the regular outgoing ones, and don't exchange transactions or
+// addresses. This makes it harder to eclipse the node, since an attacker that fills the address manager
+// with its own addresses doesn't learn about these connections, and harder to deduce the origin of
+// transactions from the order in which they're relayed.
+func (c *ConnectionManager) checkBlockRelayOnlyOutgoingConnections(connSet connectionSet) {
+	c.checkOutgoingConnectionsOfKind(connSet, c.activeBlockRelayOnlyOutgoing, c.targetBlockRelayOnlyOutgoing,
+		"block-relay-only outgoing", c.initiateBlockRelayOnlyConnection)
+}
+
+func (c *ConnectionManager) checkOutgoingConnectionsOfKind(connSet connectionSet, activeOutgoing map[string]struct{},
+	targetOutgoing int, kind string, initiateConnection func(address string) error) {
+
+	for address := range activeOutgoing {
 		connection, ok := connSet.get(address)
 		if ok { // connection is still connected
 			connSet.remove(connection)
@@ -13,33 +32,37 @@ func (c *ConnectionManager) checkOutgoingConnections(connSet connectionSet) {
 		}
 
 		// if connection is dead - remove from list of active ones
-		delete(c.activeOutgoing, address)
+		delete(activeOutgoing, address)
 	}
 
 	connections := c.netAdapter.P2PConnections()
 	connectedAddresses := make([]*appmessage.NetAddress, len(connections))
+	outboundAddresses := make([]*appmessage.NetAddress, 0, len(connections))
 	for i, connection := range connections {
 		connectedAddresses[i] = connection.NetAddress()
+		if connection.IsOutbound() {
+			outboundAddresses = append(outboundAddresses, connection.NetAddress())
+		}
 	}
 
-	liveConnections := len(c.activeOutgoing)
-	if c.targetOutgoing == liveConnections {
+	liveConnections := len(activeOutgoing)
+	if targetOutgoing == liveConnections {
 		return
 	}
 
-	log.Debugf("Have got %d outgoing connections out of target %d, adding %d more",
-		liveConnections, c.targetOutgoing, c.targetOutgoing-liveConnections)
+	log.Debugf("Have got %d %s connections out of target %d, adding %d more",
+		liveConnections, kind, targetOutgoing, targetOutgoing-liveConnections)
 
-	connectionsNeededCount := c.targetOutgoing - len(c.activeOutgoing)
-	netAddresses := c.addressManager.RandomAddresses(connectionsNeededCount, connectedAddresses)
+	connectionsNeededCount := targetOutgoing - len(activeOutgoing)
+	netAddresses := c.addressManager.RandomOutboundAddresses(connectionsNeededCount, connectedAddresses, outboundAddresses)
 
 	for _, netAddress := range netAddresses {
-		addressString := netAddress.TCPAddress().String()
+		addressString := netAddress.String()
 
-		log.Debugf("Connecting to %s because we have %d outgoing connections and the target is "+
-			"%d", addressString, len(c.activeOutgoing), c.targetOutgoing)
+		log.Debugf("Connecting to %s because we have %d %s connections and the target is "+
+			"%d", addressString, len(activeOutgoing), kind, targetOutgoing)
 
-		err := c.initiateConnection(addressString)
+		err := initiateConnection(addressString)
 		if err != nil {
 			log.Debugf("Couldn't connect to %s: %s", addressString, err)
 			c.addressManager.MarkConnectionFailure(netAddress)
@@ -47,12 +70,12 @@ func (c *ConnectionManager) checkOutgoingConnections(connSet connectionSet) {
 		}
 		c.addressManager.MarkConnectionSuccess(netAddress)
 
-		c.activeOutgoing[addressString] = struct{}{}
+		activeOutgoing[addressString] = struct{}{}
 	}
 
 	if len(netAddresses) < connectionsNeededCount {
-		log.Debugf("Need %d more outgoing connections - seeding addresses from DNS",
-			connectionsNeededCount-len(netAddresses))
+		log.Debugf("Need %d more %s connections - seeding addresses from DNS",
+			connectionsNeededCount-len(netAddresses), kind)
 
 		// seedFromDNS is an asynchronous method, therefore addresses for connection
 		// should be available on next iteration
@@ -0,0 +1,122 @@
+package connmanager
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter"
+)
+
+// scoreHalfLife is the time it takes for the ban score and the reputation of a
+// peer to decay to half of their value, so that old misbehavior is eventually
+// forgiven and old good behavior eventually forgotten
+const scoreHalfLife = 10 * time.Minute
+
+// peerScoresCleanupSize is the amount of peer scores above which scores that
+// decayed to a negligible value are removed
+const peerScoresCleanupSize = 1000
+
+// negligibleScore is the value below which a decayed score is considered to be 0
+const negligibleScore = 0.1
+
+// decayingScore is a score that decays exponentially with a half-life of scoreHalfLife
+type decayingScore struct {
+	value      float64
+	lastUpdate time.Time
+}
+
+func (s *decayingScore) valueAt(now time.Time) float64 {
+	if s.value == 0 {
+		return 0
+	}
+	elapsed := now.Sub(s.lastUpdate)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(elapsed)/float64(scoreHalfLife))
+}
+
+func (s *decayingScore) add(now time.Time, points float64) float64 {
+	s.value = s.valueAt(now) + points
+	s.lastUpdate = now
+	return s.value
+}
+
+// PeerScore holds the ban score and the reputation of a peer. It's kept by the
+// ConnectionManager per IP, so that a peer can't reset its ban score by reconnecting.
+// The zero value is an empty score.
+type PeerScore struct {
+	lock      sync.Mutex
+	banScore  decayingScore // Accumulated misbehavior points
+	goodScore decayingScore // Accumulated good behavior points
+}
+
+// AddBanScore adds the given misbehavior points to the ban score, and returns the new ban score
+func (s *PeerScore) AddBanScore(points float64) float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.banScore.add(time.Now(), points)
+}
+
+// AddReputation adds the given good behavior points to the reputation
+func (s *PeerScore) AddReputation(points float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.goodScore.add(time.Now(), points)
+}
+
+// BanScore returns the accumulated misbehavior points, after decay
+func (s *PeerScore) BanScore() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.banScore.valueAt(time.Now())
+}
+
+// Reputation returns the accumulated good behavior points minus the ban score, after decay
+func (s *PeerScore) Reputation() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	return s.goodScore.valueAt(now) - s.banScore.valueAt(now)
+}
+
+func (s *PeerScore) isNegligible(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.banScore.valueAt(now) < negligibleScore && s.goodScore.valueAt(now) < negligibleScore
+}
+
+// PeerScore returns the score of the peer of the given connection. Peers connecting from the same IP
+// share their score, except for inbound onion peers, which all come from the local Tor daemon.
+func (c *ConnectionManager) PeerScore(netConnection *netadapter.NetConnection) *PeerScore {
+	c.peerScoresLock.Lock()
+	defer c.peerScoresLock.Unlock()
+
+	key := netConnection.NetAddress().IP.String()
+	if c.isInboundOnionConnection(netConnection) {
+		key = netConnection.Address()
+	}
+
+	score, ok := c.peerScores[key]
+	if ok {
+		return score
+	}
+
+	if len(c.peerScores) >= peerScoresCleanupSize {
+		now := time.Now()
+		for scoreKey, score := range c.peerScores {
+			if score.isNegligible(now) {
+				delete(c.peerScores, scoreKey)
+			}
+		}
+	}
+	score = &PeerScore{}
+	c.peerScores[key] = score
+	return score
+}
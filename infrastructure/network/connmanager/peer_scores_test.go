@@ -0,0 +1,46 @@
+package connmanager
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecayingScore(t *testing.T) {
+	start := time.Now()
+	score := decayingScore{}
+
+	if value := score.valueAt(start); value != 0 {
+		t.Fatalf("expected a new score to be 0, got %f", value)
+	}
+
+	if value := score.add(start, 100); value != 100 {
+		t.Fatalf("expected the score to be 100, got %f", value)
+	}
+
+	tests := []struct {
+		elapsed       time.Duration
+		expectedValue float64
+	}{
+		{elapsed: 0, expectedValue: 100},
+		{elapsed: scoreHalfLife, expectedValue: 50},
+		{elapsed: 2 * scoreHalfLife, expectedValue: 25},
+		{elapsed: 10 * scoreHalfLife, expectedValue: 100.0 / 1024},
+	}
+	for _, test := range tests {
+		value := score.valueAt(start.Add(test.elapsed))
+		if math.Abs(value-test.expectedValue) > 1e-9 {
+			t.Errorf("expected the score after %s to be %f, got %f", test.elapsed, test.expectedValue, value)
+		}
+	}
+
+	// Points are added on top of the decayed value
+	value := score.add(start.Add(scoreHalfLife), 20)
+	if math.Abs(value-70) > 1e-9 {
+		t.Fatalf("expected the score to be 70, got %f", value)
+	}
+	value = score.valueAt(start.Add(2 * scoreHalfLife))
+	if math.Abs(value-35) > 1e-9 {
+		t.Fatalf("expected the score to be 35, got %f", value)
+	}
+}
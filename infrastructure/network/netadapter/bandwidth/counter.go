@@ -0,0 +1,161 @@
+package bandwidth
+
+import (
+	"sort"
+	"sync"
+)
+
+// Totals is the amount of bytes and messages that were sent and received
+type Totals struct {
+	BytesSent        uint64
+	BytesReceived    uint64
+	MessagesSent     uint64
+	MessagesReceived uint64
+}
+
+// MessageTypeTotals is the Totals of a single message type
+type MessageTypeTotals struct {
+	Command string
+	Totals
+}
+
+// Counter counts the bytes that are sent and received over a connection, or over
+// all the connections of a server, in total and per message type. A Counter may have
+// a parent, which everything that's counted by it is counted by as well, and an upload
+// rate limit, which the bytes charged with ChargeUpload are charged to.
+type Counter struct {
+	parent *Counter
+
+	lock          sync.Mutex
+	totals        Totals
+	byMessageType map[string]*Totals
+
+	uploadLimiter *limiter
+}
+
+// NewCounter returns a new Counter. parent may be nil.
+func NewCounter(parent *Counter) *Counter {
+	return &Counter{
+		parent:        parent,
+		byMessageType: make(map[string]*Totals),
+	}
+}
+
+// SetUploadRateLimit sets the maximum upload rate of the counter, in bytes per second.
+// A limit of 0 means the upload rate is unlimited.
+func (c *Counter) SetUploadRateLimit(bytesPerSecond uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if bytesPerSecond == 0 {
+		c.uploadLimiter = nil
+		return
+	}
+	c.uploadLimiter = newLimiter(bytesPerSecond)
+}
+
+// UploadRateLimit returns the maximum upload rate of the counter, in bytes per second,
+// or 0 if it's unlimited
+func (c *Counter) UploadRateLimit() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.uploadLimiter == nil {
+		return 0
+	}
+	return c.uploadLimiter.bytesPerSecond
+}
+
+// AddSent counts a message of the given command and size that was sent
+func (c *Counter) AddSent(command string, size int) {
+	c.lock.Lock()
+	totals := c.messageTypeTotals(command)
+	totals.BytesSent += uint64(size)
+	totals.MessagesSent++
+	c.totals.BytesSent += uint64(size)
+	c.totals.MessagesSent++
+	c.lock.Unlock()
+
+	if c.parent != nil {
+		c.parent.AddSent(command, size)
+	}
+}
+
+// AddReceived counts a message of the given command and size that was received
+func (c *Counter) AddReceived(command string, size int) {
+	c.lock.Lock()
+	totals := c.messageTypeTotals(command)
+	totals.BytesReceived += uint64(size)
+	totals.MessagesReceived++
+	c.totals.BytesReceived += uint64(size)
+	c.totals.MessagesReceived++
+	c.lock.Unlock()
+
+	if c.parent != nil {
+		c.parent.AddReceived(command, size)
+	}
+}
+
+func (c *Counter) messageTypeTotals(command string) *Totals {
+	totals, ok := c.byMessageType[command]
+	if !ok {
+		totals = &Totals{}
+		c.byMessageType[command] = totals
+	}
+	return totals
+}
+
+// Totals returns the total amount of bytes and messages that were counted
+func (c *Counter) Totals() Totals {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.totals
+}
+
+// MessageTypeTotals returns the amount of bytes and messages that were counted
+// for every message type, sorted by command
+func (c *Counter) MessageTypeTotals() []*MessageTypeTotals {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	messageTypeTotals := make([]*MessageTypeTotals, 0, len(c.byMessageType))
+	for command, totals := range c.byMessageType {
+		messageTypeTotals = append(messageTypeTotals, &MessageTypeTotals{Command: command, Totals: *totals})
+	}
+	sort.Slice(messageTypeTotals, func(i, j int) bool {
+		return messageTypeTotals[i].Command < messageTypeTotals[j].Command
+	})
+	return messageTypeTotals
+}
+
+// ChargeUpload charges the given amount of bytes to the upload rate limits of the counter
+// and of its ancestors. The bytes are charged when they're queued to be sent rather than
+// once they're sent, so that the messages that are already queued count towards the limits.
+func (c *Counter) ChargeUpload(size int) {
+	for counter := c; counter != nil; counter = counter.parent {
+		counter.lock.Lock()
+		uploadLimiter := counter.uploadLimiter
+		counter.lock.Unlock()
+
+		if uploadLimiter != nil {
+			uploadLimiter.charge(size)
+		}
+	}
+}
+
+// WaitForUploadBandwidth blocks until the bytes that were charged are within the upload
+// rate limits of the counter and of its ancestors, or until isCancelled returns true.
+// Flows that serve large amounts of data call it before queueing every message, so that
+// serving peers doesn't saturate the upload bandwidth.
+func (c *Counter) WaitForUploadBandwidth(isCancelled func() bool) {
+	for counter := c; counter != nil; counter = counter.parent {
+		counter.lock.Lock()
+		uploadLimiter := counter.uploadLimiter
+		counter.lock.Unlock()
+
+		if uploadLimiter != nil {
+			uploadLimiter.wait(isCancelled)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package bandwidth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounter(t *testing.T) {
+	parent := NewCounter(nil)
+	first := NewCounter(parent)
+	second := NewCounter(parent)
+
+	first.AddSent("block", 1000)
+	first.AddSent("block", 500)
+	first.AddReceived("requestIBDBlocks", 40)
+	second.AddSent("ping", 8)
+	second.AddReceived("block", 700)
+
+	expectedFirstTotals := Totals{BytesSent: 1500, BytesReceived: 40, MessagesSent: 2, MessagesReceived: 1}
+	if totals := first.Totals(); totals != expectedFirstTotals {
+		t.Fatalf("expected totals %+v, got %+v", expectedFirstTotals, totals)
+	}
+	expectedParentTotals := Totals{BytesSent: 1508, BytesReceived: 740, MessagesSent: 3, MessagesReceived: 2}
+	if totals := parent.Totals(); totals != expectedParentTotals {
+		t.Fatalf("expected totals %+v, got %+v", expectedParentTotals, totals)
+	}
+
+	expectedMessageTypeTotals := []MessageTypeTotals{
+		{Command: "block", Totals: Totals{BytesSent: 1500, BytesReceived: 700, MessagesSent: 2, MessagesReceived: 1}},
+		{Command: "ping", Totals: Totals{BytesSent: 8, MessagesSent: 1}},
+		{Command: "requestIBDBlocks", Totals: Totals{BytesReceived: 40, MessagesReceived: 1}},
+	}
+	messageTypeTotals := parent.MessageTypeTotals()
+	if len(messageTypeTotals) != len(expectedMessageTypeTotals) {
+		t.Fatalf("expected %d message types, got %d", len(expectedMessageTypeTotals), len(messageTypeTotals))
+	}
+	for i, expected := range expectedMessageTypeTotals {
+		if *messageTypeTotals[i] != expected {
+			t.Fatalf("expected message type totals %+v, got %+v", expected, *messageTypeTotals[i])
+		}
+	}
+}
+
+func TestCounterChargeUpload(t *testing.T) {
+	parent := NewCounter(nil)
+	parent.SetUploadRateLimit(1000)
+	child := NewCounter(parent)
+	child.SetUploadRateLimit(1000)
+
+	// Sent bytes are only counted, since queued messages are charged when they're queued
+	child.AddSent("block", 5000)
+	if debtDuration := parent.uploadLimiter.debtDuration(); debtDuration != 0 {
+		t.Fatalf("expected sent bytes not to be charged, got a debt of %s", debtDuration)
+	}
+
+	child.ChargeUpload(3000)
+	for _, counter := range []*Counter{child, parent} {
+		if debtDuration := counter.uploadLimiter.debtDuration(); debtDuration < time.Second {
+			t.Fatalf("expected the charged bytes to put the counter in debt, got a debt of %s", debtDuration)
+		}
+	}
+}
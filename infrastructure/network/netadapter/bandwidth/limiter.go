@@ -0,0 +1,79 @@
+package bandwidth
+
+import (
+	"sync"
+	"time"
+)
+
+// maxLimiterSleep is the maximum time a limiter sleeps before checking
+// whether the wait was cancelled
+const maxLimiterSleep = 100 * time.Millisecond
+
+// limiter is a token bucket that limits an upload rate. Bytes are charged after
+// waiting for the bucket, which may put it in debt, and waiting blocks until the
+// debt is repaid. The bucket holds up to one second worth of bytes.
+type limiter struct {
+	bytesPerSecond uint64
+
+	lock       sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+	now        func() time.Time
+}
+
+func newLimiter(bytesPerSecond uint64) *limiter {
+	return &limiter{
+		bytesPerSecond: bytesPerSecond,
+		tokens:         float64(bytesPerSecond),
+		lastRefill:     time.Now(),
+		now:            time.Now,
+	}
+}
+
+// refill adds the tokens that were accumulated since the last refill. It must
+// be called with the lock held.
+func (l *limiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.lastRefill)
+	l.lastRefill = now
+	if elapsed <= 0 {
+		return
+	}
+	l.tokens += elapsed.Seconds() * float64(l.bytesPerSecond)
+	if l.tokens > float64(l.bytesPerSecond) {
+		l.tokens = float64(l.bytesPerSecond)
+	}
+}
+
+func (l *limiter) charge(size int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.refill()
+	l.tokens -= float64(size)
+}
+
+// debtDuration returns how long it takes to repay the current debt
+func (l *limiter) debtDuration() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.refill()
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.bytesPerSecond) * float64(time.Second))
+}
+
+func (l *limiter) wait(isCancelled func() bool) {
+	for {
+		debtDuration := l.debtDuration()
+		if debtDuration == 0 || isCancelled() {
+			return
+		}
+		if debtDuration > maxLimiterSleep {
+			debtDuration = maxLimiterSleep
+		}
+		time.Sleep(debtDuration)
+	}
+}
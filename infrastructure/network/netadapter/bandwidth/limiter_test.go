@@ -0,0 +1,43 @@
+package bandwidth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newLimiter(1000)
+	limiter.now = func() time.Time { return now }
+	limiter.lastRefill = now
+
+	// The bucket starts full, so sending up to a second worth of bytes doesn't require waiting
+	limiter.charge(1000)
+	if debtDuration := limiter.debtDuration(); debtDuration != 0 {
+		t.Fatalf("expected no debt, got %s", debtDuration)
+	}
+
+	limiter.charge(2000)
+	if debtDuration := limiter.debtDuration(); debtDuration != 2*time.Second {
+		t.Fatalf("expected a debt of 2s, got %s", debtDuration)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if debtDuration := limiter.debtDuration(); debtDuration != 500*time.Millisecond {
+		t.Fatalf("expected a debt of 500ms, got %s", debtDuration)
+	}
+
+	// The bucket never holds more than a second worth of bytes
+	now = now.Add(time.Hour)
+	limiter.charge(1500)
+	if debtDuration := limiter.debtDuration(); debtDuration != 500*time.Millisecond {
+		t.Fatalf("expected a debt of 500ms, got %s", debtDuration)
+	}
+
+	cancelled := true
+	start := time.Now()
+	limiter.wait(func() bool { return cancelled })
+	if time.Since(start) > maxLimiterSleep {
+		t.Fatalf("expected a cancelled wait to return immediately")
+	}
+}
@@ -1,18 +1,31 @@
 package netadapter
 
 import (
+	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/infrastructure/config"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/id"
 	routerpkg "github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/jsonrpcserver"
 	"github.com/pkg/errors"
 )
 
+const (
+	// dialTimeout is the time after which dialing a peer directly is given up
+	dialTimeout = 1 * time.Second
+
+	// proxyDialTimeout is the time after which dialing a peer through a proxy is given
+	// up. It's much longer than dialTimeout since building a Tor circuit takes a while.
+	proxyDialTimeout = 30 * time.Second
+)
+
 // RouterInitializer is a function that initializes a new
 // router to be used with a new connection
 type RouterInitializer func(*routerpkg.Router, *NetConnection)
@@ -28,11 +41,16 @@ type NetAdapter struct {
 	p2pServer            server.P2PServer
 	p2pRouterInitializer RouterInitializer
 	rpcServer            server.Server
+	jsonRPCServer        server.Server
 	rpcRouterInitializer RouterInitializer
 	stop                 uint32
 
 	p2pConnections     map[*NetConnection]struct{}
 	p2pConnectionsLock sync.RWMutex
+
+	// The addresses of the block-relay-only connections that are currently being initiated
+	pendingBlockRelayOnlyAddresses     map[string]struct{}
+	pendingBlockRelayOnlyAddressesLock sync.Mutex
 }
 
 // NewNetAdapter creates and starts a new NetAdapter on the
@@ -42,25 +60,55 @@ func NewNetAdapter(cfg *config.Config) (*NetAdapter, error) {
 	if err != nil {
 		return nil, err
 	}
-	p2pServer, err := grpcserver.NewP2PServer(cfg.Listeners)
+	dial := cfg.Dial
+	if dial == nil {
+		dial = net.DialTimeout
+	}
+	p2pDialTimeout := dialTimeout
+	if cfg.Proxy != "" {
+		p2pDialTimeout = proxyDialTimeout
+	}
+	var p2pEncryption *grpcserver.P2PEncryptionConfig
+	if cfg.P2PIdentityKey != nil {
+		p2pEncryption = &grpcserver.P2PEncryptionConfig{
+			IdentityKey: cfg.P2PIdentityKey,
+			PinnedKeys:  cfg.PinnedPeerKeys,
+		}
+		log.Infof("P2P identity key: %x", cfg.P2PIdentityKey.Public())
+	}
+	p2pServer, err := grpcserver.NewP2PServer(cfg.Listeners, dial, p2pDialTimeout, p2pEncryption)
 	if err != nil {
 		return nil, err
 	}
+	p2pServer.SetUploadRateLimits(cfg.MaxUploadRate*1024, cfg.MaxPeerUploadRate*1024)
 	rpcServer, err := grpcserver.NewRPCServer(cfg.RPCListeners, cfg.RPCMaxClients)
 	if err != nil {
 		return nil, err
 	}
+	var jsonRPCServer server.Server
+	if len(cfg.RPCJSONListeners) > 0 {
+		jsonRPCServer, err = jsonrpcserver.NewJSONRPCServer(cfg.RPCJSONListeners, cfg.RPCJSONAllowedOrigins,
+			cfg.RPCMaxWebsockets, cfg.RPCMaxConcurrentReqs)
+		if err != nil {
+			return nil, err
+		}
+	}
 	adapter := NetAdapter{
-		cfg:       cfg,
-		id:        netAdapterID,
-		p2pServer: p2pServer,
-		rpcServer: rpcServer,
-
-		p2pConnections: make(map[*NetConnection]struct{}),
+		cfg:           cfg,
+		id:            netAdapterID,
+		p2pServer:     p2pServer,
+		rpcServer:     rpcServer,
+		jsonRPCServer: jsonRPCServer,
+
+		p2pConnections:                 make(map[*NetConnection]struct{}),
+		pendingBlockRelayOnlyAddresses: make(map[string]struct{}),
 	}
 
 	adapter.p2pServer.SetOnConnectedHandler(adapter.onP2PConnectedHandler)
 	adapter.rpcServer.SetOnConnectedHandler(adapter.onRPCConnectedHandler)
+	if adapter.jsonRPCServer != nil {
+		adapter.jsonRPCServer.SetOnConnectedHandler(adapter.onRPCConnectedHandler)
+	}
 
 	return &adapter, nil
 }
@@ -82,6 +130,12 @@ func (na *NetAdapter) Start() error {
 	if err != nil {
 		return err
 	}
+	if na.jsonRPCServer != nil {
+		err = na.jsonRPCServer.Start()
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -95,6 +149,12 @@ func (na *NetAdapter) Stop() error {
 	if err != nil {
 		return err
 	}
+	if na.jsonRPCServer != nil {
+		err = na.jsonRPCServer.Stop()
+		if err != nil {
+			return err
+		}
+	}
 	return na.rpcServer.Stop()
 }
 
@@ -105,6 +165,35 @@ func (na *NetAdapter) P2PConnect(address string) error {
 	return err
 }
 
+// P2PConnectBlockRelayOnly tells the NetAdapter's underlying p2p server to initiate a
+// block-relay-only connection to the given address
+func (na *NetAdapter) P2PConnectBlockRelayOnly(address string) error {
+	na.pendingBlockRelayOnlyAddressesLock.Lock()
+	na.pendingBlockRelayOnlyAddresses[address] = struct{}{}
+	na.pendingBlockRelayOnlyAddressesLock.Unlock()
+
+	defer func() {
+		na.pendingBlockRelayOnlyAddressesLock.Lock()
+		delete(na.pendingBlockRelayOnlyAddresses, address)
+		na.pendingBlockRelayOnlyAddressesLock.Unlock()
+	}()
+
+	_, err := na.p2pServer.Connect(address)
+	return err
+}
+
+func (na *NetAdapter) isPendingBlockRelayOnly(connection server.Connection) bool {
+	if !connection.IsOutbound() {
+		return false
+	}
+
+	na.pendingBlockRelayOnlyAddressesLock.Lock()
+	defer na.pendingBlockRelayOnlyAddressesLock.Unlock()
+
+	_, ok := na.pendingBlockRelayOnlyAddresses[connection.NetAddress().String()]
+	return ok
+}
+
 // P2PConnections returns a list of p2p connections currently connected and active
 func (na *NetAdapter) P2PConnections() []*NetConnection {
 	na.p2pConnectionsLock.RLock()
@@ -128,7 +217,8 @@ func (na *NetAdapter) P2PConnectionCount() int {
 }
 
 func (na *NetAdapter) onP2PConnectedHandler(connection server.Connection) error {
-	netConnection := newNetConnection(connection, na.p2pRouterInitializer, "on P2P connected")
+	netConnection := newNetConnection(connection, na.p2pRouterInitializer, "on P2P connected",
+		na.isPendingBlockRelayOnly(connection))
 
 	na.p2pConnectionsLock.Lock()
 	defer na.p2pConnectionsLock.Unlock()
@@ -148,7 +238,7 @@ func (na *NetAdapter) onP2PConnectedHandler(connection server.Connection) error
 }
 
 func (na *NetAdapter) onRPCConnectedHandler(connection server.Connection) error {
-	netConnection := newNetConnection(connection, na.rpcRouterInitializer, "on RPC connected")
+	netConnection := newNetConnection(connection, na.rpcRouterInitializer, "on RPC connected", false)
 	netConnection.setOnDisconnectedHandler(func() {})
 	netConnection.start()
 
@@ -167,6 +257,11 @@ func (na *NetAdapter) SetRPCRouterInitializer(routerInitializer RouterInitialize
 	na.rpcRouterInitializer = routerInitializer
 }
 
+// P2PBandwidthCounter returns the counter of the bytes sent and received over all P2P connections
+func (na *NetAdapter) P2PBandwidthCounter() *bandwidth.Counter {
+	return na.p2pServer.BandwidthCounter()
+}
+
 // ID returns this netAdapter's ID in the network
 func (na *NetAdapter) ID() *id.ID {
 	return na.id
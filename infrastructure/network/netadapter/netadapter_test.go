@@ -1,6 +1,9 @@
 package netadapter
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
 	"sync"
 	"testing"
@@ -164,3 +167,103 @@ func TestNetAdapter(t *testing.T) {
 		t.Fatalf("TestNetAdapter: error expected at attempt to stop adapter second time, but got nothing")
 	}
 }
+
+func TestEncryptedConnections(t *testing.T) {
+	const host = "127.0.0.1"
+
+	newAdapter := func(port int, isEncrypted bool, pinnedKeys map[string]ed25519.PublicKey) (
+		*NetAdapter, ed25519.PublicKey) {
+
+		cfg := config.DefaultConfig()
+		cfg.Listeners = []string{fmt.Sprintf("%s:%d", host, port)}
+		cfg.PinnedPeerKeys = pinnedKeys
+		if isEncrypted {
+			var err error
+			_, cfg.P2PIdentityKey, err = ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatalf("GenerateKey: %s", err)
+			}
+		}
+		adapter, err := NewNetAdapter(cfg)
+		if err != nil {
+			t.Fatalf("NetAdapter instantiation failed: %+v", err)
+		}
+		adapter.SetP2PRouterInitializer(func(router *router.Router, connection *NetConnection) {})
+		adapter.SetRPCRouterInitializer(func(router *router.Router, connection *NetConnection) {})
+		err = adapter.Start()
+		if err != nil {
+			t.Fatalf("Start() failed: %+v", err)
+		}
+		t.Cleanup(func() { adapter.Stop() })
+
+		if !isEncrypted {
+			return adapter, nil
+		}
+		return adapter, cfg.P2PIdentityKey.Public().(ed25519.PublicKey)
+	}
+	connectionTo := func(adapter *NetAdapter, address string) *NetConnection {
+		for _, connection := range adapter.P2PConnections() {
+			if connection.Address() == address {
+				return connection
+			}
+		}
+		t.Fatalf("No connection to %s", address)
+		return nil
+	}
+
+	addressB := fmt.Sprintf("%s:%d", host, 3011)
+	addressC := fmt.Sprintf("%s:%d", host, 3012)
+	adapterB, identityKeyB := newAdapter(3011, true, nil)
+	adapterC, _ := newAdapter(3012, false, nil)
+	adapterA, identityKeyA := newAdapter(3010, true, map[string]ed25519.PublicKey{addressB: identityKeyB})
+
+	// Both sides are encrypted and authenticated
+	err := adapterA.P2PConnect(addressB)
+	if err != nil {
+		t.Fatalf("Connection to %s failed: %+v", addressB, err)
+	}
+	connectionToB := connectionTo(adapterA, addressB)
+	if !connectionToB.IsEncrypted() || !bytes.Equal(connectionToB.IdentityKey(), identityKeyB) {
+		t.Fatalf("Expected an encrypted connection to a peer with identity key %x, got %x",
+			identityKeyB, connectionToB.IdentityKey())
+	}
+	// The inbound connection is registered asynchronously
+	var connectionsOfB []*NetConnection
+	for i := 0; i < 100 && len(connectionsOfB) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		connectionsOfB = adapterB.P2PConnections()
+	}
+	if len(connectionsOfB) != 1 || !bytes.Equal(connectionsOfB[0].IdentityKey(), identityKeyA) {
+		t.Fatalf("Expected an encrypted inbound connection from a peer with identity key %x", identityKeyA)
+	}
+
+	// A peer that doesn't support encryption is connected to in plaintext
+	err = adapterA.P2PConnect(addressC)
+	if err != nil {
+		t.Fatalf("Connection to %s failed: %+v", addressC, err)
+	}
+	if connectionTo(adapterA, addressC).IsEncrypted() {
+		t.Fatalf("Expected a plaintext connection to %s", addressC)
+	}
+
+	// A peer that doesn't support encryption can connect to one that does
+	err = adapterC.P2PConnect(addressB)
+	if err != nil {
+		t.Fatalf("Connection from %s to %s failed: %+v", addressC, addressB, err)
+	}
+	if connectionTo(adapterC, addressB).IsEncrypted() {
+		t.Fatalf("Expected a plaintext connection to %s", addressB)
+	}
+
+	// A peer that has a different identity key than the pinned one is rejected
+	_, wrongKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err)
+	}
+	adapterD, _ := newAdapter(3013, true,
+		map[string]ed25519.PublicKey{addressB: wrongKey.Public().(ed25519.PublicKey)})
+	err = adapterD.P2PConnect(addressB)
+	if err == nil {
+		t.Fatalf("Expected the connection to a peer with an unexpected identity key to fail")
+	}
+}
@@ -1,14 +1,18 @@
 package netadapter
 
 import (
+	"crypto/ed25519"
 	"fmt"
 	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
 	routerpkg "github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 	"github.com/pkg/errors"
 	"sync/atomic"
 
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/id"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 // NetConnection is a wrapper to a server connection for use by services external to NetAdapter
@@ -18,14 +22,18 @@ type NetConnection struct {
 	router                *routerpkg.Router
 	onDisconnectedHandler server.OnDisconnectedHandler
 	isRouterClosed        uint32
+	isBlockRelayOnly      bool
 }
 
-func newNetConnection(connection server.Connection, routerInitializer RouterInitializer, name string) *NetConnection {
+func newNetConnection(connection server.Connection, routerInitializer RouterInitializer, name string,
+	isBlockRelayOnly bool) *NetConnection {
+
 	router := routerpkg.NewRouter(name)
 
 	netConnection := &NetConnection{
-		connection: connection,
-		router:     router,
+		connection:       connection,
+		router:           router,
+		isBlockRelayOnly: isBlockRelayOnly,
 	}
 
 	netConnection.connection.SetOnDisconnectedHandler(func() {
@@ -65,9 +73,10 @@ func (c *NetConnection) SetID(peerID *id.ID) {
 	c.id = peerID
 }
 
-// Address returns the address associated with this connection
+// Address returns the address associated with this connection, in the form host:port.
+// For onion addresses, host is the onion host name.
 func (c *NetConnection) Address() string {
-	return c.connection.Address().String()
+	return c.NetAddress().String()
 }
 
 // IsOutbound returns whether the connection is outbound
@@ -75,9 +84,49 @@ func (c *NetConnection) IsOutbound() bool {
 	return c.connection.IsOutbound()
 }
 
+// IsBlockRelayOnly returns whether the connection was initiated as a block-relay-only
+// connection, over which only blocks are exchanged
+func (c *NetConnection) IsBlockRelayOnly() bool {
+	return c.isBlockRelayOnly
+}
+
 // NetAddress returns the NetAddress associated with this connection
 func (c *NetConnection) NetAddress() *appmessage.NetAddress {
-	return appmessage.NewNetAddress(c.connection.Address())
+	return c.connection.NetAddress()
+}
+
+// IdentityKey returns the identity key the peer authenticated with, or nil if the
+// connection isn't encrypted
+func (c *NetConnection) IdentityKey() ed25519.PublicKey {
+	return c.connection.IdentityKey()
+}
+
+// IsEncrypted returns whether the connection is encrypted
+func (c *NetConnection) IsEncrypted() bool {
+	return c.connection.IdentityKey() != nil
+}
+
+// BandwidthCounter returns the counter of the bytes sent and received over this connection
+func (c *NetConnection) BandwidthCounter() *bandwidth.Counter {
+	return c.connection.BandwidthCounter()
+}
+
+// ReserveUploadBandwidth blocks until the bytes queued to be sent over this connection, and
+// over all the connections together, are within the upload rate limits, or until the connection
+// is disconnected, and then charges the size of the given message to the limits. Flows that
+// serve large amounts of data call it before queueing every message.
+func (c *NetConnection) ReserveUploadBandwidth(message appmessage.Message) error {
+	bandwidthCounter := c.connection.BandwidthCounter()
+	bandwidthCounter.WaitForUploadBandwidth(func() bool {
+		return !c.connection.IsConnected()
+	})
+
+	messageProto, err := protowire.FromAppMessage(message)
+	if err != nil {
+		return err
+	}
+	bandwidthCounter.ChargeUpload(proto.Size(messageProto))
+	return nil
 }
 
 func (c *NetConnection) setOnDisconnectedHandler(onDisconnectedHandler server.OnDisconnectedHandler) {
@@ -12,6 +12,7 @@ import (
 
 	routerpkg "github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver/protowire"
 )
@@ -71,6 +72,7 @@ func (c *gRPCConnection) sendLoop() error {
 		if err != nil {
 			return err
 		}
+		c.bandwidthCounter.AddSent(message.Command().String(), proto.Size(messageProto))
 	}
 	return nil
 }
@@ -92,6 +94,7 @@ func (c *gRPCConnection) receiveLoop() error {
 			}
 			return err
 		}
+		c.bandwidthCounter.AddReceived(message.Command().String(), proto.Size(protoMessage))
 
 		messageNumber++
 		message.SetMessageNumber(messageNumber)
@@ -1,10 +1,13 @@
 package grpcserver
 
 import (
+	"crypto/ed25519"
 	"net"
 	"sync"
 	"sync/atomic"
 
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver/protowire"
 	"github.com/pkg/errors"
@@ -18,6 +21,8 @@ import (
 type gRPCConnection struct {
 	server                   *gRPCServer
 	address                  *net.TCPAddr
+	netAddress               *appmessage.NetAddress
+	identityKey              ed25519.PublicKey
 	stream                   grpcStream
 	router                   *router.Router
 	lowLevelClientConnection *grpc.ClientConn
@@ -34,6 +39,8 @@ type gRPCConnection struct {
 	onInvalidMessageHandler server.OnInvalidMessageHandler
 
 	isConnected uint32
+
+	bandwidthCounter *bandwidth.Counter
 }
 
 type grpcStream interface {
@@ -41,16 +48,20 @@ type grpcStream interface {
 	Recv() (*protowire.HarbidMessage, error)
 }
 
-func newConnection(server *gRPCServer, address *net.TCPAddr, stream grpcStream,
-	lowLevelClientConnection *grpc.ClientConn) *gRPCConnection {
+func newConnection(server *gRPCServer, netAddress *appmessage.NetAddress, identityKey ed25519.PublicKey,
+	stream grpcStream, lowLevelClientConnection *grpc.ClientConn) *gRPCConnection {
 	connection := &gRPCConnection{
 		server:                   server,
-		address:                  address,
+		address:                  netAddress.TCPAddress(),
+		netAddress:               netAddress,
+		identityKey:              identityKey,
 		stream:                   stream,
 		stopChan:                 make(chan struct{}),
 		isConnected:              1,
 		lowLevelClientConnection: lowLevelClientConnection,
+		bandwidthCounter:         bandwidth.NewCounter(server.bandwidthCounter),
 	}
+	connection.bandwidthCounter.SetUploadRateLimit(server.peerUploadRateLimit)
 
 	return connection
 }
@@ -123,10 +134,33 @@ func (c *gRPCConnection) Disconnect() {
 	}
 }
 
+// BandwidthCounter returns the counter of the bytes sent and received over the connection
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) BandwidthCounter() *bandwidth.Counter {
+	return c.bandwidthCounter
+}
+
 func (c *gRPCConnection) Address() *net.TCPAddr {
 	return c.address
 }
 
+// NetAddress returns the address of the remote side of the connection. Unlike Address, it
+// keeps the onion host name of onion addresses.
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) NetAddress() *appmessage.NetAddress {
+	return c.netAddress
+}
+
+// IdentityKey returns the identity key the peer authenticated with, or nil if the
+// connection isn't encrypted
+//
+// This is part of the Connection interface
+func (c *gRPCConnection) IdentityKey() ed25519.PublicKey {
+	return c.identityKey
+}
+
 func (c *gRPCConnection) receive() (*protowire.HarbidMessage, error) {
 	// We use RLock here and in send() because they can work
 	// in parallel. closeSend(), however, must not have either
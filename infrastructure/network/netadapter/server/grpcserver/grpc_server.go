@@ -3,6 +3,8 @@ package grpcserver
 import (
 	"context"
 	"fmt"
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
 	"github.com/kobradag/kobrad/util/panics"
 	"github.com/pkg/errors"
@@ -22,18 +24,29 @@ type gRPCServer struct {
 	maxInboundConnections      int
 	inboundConnectionCount     int
 	inboundConnectionCountLock *sync.Mutex
+
+	// bandwidthCounter counts the bytes of all the connections of the server
+	bandwidthCounter *bandwidth.Counter
+
+	// peerUploadRateLimit is the upload rate limit of every connection, in bytes
+	// per second. 0 means unlimited.
+	peerUploadRateLimit uint64
 }
 
-// newGRPCServer creates a gRPC server
-func newGRPCServer(listeningAddresses []string, maxMessageSize int, maxInboundConnections int, name string) *gRPCServer {
+// newGRPCServer creates a gRPC server. serverOptions are added to the options of the underlying grpc.Server.
+func newGRPCServer(listeningAddresses []string, maxMessageSize int, maxInboundConnections int, name string,
+	serverOptions ...grpc.ServerOption) *gRPCServer {
+
 	log.Debugf("Created new %s GRPC server with maxMessageSize %d and maxInboundConnections %d", name, maxMessageSize, maxInboundConnections)
+	serverOptions = append(serverOptions, grpc.MaxRecvMsgSize(maxMessageSize), grpc.MaxSendMsgSize(maxMessageSize))
 	return &gRPCServer{
-		server:                     grpc.NewServer(grpc.MaxRecvMsgSize(maxMessageSize), grpc.MaxSendMsgSize(maxMessageSize)),
+		server:                     grpc.NewServer(serverOptions...),
 		listeningAddresses:         listeningAddresses,
 		name:                       name,
 		maxInboundConnections:      maxInboundConnections,
 		inboundConnectionCount:     0,
 		inboundConnectionCountLock: &sync.Mutex{},
+		bandwidthCounter:           bandwidth.NewCounter(nil),
 	}
 }
 
@@ -93,6 +106,19 @@ func (s *gRPCServer) SetOnConnectedHandler(onConnectedHandler server.OnConnected
 	s.onConnectedHandler = onConnectedHandler
 }
 
+// BandwidthCounter returns the counter of the bytes of all the connections of the server
+func (s *gRPCServer) BandwidthCounter() *bandwidth.Counter {
+	return s.bandwidthCounter
+}
+
+// SetUploadRateLimits sets the upload rate limits, in bytes per second, of all the
+// connections of the server together and of every connection separately. 0 means unlimited.
+// It must be called before the server is started.
+func (s *gRPCServer) SetUploadRateLimits(uploadRateLimit uint64, peerUploadRateLimit uint64) {
+	s.bandwidthCounter.SetUploadRateLimit(uploadRateLimit)
+	s.peerUploadRateLimit = peerUploadRateLimit
+}
+
 func (s *gRPCServer) handleInboundConnection(ctx context.Context, stream grpcStream) error {
 	connectionCount, err := s.incrementInboundConnectionCountAndLimitIfRequired()
 	if err != nil {
@@ -109,7 +135,8 @@ func (s *gRPCServer) handleInboundConnection(ctx context.Context, stream grpcStr
 		return errors.Errorf("non-tcp connections are not supported")
 	}
 
-	connection := newConnection(s, tcpAddress, stream, nil)
+	connection := newConnection(s, appmessage.NewNetAddress(tcpAddress), identityKeyFromAuthInfo(peerInfo.AuthInfo),
+		stream, nil)
 
 	err = s.onConnectedHandler(connection)
 	if err != nil {
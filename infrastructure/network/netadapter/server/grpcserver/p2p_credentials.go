@@ -0,0 +1,215 @@
+package grpcserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+// P2PEncryptionConfig configures the encryption of P2P connections. P2P connections are
+// encrypted with TLS 1.3 opportunistically: outbound connections try TLS first and fall back
+// to plaintext if the peer doesn't support it, and inbound connections are served over TLS
+// if the peer starts a TLS handshake. Both sides authenticate with their identity key, which
+// is the key of a self-signed certificate that's created on startup.
+type P2PEncryptionConfig struct {
+	IdentityKey ed25519.PrivateKey
+
+	// PinnedKeys maps the addresses of peers to the identity keys they must have. Connections
+	// to these addresses are never made in plaintext.
+	PinnedKeys map[string]ed25519.PublicKey
+}
+
+// tlsRecordTypeHandshake is the first byte of a TLS ClientHello. Plaintext HTTP/2
+// connections start with "PRI", so they're never mistaken for TLS ones.
+const tlsRecordTypeHandshake = 0x16
+
+const p2pAuthType = "kobrad-p2p-tls"
+
+// p2pAuthInfo is the AuthInfo of P2P connections. identityKey is nil if the
+// connection isn't encrypted.
+type p2pAuthInfo struct {
+	credentials.CommonAuthInfo
+	identityKey ed25519.PublicKey
+}
+
+func (p2pAuthInfo) AuthType() string {
+	return p2pAuthType
+}
+
+// p2pCredentials implements credentials.TransportCredentials for P2P connections
+type p2pCredentials struct {
+	certificate tls.Certificate
+
+	// pinnedKey is the identity key the peer must have in an outbound connection, or nil
+	pinnedKey ed25519.PublicKey
+
+	// handshakeStarted is set once a client handshake starts, meaning the peer was reached
+	// and a failure to connect is due to the handshake
+	handshakeStarted uint32
+}
+
+func newP2PCredentials(identityKey ed25519.PrivateKey, pinnedKey ed25519.PublicKey) (*p2pCredentials, error) {
+	certificate, err := selfSignedCertificate(identityKey)
+	if err != nil {
+		return nil, err
+	}
+	return &p2pCredentials{certificate: certificate, pinnedKey: pinnedKey}, nil
+}
+
+// selfSignedCertificate creates a certificate for the given identity key. Certificates
+// aren't checked against any authority, they only carry the identity key, which the TLS
+// handshake proves the possession of.
+func selfSignedCertificate(identityKey ed25519.PrivateKey) (tls.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, identityKey.Public(), identityKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{certificate}, PrivateKey: identityKey}, nil
+}
+
+// verifyPeerCertificate returns a function that extracts the identity key of the peer
+// into identityKey, and checks it against pinnedKey if it's not nil
+func verifyPeerCertificate(pinnedKey ed25519.PublicKey,
+	identityKey *ed25519.PublicKey) func([][]byte, [][]*x509.Certificate) error {
+
+	return func(rawCertificates [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCertificates) != 1 {
+			return errors.Errorf("expected a single certificate, got %d", len(rawCertificates))
+		}
+		certificate, err := x509.ParseCertificate(rawCertificates[0])
+		if err != nil {
+			return err
+		}
+		publicKey, ok := certificate.PublicKey.(ed25519.PublicKey)
+		if !ok {
+			return errors.Errorf("the identity key of the peer is not an ed25519 key")
+		}
+		if pinnedKey != nil && !bytes.Equal(publicKey, pinnedKey) {
+			return errors.Errorf("the identity key of the peer is %x instead of the pinned %x", publicKey, pinnedKey)
+		}
+		*identityKey = publicKey
+		return nil
+	}
+}
+
+func (c *p2pCredentials) tlsConfig(identityKey *ed25519.PublicKey) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{c.certificate},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// Certificates are self-signed, and are verified by VerifyPeerCertificate instead
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyPeerCertificate(c.pinnedKey, identityKey),
+	}
+}
+
+func (c *p2pCredentials) ClientHandshake(ctx context.Context, _ string, rawConnection net.Conn) (
+	net.Conn, credentials.AuthInfo, error) {
+
+	atomic.StoreUint32(&c.handshakeStarted, 1)
+
+	var identityKey ed25519.PublicKey
+	connection := tls.Client(rawConnection, c.tlsConfig(&identityKey))
+	err := connection.HandshakeContext(ctx)
+	if err != nil {
+		connection.Close()
+		return nil, nil, handshakeError{err}
+	}
+	return connection, newP2PAuthInfo(identityKey), nil
+}
+
+// handshakeError is an error of a client handshake. gRPC retries connecting after temporary
+// errors until the dial times out, while a failed handshake means that the peer doesn't support
+// encryption, or doesn't have the pinned identity key.
+type handshakeError struct {
+	error
+}
+
+func (handshakeError) Temporary() bool {
+	return false
+}
+
+// ServerHandshake serves the connection over TLS if the peer starts a TLS handshake, and in
+// plaintext otherwise
+func (c *p2pCredentials) ServerHandshake(rawConnection net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	connection := &peekedConnection{Conn: rawConnection, reader: bufio.NewReader(rawConnection)}
+	firstByte, err := connection.reader.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if firstByte[0] != tlsRecordTypeHandshake {
+		return connection, newP2PAuthInfo(nil), nil
+	}
+
+	var identityKey ed25519.PublicKey
+	tlsConnection := tls.Server(connection, c.tlsConfig(&identityKey))
+	err = tlsConnection.Handshake()
+	if err != nil {
+		tlsConnection.Close()
+		return nil, nil, err
+	}
+	return tlsConnection, newP2PAuthInfo(identityKey), nil
+}
+
+func newP2PAuthInfo(identityKey ed25519.PublicKey) p2pAuthInfo {
+	securityLevel := credentials.NoSecurity
+	if identityKey != nil {
+		securityLevel = credentials.PrivacyAndIntegrity
+	}
+	return p2pAuthInfo{
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: securityLevel},
+		identityKey:    identityKey,
+	}
+}
+
+func (c *p2pCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: p2pAuthType}
+}
+
+func (c *p2pCredentials) Clone() credentials.TransportCredentials {
+	return &p2pCredentials{certificate: c.certificate, pinnedKey: c.pinnedKey}
+}
+
+func (c *p2pCredentials) OverrideServerName(string) error {
+	return nil
+}
+
+// peekedConnection is a connection whose first bytes may have been peeked at
+type peekedConnection struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConnection) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// identityKeyFromAuthInfo returns the identity key of the peer of a P2P connection,
+// or nil if the connection isn't encrypted
+func identityKeyFromAuthInfo(authInfo credentials.AuthInfo) ed25519.PublicKey {
+	p2pAuthInfo, ok := authInfo.(p2pAuthInfo)
+	if !ok {
+		return nil
+	}
+	return p2pAuthInfo.identityKey
+}
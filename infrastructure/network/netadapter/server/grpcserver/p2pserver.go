@@ -2,20 +2,27 @@ package grpcserver
 
 import (
 	"context"
+	"github.com/kobradag/kobrad/app/appmessage"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver/protowire"
 	"github.com/kobradag/kobrad/util/panics"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/peer"
 	"net"
+	"sync/atomic"
 	"time"
 )
 
 type p2pServer struct {
 	protowire.UnimplementedP2PServer
 	gRPCServer
+	dial        server.DialFunc
+	dialTimeout time.Duration
+	encryption  *P2PEncryptionConfig
 }
 
 const p2pMaxMessageSize = 1024 * 1024 * 1024 // 1GB
@@ -26,10 +33,27 @@ const p2pMaxMessageSize = 1024 * 1024 * 1024 // 1GB
 // is handled in the ConnectionManager instead.
 const p2pMaxInboundConnections = 0
 
-// NewP2PServer creates a new P2PServer
-func NewP2PServer(listeningAddresses []string) (server.P2PServer, error) {
-	gRPCServer := newGRPCServer(listeningAddresses, p2pMaxMessageSize, p2pMaxInboundConnections, "P2P")
-	p2pServer := &p2pServer{gRPCServer: *gRPCServer}
+// NewP2PServer creates a new P2PServer. Outbound connections are made using dial, which
+// may go through a proxy, and time out after dialTimeout. Connections are encrypted
+// opportunistically according to encryption, or never if it's nil.
+func NewP2PServer(listeningAddresses []string, dial server.DialFunc, dialTimeout time.Duration,
+	encryption *P2PEncryptionConfig) (server.P2PServer, error) {
+
+	var serverOptions []grpc.ServerOption
+	if encryption != nil {
+		serverCredentials, err := newP2PCredentials(encryption.IdentityKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		serverOptions = append(serverOptions, grpc.Creds(serverCredentials))
+	}
+	gRPCServer := newGRPCServer(listeningAddresses, p2pMaxMessageSize, p2pMaxInboundConnections, "P2P", serverOptions...)
+	p2pServer := &p2pServer{
+		gRPCServer:  *gRPCServer,
+		dial:        dial,
+		dialTimeout: dialTimeout,
+		encryption:  encryption,
+	}
 	protowire.RegisterP2PServer(gRPCServer.server, p2pServer)
 	return p2pServer, nil
 }
@@ -40,16 +64,38 @@ func (p *p2pServer) MessageStream(stream protowire.P2P_MessageStreamServer) erro
 	return p.handleInboundConnection(stream.Context(), stream)
 }
 
-// Connect connects to the given address
+// Connect connects to the given address. If encryption is enabled, an encrypted connection
+// is tried first, and a plaintext one is made if the peer doesn't support encryption, unless
+// the identity key of the peer is pinned.
 // This is part of the P2PServer interface
 func (p *p2pServer) Connect(address string) (server.Connection, error) {
+	if p.encryption == nil {
+		return p.connect(address, insecure.NewCredentials())
+	}
+
+	pinnedKey := p.encryption.PinnedKeys[address]
+	clientCredentials, err := newP2PCredentials(p.encryption.IdentityKey, pinnedKey)
+	if err != nil {
+		return nil, err
+	}
+	connection, err := p.connect(address, clientCredentials)
+	if err == nil || pinnedKey != nil || atomic.LoadUint32(&clientCredentials.handshakeStarted) == 0 {
+		return connection, err
+	}
+	log.Debugf("%s Couldn't make an encrypted connection to %s, falling back to plaintext: %s", p.name, address, err)
+	return p.connect(address, insecure.NewCredentials())
+}
+
+func (p *p2pServer) connect(address string, transportCredentials credentials.TransportCredentials) (
+	server.Connection, error) {
+
 	log.Debugf("%s Dialing to %s", p.name, address)
 
-	const dialTimeout = 1 * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), p.dialTimeout)
 	defer cancel()
 
-	gRPCClientConnection, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	gRPCClientConnection, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithBlock(), grpc.FailOnNonTempDialError(true), grpc.WithContextDialer(p.dialContext))
 	if err != nil {
 		return nil, errors.Wrapf(err, "%s error connecting to %s", p.name, address)
 	}
@@ -65,12 +111,13 @@ func (p *p2pServer) Connect(address string) (server.Connection, error) {
 	if !ok {
 		return nil, errors.Errorf("%s error getting stream peer info from context for %s", p.name, address)
 	}
-	tcpAddress, ok := peerInfo.Addr.(*net.TCPAddr)
-	if !ok {
-		return nil, errors.Errorf("non-tcp addresses are not supported")
+	netAddress, err := outboundNetAddress(address, peerInfo.Addr)
+	if err != nil {
+		return nil, err
 	}
 
-	connection := newConnection(&p.gRPCServer, tcpAddress, stream, gRPCClientConnection)
+	connection := newConnection(&p.gRPCServer, netAddress, identityKeyFromAuthInfo(peerInfo.AuthInfo), stream,
+		gRPCClientConnection)
 
 	err = p.onConnectedHandler(connection)
 	if err != nil {
@@ -81,3 +128,26 @@ func (p *p2pServer) Connect(address string) (server.Connection, error) {
 
 	return connection, nil
 }
+
+func (p *p2pServer) dialContext(ctx context.Context, address string) (net.Conn, error) {
+	timeout := p.dialTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	return p.dial("tcp", address, timeout)
+}
+
+// outboundNetAddress returns the address of an outbound connection to the given dialed
+// address. Connections that were made through a proxy have the address of the proxy as
+// their remote address, so the dialed address is used if it's an IP or an onion address.
+func outboundNetAddress(dialedAddress string, remoteAddress net.Addr) (*appmessage.NetAddress, error) {
+	netAddress, err := appmessage.ParseNetAddress(dialedAddress)
+	if err == nil {
+		return netAddress, nil
+	}
+	tcpAddress, ok := remoteAddress.(*net.TCPAddr)
+	if !ok {
+		return nil, errors.Errorf("non-tcp addresses are not supported")
+	}
+	return appmessage.NewNetAddress(tcpAddress), nil
+}
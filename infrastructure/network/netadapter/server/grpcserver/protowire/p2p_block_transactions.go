@@ -0,0 +1,48 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_BlockTransactions) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_BlockTransactions is nil")
+	}
+	return x.BlockTransactions.toAppMessage()
+}
+
+func (x *BlockTransactionsMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "BlockTransactionsMessage is nil")
+	}
+	blockHash, err := x.BlockHash.toDomain()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]*appmessage.MsgTx, len(x.Transactions))
+	for i, protoTransaction := range x.Transactions {
+		msgTx, err := protoTransaction.toAppMessage()
+		if err != nil {
+			return nil, err
+		}
+		transactions[i] = msgTx.(*appmessage.MsgTx)
+	}
+	return appmessage.NewMsgBlockTransactions(blockHash, transactions), nil
+}
+
+func (x *HarbidMessage_BlockTransactions) fromAppMessage(msgBlockTransactions *appmessage.MsgBlockTransactions) error {
+	protoTransactions := make([]*TransactionMessage, len(msgBlockTransactions.Transactions))
+	for i, transaction := range msgBlockTransactions.Transactions {
+		protoTransaction := new(TransactionMessage)
+		protoTransaction.fromAppMessage(transaction)
+		protoTransactions[i] = protoTransaction
+	}
+
+	x.BlockTransactions = &BlockTransactionsMessage{
+		BlockHash:    domainHashToProto(msgBlockTransactions.BlockHash),
+		Transactions: protoTransactions,
+	}
+	return nil
+}
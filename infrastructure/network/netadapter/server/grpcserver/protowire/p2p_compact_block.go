@@ -0,0 +1,78 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_CompactBlock) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_CompactBlock is nil")
+	}
+	return x.CompactBlock.toAppMessage()
+}
+
+func (x *HarbidMessage_CompactBlock) fromAppMessage(msgCompactBlock *appmessage.MsgCompactBlock) error {
+	x.CompactBlock = new(CompactBlockMessage)
+	return x.CompactBlock.fromAppMessage(msgCompactBlock)
+}
+
+func (x *CompactBlockMessage) toAppMessage() (*appmessage.MsgCompactBlock, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "CompactBlockMessage is nil")
+	}
+	header, err := x.Header.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	prefilledTransactions := make([]*appmessage.PrefilledTransaction, len(x.PrefilledTransactions))
+	for i, protoPrefilledTransaction := range x.PrefilledTransactions {
+		prefilledTransactions[i], err = protoPrefilledTransaction.toAppMessage()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return appmessage.NewMsgCompactBlock(header, x.ShortIdNonce, x.ShortIds, prefilledTransactions), nil
+}
+
+func (x *CompactBlockMessage) fromAppMessage(msgCompactBlock *appmessage.MsgCompactBlock) error {
+	protoHeader := new(BlockHeader)
+	err := protoHeader.fromAppMessage(msgCompactBlock.Header)
+	if err != nil {
+		return err
+	}
+
+	protoPrefilledTransactions := make([]*PrefilledTransaction, len(msgCompactBlock.PrefilledTransactions))
+	for i, prefilledTransaction := range msgCompactBlock.PrefilledTransactions {
+		protoTransaction := new(TransactionMessage)
+		protoTransaction.fromAppMessage(prefilledTransaction.Transaction)
+		protoPrefilledTransactions[i] = &PrefilledTransaction{
+			Index:       prefilledTransaction.Index,
+			Transaction: protoTransaction,
+		}
+	}
+
+	*x = CompactBlockMessage{
+		Header:                protoHeader,
+		ShortIdNonce:          msgCompactBlock.ShortIDNonce,
+		ShortIds:              msgCompactBlock.ShortIDs,
+		PrefilledTransactions: protoPrefilledTransactions,
+	}
+	return nil
+}
+
+func (x *PrefilledTransaction) toAppMessage() (*appmessage.PrefilledTransaction, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "PrefilledTransaction is nil")
+	}
+	msgTx, err := x.Transaction.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+	return &appmessage.PrefilledTransaction{
+		Index:       x.Index,
+		Transaction: msgTx.(*appmessage.MsgTx),
+	}, nil
+}
@@ -0,0 +1,34 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_RequestBlockTransactions) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_RequestBlockTransactions is nil")
+	}
+	return x.RequestBlockTransactions.toAppMessage()
+}
+
+func (x *RequestBlockTransactionsMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "RequestBlockTransactionsMessage is nil")
+	}
+	blockHash, err := x.BlockHash.toDomain()
+	if err != nil {
+		return nil, err
+	}
+	return appmessage.NewMsgRequestBlockTransactions(blockHash, x.Indexes), nil
+}
+
+func (x *HarbidMessage_RequestBlockTransactions) fromAppMessage(
+	msgRequestBlockTransactions *appmessage.MsgRequestBlockTransactions) error {
+
+	x.RequestBlockTransactions = &RequestBlockTransactionsMessage{
+		BlockHash: domainHashToProto(msgRequestBlockTransactions.BlockHash),
+		Indexes:   msgRequestBlockTransactions.Indexes,
+	}
+	return nil
+}
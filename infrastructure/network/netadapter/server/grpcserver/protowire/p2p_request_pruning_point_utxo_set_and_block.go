@@ -2,6 +2,7 @@ package protowire
 
 import (
 	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/domain/consensus/model/externalapi"
 	"github.com/pkg/errors"
 )
 
@@ -20,7 +21,21 @@ func (x *RequestPruningPointUTXOSetMessage) toAppMessage() (appmessage.Message,
 	if err != nil {
 		return nil, err
 	}
-	return &appmessage.MsgRequestPruningPointUTXOSet{PruningPointHash: pruningPointHash}, nil
+	var fromOutpoint *externalapi.DomainOutpoint
+	if x.FromOutpoint != nil {
+		outpoint, err := x.FromOutpoint.toAppMessage()
+		if err != nil {
+			return nil, err
+		}
+		fromOutpoint = &externalapi.DomainOutpoint{
+			TransactionID: outpoint.TxID,
+			Index:         outpoint.Index,
+		}
+	}
+	return &appmessage.MsgRequestPruningPointUTXOSet{
+		PruningPointHash: pruningPointHash,
+		FromOutpoint:     fromOutpoint,
+	}, nil
 }
 
 func (x *HarbidMessage_RequestPruningPointUTXOSet) fromAppMessage(
@@ -28,5 +43,11 @@ func (x *HarbidMessage_RequestPruningPointUTXOSet) fromAppMessage(
 
 	x.RequestPruningPointUTXOSet = &RequestPruningPointUTXOSetMessage{}
 	x.RequestPruningPointUTXOSet.PruningPointHash = domainHashToProto(msgRequestPruningPointUTXOSet.PruningPointHash)
+	if msgRequestPruningPointUTXOSet.FromOutpoint != nil {
+		x.RequestPruningPointUTXOSet.FromOutpoint = &Outpoint{
+			TransactionId: domainTransactionIDToProto(&msgRequestPruningPointUTXOSet.FromOutpoint.TransactionID),
+			Index:         msgRequestPruningPointUTXOSet.FromOutpoint.Index,
+		}
+	}
 	return nil
 }
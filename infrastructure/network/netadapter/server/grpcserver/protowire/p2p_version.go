@@ -53,6 +53,7 @@ func (x *VersionMessage) toAppMessage() (appmessage.Message, error) {
 		ID:              appMsgID,
 		UserAgent:       x.UserAgent,
 		DisableRelayTx:  x.DisableRelayTx,
+		BlockRelayOnly:  x.BlockRelayOnly,
 		SubnetworkID:    subnetworkID,
 	}, nil
 }
@@ -83,6 +84,7 @@ func (x *HarbidMessage_Version) fromAppMessage(msgVersion *appmessage.MsgVersion
 		Id:              versionID,
 		UserAgent:       msgVersion.UserAgent,
 		DisableRelayTx:  msgVersion.DisableRelayTx,
+		BlockRelayOnly:  msgVersion.BlockRelayOnly,
 		SubnetworkId:    domainSubnetworkIDToProto(msgVersion.SubnetworkID),
 	}
 	return nil
@@ -0,0 +1,69 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_ExportPruningPointSnapshotRequest) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_ExportPruningPointSnapshotRequest is nil")
+	}
+	return x.ExportPruningPointSnapshotRequest.toAppMessage()
+}
+
+func (x *HarbidMessage_ExportPruningPointSnapshotRequest) fromAppMessage(message *appmessage.ExportPruningPointSnapshotRequestMessage) error {
+	x.ExportPruningPointSnapshotRequest = &ExportPruningPointSnapshotRequestMessage{
+		Path: message.Path,
+	}
+	return nil
+}
+
+func (x *ExportPruningPointSnapshotRequestMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "ExportPruningPointSnapshotRequestMessage is nil")
+	}
+	return &appmessage.ExportPruningPointSnapshotRequestMessage{
+		Path: x.Path,
+	}, nil
+}
+
+func (x *HarbidMessage_ExportPruningPointSnapshotResponse) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_ExportPruningPointSnapshotResponse is nil")
+	}
+	return x.ExportPruningPointSnapshotResponse.toAppMessage()
+}
+
+func (x *HarbidMessage_ExportPruningPointSnapshotResponse) fromAppMessage(message *appmessage.ExportPruningPointSnapshotResponseMessage) error {
+	var err *RPCError
+	if message.Error != nil {
+		err = &RPCError{Message: message.Error.Message}
+	}
+	x.ExportPruningPointSnapshotResponse = &ExportPruningPointSnapshotResponseMessage{
+		PruningPointHash: message.PruningPointHash,
+		HeaderCount:      message.HeaderCount,
+		BlockCount:       message.BlockCount,
+		UtxoCount:        message.UTXOCount,
+		Error:            err,
+	}
+	return nil
+}
+
+func (x *ExportPruningPointSnapshotResponseMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "ExportPruningPointSnapshotResponseMessage is nil")
+	}
+	rpcErr, err := x.Error.toAppMessage()
+	// Error is an optional field
+	if err != nil && !errors.Is(err, errorNil) {
+		return nil, err
+	}
+	return &appmessage.ExportPruningPointSnapshotResponseMessage{
+		PruningPointHash: x.PruningPointHash,
+		HeaderCount:      x.HeaderCount,
+		BlockCount:       x.BlockCount,
+		UTXOCount:        x.UtxoCount,
+		Error:            rpcErr,
+	}, nil
+}
@@ -37,6 +37,14 @@ func (x *HarbidMessage_GetConnectedPeerInfoResponse) fromAppMessage(message *app
 			AdvertisedProtocolVersion: info.AdvertisedProtocolVersion,
 			TimeConnected:             info.TimeConnected,
 			IsIbdPeer:                 info.IsIBDPeer,
+			BanScore:                  info.BanScore,
+			Reputation:                info.Reputation,
+			BytesSent:                 info.BytesSent,
+			BytesReceived:             info.BytesReceived,
+			BandwidthByMessageType:    messageTypeBandwidthsFromAppMessage(info.BandwidthByMessageType),
+			IsBlockRelayOnly:          info.IsBlockRelayOnly,
+			IsEncrypted:               info.IsEncrypted,
+			IdentityKey:               info.IdentityKey,
 		}
 	}
 	x.GetConnectedPeerInfoResponse = &GetConnectedPeerInfoResponseMessage{
@@ -78,6 +86,10 @@ func (x *GetConnectedPeerInfoMessage) toAppMessage() (*appmessage.GetConnectedPe
 	if x == nil {
 		return nil, errors.Wrapf(errorNil, "GetConnectedPeerInfoMessage is nil")
 	}
+	bandwidthByMessageType, err := messageTypeBandwidthsToAppMessage(x.BandwidthByMessageType)
+	if err != nil {
+		return nil, err
+	}
 	return &appmessage.GetConnectedPeerInfoMessage{
 		ID:                        x.Id,
 		Address:                   x.Address,
@@ -88,5 +100,13 @@ func (x *GetConnectedPeerInfoMessage) toAppMessage() (*appmessage.GetConnectedPe
 		AdvertisedProtocolVersion: x.AdvertisedProtocolVersion,
 		TimeConnected:             x.TimeOffset,
 		IsIBDPeer:                 x.IsIbdPeer,
+		BanScore:                  x.BanScore,
+		Reputation:                x.Reputation,
+		BytesSent:                 x.BytesSent,
+		BytesReceived:             x.BytesReceived,
+		BandwidthByMessageType:    bandwidthByMessageType,
+		IsBlockRelayOnly:          x.IsBlockRelayOnly,
+		IsEncrypted:               x.IsEncrypted,
+		IdentityKey:               x.IdentityKey,
 	}, nil
 }
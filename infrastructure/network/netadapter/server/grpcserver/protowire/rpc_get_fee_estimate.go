@@ -0,0 +1,117 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_GetFeeEstimateRequest) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_GetFeeEstimateRequest is nil")
+	}
+	return &appmessage.GetFeeEstimateRequestMessage{}, nil
+}
+
+func (x *HarbidMessage_GetFeeEstimateRequest) fromAppMessage(_ *appmessage.GetFeeEstimateRequestMessage) error {
+	x.GetFeeEstimateRequest = &GetFeeEstimateRequestMessage{}
+	return nil
+}
+
+func (x *HarbidMessage_GetFeeEstimateResponse) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_GetFeeEstimateResponse is nil")
+	}
+	return x.GetFeeEstimateResponse.toAppMessage()
+}
+
+func (x *HarbidMessage_GetFeeEstimateResponse) fromAppMessage(message *appmessage.GetFeeEstimateResponseMessage) error {
+	var rpcErr *RPCError
+	if message.Error != nil {
+		rpcErr = &RPCError{Message: message.Error.Message}
+	}
+	var estimate *RpcFeeEstimate
+	if message.Estimate != nil {
+		estimate = &RpcFeeEstimate{}
+		estimate.fromAppMessage(message.Estimate)
+	}
+	x.GetFeeEstimateResponse = &GetFeeEstimateResponseMessage{
+		Estimate: estimate,
+		Error:    rpcErr,
+	}
+	return nil
+}
+
+func (x *GetFeeEstimateResponseMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "GetFeeEstimateResponseMessage is nil")
+	}
+	rpcErr, err := x.Error.toAppMessage()
+	// Error is an optional field
+	if err != nil && !errors.Is(err, errorNil) {
+		return nil, err
+	}
+
+	if rpcErr != nil && x.Estimate != nil {
+		return nil, errors.New("GetFeeEstimateResponseMessage contains both an error and a response")
+	}
+
+	var estimate *appmessage.RPCFeeEstimate
+	if rpcErr == nil {
+		estimate, err = x.Estimate.toAppMessage()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &appmessage.GetFeeEstimateResponseMessage{
+		Estimate: estimate,
+		Error:    rpcErr,
+	}, nil
+}
+
+func (x *RpcFeeEstimate) toAppMessage() (*appmessage.RPCFeeEstimate, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "RpcFeeEstimate is nil")
+	}
+	priorityBucket, err := x.PriorityBucket.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+	normalBucket, err := x.NormalBucket.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+	lowBucket, err := x.LowBucket.toAppMessage()
+	if err != nil {
+		return nil, err
+	}
+	return &appmessage.RPCFeeEstimate{
+		PriorityBucket: priorityBucket,
+		NormalBucket:   normalBucket,
+		LowBucket:      lowBucket,
+	}, nil
+}
+
+func (x *RpcFeeEstimate) fromAppMessage(message *appmessage.RPCFeeEstimate) {
+	x.PriorityBucket = &RpcFeeRateBucket{}
+	x.PriorityBucket.fromAppMessage(message.PriorityBucket)
+	x.NormalBucket = &RpcFeeRateBucket{}
+	x.NormalBucket.fromAppMessage(message.NormalBucket)
+	x.LowBucket = &RpcFeeRateBucket{}
+	x.LowBucket.fromAppMessage(message.LowBucket)
+}
+
+func (x *RpcFeeRateBucket) toAppMessage() (*appmessage.RPCFeeRateBucket, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "RpcFeeRateBucket is nil")
+	}
+	return &appmessage.RPCFeeRateBucket{
+		FeeRate:          x.FeeRate,
+		EstimatedSeconds: x.EstimatedSeconds,
+	}, nil
+}
+
+func (x *RpcFeeRateBucket) fromAppMessage(message *appmessage.RPCFeeRateBucket) {
+	x.FeeRate = message.FeeRate
+	x.EstimatedSeconds = message.EstimatedSeconds
+}
@@ -0,0 +1,95 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_GetNetTotalsRequest) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_GetNetTotalsRequest is nil")
+	}
+	return &appmessage.GetNetTotalsRequestMessage{}, nil
+}
+
+func (x *HarbidMessage_GetNetTotalsRequest) fromAppMessage(_ *appmessage.GetNetTotalsRequestMessage) error {
+	x.GetNetTotalsRequest = &GetNetTotalsRequestMessage{}
+	return nil
+}
+
+func (x *HarbidMessage_GetNetTotalsResponse) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_GetNetTotalsResponse is nil")
+	}
+	return x.GetNetTotalsResponse.toAppMessage()
+}
+
+func (x *HarbidMessage_GetNetTotalsResponse) fromAppMessage(message *appmessage.GetNetTotalsResponseMessage) error {
+	var err *RPCError
+	if message.Error != nil {
+		err = &RPCError{Message: message.Error.Message}
+	}
+	x.GetNetTotalsResponse = &GetNetTotalsResponseMessage{
+		TotalBytesSent:         message.TotalBytesSent,
+		TotalBytesReceived:     message.TotalBytesReceived,
+		MaxUploadRate:          message.MaxUploadRate,
+		MaxPeerUploadRate:      message.MaxPeerUploadRate,
+		BandwidthByMessageType: messageTypeBandwidthsFromAppMessage(message.BandwidthByMessageType),
+		Error:                  err,
+	}
+	return nil
+}
+
+func (x *GetNetTotalsResponseMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "GetNetTotalsResponseMessage is nil")
+	}
+	rpcErr, err := x.Error.toAppMessage()
+	// Error is an optional field
+	if err != nil && !errors.Is(err, errorNil) {
+		return nil, err
+	}
+	bandwidthByMessageType, err := messageTypeBandwidthsToAppMessage(x.BandwidthByMessageType)
+	if err != nil {
+		return nil, err
+	}
+	return &appmessage.GetNetTotalsResponseMessage{
+		TotalBytesSent:         x.TotalBytesSent,
+		TotalBytesReceived:     x.TotalBytesReceived,
+		MaxUploadRate:          x.MaxUploadRate,
+		MaxPeerUploadRate:      x.MaxPeerUploadRate,
+		BandwidthByMessageType: bandwidthByMessageType,
+		Error:                  rpcErr,
+	}, nil
+}
+
+func messageTypeBandwidthsFromAppMessage(bandwidths []*appmessage.MessageTypeBandwidth) []*MessageTypeBandwidthMessage {
+	protoBandwidths := make([]*MessageTypeBandwidthMessage, len(bandwidths))
+	for i, bandwidth := range bandwidths {
+		protoBandwidths[i] = &MessageTypeBandwidthMessage{
+			Command:          bandwidth.Command,
+			BytesSent:        bandwidth.BytesSent,
+			BytesReceived:    bandwidth.BytesReceived,
+			MessagesSent:     bandwidth.MessagesSent,
+			MessagesReceived: bandwidth.MessagesReceived,
+		}
+	}
+	return protoBandwidths
+}
+
+func messageTypeBandwidthsToAppMessage(protoBandwidths []*MessageTypeBandwidthMessage) ([]*appmessage.MessageTypeBandwidth, error) {
+	bandwidths := make([]*appmessage.MessageTypeBandwidth, len(protoBandwidths))
+	for i, protoBandwidth := range protoBandwidths {
+		if protoBandwidth == nil {
+			return nil, errors.Wrapf(errorNil, "MessageTypeBandwidthMessage is nil")
+		}
+		bandwidths[i] = &appmessage.MessageTypeBandwidth{
+			Command:          protoBandwidth.Command,
+			BytesSent:        protoBandwidth.BytesSent,
+			BytesReceived:    protoBandwidth.BytesReceived,
+			MessagesSent:     protoBandwidth.MessagesSent,
+			MessagesReceived: protoBandwidth.MessagesReceived,
+		}
+	}
+	return bandwidths, nil
+}
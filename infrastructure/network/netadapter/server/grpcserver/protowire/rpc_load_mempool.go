@@ -0,0 +1,49 @@
+package protowire
+
+import (
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/pkg/errors"
+)
+
+func (x *HarbidMessage_LoadMempoolRequest) toAppMessage() (appmessage.Message, error) {
+	return &appmessage.LoadMempoolRequestMessage{}, nil
+}
+
+func (x *HarbidMessage_LoadMempoolRequest) fromAppMessage(_ *appmessage.LoadMempoolRequestMessage) error {
+	x.LoadMempoolRequest = &LoadMempoolRequestMessage{}
+	return nil
+}
+
+func (x *HarbidMessage_LoadMempoolResponse) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "HarbidMessage_LoadMempoolResponse is nil")
+	}
+	return x.LoadMempoolResponse.toAppMessage()
+}
+
+func (x *HarbidMessage_LoadMempoolResponse) fromAppMessage(message *appmessage.LoadMempoolResponseMessage) error {
+	var err *RPCError
+	if message.Error != nil {
+		err = &RPCError{Message: message.Error.Message}
+	}
+	x.LoadMempoolResponse = &LoadMempoolResponseMessage{
+		AcceptedTransactionCount: message.AcceptedTransactionCount,
+		Error:                    err,
+	}
+	return nil
+}
+
+func (x *LoadMempoolResponseMessage) toAppMessage() (appmessage.Message, error) {
+	if x == nil {
+		return nil, errors.Wrapf(errorNil, "LoadMempoolResponseMessage is nil")
+	}
+	rpcErr, err := x.Error.toAppMessage()
+	// Error is an optional field
+	if err != nil && !errors.Is(err, errorNil) {
+		return nil, err
+	}
+	return &appmessage.LoadMempoolResponseMessage{
+		AcceptedTransactionCount: x.AcceptedTransactionCount,
+		Error:                    rpcErr,
+	}, nil
+}
@@ -16,8 +16,9 @@ func (x *HarbidMessage_SubmitTransactionRequest) toAppMessage() (appmessage.Mess
 
 func (x *HarbidMessage_SubmitTransactionRequest) fromAppMessage(message *appmessage.SubmitTransactionRequestMessage) error {
 	x.SubmitTransactionRequest = &SubmitTransactionRequestMessage{
-		Transaction: &RpcTransaction{},
-		AllowOrphan: message.AllowOrphan,
+		Transaction:      &RpcTransaction{},
+		AllowOrphan:      message.AllowOrphan,
+		AllowReplacement: message.AllowReplacement,
 	}
 	x.SubmitTransactionRequest.Transaction.fromAppMessage(message.Transaction)
 	return nil
@@ -32,8 +33,9 @@ func (x *SubmitTransactionRequestMessage) toAppMessage() (appmessage.Message, er
 		return nil, err
 	}
 	return &appmessage.SubmitTransactionRequestMessage{
-		Transaction: rpcTransaction,
-		AllowOrphan: x.AllowOrphan,
+		Transaction:      rpcTransaction,
+		AllowOrphan:      x.AllowOrphan,
+		AllowReplacement: x.AllowReplacement,
 	}, nil
 }
 
@@ -50,8 +52,9 @@ func (x *HarbidMessage_SubmitTransactionResponse) fromAppMessage(message *appmes
 		err = &RPCError{Message: message.Error.Message}
 	}
 	x.SubmitTransactionResponse = &SubmitTransactionResponseMessage{
-		TransactionId: message.TransactionID,
-		Error:         err,
+		TransactionId:          message.TransactionID,
+		ReplacedTransactionIds: message.ReplacedTransactionIDs,
+		Error:                  err,
 	}
 	return nil
 }
@@ -66,8 +69,9 @@ func (x *SubmitTransactionResponseMessage) toAppMessage() (appmessage.Message, e
 		return nil, err
 	}
 	return &appmessage.SubmitTransactionResponseMessage{
-		TransactionID: x.TransactionId,
-		Error:         rpcErr,
+		TransactionID:          x.TransactionId,
+		ReplacedTransactionIDs: x.ReplacedTransactionIds,
+		Error:                  rpcErr,
 	}, nil
 }
 
@@ -0,0 +1,303 @@
+package jsonrpcserver
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/logger"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
+	routerpkg "github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
+	"github.com/pkg/errors"
+)
+
+// writeFunc sends data to the client of a connection.
+// It's called with nil data when a request was handled but no
+// response should be sent, as is the case for JSON-RPC notifications.
+type writeFunc func(data []byte) error
+
+// pendingRequest is a request that was passed to the router and
+// whose response is yet to be sent
+type pendingRequest struct {
+	id          json.RawMessage
+	expectReply bool
+}
+
+type jsonRPCConnection struct {
+	server      *jsonRPCServer
+	address     *net.TCPAddr
+	isWebSocket bool
+	write       writeFunc
+	router      *routerpkg.Router
+
+	// The RPC handlers process the requests of every connection one at a
+	// time, so responses arrive in the same order their requests were
+	// routed. pendingRequests is used to match each response to its ID.
+	pendingRequests     []*pendingRequest
+	pendingRequestsLock sync.Mutex
+	messageNumber       uint64
+
+	stopChan                chan struct{}
+	onDisconnectedHandler   server.OnDisconnectedHandler
+	onInvalidMessageHandler server.OnInvalidMessageHandler
+
+	isConnected uint32
+
+	bandwidthCounter *bandwidth.Counter
+}
+
+func newConnection(server *jsonRPCServer, address *net.TCPAddr, isWebSocket bool, write writeFunc) *jsonRPCConnection {
+	return &jsonRPCConnection{
+		server:      server,
+		address:     address,
+		isWebSocket: isWebSocket,
+		write:       write,
+		stopChan:    make(chan struct{}),
+		isConnected: 1,
+
+		bandwidthCounter: bandwidth.NewCounter(nil),
+	}
+}
+
+func (c *jsonRPCConnection) Start(router *routerpkg.Router) {
+	if c.onDisconnectedHandler == nil {
+		panic(errors.New("onDisconnectedHandler is nil"))
+	}
+
+	c.router = router
+
+	spawn("jsonRPCConnection.Start-sendLoop", func() {
+		err := c.sendLoop()
+		if err != nil {
+			log.Errorf("error from sendLoop for %s: %s", c, err)
+		}
+		c.Disconnect()
+	})
+}
+
+func (c *jsonRPCConnection) String() string {
+	return c.Address().String()
+}
+
+func (c *jsonRPCConnection) IsConnected() bool {
+	return atomic.LoadUint32(&c.isConnected) != 0
+}
+
+func (c *jsonRPCConnection) SetOnDisconnectedHandler(onDisconnectedHandler server.OnDisconnectedHandler) {
+	c.onDisconnectedHandler = onDisconnectedHandler
+}
+
+func (c *jsonRPCConnection) SetOnInvalidMessageHandler(onInvalidMessageHandler server.OnInvalidMessageHandler) {
+	c.onInvalidMessageHandler = onInvalidMessageHandler
+}
+
+// IsOutbound always returns false, since JSON-RPC connections are only ever inbound
+func (c *jsonRPCConnection) IsOutbound() bool {
+	return false
+}
+
+// BandwidthCounter returns the counter of the bytes sent and received over the connection
+//
+// This is part of the Connection interface
+func (c *jsonRPCConnection) BandwidthCounter() *bandwidth.Counter {
+	return c.bandwidthCounter
+}
+
+func (c *jsonRPCConnection) Address() *net.TCPAddr {
+	return c.address
+}
+
+// NetAddress returns the address of the remote side of the connection
+//
+// This is part of the Connection interface
+func (c *jsonRPCConnection) NetAddress() *appmessage.NetAddress {
+	return appmessage.NewNetAddress(c.address)
+}
+
+// IdentityKey always returns nil, since JSON-RPC clients don't have identity keys
+//
+// This is part of the Connection interface
+func (c *jsonRPCConnection) IdentityKey() ed25519.PublicKey {
+	return nil
+}
+
+// Disconnect disconnects the connection
+// Calling this function a second time doesn't do anything
+//
+// This is part of the Connection interface
+func (c *jsonRPCConnection) Disconnect() {
+	if !atomic.CompareAndSwapUint32(&c.isConnected, 1, 0) {
+		return
+	}
+
+	close(c.stopChan)
+
+	log.Debugf("Disconnecting from %s", c)
+	if c.onDisconnectedHandler != nil {
+		c.onDisconnectedHandler()
+	}
+}
+
+// handleRequest parses a single JSON-RPC request and passes it to the router.
+// Malformed requests are answered directly. The returned error is non-nil only
+// if the connection can't be used anymore.
+func (c *jsonRPCConnection) handleRequest(data []byte) error {
+	req, responseErr := parseRequest(data)
+	if responseErr != nil {
+		return c.writeError(req, responseErr)
+	}
+	if !c.isWebSocket && isSubscriptionMethod(req.Method) {
+		return c.writeError(req, newResponseError(errorCodeInvalidRequest,
+			"method %s is only available over WebSocket", req.Method))
+	}
+	message, responseErr := requestToAppMessage(req)
+	if responseErr != nil {
+		return c.writeError(req, responseErr)
+	}
+
+	message.SetMessageNumber(atomic.AddUint64(&c.messageNumber, 1))
+	message.SetReceivedAt(time.Now())
+	c.bandwidthCounter.AddReceived(message.Command().String(), len(data))
+
+	log.Debugf("incoming '%s' message from %s (message number %d)", message.Command(), c,
+		message.MessageNumber())
+	log.Tracef("incoming '%s' message from %s (message number %d): %s", message.Command(),
+		c, message.MessageNumber(), logger.NewLogClosure(func() string {
+			return spew.Sdump(message)
+		}))
+
+	c.pushPendingRequest(&pendingRequest{id: req.ID, expectReply: !req.isNotification()})
+	err := c.router.EnqueueIncomingMessage(message)
+	if err != nil {
+		if errors.Is(err, routerpkg.ErrRouteClosed) {
+			return nil
+		}
+		if errors.Is(err, routerpkg.ErrRouteCapacityReached) {
+			return err
+		}
+
+		// The message is a valid RPC message that isn't handled by this node
+		c.popLastPendingRequest()
+		if c.onInvalidMessageHandler != nil {
+			c.onInvalidMessageHandler(err)
+		}
+		return c.writeError(req, newResponseError(errorCodeMethodNotFound, "method %s is not supported", req.Method))
+	}
+	return nil
+}
+
+func (c *jsonRPCConnection) writeError(req *request, responseErr *responseError) error {
+	var id json.RawMessage
+	if req != nil {
+		if req.isNotification() && responseErr.Code != errorCodeInvalidRequest {
+			return c.write(nil)
+		}
+		id = req.ID
+	}
+	data, err := marshalResponse(id, nil, responseErr)
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+func (c *jsonRPCConnection) sendLoop() error {
+	outgoingRoute := c.router.OutgoingRoute()
+	for c.IsConnected() {
+		message, err := outgoingRoute.Dequeue()
+		if err != nil {
+			if errors.Is(err, routerpkg.ErrRouteClosed) {
+				return nil
+			}
+			return err
+		}
+
+		log.Debugf("outgoing '%s' message to %s", message.Command(), c)
+		log.Tracef("outgoing '%s' message to %s: %s", message.Command(), c, logger.NewLogClosure(func() string {
+			return spew.Sdump(message)
+		}))
+
+		encoded, err := encodeAppMessage(message)
+		if err != nil {
+			return err
+		}
+
+		if encoded.isNotification {
+			if !c.isWebSocket {
+				continue
+			}
+			data, err := marshalNotification(encoded.method, encoded.body)
+			if err != nil {
+				return err
+			}
+			err = c.writeMessage(message, data)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		pending, ok := c.popPendingRequest()
+		if !ok {
+			return errors.Errorf("got a '%s' response with no matching request", message.Command())
+		}
+		if !pending.expectReply {
+			err = c.write(nil)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := marshalResponse(pending.id, encoded.body, encoded.rpcError)
+		if err != nil {
+			return err
+		}
+		err = c.writeMessage(message, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMessage writes the given encoding of the given message and counts its size
+func (c *jsonRPCConnection) writeMessage(message appmessage.Message, data []byte) error {
+	err := c.write(data)
+	if err != nil {
+		return err
+	}
+	c.bandwidthCounter.AddSent(message.Command().String(), len(data))
+	return nil
+}
+
+func (c *jsonRPCConnection) pushPendingRequest(pending *pendingRequest) {
+	c.pendingRequestsLock.Lock()
+	defer c.pendingRequestsLock.Unlock()
+
+	c.pendingRequests = append(c.pendingRequests, pending)
+}
+
+func (c *jsonRPCConnection) popPendingRequest() (*pendingRequest, bool) {
+	c.pendingRequestsLock.Lock()
+	defer c.pendingRequestsLock.Unlock()
+
+	if len(c.pendingRequests) == 0 {
+		return nil, false
+	}
+	pending := c.pendingRequests[0]
+	c.pendingRequests = c.pendingRequests[1:]
+	return pending, true
+}
+
+func (c *jsonRPCConnection) popLastPendingRequest() {
+	c.pendingRequestsLock.Lock()
+	defer c.pendingRequestsLock.Unlock()
+
+	c.pendingRequests = c.pendingRequests[:len(c.pendingRequests)-1]
+}
@@ -0,0 +1,25 @@
+/*
+Package jsonrpcserver implements a JSON-RPC 2.0 server for the node RPC,
+served over WebSocket and over plain HTTP POST.
+
+Requests are mapped onto the same RPC messages that are served over gRPC (see
+protowire/rpc.md). The method name of a request is the name of its message
+field in HarbidMessage without the "Request" suffix, and its params are the
+protobuf JSON representation of the request message:
+
+	{"jsonrpc":"2.0","id":1,"method":"getBlock","params":{"hash":"...","includeTransactions":true}}
+
+Results are the protobuf JSON representation of the response message, with
+all fields present. Note that 64-bit integers are represented as strings.
+If the node returns an RPC error, it is converted to a JSON-RPC error with
+code -32000.
+
+Notification subscriptions (notifyBlockAdded, notifyUtxosChanged, etc.) are
+only available over WebSocket. Notifications are pushed as JSON-RPC
+notifications whose method is the notification's message field name, e.g.:
+
+	{"jsonrpc":"2.0","method":"blockAddedNotification","params":{"block":{...}}}
+
+Batch requests are not supported.
+*/
+package jsonrpcserver
@@ -0,0 +1,241 @@
+package jsonrpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver/protowire"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// jsonRPCVersion is the only JSON-RPC version supported by the server
+const jsonRPCVersion = "2.0"
+
+// Error codes as defined by the JSON-RPC 2.0 specification
+const (
+	errorCodeParseError     = -32700
+	errorCodeInvalidRequest = -32600
+	errorCodeMethodNotFound = -32601
+	errorCodeInvalidParams  = -32602
+	errorCodeInternalError  = -32603
+
+	// errorCodeRPCError is used for errors returned by the RPC handlers
+	// themselves, such as a missing block or an invalid transaction
+	errorCodeRPCError = -32000
+)
+
+const (
+	requestSuffix      = "Request"
+	notificationSuffix = "Notification"
+
+	// errorFieldName is the name of the RPCError field that every RPC response message has
+	errorFieldName = "error"
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification returns whether the client does not expect a response to this request
+func (r *request) isNotification() bool {
+	return r.ID == nil
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+func newResponseError(code int, format string, args ...interface{}) *responseError {
+	return &responseError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+type notification struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+var marshalOptions = protojson.MarshalOptions{EmitUnpopulated: true}
+
+// payloadFields maps the JSON-RPC method names to their HarbidMessage payload fields.
+// The method name of a request is the JSON name of its payload field without the
+// "Request" suffix, e.g. "getBlockDagInfo" for "getBlockDagInfoRequest".
+var payloadFields = buildPayloadFields()
+
+func buildPayloadFields() map[string]protoreflect.FieldDescriptor {
+	fields := (&protowire.HarbidMessage{}).ProtoReflect().Descriptor().Oneofs().ByName("payload").Fields()
+
+	payloadFields := make(map[string]protoreflect.FieldDescriptor)
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !strings.HasSuffix(field.JSONName(), requestSuffix) {
+			continue
+		}
+		payloadFields[strings.TrimSuffix(field.JSONName(), requestSuffix)] = field
+	}
+	return payloadFields
+}
+
+// isSubscriptionMethod returns whether the given method registers or unregisters
+// a notification listener, which only makes sense on a persistent connection
+func isSubscriptionMethod(method string) bool {
+	return strings.HasPrefix(method, "notify") || strings.HasPrefix(method, "stopNotifying")
+}
+
+// parseRequest parses a single JSON-RPC request. If the returned request is not nil
+// and an error is returned, the error should be sent back with the request's ID.
+func parseRequest(data []byte) (*request, *responseError) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return nil, newResponseError(errorCodeInvalidRequest, "batch requests are not supported")
+	}
+
+	req := &request{}
+	err := json.Unmarshal(data, req)
+	if err != nil {
+		return nil, newResponseError(errorCodeParseError, "could not parse request: %s", err)
+	}
+	if req.JSONRPC != jsonRPCVersion {
+		return req, newResponseError(errorCodeInvalidRequest, "jsonrpc must be exactly \"%s\"", jsonRPCVersion)
+	}
+	if req.Method == "" {
+		return req, newResponseError(errorCodeInvalidRequest, "method is missing")
+	}
+	return req, nil
+}
+
+// requestToAppMessage converts the given JSON-RPC request into the appmessage of the
+// matching RPC request. The request params are the protobuf JSON representation of the
+// request message.
+func requestToAppMessage(req *request) (appmessage.Message, *responseError) {
+	field, ok := payloadFields[req.Method]
+	if !ok {
+		return nil, newResponseError(errorCodeMethodNotFound, "method %s does not exist", req.Method)
+	}
+
+	harbidMessage := &protowire.HarbidMessage{}
+	payload := harbidMessage.ProtoReflect().NewField(field)
+	params := bytes.TrimSpace(req.Params)
+	if len(params) > 0 && !bytes.Equal(params, []byte("null")) {
+		if params[0] != '{' {
+			return nil, newResponseError(errorCodeInvalidParams, "params must be an object")
+		}
+		err := protojson.Unmarshal(params, payload.Message().Interface())
+		if err != nil {
+			return nil, newResponseError(errorCodeInvalidParams, "invalid params: %s", err)
+		}
+	}
+	harbidMessage.ProtoReflect().Set(field, payload)
+
+	message, err := harbidMessage.ToAppMessage()
+	if err != nil {
+		return nil, newResponseError(errorCodeInvalidParams, "invalid params: %s", err)
+	}
+	if _, ok := appmessage.RPCMessageCommandToString[message.Command()]; !ok {
+		return nil, newResponseError(errorCodeMethodNotFound, "method %s does not exist", req.Method)
+	}
+	return message, nil
+}
+
+// encodedMessage is an outgoing appmessage converted to its JSON-RPC representation
+type encodedMessage struct {
+	// method is the JSON name of the message's payload field, e.g.
+	// "getBlockDagInfoResponse" or "blockAddedNotification"
+	method         string
+	isNotification bool
+
+	// body is the JSON representation of the payload, without its RPC error
+	body     json.RawMessage
+	rpcError *responseError
+}
+
+func encodeAppMessage(message appmessage.Message) (*encodedMessage, error) {
+	harbidMessage, err := protowire.FromAppMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	reflectMessage := harbidMessage.ProtoReflect()
+	field := reflectMessage.WhichOneof(reflectMessage.Descriptor().Oneofs().ByName("payload"))
+	if field == nil {
+		return nil, errors.Errorf("message %s has no payload", message.Command())
+	}
+	payload := reflectMessage.Get(field).Message()
+
+	encoded := &encodedMessage{
+		method:         field.JSONName(),
+		isNotification: strings.HasSuffix(field.JSONName(), notificationSuffix),
+	}
+
+	errorField := payload.Descriptor().Fields().ByJSONName(errorFieldName)
+	if errorField != nil && payload.Has(errorField) {
+		rpcError, ok := payload.Get(errorField).Message().Interface().(*protowire.RPCError)
+		if !ok {
+			return nil, errors.Errorf("unexpected error field in %s", field.JSONName())
+		}
+		encoded.rpcError = &responseError{Code: errorCodeRPCError, Message: rpcError.Message}
+		return encoded, nil
+	}
+
+	body, err := marshalOptions.Marshal(payload.Interface())
+	if err != nil {
+		return nil, errors.Wrapf(err, "error marshalling %s", field.JSONName())
+	}
+	if errorField != nil {
+		// Since unpopulated fields are emitted, the unset error field has to be removed explicitly
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(body, &fields)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		delete(fields, errorFieldName)
+		body, err = json.Marshal(fields)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	encoded.body = body
+	return encoded, nil
+}
+
+func marshalResponse(id json.RawMessage, result json.RawMessage, responseErr *responseError) ([]byte, error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	data, err := json.Marshal(&response{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Result:  result,
+		Error:   responseErr,
+	})
+	return data, errors.WithStack(err)
+}
+
+func marshalNotification(method string, params json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(&notification{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+	return data, errors.WithStack(err)
+}
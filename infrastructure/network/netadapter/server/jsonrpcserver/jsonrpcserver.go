@@ -0,0 +1,335 @@
+package jsonrpcserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server/grpcserver"
+	"github.com/kobradag/kobrad/util/panics"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// MaxMessageSize is the max size of a single JSON-RPC request
+const MaxMessageSize = grpcserver.RPCMaxMessageSize
+
+type jsonRPCServer struct {
+	onConnectedHandler server.OnConnectedHandler
+	listeningAddresses []string
+	allowedOrigins     []string
+	httpServer         *http.Server
+	webSocketServer    websocket.Server
+
+	maxWebSockets             int
+	maxConcurrentHTTPRequests int
+	webSocketCount            int
+	httpRequestCount          int
+	countLock                 sync.Mutex
+
+	connections     map[*jsonRPCConnection]struct{}
+	connectionsLock sync.Mutex
+}
+
+// NewJSONRPCServer creates a new server that serves JSON-RPC 2.0 requests over
+// WebSocket and over plain HTTP POST. Notifications are pushed to WebSocket
+// clients only.
+//
+// A maxWebSockets or maxConcurrentHTTPRequests of 0 means no limit.
+func NewJSONRPCServer(listeningAddresses []string, allowedOrigins []string,
+	maxWebSockets int, maxConcurrentHTTPRequests int) (server.Server, error) {
+
+	log.Debugf("Created new JSON-RPC server with maxWebSockets %d and maxConcurrentHTTPRequests %d",
+		maxWebSockets, maxConcurrentHTTPRequests)
+
+	s := &jsonRPCServer{
+		listeningAddresses:        listeningAddresses,
+		allowedOrigins:            allowedOrigins,
+		maxWebSockets:             maxWebSockets,
+		maxConcurrentHTTPRequests: maxConcurrentHTTPRequests,
+		connections:               make(map[*jsonRPCConnection]struct{}),
+	}
+	// Origins are checked in ServeHTTP, so no additional handshake is required
+	s.webSocketServer = websocket.Server{Handler: s.handleWebSocket}
+	s.httpServer = &http.Server{Handler: s}
+	return s, nil
+}
+
+func (s *jsonRPCServer) Start() error {
+	if s.onConnectedHandler == nil {
+		return errors.New("onConnectedHandler is nil")
+	}
+
+	for _, listenAddress := range s.listeningAddresses {
+		err := s.listenOn(listenAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *jsonRPCServer) listenOn(listenAddr string) error {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return errors.Wrapf(err, "JSON-RPC error listening on %s", listenAddr)
+	}
+
+	spawn("jsonRPCServer.listenOn-Serve", func() {
+		err := s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panics.Exit(log, fmt.Sprintf("error serving JSON-RPC on %s: %+v", listenAddr, err))
+		}
+	})
+
+	log.Infof("JSON-RPC Server listening on %s", listener.Addr())
+	return nil
+}
+
+func (s *jsonRPCServer) Stop() error {
+	const stopTimeout = 2 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		log.Warnf("Could not gracefully stop the JSON-RPC server: %s", err)
+		s.httpServer.Close()
+	}
+
+	// WebSocket connections are hijacked from the HTTP server, so they have to be closed separately
+	s.connectionsLock.Lock()
+	connections := make([]*jsonRPCConnection, 0, len(s.connections))
+	for connection := range s.connections {
+		connections = append(connections, connection)
+	}
+	s.connectionsLock.Unlock()
+	for _, connection := range connections {
+		connection.Disconnect()
+	}
+
+	return nil
+}
+
+// SetOnConnectedHandler sets the client connected handler
+// function for the server
+func (s *jsonRPCServer) SetOnConnectedHandler(onConnectedHandler server.OnConnectedHandler) {
+	s.onConnectedHandler = onConnectedHandler
+}
+
+// ServeHTTP dispatches WebSocket upgrade requests to the WebSocket server
+// and serves everything else as JSON-RPC over HTTP POST
+func (s *jsonRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer panics.HandlePanic(log, "jsonRPCServer.ServeHTTP", nil)
+
+	origin := r.Header.Get("Origin")
+	if !s.isOriginAllowed(origin) {
+		log.Debugf("Rejected JSON-RPC request from %s with origin %s", r.RemoteAddr, origin)
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		connectionCount, ok := s.incrementCount(&s.webSocketCount, s.maxWebSockets)
+		if !ok {
+			log.Warnf("Limit of %d JSON-RPC WebSocket connections has been exceeded", s.maxWebSockets)
+			http.Error(w, "too many WebSocket connections", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.decrementCount(&s.webSocketCount)
+
+		log.Infof("JSON-RPC Incoming WebSocket connection from %s #%d", r.RemoteAddr, connectionCount)
+		s.webSocketServer.ServeHTTP(w, r)
+		return
+	}
+
+	if origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", http.MethodPost)
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests and WebSocket connections are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, ok := s.incrementCount(&s.httpRequestCount, s.maxConcurrentHTTPRequests)
+	if !ok {
+		log.Warnf("Limit of %d concurrent JSON-RPC HTTP requests has been exceeded", s.maxConcurrentHTTPRequests)
+		http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.decrementCount(&s.httpRequestCount)
+
+	s.handleHTTPRequest(w, r)
+}
+
+func (s *jsonRPCServer) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxMessageSize))
+	if err != nil {
+		http.Error(w, "could not read request body", http.StatusBadRequest)
+		return
+	}
+
+	address, err := remoteTCPAddress(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Exactly one response is written per request, so it never blocks
+	responseChan := make(chan []byte, 1)
+	connection := newConnection(s, address, false, func(data []byte) error {
+		responseChan <- data
+		return nil
+	})
+	err = s.onConnectedHandler(connection)
+	if err != nil {
+		log.Warnf("Error handling JSON-RPC HTTP request from %s: %s", address, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer connection.Disconnect()
+
+	log.Debugf("JSON-RPC Incoming HTTP request from %s", address)
+
+	err = connection.handleRequest(data)
+	if err != nil {
+		log.Warnf("Error handling JSON-RPC HTTP request from %s: %s", address, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case response := <-responseChan:
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(response)
+		if err != nil {
+			log.Debugf("Error writing JSON-RPC response to %s: %s", address, err)
+		}
+	case <-connection.stopChan:
+		http.Error(w, "the request could not be handled", http.StatusInternalServerError)
+	case <-r.Context().Done():
+	}
+}
+
+func (s *jsonRPCServer) handleWebSocket(ws *websocket.Conn) {
+	defer panics.HandlePanic(log, "jsonRPCServer.handleWebSocket", nil)
+
+	address, err := remoteTCPAddress(ws.Request())
+	if err != nil {
+		log.Warnf("Error handling JSON-RPC WebSocket connection: %s", err)
+		return
+	}
+
+	ws.MaxPayloadBytes = MaxMessageSize
+	connection := newConnection(s, address, true, func(data []byte) error {
+		if data == nil {
+			return nil
+		}
+		return websocket.Message.Send(ws, string(data))
+	})
+
+	s.addConnection(connection)
+	defer s.removeConnection(connection)
+
+	err = s.onConnectedHandler(connection)
+	if err != nil {
+		log.Warnf("Error handling JSON-RPC WebSocket connection from %s: %s", address, err)
+		return
+	}
+
+	spawn("jsonRPCServer.handleWebSocket-receiveLoop", func() {
+		defer connection.Disconnect()
+
+		for connection.IsConnected() {
+			var data []byte
+			err := websocket.Message.Receive(ws, &data)
+			if err != nil {
+				if !errors.Is(err, io.EOF) && connection.IsConnected() {
+					log.Debugf("Error receiving from JSON-RPC WebSocket %s: %s", address, err)
+				}
+				return
+			}
+			err = connection.handleRequest(data)
+			if err != nil {
+				log.Warnf("Error handling JSON-RPC request from %s: %s", address, err)
+				return
+			}
+		}
+	})
+
+	// The WebSocket is closed once this function returns
+	<-connection.stopChan
+}
+
+func (s *jsonRPCServer) isOriginAllowed(origin string) bool {
+	// Non-browser clients don't send an Origin header
+	if origin == "" {
+		return true
+	}
+	for _, allowedOrigin := range s.allowedOrigins {
+		if allowedOrigin == "*" || strings.EqualFold(allowedOrigin, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *jsonRPCServer) incrementCount(count *int, max int) (int, bool) {
+	s.countLock.Lock()
+	defer s.countLock.Unlock()
+
+	if max > 0 && *count >= max {
+		return *count, false
+	}
+	*count++
+	return *count, true
+}
+
+func (s *jsonRPCServer) decrementCount(count *int) {
+	s.countLock.Lock()
+	defer s.countLock.Unlock()
+
+	*count--
+}
+
+func (s *jsonRPCServer) addConnection(connection *jsonRPCConnection) {
+	s.connectionsLock.Lock()
+	defer s.connectionsLock.Unlock()
+
+	s.connections[connection] = struct{}{}
+}
+
+func (s *jsonRPCServer) removeConnection(connection *jsonRPCConnection) {
+	s.connectionsLock.Lock()
+	defer s.connectionsLock.Unlock()
+
+	delete(s.connections, connection)
+}
+
+func remoteTCPAddress(r *http.Request) (*net.TCPAddr, error) {
+	address, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid remote address %s", r.RemoteAddr)
+	}
+	return address, nil
+}
@@ -0,0 +1,284 @@
+package jsonrpcserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kobradag/kobrad/app/appmessage"
+	routerpkg "github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/server"
+	"golang.org/x/net/websocket"
+)
+
+const testOrigin = "http://localhost"
+
+// setupTestServer starts a JSON-RPC server whose connections are served by a
+// minimal set of RPC handlers
+func setupTestServer(t *testing.T) (httpServer *httptest.Server, teardown func()) {
+	testServer, err := NewJSONRPCServer(nil, []string{testOrigin}, 1, 0)
+	if err != nil {
+		t.Fatalf("NewJSONRPCServer: %s", err)
+	}
+	testServer.SetOnConnectedHandler(func(connection server.Connection) error {
+		router := routerpkg.NewRouter("test")
+		incomingRoute, err := router.AddIncomingRoute("test", []appmessage.MessageCommand{
+			appmessage.CmdGetCurrentNetworkRequestMessage,
+			appmessage.CmdGetBlockDAGInfoRequestMessage,
+			appmessage.CmdNotifyVirtualDaaScoreChangedRequestMessage,
+		})
+		if err != nil {
+			return err
+		}
+		connection.SetOnDisconnectedHandler(router.Close)
+		connection.Start(router)
+
+		go func() {
+			for {
+				request, err := incomingRoute.Dequeue()
+				if err != nil {
+					return
+				}
+				var responses []appmessage.Message
+				switch request.Command() {
+				case appmessage.CmdGetCurrentNetworkRequestMessage:
+					responses = append(responses, appmessage.NewGetCurrentNetworkResponseMessage("kobra-simnet"))
+				case appmessage.CmdGetBlockDAGInfoRequestMessage:
+					response := appmessage.NewGetBlockDAGInfoResponseMessage()
+					response.Error = appmessage.RPCErrorf("test error")
+					responses = append(responses, response)
+				case appmessage.CmdNotifyVirtualDaaScoreChangedRequestMessage:
+					responses = append(responses, appmessage.NewNotifyVirtualDaaScoreChangedResponseMessage(),
+						appmessage.NewVirtualDaaScoreChangedNotificationMessage(1234))
+				}
+				for _, response := range responses {
+					err := router.OutgoingRoute().Enqueue(response)
+					if err != nil {
+						return
+					}
+				}
+			}
+		}()
+		return nil
+	})
+
+	httpServer = httptest.NewServer(testServer.(*jsonRPCServer))
+	return httpServer, func() {
+		httpServer.Close()
+		err := testServer.Stop()
+		if err != nil {
+			t.Errorf("Stop: %s", err)
+		}
+	}
+}
+
+func postRequest(t *testing.T, url string, body string) (int, map[string]interface{}) {
+	response, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Post: %s", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %s", err)
+	}
+	if len(data) == 0 {
+		return response.StatusCode, nil
+	}
+	decoded := make(map[string]interface{})
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("Unexpected response %s: %s", data, err)
+	}
+	return response.StatusCode, decoded
+}
+
+func TestHTTPRequests(t *testing.T) {
+	httpServer, teardown := setupTestServer(t)
+	defer teardown()
+
+	tests := []struct {
+		name          string
+		request       string
+		expectedID    interface{}
+		expectedCode  float64
+		checkResponse func(t *testing.T, response map[string]interface{})
+	}{
+		{
+			name:       "valid request",
+			request:    `{"jsonrpc":"2.0","id":1,"method":"getCurrentNetwork"}`,
+			expectedID: float64(1),
+			checkResponse: func(t *testing.T, response map[string]interface{}) {
+				result, ok := response["result"].(map[string]interface{})
+				if !ok {
+					t.Fatalf("Unexpected result: %v", response)
+				}
+				if result["currentNetwork"] != "kobra-simnet" {
+					t.Fatalf("Unexpected currentNetwork: %v", result["currentNetwork"])
+				}
+				if _, ok := result["error"]; ok {
+					t.Fatalf("Unexpected error field in result: %v", result)
+				}
+			},
+		},
+		{
+			name:         "RPC error",
+			request:      `{"jsonrpc":"2.0","id":"a","method":"getBlockDagInfo","params":{}}`,
+			expectedID:   "a",
+			expectedCode: errorCodeRPCError,
+		},
+		{
+			name:         "unknown method",
+			request:      `{"jsonrpc":"2.0","id":2,"method":"getSomethingElse"}`,
+			expectedID:   float64(2),
+			expectedCode: errorCodeMethodNotFound,
+		},
+		{
+			name:         "p2p message",
+			request:      `{"jsonrpc":"2.0","id":3,"method":"ibdBlockLocator","params":{}}`,
+			expectedID:   float64(3),
+			expectedCode: errorCodeMethodNotFound,
+		},
+		{
+			name:         "invalid params",
+			request:      `{"jsonrpc":"2.0","id":4,"method":"getCurrentNetwork","params":[1]}`,
+			expectedID:   float64(4),
+			expectedCode: errorCodeInvalidParams,
+		},
+		{
+			name:         "subscription over HTTP",
+			request:      `{"jsonrpc":"2.0","id":5,"method":"notifyVirtualDaaScoreChanged"}`,
+			expectedID:   float64(5),
+			expectedCode: errorCodeInvalidRequest,
+		},
+		{
+			name:         "wrong version",
+			request:      `{"jsonrpc":"1.0","id":6,"method":"getCurrentNetwork"}`,
+			expectedID:   float64(6),
+			expectedCode: errorCodeInvalidRequest,
+		},
+		{
+			name:         "parse error",
+			request:      `{"jsonrpc":`,
+			expectedID:   nil,
+			expectedCode: errorCodeParseError,
+		},
+	}
+
+	for _, test := range tests {
+		statusCode, response := postRequest(t, httpServer.URL, test.request)
+		if statusCode != http.StatusOK {
+			t.Fatalf("%s: unexpected status code %d", test.name, statusCode)
+		}
+		if response["jsonrpc"] != jsonRPCVersion {
+			t.Fatalf("%s: unexpected jsonrpc %v", test.name, response["jsonrpc"])
+		}
+		if response["id"] != test.expectedID {
+			t.Fatalf("%s: expected id %v but got %v", test.name, test.expectedID, response["id"])
+		}
+		if test.expectedCode != 0 {
+			responseErr, ok := response["error"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("%s: expected an error but got %v", test.name, response)
+			}
+			if responseErr["code"] != test.expectedCode {
+				t.Fatalf("%s: expected error code %v but got %v", test.name, test.expectedCode, responseErr["code"])
+			}
+			continue
+		}
+		test.checkResponse(t, response)
+	}
+
+	// Notifications are handled without a response
+	statusCode, response := postRequest(t, httpServer.URL, `{"jsonrpc":"2.0","method":"getCurrentNetwork"}`)
+	if statusCode != http.StatusNoContent || response != nil {
+		t.Fatalf("Unexpected response to a notification: %d %v", statusCode, response)
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	httpServer, teardown := setupTestServer(t)
+	defer teardown()
+
+	for origin, expectedStatusCode := range map[string]int{
+		testOrigin:            http.StatusOK,
+		"http://evil.example": http.StatusForbidden,
+	} {
+		request, err := http.NewRequest(http.MethodPost, httpServer.URL,
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"getCurrentNetwork"}`))
+		if err != nil {
+			t.Fatalf("NewRequest: %s", err)
+		}
+		request.Header.Set("Origin", origin)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Do: %s", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != expectedStatusCode {
+			t.Fatalf("Origin %s: expected status code %d but got %d", origin, expectedStatusCode, response.StatusCode)
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	httpServer, teardown := setupTestServer(t)
+	defer teardown()
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	ws, err := websocket.Dial(url, "", testOrigin)
+	if err != nil {
+		t.Fatalf("Dial: %s", err)
+	}
+	defer ws.Close()
+
+	// The server is limited to a single WebSocket connection
+	_, err = websocket.Dial(url, "", testOrigin)
+	if err == nil {
+		t.Fatalf("Dial unexpectedly succeeded above the WebSocket limit")
+	}
+
+	receive := func() map[string]interface{} {
+		err := ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err != nil {
+			t.Fatalf("SetReadDeadline: %s", err)
+		}
+		message := make(map[string]interface{})
+		err = websocket.JSON.Receive(ws, &message)
+		if err != nil {
+			t.Fatalf("Receive: %s", err)
+		}
+		return message
+	}
+
+	err = websocket.Message.Send(ws, `{"jsonrpc":"2.0","id":1,"method":"notifyVirtualDaaScoreChanged"}`)
+	if err != nil {
+		t.Fatalf("Send: %s", err)
+	}
+	response := receive()
+	if response["id"] != float64(1) || response["result"] == nil {
+		t.Fatalf("Unexpected response: %v", response)
+	}
+	notification := receive()
+	if notification["method"] != "virtualDaaScoreChangedNotification" {
+		t.Fatalf("Unexpected notification: %v", notification)
+	}
+	params, ok := notification["params"].(map[string]interface{})
+	if !ok || params["virtualDaaScore"] != "1234" {
+		t.Fatalf("Unexpected notification params: %v", notification["params"])
+	}
+
+	err = websocket.Message.Send(ws, `{"jsonrpc":"2.0","id":2,"method":"getCurrentNetwork"}`)
+	if err != nil {
+		t.Fatalf("Send: %s", err)
+	}
+	response = receive()
+	if response["id"] != float64(2) {
+		t.Fatalf("Unexpected response: %v", response)
+	}
+}
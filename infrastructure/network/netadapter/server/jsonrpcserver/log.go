@@ -0,0 +1,9 @@
+package jsonrpcserver
+
+import (
+	"github.com/kobradag/kobrad/infrastructure/logger"
+	"github.com/kobradag/kobrad/util/panics"
+)
+
+var log = logger.RegisterSubSystem("JSRP")
+var spawn = panics.GoroutineWrapperFunc(log)
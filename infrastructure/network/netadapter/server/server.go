@@ -1,9 +1,13 @@
 package server
 
 import (
+	"crypto/ed25519"
 	"fmt"
 	"net"
+	"time"
 
+	"github.com/kobradag/kobrad/app/appmessage"
+	"github.com/kobradag/kobrad/infrastructure/network/netadapter/bandwidth"
 	"github.com/kobradag/kobrad/infrastructure/network/netadapter/router"
 )
 
@@ -11,6 +15,10 @@ import (
 // once a new Connection is successfully established.
 type OnConnectedHandler func(connection Connection) error
 
+// DialFunc is a function that connects to the given address on the given network,
+// such as net.DialTimeout
+type DialFunc func(network, address string, timeout time.Duration) (net.Conn, error)
+
 // OnDisconnectedHandler is a function that is to be
 // called once a Connection has been disconnected.
 type OnDisconnectedHandler func()
@@ -31,6 +39,8 @@ type Server interface {
 type P2PServer interface {
 	Server
 	Connect(address string) (Connection, error)
+	BandwidthCounter() *bandwidth.Counter
+	SetUploadRateLimits(uploadRateLimit uint64, peerUploadRateLimit uint64)
 }
 
 // Connection represents a server connection.
@@ -43,4 +53,7 @@ type Connection interface {
 	SetOnDisconnectedHandler(onDisconnectedHandler OnDisconnectedHandler)
 	SetOnInvalidMessageHandler(onInvalidMessageHandler OnInvalidMessageHandler)
 	Address() *net.TCPAddr
+	NetAddress() *appmessage.NetAddress
+	IdentityKey() ed25519.PublicKey
+	BandwidthCounter() *bandwidth.Counter
 }
@@ -0,0 +1,20 @@
+package rpcclient
+
+import "github.com/kobradag/kobrad/app/appmessage"
+
+// ExportPruningPointSnapshot sends an RPC request respective to the function's name and returns the RPC server's response
+func (c *RPCClient) ExportPruningPointSnapshot(path string) (*appmessage.ExportPruningPointSnapshotResponseMessage, error) {
+	err := c.rpcRouter.outgoingRoute().Enqueue(appmessage.NewExportPruningPointSnapshotRequestMessage(path))
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.route(appmessage.CmdExportPruningPointSnapshotResponseMessage).DequeueWithTimeout(c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	exportPruningPointSnapshotResponse := response.(*appmessage.ExportPruningPointSnapshotResponseMessage)
+	if exportPruningPointSnapshotResponse.Error != nil {
+		return nil, c.convertRPCError(exportPruningPointSnapshotResponse.Error)
+	}
+	return exportPruningPointSnapshotResponse, nil
+}
@@ -0,0 +1,20 @@
+package rpcclient
+
+import "github.com/kobradag/kobrad/app/appmessage"
+
+// SaveMempool sends an RPC request respective to the function's name and returns the RPC server's response
+func (c *RPCClient) SaveMempool() (*appmessage.SaveMempoolResponseMessage, error) {
+	err := c.rpcRouter.outgoingRoute().Enqueue(appmessage.NewSaveMempoolRequestMessage())
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.route(appmessage.CmdSaveMempoolResponseMessage).DequeueWithTimeout(c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	saveMempoolResponse := response.(*appmessage.SaveMempoolResponseMessage)
+	if saveMempoolResponse.Error != nil {
+		return nil, c.convertRPCError(saveMempoolResponse.Error)
+	}
+	return saveMempoolResponse, nil
+}
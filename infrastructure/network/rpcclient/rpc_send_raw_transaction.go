@@ -21,3 +21,24 @@ func (c *RPCClient) SubmitTransaction(transaction *appmessage.RPCTransaction, al
 
 	return submitTransactionResponse, nil
 }
+
+// SubmitTransactionReplacement sends an RPC request respective to the function's name and returns the RPC server's response.
+// The submitted transaction may replace the mempool transactions it conflicts with by paying a higher fee
+func (c *RPCClient) SubmitTransactionReplacement(transaction *appmessage.RPCTransaction, allowOrphan bool) (*appmessage.SubmitTransactionResponseMessage, error) {
+	request := appmessage.NewSubmitTransactionRequestMessage(transaction, allowOrphan)
+	request.AllowReplacement = true
+	err := c.rpcRouter.outgoingRoute().Enqueue(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.route(appmessage.CmdSubmitTransactionResponseMessage).DequeueWithTimeout(c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	submitTransactionResponse := response.(*appmessage.SubmitTransactionResponseMessage)
+	if submitTransactionResponse.Error != nil {
+		return nil, c.convertRPCError(submitTransactionResponse.Error)
+	}
+
+	return submitTransactionResponse, nil
+}
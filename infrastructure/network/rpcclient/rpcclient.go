@@ -15,6 +15,11 @@ import (
 
 const defaultTimeout = 30 * time.Second
 
+// OnReconnectedHandler is a function that is triggered when the RPC client reconnects.
+// Notification registrations don't survive reconnecting, so clients that rely on them
+// use it to register again.
+type OnReconnectedHandler func()
+
 // RPCClient is an RPC client
 type RPCClient struct {
 	*grpcclient.GRPCClient
@@ -25,6 +30,7 @@ type RPCClient struct {
 	isClosed             uint32
 	isReconnecting       uint32
 	lastDisconnectedTime time.Time
+	onReconnectedHandler OnReconnectedHandler
 
 	timeout time.Duration
 }
@@ -118,6 +124,9 @@ func (c *RPCClient) Reconnect() error {
 		if time.Since(c.lastDisconnectedTime) > retryDelay {
 			err := c.connect()
 			if err == nil {
+				if c.onReconnectedHandler != nil {
+					c.onReconnectedHandler()
+				}
 				return nil
 			}
 			log.Warnf("Could not automatically reconnect to %s: %s", c.rpcAddress, err)
@@ -150,6 +159,12 @@ func (c *RPCClient) handleClientError(err error) {
 	c.handleClientDisconnected()
 }
 
+// SetOnReconnectedHandler sets the handler function to be called after the client
+// reconnects
+func (c *RPCClient) SetOnReconnectedHandler(onReconnectedHandler OnReconnectedHandler) {
+	c.onReconnectedHandler = onReconnectedHandler
+}
+
 // SetTimeout sets the timeout by which to wait for RPC responses
 func (c *RPCClient) SetTimeout(timeout time.Duration) {
 	c.timeout = timeout
@@ -0,0 +1,230 @@
+// Package torcontrol implements the parts of the Tor control protocol that are needed
+// to create an onion service, through which the node accepts incoming connections over Tor.
+// See https://spec.torproject.org/control-spec for the protocol.
+package torcontrol
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+
+	statusOK = 250
+
+	safeCookieServerToControllerKey = "Tor safe cookie authentication server-to-controller hash"
+	safeCookieControllerToServerKey = "Tor safe cookie authentication controller-to-server hash"
+	safeCookieNonceSize             = 32
+)
+
+// Controller is a connection to the control port of a Tor daemon. Onion services that
+// are created through a Controller are removed by Tor once it's closed.
+type Controller struct {
+	connection *textproto.Conn
+}
+
+// Connect connects to the Tor control port at the given address, and authenticates with
+// the given password if it isn't empty, or otherwise with the authentication cookie.
+func Connect(address string, password string) (*Controller, error) {
+	connection, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not connect to the Tor control port at %s", address)
+	}
+	controller := &Controller{connection: textproto.NewConn(connection)}
+
+	err = controller.authenticate(password)
+	if err != nil {
+		controller.Close()
+		return nil, err
+	}
+	return controller, nil
+}
+
+// Close closes the connection to the control port
+func (c *Controller) Close() error {
+	return c.connection.Close()
+}
+
+// AddOnion creates an onion service that forwards connections to virtualPort to the given
+// target address. If privateKey is empty a new key is generated, otherwise it's a key that
+// was previously returned by AddOnion, in the form "ED25519-V3:<base64 key>". AddOnion
+// returns the host name of the onion service and its private key.
+func (c *Controller) AddOnion(privateKey string, virtualPort uint16, target string) (host string, key string, err error) {
+	keyArgument := privateKey
+	if keyArgument == "" {
+		keyArgument = "NEW:ED25519-V3"
+	}
+	lines, err := c.command("ADD_ONION %s Port=%d,%s", keyArgument, virtualPort, target)
+	if err != nil {
+		return "", "", err
+	}
+
+	key = privateKey
+	var serviceID string
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "ServiceID":
+			serviceID = value
+		case "PrivateKey":
+			key = value
+		}
+	}
+	if serviceID == "" {
+		return "", "", errors.Errorf("ADD_ONION reply is missing the service ID")
+	}
+	return serviceID + ".onion", key, nil
+}
+
+// command sends a command to the control port and returns the lines of its reply
+func (c *Controller) command(format string, args ...interface{}) ([]string, error) {
+	id, err := c.connection.Cmd(format, args...)
+	if err != nil {
+		return nil, err
+	}
+	c.connection.StartResponse(id)
+	defer c.connection.EndResponse(id)
+
+	_, message, err := c.connection.ReadResponse(statusOK)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Tor control command %s failed", strings.Fields(format)[0])
+	}
+	return strings.Split(message, "\n"), nil
+}
+
+func (c *Controller) authenticate(password string) error {
+	methods, cookieFile, err := c.protocolInfo()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case password != "" && methods["HASHEDPASSWORD"]:
+		_, err = c.command("AUTHENTICATE %s", quote(password))
+	case methods["NULL"]:
+		_, err = c.command("AUTHENTICATE")
+	case methods["SAFECOOKIE"]:
+		err = c.authenticateWithSafeCookie(cookieFile)
+	case methods["COOKIE"]:
+		var cookie []byte
+		cookie, err = os.ReadFile(cookieFile)
+		if err != nil {
+			return errors.Wrapf(err, "could not read the Tor authentication cookie")
+		}
+		_, err = c.command("AUTHENTICATE %s", hex.EncodeToString(cookie))
+	case methods["HASHEDPASSWORD"]:
+		return errors.Errorf("the Tor control port requires a password, which can be set with --torpassword")
+	default:
+		return errors.Errorf("the Tor control port doesn't support any known authentication method")
+	}
+	return err
+}
+
+// protocolInfo returns the authentication methods the control port supports, and the path
+// of its authentication cookie file
+func (c *Controller) protocolInfo() (methods map[string]bool, cookieFile string, err error) {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return nil, "", err
+	}
+
+	methods = make(map[string]bool)
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "AUTH ") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, "METHODS=") {
+				for _, method := range strings.Split(strings.TrimPrefix(field, "METHODS="), ",") {
+					methods[method] = true
+				}
+			}
+		}
+		// The cookie file is a quoted string, which may contain spaces
+		if _, value, ok := strings.Cut(line, " COOKIEFILE="); ok {
+			quotedCookieFile, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, "", errors.Wrapf(err, "invalid cookie file %s", value)
+			}
+			cookieFile, err = strconv.Unquote(quotedCookieFile)
+			if err != nil {
+				return nil, "", errors.Wrapf(err, "invalid cookie file %s", value)
+			}
+		}
+	}
+	return methods, cookieFile, nil
+}
+
+// authenticateWithSafeCookie proves knowledge of the authentication cookie without sending
+// it, so that it isn't leaked to a process that impersonates the control port
+func (c *Controller) authenticateWithSafeCookie(cookieFile string) error {
+	cookie, err := os.ReadFile(cookieFile)
+	if err != nil {
+		return errors.Wrapf(err, "could not read the Tor authentication cookie")
+	}
+
+	clientNonce := make([]byte, safeCookieNonceSize)
+	_, err = rand.Read(clientNonce)
+	if err != nil {
+		return err
+	}
+	lines, err := c.command("AUTHCHALLENGE SAFECOOKIE %s", hex.EncodeToString(clientNonce))
+	if err != nil {
+		return err
+	}
+
+	var serverHash, serverNonce []byte
+	for _, field := range strings.Fields(strings.Join(lines, " ")) {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "SERVERHASH":
+			serverHash, err = hex.DecodeString(value)
+		case "SERVERNONCE":
+			serverNonce, err = hex.DecodeString(value)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "invalid AUTHCHALLENGE reply")
+		}
+	}
+
+	message := make([]byte, 0, len(cookie)+len(clientNonce)+len(serverNonce))
+	message = append(message, cookie...)
+	message = append(message, clientNonce...)
+	message = append(message, serverNonce...)
+	if !hmac.Equal(serverHash, safeCookieHash(safeCookieServerToControllerKey, message)) {
+		return errors.Errorf("the Tor control port doesn't know the authentication cookie")
+	}
+
+	_, err = c.command("AUTHENTICATE %s", hex.EncodeToString(safeCookieHash(safeCookieControllerToServerKey, message)))
+	return err
+}
+
+func safeCookieHash(key string, message []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// quote returns s as a quoted string of the control protocol
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,145 @@
+package torcontrol
+
+import (
+	"encoding/hex"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTor serves a single control connection, replying to every command with the reply
+// that handleCommand returns for it
+func fakeTor(t *testing.T, handleCommand func(command string) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		connection, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		textConnection := textproto.NewConn(connection)
+		for {
+			command, err := textConnection.ReadLine()
+			if err != nil {
+				return
+			}
+			err = textConnection.PrintfLine("%s", handleCommand(command))
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestAddOnionWithPassword(t *testing.T) {
+	// The commands are recorded by the fake server's goroutine, so they're guarded by a lock
+	var commandsLock sync.Mutex
+	var commands []string
+	address := fakeTor(t, func(command string) string {
+		commandsLock.Lock()
+		commands = append(commands, command)
+		commandsLock.Unlock()
+		switch {
+		case command == "PROTOCOLINFO 1":
+			return "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=HASHEDPASSWORD\r\n250-VERSION Tor=\"0.4.8.9\"\r\n250 OK"
+		case command == `AUTHENTICATE "pass\"word"`:
+			return "250 OK"
+		case strings.HasPrefix(command, "ADD_ONION NEW:ED25519-V3 Port=25111,127.0.0.1:25111"):
+			return "250-ServiceID=duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad\r\n" +
+				"250-PrivateKey=ED25519-V3:c2VjcmV0\r\n250 OK"
+		}
+		return "510 Unrecognized command"
+	})
+
+	controller, err := Connect(address, `pass"word`)
+	if err != nil {
+		t.Fatalf("Connect: %s", err)
+	}
+	defer controller.Close()
+
+	host, key, err := controller.AddOnion("", 25111, "127.0.0.1:25111")
+	if err != nil {
+		commandsLock.Lock()
+		defer commandsLock.Unlock()
+		t.Fatalf("AddOnion: %s (commands: %q)", err, commands)
+	}
+	if host != "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion" {
+		t.Errorf("AddOnion: unexpected host %s", host)
+	}
+	if key != "ED25519-V3:c2VjcmV0" {
+		t.Errorf("AddOnion: unexpected key %s", key)
+	}
+}
+
+func TestConnectWithSafeCookie(t *testing.T) {
+	cookie := []byte("0123456789abcdef0123456789abcdef")
+	cookieFile := filepath.Join(t.TempDir(), "control auth cookie")
+	err := os.WriteFile(cookieFile, cookie, 0600)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	serverNonce := []byte("server nonce")
+
+	var clientNonce []byte
+	var authenticatedLock sync.Mutex
+	authenticated := false
+	address := fakeTor(t, func(command string) string {
+		fields := strings.Fields(command)
+		switch fields[0] {
+		case "PROTOCOLINFO":
+			return "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=COOKIE,SAFECOOKIE COOKIEFILE=\"" + cookieFile +
+				"\"\r\n250 OK"
+		case "AUTHCHALLENGE":
+			clientNonce, _ = hex.DecodeString(fields[2])
+			message := append(append(append([]byte{}, cookie...), clientNonce...), serverNonce...)
+			return "250 AUTHCHALLENGE SERVERHASH=" +
+				hex.EncodeToString(safeCookieHash(safeCookieServerToControllerKey, message)) +
+				" SERVERNONCE=" + hex.EncodeToString(serverNonce)
+		case "AUTHENTICATE":
+			message := append(append(append([]byte{}, cookie...), clientNonce...), serverNonce...)
+			if fields[1] != hex.EncodeToString(safeCookieHash(safeCookieControllerToServerKey, message)) {
+				return "515 Authentication failed"
+			}
+			authenticatedLock.Lock()
+			authenticated = true
+			authenticatedLock.Unlock()
+			return "250 OK"
+		}
+		return "510 Unrecognized command"
+	})
+
+	controller, err := Connect(address, "")
+	if err != nil {
+		t.Fatalf("Connect: %s", err)
+	}
+	defer controller.Close()
+	authenticatedLock.Lock()
+	defer authenticatedLock.Unlock()
+	if !authenticated {
+		t.Fatalf("Connect: didn't authenticate")
+	}
+}
+
+func TestConnectRequiresPassword(t *testing.T) {
+	address := fakeTor(t, func(command string) string {
+		if command == "PROTOCOLINFO 1" {
+			return "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=HASHEDPASSWORD\r\n250 OK"
+		}
+		return "510 Unrecognized command"
+	})
+
+	_, err := Connect(address, "")
+	if err == nil || !strings.Contains(err.Error(), "--torpassword") {
+		t.Fatalf("Connect: expected an error about the missing password, got %v", err)
+	}
+}